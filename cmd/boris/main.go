@@ -1,19 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -69,18 +77,54 @@ type CLI struct {
 	Port        int         `help:"Listen port (HTTP mode)." default:"8080" env:"BORIS_PORT"`
 	Transport   string      `help:"Transport: http or stdio." default:"http" enum:"http,stdio" env:"BORIS_TRANSPORT"`
 	Workdir     string      `help:"Initial working directory." default:"." env:"BORIS_WORKDIR"`
+	WorkdirPerSession bool  `help:"Start each HTTP session in its own fresh temporary directory, removed when the session ends." env:"BORIS_WORKDIR_PER_SESSION"`
+	SessionWorkdirBase string `help:"Parent directory for per-session workdirs (default: system temp dir)." env:"BORIS_SESSION_WORKDIR_BASE"`
 	Timeout     int         `help:"Default bash timeout in seconds." default:"120" env:"BORIS_TIMEOUT"`
+	Shell       string      `help:"Shell used to run bash commands, as a path or a name on PATH (default: /bin/bash if present, else /bin/sh)." env:"BORIS_SHELL"`
+	MaxBashTimeoutMs int    `name:"max-bash-timeout-ms" help:"Largest timeout a bash call may request, in milliseconds; longer requests are clamped." default:"600000" env:"BORIS_MAX_BASH_TIMEOUT_MS"`
 	AllowDir    []string    `help:"Allowed directories (repeatable)." env:"BORIS_ALLOW_DIRS"`
+	IncludeWorkdir bool     `help:"Add the working directory to the allowed directories when --allow-dir is set." default:"true" negatable:"" env:"BORIS_INCLUDE_WORKDIR"`
 	DenyDir     []string    `help:"Denied directories/patterns (repeatable)." env:"BORIS_DENY_DIRS"`
+	DenyWritePattern []string `help:"Patterns that file tools may read but not modify (repeatable)." env:"BORIS_DENY_WRITE_PATTERNS"`
+	ExcludeDir  []string    `help:"Directory names skipped by grep, glob, and view in addition to .git and node_modules (repeatable)." default:".venv,target,build,.next" env:"BORIS_EXCLUDE_DIRS"`
+	IgnoreFile  []string    `help:"Ignore files honored alongside .gitignore by grep, glob, and directory listings, with the same syntax (repeatable)." default:".ignore,.rgignore" env:"BORIS_IGNORE_FILES"`
 	Token           string      `help:"Bearer token for HTTP authentication." env:"BORIS_TOKEN"`
 	GenerateToken   bool        `help:"Generate a random bearer token on startup." env:"BORIS_GENERATE_TOKEN"`
 	DisableTools    []string    `help:"Tools to disable (repeatable)." env:"BORIS_DISABLE_TOOLS"`
+	EnableTools     []string    `help:"Tools to enable; all others are disabled (repeatable, exclusive with --disable-tools)." env:"BORIS_ENABLE_TOOLS"`
 	BackgroundTaskTimeout int   `help:"Background task safety-net timeout in seconds (0=disabled)." default:"0" env:"BORIS_BACKGROUND_TASK_TIMEOUT"`
+	EditDiffs       bool        `help:"Append a unified diff of each edit (previous content against new) to str_replace and create_file results." env:"BORIS_EDIT_DIFFS"`
+	SanitizeUTF8    bool        `name:"sanitize-utf8" help:"Replace invalid UTF-8 in tool output (e.g. binary bytes from bash or grep) with U+FFFD." default:"true" negatable:"" env:"BORIS_SANITIZE_UTF8"`
+	StructuredBashOutput bool   `help:"Attach a JSON result block (exit_code, stdout, stderr, ...) to bash and task_output responses." env:"BORIS_STRUCTURED_BASH_OUTPUT"`
+	SentinelWarning bool        `help:"Note in bash output when the working directory could not be tracked (timeout, exit, or shell error)." default:"true" negatable:"" env:"BORIS_SENTINEL_WARNING"`
+	MaxBackgroundTasks int      `help:"Max concurrent background tasks per session (0=disable background tasks)." default:"10" env:"BORIS_MAX_BACKGROUND_TASKS"`
+	BackgroundNiceness int      `help:"Niceness for background task process groups, -20 to 19 (0=unchanged; negative values need privileges)." default:"0" env:"BORIS_BACKGROUND_NICENESS"`
+	ToolTimeout     int         `help:"Per-call timeout in seconds for read-only tools; bash and file-writing tools are exempt (0=unlimited)." default:"0" env:"BORIS_TOOL_TIMEOUT"`
 	MaxFileSize     string      `help:"Max file size for view/create." default:"10MB" env:"BORIS_MAX_FILE_SIZE"`
+	MaxViewFileSize string      `help:"Max file size for view (default: --max-file-size)." env:"BORIS_MAX_VIEW_FILE_SIZE"`
+	MaxCreateFileSize string    `help:"Max content size for create_file (default: --max-file-size)." env:"BORIS_MAX_CREATE_FILE_SIZE"`
+	NormalizeLineEndings bool   `help:"Show CRLF/CR line endings as LF in view and grep output." default:"true" negatable:"" env:"BORIS_NORMALIZE_LINE_ENDINGS"`
+	FollowSymlinks  bool        `help:"Let view read through symlinks whose targets are within scope." default:"true" negatable:"" env:"BORIS_FOLLOW_SYMLINKS"`
+	MaxRequestBytes string      `help:"Max HTTP request body size (0=unlimited)." default:"32MB" env:"BORIS_MAX_REQUEST_BYTES"`
+	Gzip            bool        `help:"Gzip-compress HTTP /mcp responses of 1KB or more for clients that send Accept-Encoding: gzip." env:"BORIS_GZIP"`
+	DrainTimeout    int         `help:"Seconds to wait on shutdown for background tasks to finish before killing them." default:"0" env:"BORIS_DRAIN_TIMEOUT"`
+	StdioIdleTimeout int        `help:"Shut down a STDIO session after this many seconds without requests (0=never)." default:"0" env:"BORIS_STDIO_IDLE_TIMEOUT"`
+	SessionTimeout  time.Duration `help:"Close an HTTP session and kill its background tasks after this long without requests (e.g. 30m; 0=never)." default:"10m" env:"BORIS_SESSION_TIMEOUT"`
 	RequireViewBeforeEdit string `help:"Require files to be viewed before editing: auto, true, false." default:"auto" enum:"auto,true,false" env:"BORIS_REQUIRE_VIEW_BEFORE_EDIT"`
 	AnthropicCompat bool        `help:"Expose combined str_replace_editor tool schema." env:"BORIS_ANTHROPIC_COMPAT"`
 	LogLevel        string      `help:"Log level: debug, info, warn, error." default:"info" enum:"debug,info,warn,error" env:"BORIS_LOG_LEVEL"`
 	LogFormat       string      `help:"Log format: text or json." default:"text" enum:"text,json" env:"BORIS_LOG_FORMAT"`
+	AuditLog        string      `help:"Append a JSON line per tool call to this file." env:"BORIS_AUDIT_LOG"`
+	GrepCache       bool        `help:"Cache grep results per session until a searched file's mtime changes." env:"BORIS_GREP_CACHE"`
+	ExposeResources bool        `help:"Expose workspace files as MCP resources." env:"BORIS_EXPOSE_RESOURCES"`
+	ReadOnly        bool        `name:"readonly" help:"Disable bash, task_output, and all tools that modify files." env:"BORIS_READONLY"`
+	RequireEditToken bool       `help:"Require search_replace_files to present an edit_token from a prior grep of the same query." env:"BORIS_REQUIRE_EDIT_TOKEN"`
+	AllowGrepInPlace bool       `help:"Let grep write its replace substitution to files when in_place is set." env:"BORIS_ALLOW_GREP_IN_PLACE"`
+	ProgressBytes   bool        `help:"Report bytes of output so far as bash progress instead of a line count." env:"BORIS_PROGRESS_BYTES"`
+	ProgressStreamPrefix bool   `help:"Prefix bash progress messages with the stream name (stdout or stderr)." env:"BORIS_PROGRESS_STREAM_PREFIX"`
+	DisableProgress bool        `help:"Never send progress notifications, for clients that do not support them." env:"BORIS_DISABLE_PROGRESS"`
+	ToolDescriptions string     `help:"JSON file mapping tool names to replacement descriptions." env:"BORIS_TOOL_DESCRIPTIONS"`
+	InstructionsFile string     `help:"Template file for the MCP server instructions; {{workdir}}, {{allow_dirs}}, {{deny_patterns}} and {{default}} are substituted." env:"BORIS_INSTRUCTIONS_FILE"`
 }
 
 // Validate is called by kong after parsing to enforce flag constraints.
@@ -88,6 +132,21 @@ func (c *CLI) Validate() error {
 	if c.Token != "" && c.GenerateToken {
 		return fmt.Errorf("--token and --generate-token are mutually exclusive")
 	}
+	if len(c.DisableTools) > 0 && len(c.EnableTools) > 0 {
+		return fmt.Errorf("--enable-tools and --disable-tools are mutually exclusive")
+	}
+	if c.MaxBackgroundTasks < 0 {
+		return fmt.Errorf("--max-background-tasks must not be negative")
+	}
+	if c.BackgroundNiceness < -20 || c.BackgroundNiceness > 19 {
+		return fmt.Errorf("--background-niceness must be between -20 and 19")
+	}
+	if c.SessionTimeout < 0 {
+		return fmt.Errorf("--session-timeout must not be negative")
+	}
+	if c.MaxBashTimeoutMs < 0 {
+		return fmt.Errorf("--max-bash-timeout-ms must not be negative")
+	}
 	return nil
 }
 
@@ -100,6 +159,17 @@ type serverConfig struct {
 	impl       *mcp.Implementation
 	toolsCfg   tools.Config
 	serverOpts *mcp.ServerOptions
+
+	instructionsTemplate string // custom instructions template ("" = built-in)
+
+	maxRequestBytes int64         // HTTP request body limit (0 = unlimited)
+	gzip            bool          // gzip /mcp responses for clients that accept it
+	drainTimeout    time.Duration // shutdown wait for background tasks (0 = kill immediately)
+	sessionTimeout  time.Duration // idle time before an HTTP session is closed (0 = never)
+	stdioIdleTimeout time.Duration // STDIO session shuts down after this long without requests (0 = never)
+
+	workdirPerSession  bool   // HTTP sessions start in a fresh directory instead of workdir
+	sessionWorkdirBase string // parent of per-session directories ("" = os.TempDir())
 }
 
 // generateToken returns a cryptographically random 64-character hex string
@@ -112,10 +182,19 @@ func generateToken() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
+// newTokenPointer returns an atomic pointer holding token, suitable for
+// sharing between bearerAuthMiddleware and rotateTokenHandler.
+func newTokenPointer(token string) *atomic.Pointer[string] {
+	var p atomic.Pointer[string]
+	p.Store(&token)
+	return &p
+}
+
 // bearerAuthMiddleware returns middleware that requires a valid
-// Authorization: Bearer <token> header. Unauthenticated requests
-// receive a 401 JSON response.
-func bearerAuthMiddleware(token string, next http.Handler) http.Handler {
+// Authorization: Bearer <token> header. The expected token is loaded on
+// every request so that it can be rotated at runtime. Unauthenticated
+// requests receive a 401 JSON response.
+func bearerAuthMiddleware(token *atomic.Pointer[string], next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auth := r.Header.Get("Authorization")
 		const prefix = "Bearer "
@@ -128,7 +207,7 @@ func bearerAuthMiddleware(token string, next http.Handler) http.Handler {
 			return
 		}
 		provided := auth[len(prefix):]
-		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(*token.Load())) != 1 {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
 			if err := json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"}); err != nil {
@@ -140,6 +219,235 @@ func bearerAuthMiddleware(token string, next http.Handler) http.Handler {
 	})
 }
 
+// requestIDMiddleware returns middleware that assigns each HTTP request a
+// random correlation ID. The ID is attached to the request context, passed to
+// tool handlers via the tools.RequestIDHeader header (overwriting any
+// client-supplied value), and echoed in the X-Request-Id response header, so
+// that all log lines for one request share a request_id field.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id := hex.EncodeToString(b)
+		r = r.WithContext(tools.WithRequestID(r.Context(), id))
+		r.Header.Set(tools.RequestIDHeader, id)
+		w.Header().Set("X-Request-Id", id)
+		slog.Debug("http request", "request_id", id, "method", r.Method, "path", r.URL.Path,
+			"mcp_session_id", r.Header.Get("Mcp-Session-Id"))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// maxBytesMiddleware returns middleware that rejects request bodies larger
+// than limit bytes with a 413 JSON response. The body is buffered so that the
+// limit is enforced for chunked requests as well as those declaring a
+// Content-Length. A non-positive limit disables the check.
+func maxBytesMiddleware(limit int64, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tooLarge := r.ContentLength > limit
+		if !tooLarge && r.Body != nil && r.Body != http.NoBody {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if !errors.As(err, &maxErr) {
+					http.Error(w, "failed to read body", http.StatusBadRequest)
+					return
+				}
+				tooLarge = true
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if tooLarge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			if err := json.NewEncoder(w).Encode(map[string]string{"error": "request body too large"}); err != nil {
+				slog.Debug("failed to write request size error response", "error", err)
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// gzipMinBytes is the smallest response body gzipMiddleware compresses.
+// Smaller bodies are sent as-is, since gzip framing would outweigh savings.
+const gzipMinBytes = 1024
+
+// gzipMiddleware returns middleware that gzip-compresses responses for
+// clients sending Accept-Encoding: gzip. The body is buffered until minBytes
+// have been written, then compressed; responses that finish below minBytes
+// are sent uncompressed. A Flush decides with what has been buffered so far,
+// so an SSE stream, which flushes after every event, is compressed only if
+// its first event reaches minBytes. Responses that already set
+// Content-Encoding are passed through.
+func gzipMiddleware(minBytes int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, minBytes: minBytes}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		return !ok || strings.Trim(q, "0.") != ""
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// to compress it; see gzipMiddleware.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minBytes int
+	status   int    // status passed to WriteHeader before the decision
+	buf      []byte // body written before the decision
+	decided  bool
+	gz       *gzip.Writer // nil if the response is sent uncompressed
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minBytes {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide commits to compressing the response or not, sends the header, and
+// writes out the buffered body.
+func (w *gzipResponseWriter) decide(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if compress && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush applies the same size threshold as Write: streamed responses flush
+// after every event, and a small event must not commit the whole response to
+// compression.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(len(w.buf) >= w.minBytes)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipResponseWriter) close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
+
+// rotateTokenHandler generates a new bearer token, atomically replaces the
+// current one, and returns it as JSON. The old token stops working
+// immediately. It must be wrapped in bearerAuthMiddleware.
+func rotateTokenHandler(token *atomic.Pointer[string]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		newToken, err := generateToken()
+		if err != nil {
+			slog.Error("failed to generate token", "error", err)
+			http.Error(w, "failed to generate token", http.StatusInternalServerError)
+			return
+		}
+		token.Store(&newToken)
+		slog.Info("rotated bearer token")
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"token": newToken}); err != nil {
+			slog.Debug("failed to write rotate token response", "error", err)
+		}
+	})
+}
+
+// allowDirsWithWorkdir returns the allowed directories with workdir added
+// when include is set, so that the starting directory is usable by default.
+// An empty list allows every path and is returned unchanged.
+func allowDirsWithWorkdir(allowDirs []string, workdir string, include bool) []string {
+	if len(allowDirs) == 0 || !include {
+		return allowDirs
+	}
+	return append(slices.Clone(allowDirs), workdir)
+}
+
+// resolveShell returns the shell to run commands with: override if set,
+// which must name an executable file, or else /bin/bash if it exists and
+// /bin/sh otherwise.
+func resolveShell(override string) (string, error) {
+	if override != "" {
+		return exec.LookPath(override)
+	}
+	if _, err := os.Stat("/bin/bash"); err == nil {
+		return "/bin/bash", nil
+	}
+	return "/bin/sh", nil
+}
+
 // parseLogLevel converts a log level string to a slog.Level.
 func parseLogLevel(s string) (slog.Level, error) {
 	switch strings.ToLower(s) {
@@ -167,9 +475,27 @@ func buildInstructions(workdir string, resolver *pathscope.Resolver) string {
 	if patterns := resolver.DenyPatterns(); len(patterns) > 0 {
 		fmt.Fprintf(&b, "\nDenied patterns: %s", strings.Join(patterns, ", "))
 	}
+	if patterns := resolver.DenyWritePatterns(); len(patterns) > 0 {
+		fmt.Fprintf(&b, "\nRead-only patterns: %s", strings.Join(patterns, ", "))
+	}
 	return b.String()
 }
 
+// renderInstructions substitutes the workdir and path scoping configuration
+// into tmpl, falling back to buildInstructions when tmpl is empty.
+func renderInstructions(tmpl, workdir string, resolver *pathscope.Resolver) string {
+	if tmpl == "" {
+		return buildInstructions(workdir, resolver)
+	}
+	r := strings.NewReplacer(
+		"{{workdir}}", workdir,
+		"{{allow_dirs}}", strings.Join(resolver.AllowDirs(), ", "),
+		"{{deny_patterns}}", strings.Join(resolver.DenyPatterns(), ", "),
+		"{{default}}", buildInstructions(workdir, resolver),
+	)
+	return r.Replace(tmpl)
+}
+
 func main() {
 	var cli CLI
 	kong.Parse(&cli,
@@ -200,6 +526,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	var maxViewFileSize, maxCreateFileSize int64
+	if cli.MaxViewFileSize != "" {
+		if maxViewFileSize, err = parseSize(cli.MaxViewFileSize); err != nil {
+			slog.Error("invalid --max-view-file-size", "error", err)
+			os.Exit(1)
+		}
+	}
+	if cli.MaxCreateFileSize != "" {
+		if maxCreateFileSize, err = parseSize(cli.MaxCreateFileSize); err != nil {
+			slog.Error("invalid --max-create-file-size", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	maxRequestBytes, err := parseSize(cli.MaxRequestBytes)
+	if err != nil {
+		slog.Error("invalid --max-request-bytes", "error", err)
+		os.Exit(1)
+	}
+
 	// Resolve workdir
 	workdir, err := filepath.Abs(cli.Workdir)
 	if err != nil {
@@ -212,15 +558,35 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Detect shell
-	shell := "/bin/sh"
-	if _, err := os.Stat("/bin/bash"); err == nil {
-		shell = "/bin/bash"
+	sessionWorkdirBase := cli.SessionWorkdirBase
+	if cli.WorkdirPerSession && sessionWorkdirBase != "" {
+		sessionWorkdirBase, err = filepath.Abs(sessionWorkdirBase)
+		if err == nil {
+			var info os.FileInfo
+			if info, err = os.Stat(sessionWorkdirBase); err == nil && !info.IsDir() {
+				err = fmt.Errorf("%s is not a directory", sessionWorkdirBase)
+			}
+		}
+		if err != nil {
+			slog.Error("invalid --session-workdir-base", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	shell, err := resolveShell(cli.Shell)
+	if err != nil {
+		slog.Error("invalid --shell", "error", err)
+		os.Exit(1)
 	}
 	slog.Info("using shell", "shell", shell)
 
 	// Create path resolver
-	resolver, err := pathscope.NewResolver(cli.AllowDir, cli.DenyDir)
+	resolver, err := pathscope.NewResolver(allowDirsWithWorkdir(cli.AllowDir, workdir, cli.IncludeWorkdir), cli.DenyDir)
+	if err != nil {
+		slog.Error("invalid path scoping config", "error", err)
+		os.Exit(1)
+	}
+	resolver, err = resolver.WithDenyWritePatterns(cli.DenyWritePattern)
 	if err != nil {
 		slog.Error("invalid path scoping config", "error", err)
 		os.Exit(1)
@@ -236,6 +602,55 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Build EnableTools set from CLI flag; nil means every tool is enabled
+	var enableTools map[string]struct{}
+	if len(cli.EnableTools) > 0 {
+		enableTools = make(map[string]struct{}, len(cli.EnableTools))
+		for _, name := range cli.EnableTools {
+			enableTools[name] = struct{}{}
+		}
+		if err := tools.ValidateDisableTools(enableTools, cli.AnthropicCompat); err != nil {
+			slog.Error("invalid --enable-tools", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load tool description overrides
+	var toolDescriptions map[string]string
+	if cli.ToolDescriptions != "" {
+		toolDescriptions, err = loadToolDescriptions(cli.ToolDescriptions)
+		if err != nil {
+			slog.Error("invalid --tool-descriptions", "error", err)
+			os.Exit(1)
+		}
+		if err := tools.ValidateToolDescriptions(toolDescriptions, cli.AnthropicCompat); err != nil {
+			slog.Error("invalid --tool-descriptions", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load instructions template
+	var instructionsTemplate string
+	if cli.InstructionsFile != "" {
+		instructionsTemplate, err = loadInstructionsTemplate(cli.InstructionsFile)
+		if err != nil {
+			slog.Error("invalid --instructions-file", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Open audit log
+	var auditLog io.Writer
+	if cli.AuditLog != "" {
+		f, err := os.OpenFile(cli.AuditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			slog.Error("invalid --audit-log", "error", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		auditLog = f
+	}
+
 	// Resolve --require-view-before-edit: "auto" → true
 	requireViewBeforeEdit := cli.RequireViewBeforeEdit == "true" || cli.RequireViewBeforeEdit == "auto"
 
@@ -248,16 +663,51 @@ func main() {
 		},
 		toolsCfg: tools.Config{
 			DisableTools:          disableTools,
+			EnableTools:           enableTools,
 			MaxFileSize:           maxFileSize,
+			MaxViewFileSize:       maxViewFileSize,
+			MaxCreateFileSize:     maxCreateFileSize,
 			DefaultTimeout:        cli.Timeout,
+			MaxBashTimeoutMs:      cli.MaxBashTimeoutMs,
 			Shell:                 shell,
 			AnthropicCompat:       cli.AnthropicCompat,
 			BackgroundTaskTimeout: cli.BackgroundTaskTimeout,
+			MaxBackgroundTasks:    cli.MaxBackgroundTasks,
+			BackgroundNiceness:    cli.BackgroundNiceness,
+			NormalizeLineEndings:  cli.NormalizeLineEndings,
+			FollowSymlinks:        cli.FollowSymlinks,
+			ExcludeDirs:           cli.ExcludeDir,
+			ExtraIgnoreFiles:      cli.IgnoreFile,
+			SanitizeUTF8:          cli.SanitizeUTF8,
+			StructuredBashOutput:  cli.StructuredBashOutput,
+			SentinelWarning:       cli.SentinelWarning,
+			EnableGrepCache:       cli.GrepCache,
+			RequireEditToken:      cli.RequireEditToken,
+			AllowGrepInPlace:      cli.AllowGrepInPlace,
+			EditDiffs:             cli.EditDiffs,
+			ReadOnly:              cli.ReadOnly,
+			ExposeResources:       cli.ExposeResources,
+			ProgressByteCount:     cli.ProgressBytes,
+			ProgressStreamPrefix:  cli.ProgressStreamPrefix,
+			DisableProgress:       cli.DisableProgress,
 			RequireViewBeforeEdit: requireViewBeforeEdit,
+			ToolTimeout:           cli.ToolTimeout,
+			AuditLog:              auditLog,
+			ToolDescriptions:      toolDescriptions,
 		},
 		serverOpts: &mcp.ServerOptions{
-			Instructions: buildInstructions(workdir, resolver),
+			Instructions: renderInstructions(instructionsTemplate, workdir, resolver),
 		},
+		instructionsTemplate: instructionsTemplate,
+
+		maxRequestBytes: maxRequestBytes,
+		gzip:            cli.Gzip,
+		drainTimeout:    time.Duration(cli.DrainTimeout) * time.Second,
+		sessionTimeout:  cli.SessionTimeout,
+		stdioIdleTimeout: time.Duration(cli.StdioIdleTimeout) * time.Second,
+
+		workdirPerSession:  cli.WorkdirPerSession,
+		sessionWorkdirBase: sessionWorkdirBase,
 	}
 
 	// Resolve bearer token
@@ -286,6 +736,34 @@ func main() {
 	}
 }
 
+// loadToolDescriptions reads a JSON object mapping tool names to
+// replacement descriptions, e.g. {"bash": "Run a shell command."}.
+func loadToolDescriptions(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var descriptions map[string]string
+	if err := json.Unmarshal(data, &descriptions); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return descriptions, nil
+}
+
+// loadInstructionsTemplate reads a custom server instructions template.
+// An empty or whitespace-only file is rejected so a misconfigured path
+// doesn't silently strip the instructions.
+func loadInstructionsTemplate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return string(data), nil
+}
+
 // corsMiddleware adds permissive CORS headers for browser-based MCP clients.
 // Non-browser clients ignore these headers, so there's no downside.
 func corsMiddleware(next http.Handler) http.Handler {
@@ -305,41 +783,192 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// buildMux creates the HTTP mux with /mcp and /health routes.
-func buildMux(mcpHandler http.Handler) *http.ServeMux {
+// healthResponse is the JSON body served by GET /health.
+type healthResponse struct {
+	Status          string `json:"status"`
+	Sessions        int    `json:"sessions"`
+	SessionsClosed  int64  `json:"sessions_closed"`
+	BackgroundTasks int    `json:"background_tasks"`
+}
+
+// buildMux creates the HTTP mux with /mcp and /health routes. The health
+// endpoint reports open and closed session counts and the running
+// background task count from registry, which may be nil.
+func buildMux(mcpHandler http.Handler, registry *session.SessionRegistry) *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.Handle("/mcp", mcpHandler)
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		resp := healthResponse{Status: "ok"}
+		if registry != nil {
+			resp.Sessions = registry.SessionCount()
+			resp.SessionsClosed = registry.ClosedCount()
+			resp.BackgroundTasks = registry.RunningTaskCount()
+		}
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]string{"status": "ok"}); err != nil {
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			slog.Debug("failed to write health response", "error", err)
 		}
 	})
 	return mux
 }
 
-func runHTTP(ctx context.Context, cfg serverConfig, port int, token string) {
-	registry := session.NewRegistry()
-	store := &session.SessionCleanupStore{Registry: registry}
+// sseSessionKey is the request context key carrying the Boris session and
+// its resolver for a legacy SSE connection from newSSEHandler into the
+// server factory.
+type sseSessionKey struct{}
+
+// sseSession is the value stored under sseSessionKey.
+type sseSession struct {
+	sess     *session.Session
+	resolver *pathscope.Resolver
+}
+
+// newSSEHandler returns a handler for the legacy SSE transport. Each GET
+// opens a new MCP session backed by its own Boris session, which is
+// registered in registry for shutdown cleanup and closed when the stream
+// ends. POSTs are routed to the existing session by the SDK.
+func newSSEHandler(cfg serverConfig, registry *session.SessionRegistry) http.Handler {
+	sse := mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
+		s, ok := r.Context().Value(sseSessionKey{}).(sseSession)
+		if !ok {
+			return nil
+		}
+		server := mcp.NewServer(cfg.impl, serverOptions(cfg, s.sess.Cwd(), s.resolver))
+		tools.RegisterAll(server, s.resolver, s.sess, cfg.toolsCfg)
+		return server
+	}, nil)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sse.ServeHTTP(w, r)
+			return
+		}
+		sess, resolver, err := newHTTPSession(cfg)
+		if err != nil {
+			slog.Error("could not create session workdir", "error", err)
+			http.Error(w, "could not create session", http.StatusInternalServerError)
+			return
+		}
+		id := "sse-" + rand.Text()
+		registry.Register(id, sess)
+		defer registry.CloseAndRemove(id)
+		sse.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sseSessionKey{}, sseSession{sess, resolver})))
+	})
+}
+
+// newHTTPSession creates the Boris session for a new HTTP connection and
+// the resolver its tools use. With --workdir-per-session it starts in a
+// fresh directory under cfg.sessionWorkdirBase, which is removed when the
+// session closes; under --allow-dir its resolver additionally allows that
+// directory, and only that directory, so other sessions' workdirs stay out
+// of reach.
+func newHTTPSession(cfg serverConfig) (*session.Session, *pathscope.Resolver, error) {
+	if !cfg.workdirPerSession {
+		return session.New(cfg.workdir), cfg.resolver, nil
+	}
+	dir, err := os.MkdirTemp(cfg.sessionWorkdirBase, "boris-session-")
+	if err != nil {
+		return nil, nil, err
+	}
+	// Canonicalize so the tracked cwd matches what the shell reports
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	resolver, err := cfg.resolver.WithAllowDir(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	sess := session.New(dir)
+	sess.OnClose(func() {
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Warn("could not remove session workdir", "dir", dir, "error", err)
+		}
+	})
+	return sess, resolver, nil
+}
+
+// serverOptions returns the MCP server options for a session starting in
+// workdir with resolver, rebuilding the instructions when it differs from
+// the shared one.
+func serverOptions(cfg serverConfig, workdir string, resolver *pathscope.Resolver) *mcp.ServerOptions {
+	if cfg.serverOpts == nil || workdir == cfg.workdir {
+		return cfg.serverOpts
+	}
+	opts := *cfg.serverOpts
+	opts.Instructions = renderInstructions(cfg.instructionsTemplate, workdir, resolver)
+	return &opts
+}
 
-	var mcpHandler http.Handler = mcp.NewStreamableHTTPHandler(func(_ *http.Request) *mcp.Server {
-		server := mcp.NewServer(cfg.impl, cfg.serverOpts)
-		sess := session.New(cfg.workdir)
+// newMCPHandler returns the streamable HTTP handler serving /mcp. Each new
+// connection gets its own Boris session, registered in registry so that it is
+// cleaned up when the SDK closes the session or the server shuts down.
+func newMCPHandler(cfg serverConfig, registry *session.SessionRegistry, sessionTimeout time.Duration) http.Handler {
+	store := &session.SessionCleanupStore{
+		Registry: registry,
+		OnClosed: func(sessionID string, killedTasks int) {
+			slog.Info("session closed", "session_id", sessionID, "killed_tasks", killedTasks)
+		},
+	}
+	return mcp.NewStreamableHTTPHandler(func(_ *http.Request) *mcp.Server {
+		sess, resolver, err := newHTTPSession(cfg)
+		if err != nil {
+			slog.Error("could not create session workdir", "error", err)
+			return nil
+		}
+		opts := serverOptions(cfg, sess.Cwd(), resolver)
+		if cfg.workdirPerSession {
+			// Register as soon as the SDK assigns the session its ID rather
+			// than on first bash call, so the session's directory is removed
+			// even if bash is never used or the client never initializes.
+			withID := mcp.ServerOptions{}
+			if opts != nil {
+				withID = *opts
+			}
+			withID.GetSessionID = func() string {
+				id := rand.Text()
+				registry.Register(id, sess)
+				return id
+			}
+			opts = &withID
+		}
+		server := mcp.NewServer(cfg.impl, opts)
 		toolsCfg := cfg.toolsCfg
 		toolsCfg.RegisterSession = func(sessionID string) {
 			registry.Register(sessionID, sess)
 		}
-		tools.RegisterAll(server, cfg.resolver, sess, toolsCfg)
+		tools.RegisterAll(server, resolver, sess, toolsCfg)
 		return server
 	}, &mcp.StreamableHTTPOptions{
-		SessionTimeout: 10 * time.Minute,
+		SessionTimeout: sessionTimeout,
 		EventStore:     store,
 	})
+}
+
+func runHTTP(ctx context.Context, cfg serverConfig, port int, token string) {
+	registry := session.NewRegistry()
+	mcpHandler := newMCPHandler(cfg, registry, cfg.sessionTimeout)
 
+	sseHandler := newSSEHandler(cfg, registry)
+
+	mcpHandler = maxBytesMiddleware(cfg.maxRequestBytes, mcpHandler)
+	sseHandler = maxBytesMiddleware(cfg.maxRequestBytes, sseHandler)
+	var tokenPtr *atomic.Pointer[string]
 	if token != "" {
-		mcpHandler = bearerAuthMiddleware(token, mcpHandler)
+		tokenPtr = newTokenPointer(token)
+		mcpHandler = bearerAuthMiddleware(tokenPtr, mcpHandler)
+		sseHandler = bearerAuthMiddleware(tokenPtr, sseHandler)
+	}
+	if cfg.gzip {
+		mcpHandler = gzipMiddleware(gzipMinBytes, mcpHandler)
+	}
+	mcpHandler = requestIDMiddleware(mcpHandler)
+	sseHandler = requestIDMiddleware(sseHandler)
+	mux := buildMux(mcpHandler, registry)
+	mux.Handle("/sse", sseHandler)
+	if tokenPtr != nil {
+		mux.Handle("POST /admin/rotate-token", bearerAuthMiddleware(tokenPtr, rotateTokenHandler(tokenPtr)))
 	}
-	mux := buildMux(mcpHandler)
 
 	addr := fmt.Sprintf(":%d", port)
 	slog.Info("boris listening", "addr", addr, "transport", "http")
@@ -352,9 +981,15 @@ func runHTTP(ctx context.Context, cfg serverConfig, port int, token string) {
 		if err := srv.Shutdown(shutdownCtx); err != nil {
 			slog.Error("shutdown error", "error", err)
 		}
-		// Clean up any sessions not yet closed by the SDK, killing orphan
-		// background processes that would otherwise survive server shutdown.
-		registry.CloseAll()
+		// Give running background tasks a chance to finish, then clean up
+		// any sessions not yet closed by the SDK, killing orphan background
+		// processes that would otherwise survive server shutdown.
+		if n := registry.RunningTaskCount(); n > 0 && cfg.drainTimeout > 0 {
+			slog.Info("draining background tasks", "running", n, "timeout", cfg.drainTimeout)
+		}
+		if n := registry.Drain(cfg.drainTimeout); n > 0 && cfg.drainTimeout > 0 {
+			slog.Warn("killed background tasks still running after drain", "count", n)
+		}
 	}()
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		slog.Error("server error", "error", err)
@@ -365,15 +1000,106 @@ func runHTTP(ctx context.Context, cfg serverConfig, port int, token string) {
 func runSTDIO(ctx context.Context, cfg serverConfig) {
 	slog.Info("boris running", "transport", "stdio")
 
-	server := mcp.NewServer(cfg.impl, cfg.serverOpts)
 	sess := session.New(cfg.workdir)
+	if err := serveSTDIO(ctx, cfg, &mcp.StdioTransport{}, sess); err != nil {
+		slog.Error("server error", "error", err)
+		os.Exit(1)
+	}
+}
+
+// errSTDIOIdle is the cancellation cause when a STDIO session is shut down
+// for inactivity.
+var errSTDIOIdle = errors.New("stdio idle timeout")
+
+// serveSTDIO runs a single MCP session for sess over transport and closes
+// sess, killing its background tasks, when the session ends. With
+// cfg.stdioIdleTimeout set, the session is shut down gracefully once no
+// request has arrived, and none has been in flight, for that long.
+func serveSTDIO(ctx context.Context, cfg serverConfig, transport mcp.Transport, sess *session.Session) error {
 	defer sess.Close()
+
+	server := mcp.NewServer(cfg.impl, cfg.serverOpts)
 	tools.RegisterAll(server, cfg.resolver, sess, cfg.toolsCfg)
 
-	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil {
-		slog.Error("server error", "error", err)
-		os.Exit(1)
+	if cfg.stdioIdleTimeout > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		idle := newIdleTimer(cfg.stdioIdleTimeout, func() {
+			slog.Info("no requests received, shutting down", "idle_timeout", cfg.stdioIdleTimeout)
+			cancel(errSTDIOIdle)
+		})
+		defer idle.stop()
+		server.AddReceivingMiddleware(idle.middleware)
+	}
+
+	err := server.Run(ctx, transport)
+	if errors.Is(context.Cause(ctx), errSTDIOIdle) {
+		return nil
+	}
+	return err
+}
+
+// idleTimer calls onIdle once no request has been received, and none has
+// been in flight, for the timeout.
+type idleTimer struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	timeout  time.Duration
+	onIdle   func()
+	inFlight int
+	last     time.Time // when the last request finished
+	stopped  bool      // set by stop or once onIdle has run; the timer is never re-armed
+}
+
+func newIdleTimer(timeout time.Duration, onIdle func()) *idleTimer {
+	t := &idleTimer{timeout: timeout, onIdle: onIdle, last: time.Now()}
+	t.timer = time.AfterFunc(timeout, t.fire)
+	return t
+}
+
+// fire runs when the timer expires. A Stop that returns false means fire has
+// already started and cannot be called off, so fire re-checks under the lock
+// that the session is still idle; if a request has arrived since, the
+// middleware has re-armed the timer or will when the request finishes.
+func (t *idleTimer) fire() {
+	t.mu.Lock()
+	if t.stopped || t.inFlight > 0 || time.Since(t.last) < t.timeout {
+		t.mu.Unlock()
+		return
 	}
+	t.stopped = true
+	t.mu.Unlock()
+	t.onIdle()
+}
+
+// middleware is an MCP receiving middleware that pauses the timer while a
+// request is being handled and restarts it when the last one finishes.
+func (t *idleTimer) middleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		t.mu.Lock()
+		t.inFlight++
+		t.timer.Stop() // if fire has already started, it sees inFlight and backs off
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.inFlight--
+			t.last = time.Now()
+			if t.inFlight == 0 && !t.stopped {
+				t.timer.Reset(t.timeout)
+			}
+		}()
+		return next(ctx, method, req)
+	}
+}
+
+// stop disarms the timer for good; requests finishing later do not re-arm it.
+func (t *idleTimer) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	t.timer.Stop()
 }
 
 // parseSize parses a human-readable size string (e.g., "10MB", "1GB").
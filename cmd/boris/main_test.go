@@ -1,13 +1,17 @@
 package main
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -70,6 +74,26 @@ func TestCLIValidate(t *testing.T) {
 			cli:     CLI{Token: "secret", GenerateToken: true},
 			wantErr: true,
 		},
+		{
+			name:    "enable-tools only",
+			cli:     CLI{EnableTools: []string{"grep"}},
+			wantErr: false,
+		},
+		{
+			name:    "enable-tools with disable-tools error",
+			cli:     CLI{EnableTools: []string{"grep"}, DisableTools: []string{"bash"}},
+			wantErr: true,
+		},
+		{
+			name:    "background-niceness in range",
+			cli:     CLI{BackgroundNiceness: 10},
+			wantErr: false,
+		},
+		{
+			name:    "background-niceness out of range error",
+			cli:     CLI{BackgroundNiceness: 20},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -112,7 +136,7 @@ func TestBearerAuthMiddleware(t *testing.T) {
 		w.Write([]byte("ok"))
 	})
 
-	mw := bearerAuthMiddleware("test-token", inner)
+	mw := bearerAuthMiddleware(newTokenPointer("test-token"), inner)
 
 	tests := []struct {
 		name       string
@@ -154,6 +178,274 @@ func TestBearerAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestMaxBytesMiddleware(t *testing.T) {
+	var gotBody string
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("oversized body with content-length", func(t *testing.T) {
+		mw := maxBytesMiddleware(16, inner)
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(strings.Repeat("x", 100)))
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d, want 413", rec.Code)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+		if body["error"] != "request body too large" {
+			t.Errorf("error body = %q, want %q", body["error"], "request body too large")
+		}
+	})
+
+	t.Run("oversized chunked body", func(t *testing.T) {
+		mw := maxBytesMiddleware(16, inner)
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(strings.Repeat("x", 100)))
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d, want 413", rec.Code)
+		}
+	})
+
+	t.Run("body within limit passes through", func(t *testing.T) {
+		mw := maxBytesMiddleware(16, inner)
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"ok":true}`))
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+		if gotBody != `{"ok":true}` {
+			t.Errorf("inner handler body = %q, want %q", gotBody, `{"ok":true}`)
+		}
+	})
+
+	t.Run("zero limit disables check", func(t *testing.T) {
+		mw := maxBytesMiddleware(0, inner)
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(strings.Repeat("x", 100)))
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+	})
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	large := strings.Repeat("hello gzip ", 500)
+	handler := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+			io.WriteString(w, body)
+		})
+	}
+	serve := func(h http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		gzipMiddleware(gzipMinBytes, h).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("large response is compressed", func(t *testing.T) {
+		rec := serve(handler(large), "gzip, deflate")
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want gzip", got)
+		}
+		if rec.Header().Get("Content-Length") != "" {
+			t.Error("Content-Length of the uncompressed body should be removed")
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != large {
+			t.Errorf("decompressed body does not match original (%d bytes)", len(body))
+		}
+	})
+
+	t.Run("plain without accept-encoding", func(t *testing.T) {
+		rec := serve(handler(large), "")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if rec.Body.String() != large {
+			t.Error("body should be passed through unchanged")
+		}
+	})
+
+	t.Run("gzip refused with q=0", func(t *testing.T) {
+		rec := serve(handler(large), "gzip;q=0")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+	})
+
+	t.Run("small response is plain", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, "ok")
+		})
+		rec := serve(h, "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if rec.Code != http.StatusAccepted || rec.Body.String() != "ok" {
+			t.Errorf("got %d %q, want 202 \"ok\"", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("small flushed SSE event is plain", func(t *testing.T) {
+		event := "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n\n"
+		h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, event)
+			http.NewResponseController(w).Flush()
+		})
+		rec := serve(h, "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if rec.Body.String() != event {
+			t.Errorf("body = %q, want %q", rec.Body.String(), event)
+		}
+		if !rec.Flushed {
+			t.Error("flush should reach the underlying writer")
+		}
+	})
+
+	t.Run("large flushed event is compressed", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "event: message\ndata: "+large+"\n\n")
+			w.(http.Flusher).Flush()
+		})
+		rec := serve(h, "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want gzip", got)
+		}
+		if !rec.Flushed {
+			t.Error("flush should reach the underlying writer")
+		}
+	})
+}
+
+func TestLoadToolDescriptions(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "descriptions.json")
+	os.WriteFile(path, []byte(`{"bash": "Run a shell command.", "grep": "Search files."}`), 0644)
+	got, err := loadToolDescriptions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["bash"] != "Run a shell command." || got["grep"] != "Search files." {
+		t.Errorf("loadToolDescriptions = %v", got)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte(`["bash"]`), 0644)
+	if _, err := loadToolDescriptions(bad); err == nil {
+		t.Error("expected error for non-object JSON")
+	}
+
+	if _, err := loadToolDescriptions(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestResolveShell(t *testing.T) {
+	t.Run("explicit path", func(t *testing.T) {
+		shell := filepath.Join(t.TempDir(), "myshell")
+		if err := os.WriteFile(shell, []byte("#!/bin/sh\nexec /bin/sh \"$@\"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		got, err := resolveShell(shell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != shell {
+			t.Errorf("resolveShell(%q) = %q", shell, got)
+		}
+	})
+
+	t.Run("invalid paths", func(t *testing.T) {
+		dir := t.TempDir()
+		notExec := filepath.Join(dir, "notexec")
+		if err := os.WriteFile(notExec, []byte("#!/bin/sh\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, shell := range []string{notExec, filepath.Join(dir, "missing"), "no-such-shell-on-path"} {
+			if got, err := resolveShell(shell); err == nil {
+				t.Errorf("resolveShell(%q) = %q, want error", shell, got)
+			}
+		}
+	})
+
+	t.Run("unset falls back to detection", func(t *testing.T) {
+		want := "/bin/sh"
+		if _, err := os.Stat("/bin/bash"); err == nil {
+			want = "/bin/bash"
+		}
+		got, err := resolveShell("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("resolveShell(\"\") = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestAllowDirsWithWorkdir(t *testing.T) {
+	tmp := t.TempDir()
+	workdir := filepath.Join(tmp, "work")
+	other := filepath.Join(tmp, "other")
+	os.MkdirAll(workdir, 0755)
+	os.MkdirAll(other, 0755)
+
+	if got := allowDirsWithWorkdir(nil, workdir, true); len(got) != 0 {
+		t.Errorf("no --allow-dir should stay unrestricted, got %v", got)
+	}
+
+	resolve := func(t *testing.T, include bool) error {
+		t.Helper()
+		resolver, err := pathscope.NewResolver(allowDirsWithWorkdir([]string{other}, workdir, include), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = resolver.Resolve(workdir, "file.txt")
+		return err
+	}
+
+	t.Run("workdir included by default", func(t *testing.T) {
+		if err := resolve(t, true); err != nil {
+			t.Errorf("expected a cwd path to be allowed, got: %v", err)
+		}
+	})
+
+	t.Run("opted out", func(t *testing.T) {
+		if err := resolve(t, false); err == nil {
+			t.Error("expected a cwd path outside --allow-dir to be denied")
+		}
+	})
+}
+
 func TestParseLogLevel(t *testing.T) {
 	tests := []struct {
 		input string
@@ -245,7 +537,7 @@ func TestCORSPreflightNotBlockedByAuth(t *testing.T) {
 	})
 
 	// Apply auth inside, CORS outside (same order as production)
-	handler := bearerAuthMiddleware("secret-token", inner)
+	handler := bearerAuthMiddleware(newTokenPointer("secret-token"), inner)
 	handler = corsMiddleware(handler)
 
 	req := httptest.NewRequest("OPTIONS", "/mcp", nil)
@@ -262,7 +554,7 @@ func TestCORSPreflightNotBlockedByAuth(t *testing.T) {
 func TestHealthEndpointGetsCORSHeaders(t *testing.T) {
 	mux := buildMux(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
-	}))
+	}), nil)
 	handler := corsMiddleware(mux)
 
 	req := httptest.NewRequest("GET", "/health", nil)
@@ -280,7 +572,7 @@ func TestHealthEndpointGetsCORSHeaders(t *testing.T) {
 func TestGracefulShutdown(t *testing.T) {
 	mux := buildMux(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
-	}))
+	}), nil)
 
 	// Pick a random available port
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -394,6 +686,45 @@ func TestBuildInstructions(t *testing.T) {
 	})
 }
 
+func TestRenderInstructionsTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "instructions.txt")
+	os.WriteFile(path, []byte("Use the project tools only.\nRoot: {{workdir}}\nDenied: {{deny_patterns}}\n\n{{default}}"), 0644)
+	tmpl, err := loadInstructionsTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := pathscope.NewResolver(nil, []string{"**/.env"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := renderInstructions(tmpl, "/workspace", r)
+	for _, want := range []string{
+		"Use the project tools only.",
+		"Root: /workspace\n",
+		"Denied: **/.env\n",
+		"Working directory: /workspace\nDenied patterns: **/.env",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("instructions missing %q:\n%s", want, got)
+		}
+	}
+
+	if got := renderInstructions("", "/workspace", r); got != buildInstructions("/workspace", r) {
+		t.Errorf("empty template should fall back to built-in, got %q", got)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	os.WriteFile(empty, []byte("  \n"), 0644)
+	if _, err := loadInstructionsTemplate(empty); err == nil {
+		t.Error("expected error for empty template")
+	}
+	if _, err := loadInstructionsTemplate(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
 func TestParseSizeErrors(t *testing.T) {
 	tests := []string{
 		"",
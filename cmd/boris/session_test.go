@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -34,9 +37,11 @@ func testServerConfig(t *testing.T, workdir string) serverConfig {
 			Version: "test",
 		},
 		toolsCfg: tools.Config{
-			Shell:          "/bin/sh",
-			DefaultTimeout: 30,
-			MaxFileSize:    10 * 1024 * 1024,
+			Shell:                "/bin/sh",
+			DefaultTimeout:       30,
+			MaxFileSize:          10 * 1024 * 1024,
+			MaxBackgroundTasks:   session.DefaultMaxTasks,
+			NormalizeLineEndings: true,
 		},
 	}
 }
@@ -265,11 +270,17 @@ func newTestHTTPServerWithMux(t *testing.T, cfg serverConfig, token string) *htt
 		return server
 	}, nil)
 
+	mcpHandler = maxBytesMiddleware(cfg.maxRequestBytes, mcpHandler)
+	var tokenPtr *atomic.Pointer[string]
 	if token != "" {
-		mcpHandler = bearerAuthMiddleware(token, mcpHandler)
+		tokenPtr = newTokenPointer(token)
+		mcpHandler = bearerAuthMiddleware(tokenPtr, mcpHandler)
 	}
 
-	mux := buildMux(mcpHandler)
+	mux := buildMux(mcpHandler, nil)
+	if tokenPtr != nil {
+		mux.Handle("POST /admin/rotate-token", bearerAuthMiddleware(tokenPtr, rotateTokenHandler(tokenPtr)))
+	}
 
 	srv := httptest.NewServer(mux)
 	t.Cleanup(func() { srv.Close() })
@@ -340,6 +351,79 @@ func TestHTTPAuthRequiredOnMCP(t *testing.T) {
 // newTestHTTPServerWithLifecycle creates a test HTTP server with full session
 // lifecycle wiring (registry, EventStore, SessionTimeout) matching production
 // runHTTP. The short timeout ensures tests don't wait long for idle cleanup.
+// TestHTTPTokenRotation verifies that POST /admin/rotate-token requires the
+// current token, returns a new one, and that only the new token is accepted
+// afterwards.
+func TestHTTPTokenRotation(t *testing.T) {
+	workdir := t.TempDir()
+	cfg := testServerConfig(t, workdir)
+	oldToken := "old-secret-token"
+	srv := newTestHTTPServerWithMux(t, cfg, oldToken)
+
+	post := func(path, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("POST", srv.URL+path, strings.NewReader(`{}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// Rotation without auth → 401
+	if resp := post("/admin/rotate-token", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("rotate without auth: status = %d, want 401", resp.StatusCode)
+	}
+
+	// Rotation with the current token → new token
+	resp := post("/admin/rotate-token", oldToken)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("rotate: status = %d, want 200", resp.StatusCode)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode rotate response: %v", err)
+	}
+	newToken := body["token"]
+	if len(newToken) != 64 || newToken == oldToken {
+		t.Fatalf("expected a fresh 64-char token, got %q", newToken)
+	}
+
+	// Old token no longer works
+	if resp := post("/mcp", oldToken); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("/mcp with old token: status = %d, want 401", resp.StatusCode)
+	}
+	if resp := post("/admin/rotate-token", oldToken); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("rotate with old token: status = %d, want 401", resp.StatusCode)
+	}
+
+	// New token works
+	if resp := post("/mcp", newToken); resp.StatusCode == http.StatusUnauthorized {
+		t.Errorf("/mcp with new token should not be 401, got %d", resp.StatusCode)
+	}
+}
+
+// TestHTTPNoRotateRouteWithoutToken verifies the admin route is absent when
+// authentication is disabled.
+func TestHTTPNoRotateRouteWithoutToken(t *testing.T) {
+	workdir := t.TempDir()
+	cfg := testServerConfig(t, workdir)
+	srv := newTestHTTPServerWithMux(t, cfg, "")
+
+	resp, err := http.Post(srv.URL+"/admin/rotate-token", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /admin/rotate-token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+}
+
 func newTestHTTPServerWithLifecycle(t *testing.T, cfg serverConfig, sessionTimeout time.Duration) *httptest.Server {
 	t.Helper()
 	registry := session.NewRegistry()
@@ -480,6 +564,93 @@ func TestHTTPSessionCleanupOnIdleTimeout(t *testing.T) {
 	}
 }
 
+// TestHTTPSessionTimeoutReapsSession verifies that the configured session
+// timeout closes an idle session through newMCPHandler, killing its
+// background tasks, and that the reap is logged and counted in /health.
+func TestHTTPSessionTimeoutReapsSession(t *testing.T) {
+	var mu sync.Mutex
+	var records []map[string]string
+	prev := slog.Default()
+	slog.SetDefault(slog.New(recordingHandler{mu: &mu, records: &records}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	workdir := t.TempDir()
+	cfg := testServerConfig(t, workdir)
+	registry := session.NewRegistry()
+	srv := httptest.NewServer(buildMux(newMCPHandler(cfg, registry, 500*time.Millisecond), registry))
+	t.Cleanup(func() {
+		srv.Close()
+		registry.CloseAll()
+	})
+
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	cs, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: srv.URL + "/mcp"}, nil)
+	if err != nil {
+		t.Fatalf("client.Connect: %v", err)
+	}
+	pidFile := filepath.Join(workdir, "bg.pid")
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name: "bash",
+		Arguments: map[string]interface{}{
+			"command":           "echo $$ > " + pidFile + " && sleep 300",
+			"run_in_background": true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("start background task: %v", err)
+	}
+	if text := toolResultText(res); !strings.Contains(text, "task_id:") {
+		t.Fatalf("expected task_id in response, got: %s", text)
+	}
+	var pid string
+	for i := 0; i < 20 && pid == ""; i++ {
+		if data, err := os.ReadFile(pidFile); err == nil {
+			pid = strings.TrimSpace(string(data))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if pid == "" {
+		t.Fatal("PID file was not written")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for registry.SessionCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("session was not closed after the session timeout")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, err := os.Stat("/proc/" + pid); err == nil {
+		t.Errorf("background task process (PID %s) should be killed when the session is reaped", pid)
+	}
+
+	resp, err := http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatalf("GET /health: %v", err)
+	}
+	defer resp.Body.Close()
+	var h healthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		t.Fatalf("decode /health: %v", err)
+	}
+	if h.Sessions != 0 || h.SessionsClosed != 1 {
+		t.Errorf("/health = %+v, want 0 sessions and 1 closed", h)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var closed []map[string]string
+	for _, rec := range records {
+		if rec["msg"] == "session closed" {
+			closed = append(closed, rec)
+		}
+	}
+	if len(closed) != 1 || closed[0]["session_id"] == "" || closed[0]["killed_tasks"] != "1" {
+		t.Errorf("expected one session closed record with killed_tasks=1, got %v", closed)
+	}
+}
+
 // TestSTDIOSessionCleanup verifies that background tasks are killed when a
 // STDIO session ends (simulated by using in-memory transport + sess.Close).
 func TestSTDIOSessionCleanup(t *testing.T) {
@@ -551,6 +722,103 @@ func TestSTDIOSessionCleanup(t *testing.T) {
 	}
 }
 
+// TestSTDIOIdleTimeout verifies that an idle STDIO session shuts down on its
+// own, closing the Boris session and killing background tasks, and that a
+// request running longer than the timeout does not trigger it.
+func TestSTDIOIdleTimeout(t *testing.T) {
+	cfg := testServerConfig(t, t.TempDir())
+	cfg.stdioIdleTimeout = 500 * time.Millisecond
+	sess := session.New(cfg.workdir)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	done := make(chan error, 1)
+	go func() { done <- serveSTDIO(ctx, cfg, serverTransport, sess) }()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	cs, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client.Connect: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+
+	// An in-flight request pauses the idle timer
+	if out := callBash(t, ctx, cs, "sleep 1 && echo slept"); !strings.Contains(out, "slept") {
+		t.Fatalf("long request should complete, got: %s", out)
+	}
+
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "bash",
+		Arguments: map[string]any{"command": "sleep 60", "run_in_background": true},
+	})
+	if err != nil || res.IsError {
+		t.Fatalf("start background task: %v %s", err, toolResultText(res))
+	}
+	tasks := sess.RunningTasks()
+	if len(tasks) != 1 {
+		t.Fatalf("expected 1 running task, got %d", len(tasks))
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("idle shutdown should not be an error, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("server did not shut down after idle timeout")
+	}
+	select {
+	case <-tasks[0].Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("background task was not killed on idle shutdown")
+	}
+	if sess.TaskCount() != 0 {
+		t.Errorf("expected 0 tasks after idle shutdown, got %d", sess.TaskCount())
+	}
+}
+
+// TestIdleTimerRaces verifies that an expiry racing with a new request, and
+// a request finishing after stop, do not shut the session down.
+func TestIdleTimerRaces(t *testing.T) {
+	var fired atomic.Int32
+	idle := newIdleTimer(time.Hour, func() { fired.Add(1) })
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := idle.middleware(func(context.Context, string, mcp.Request) (mcp.Result, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	done := make(chan struct{})
+	go func() {
+		handler(context.Background(), "tools/call", nil)
+		close(done)
+	}()
+	<-started
+
+	// An expiry whose Stop came too late finds a request in flight
+	idle.fire()
+	close(release)
+	<-done
+	// ...and, once it finishes, one that has just been handled
+	idle.fire()
+	if n := fired.Load(); n != 0 {
+		t.Fatalf("onIdle ran %d times while the session was active", n)
+	}
+
+	// After stop, a finishing request does not re-arm the timer
+	idle.stop()
+	idle.mu.Lock()
+	idle.timeout = time.Millisecond
+	idle.mu.Unlock()
+	handler = idle.middleware(func(context.Context, string, mcp.Request) (mcp.Result, error) { return nil, nil })
+	handler(context.Background(), "tools/call", nil)
+	time.Sleep(50 * time.Millisecond)
+	if n := fired.Load(); n != 0 {
+		t.Errorf("onIdle ran %d times after stop", n)
+	}
+}
+
 // TestHTTPNoAuthWhenNoToken verifies that without a token, /mcp is accessible
 // without authentication.
 func TestHTTPNoAuthWhenNoToken(t *testing.T) {
@@ -578,3 +846,491 @@ func TestHTTPNoAuthWhenNoToken(t *testing.T) {
 		t.Errorf("/health: status = %d, want 200", resp2.StatusCode)
 	}
 }
+
+// newTestSSEServer creates an httptest.Server serving the legacy SSE
+// transport via newSSEHandler, returning the registry that tracks its
+// sessions.
+func newTestSSEServer(t *testing.T, cfg serverConfig) (*httptest.Server, *session.SessionRegistry) {
+	t.Helper()
+	registry := session.NewRegistry()
+	mux := http.NewServeMux()
+	mux.Handle("/sse", newSSEHandler(cfg, registry))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		srv.Close()
+		registry.CloseAll()
+	})
+	return srv, registry
+}
+
+// connectSSEClient creates an MCP client connected over the legacy SSE transport.
+func connectSSEClient(t *testing.T, ctx context.Context, srv *httptest.Server) *mcp.ClientSession {
+	t.Helper()
+	client := mcp.NewClient(&mcp.Implementation{
+		Name:    "test-client",
+		Version: "test",
+	}, nil)
+	clientSession, err := client.Connect(ctx, &mcp.SSEClientTransport{
+		Endpoint: srv.URL + "/sse",
+	}, nil)
+	if err != nil {
+		t.Fatalf("client.Connect: %v", err)
+	}
+	t.Cleanup(func() { clientSession.Close() })
+	return clientSession
+}
+
+// TestSSETransportToolCall verifies that a legacy SSE client can issue tool
+// calls and that each SSE connection gets an isolated working directory.
+func TestSSETransportToolCall(t *testing.T) {
+	workdir := t.TempDir()
+	dirA := workdir + "/a"
+	dirB := workdir + "/b"
+	os.Mkdir(dirA, 0755)
+	os.Mkdir(dirB, 0755)
+	cfg := testServerConfig(t, workdir)
+	srv, _ := newTestSSEServer(t, cfg)
+
+	ctx := context.Background()
+	csA := connectSSEClient(t, ctx, srv)
+	csB := connectSSEClient(t, ctx, srv)
+
+	if out := callBash(t, ctx, csA, "echo hello-sse"); !strings.Contains(out, "hello-sse") {
+		t.Fatalf("expected bash output over SSE, got: %s", out)
+	}
+
+	callBash(t, ctx, csA, "cd "+dirA)
+	callBash(t, ctx, csB, "cd "+dirB)
+	if out := callBash(t, ctx, csA, "pwd"); !strings.Contains(out, dirA) {
+		t.Errorf("client A cwd: expected %s, got: %s", dirA, out)
+	}
+	if out := callBash(t, ctx, csB, "pwd"); !strings.Contains(out, dirB) {
+		t.Errorf("client B cwd: expected %s, got: %s", dirB, out)
+	}
+}
+
+// TestSSESessionCleanupOnDisconnect verifies that closing an SSE client
+// kills its background tasks and removes its session from the registry.
+func TestSSESessionCleanupOnDisconnect(t *testing.T) {
+	workdir := t.TempDir()
+	cfg := testServerConfig(t, workdir)
+	srv, registry := newTestSSEServer(t, cfg)
+
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	cs, err := client.Connect(ctx, &mcp.SSEClientTransport{Endpoint: srv.URL + "/sse"}, nil)
+	if err != nil {
+		t.Fatalf("client.Connect: %v", err)
+	}
+
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name: "bash",
+		Arguments: map[string]interface{}{
+			"command":           "sleep 300",
+			"run_in_background": true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("start background task: %v", err)
+	}
+	if text := toolResultText(res); !strings.Contains(text, "task_id:") {
+		t.Fatalf("expected task_id in response, got: %s", text)
+	}
+	if n := registry.RunningTaskCount(); n != 1 {
+		t.Fatalf("RunningTaskCount() = %d, want 1", n)
+	}
+
+	cs.Close()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for registry.RunningTaskCount() != 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if n := registry.RunningTaskCount(); n != 0 {
+		t.Errorf("RunningTaskCount() after disconnect = %d, want 0", n)
+	}
+}
+
+// TestHealthReportsSessionAndTaskCounts verifies that /health reflects a
+// connected session with a running background task.
+func TestHealthReportsSessionAndTaskCounts(t *testing.T) {
+	workdir := t.TempDir()
+	cfg := testServerConfig(t, workdir)
+	registry := session.NewRegistry()
+	mux := buildMux(http.NotFoundHandler(), registry)
+	mux.Handle("/sse", newSSEHandler(cfg, registry))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		srv.Close()
+		registry.CloseAll()
+	})
+
+	getHealth := func() healthResponse {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/health")
+		if err != nil {
+			t.Fatalf("GET /health: %v", err)
+		}
+		defer resp.Body.Close()
+		var h healthResponse
+		if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+			t.Fatalf("decode /health: %v", err)
+		}
+		return h
+	}
+
+	if h := getHealth(); h.Status != "ok" || h.Sessions != 0 || h.BackgroundTasks != 0 {
+		t.Fatalf("/health before connect = %+v, want ok with zero counts", h)
+	}
+
+	ctx := context.Background()
+	cs := connectSSEClient(t, ctx, srv)
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name: "bash",
+		Arguments: map[string]interface{}{
+			"command":           "sleep 300",
+			"run_in_background": true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("start background task: %v", err)
+	}
+	if text := toolResultText(res); !strings.Contains(text, "task_id:") {
+		t.Fatalf("expected task_id in response, got: %s", text)
+	}
+
+	h := getHealth()
+	if h.Sessions != 1 {
+		t.Errorf("sessions = %d, want 1", h.Sessions)
+	}
+	if h.BackgroundTasks != 1 {
+		t.Errorf("background_tasks = %d, want 1", h.BackgroundTasks)
+	}
+}
+
+// TestHTTPWorkdirPerSession verifies that with per-session workdirs each
+// HTTP client starts in its own fresh directory, and that the directory is
+// removed when the session closes.
+func TestHTTPWorkdirPerSession(t *testing.T) {
+	base := t.TempDir()
+	cfg := testServerConfig(t, t.TempDir())
+	cfg.workdirPerSession = true
+	cfg.sessionWorkdirBase = base
+
+	registry := session.NewRegistry()
+	srv := httptest.NewServer(newMCPHandler(cfg, registry, 10*time.Minute))
+	t.Cleanup(func() {
+		srv.Close()
+		registry.CloseAll()
+	})
+
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	clientA, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: srv.URL}, nil)
+	if err != nil {
+		t.Fatalf("client.Connect: %v", err)
+	}
+	clientB := connectHTTPClient(t, ctx, srv)
+
+	// pwd output is the only stdout line of the bash result
+	pwd := func(cs *mcp.ClientSession) string {
+		out := callBash(t, ctx, cs, "pwd")
+		out = out[strings.Index(out, "stdout:\n")+len("stdout:\n"):]
+		return out[:strings.Index(out, "\n")]
+	}
+	dirA, dirB := pwd(clientA), pwd(clientB)
+	if dirA == dirB {
+		t.Fatalf("sessions share workdir %q", dirA)
+	}
+	for _, dir := range []string{dirA, dirB} {
+		if filepath.Dir(dir) != base {
+			t.Errorf("session workdir %q should be directly under %q", dir, base)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("session workdir %q should start empty, has %d entries", dir, len(entries))
+		}
+	}
+
+	// Files written by one session are not visible in the other's workdir
+	callBash(t, ctx, clientA, "echo secret > a.txt")
+	if text := callBash(t, ctx, clientB, "ls"); strings.Contains(text, "a.txt") {
+		t.Errorf("client B should not see client A's files, got: %s", text)
+	}
+
+	// Closing the session removes its directory but leaves the other alone
+	clientA.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(dirA); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session workdir %q was not removed after close", dirA)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, err := os.Stat(dirB); err != nil {
+		t.Errorf("other session's workdir should remain: %v", err)
+	}
+}
+
+// TestHTTPWorkdirPerSessionCleanupWithoutBash verifies that a per-session
+// workdir is removed even if the client never calls a tool.
+func TestHTTPWorkdirPerSessionCleanupWithoutBash(t *testing.T) {
+	base := t.TempDir()
+	cfg := testServerConfig(t, t.TempDir())
+	cfg.workdirPerSession = true
+	cfg.sessionWorkdirBase = base
+
+	registry := session.NewRegistry()
+	srv := httptest.NewServer(newMCPHandler(cfg, registry, 10*time.Minute))
+	t.Cleanup(func() {
+		srv.Close()
+		registry.CloseAll()
+	})
+
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	cs, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: srv.URL}, nil)
+	if err != nil {
+		t.Fatalf("client.Connect: %v", err)
+	}
+	if entries, _ := os.ReadDir(base); len(entries) != 1 {
+		t.Fatalf("expected one session workdir under base, got %d", len(entries))
+	}
+
+	cs.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if entries, _ := os.ReadDir(base); len(entries) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session workdir was not removed after close")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestHTTPWorkdirPerSessionAllowDir verifies that with --allow-dir each
+// session may use its own workdir but not another session's, nor the rest
+// of the workdir base.
+func TestHTTPWorkdirPerSessionAllowDir(t *testing.T) {
+	base := t.TempDir()
+	allowed := t.TempDir()
+	cfg := testServerConfig(t, allowed)
+	resolver, err := pathscope.NewResolver([]string{allowed}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.resolver = resolver
+	cfg.workdirPerSession = true
+	cfg.sessionWorkdirBase = base
+	stray := filepath.Join(base, "stray.txt")
+	os.WriteFile(stray, []byte("stray\n"), 0644)
+
+	registry := session.NewRegistry()
+	srv := httptest.NewServer(newMCPHandler(cfg, registry, 10*time.Minute))
+	t.Cleanup(func() {
+		srv.Close()
+		registry.CloseAll()
+	})
+
+	ctx := context.Background()
+	clientA := connectHTTPClient(t, ctx, srv)
+	clientB := connectHTTPClient(t, ctx, srv)
+	call := func(cs *mcp.ClientSession, name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool %s: %v", name, err)
+		}
+		return res
+	}
+	text := func(res *mcp.CallToolResult) string {
+		return res.Content[0].(*mcp.TextContent).Text
+	}
+
+	if res := call(clientA, "create_file", map[string]any{"path": "a.txt", "content": "from A\n"}); res.IsError {
+		t.Fatalf("session should be able to write its own workdir: %s", text(res))
+	}
+	entries, _ := filepath.Glob(filepath.Join(base, "boris-session-*", "a.txt"))
+	if len(entries) != 1 {
+		t.Fatalf("expected a.txt in one session workdir, got %v", entries)
+	}
+
+	if res := call(clientB, "view", map[string]any{"path": entries[0]}); !res.IsError {
+		t.Errorf("session B should not read session A's workdir, got: %s", text(res))
+	}
+	if res := call(clientB, "view", map[string]any{"path": stray}); !res.IsError {
+		t.Errorf("session should not read the rest of the workdir base, got: %s", text(res))
+	}
+}
+
+// TestHTTPWorkdirPerSessionCleanupWithoutInitialized verifies that a
+// per-session workdir is tracked from the initialize request, so it is
+// removed even if the client never sends the initialized notification.
+func TestHTTPWorkdirPerSessionCleanupWithoutInitialized(t *testing.T) {
+	base := t.TempDir()
+	cfg := testServerConfig(t, t.TempDir())
+	cfg.workdirPerSession = true
+	cfg.sessionWorkdirBase = base
+
+	registry := session.NewRegistry()
+	srv := httptest.NewServer(newMCPHandler(cfg, registry, 10*time.Minute))
+	t.Cleanup(srv.Close)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"raw","version":"test"}}}`
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize status = %d", resp.StatusCode)
+	}
+
+	if entries, _ := os.ReadDir(base); len(entries) != 1 {
+		t.Fatalf("expected one session workdir under base, got %d", len(entries))
+	}
+	if n := registry.SessionCount(); n != 1 {
+		t.Fatalf("session should be registered before initialized, got %d sessions", n)
+	}
+	registry.CloseAll()
+	if entries, _ := os.ReadDir(base); len(entries) != 0 {
+		t.Errorf("session workdir was not removed on shutdown, %d entries remain", len(entries))
+	}
+}
+
+// recordingHandler is a slog.Handler that captures the request_id attribute
+// of each record, including attributes added via Logger.With.
+type recordingHandler struct {
+	mu      *sync.Mutex
+	records *[]map[string]string
+	attrs   []slog.Attr
+}
+
+func (h recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	rec := map[string]string{"msg": r.Message}
+	for _, a := range h.attrs {
+		rec[a.Key] = a.Value.String()
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		rec[a.Key] = a.Value.String()
+		return true
+	})
+	h.mu.Lock()
+	*h.records = append(*h.records, rec)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return h
+}
+
+func (h recordingHandler) WithGroup(string) slog.Handler { return h }
+
+// TestHTTPRequestIDLogging verifies that the log records produced while
+// serving a single tool call over HTTP share one request_id, and that
+// separate requests get different IDs.
+func TestHTTPRequestIDLogging(t *testing.T) {
+	var mu sync.Mutex
+	var records []map[string]string
+	prev := slog.Default()
+	slog.SetDefault(slog.New(recordingHandler{mu: &mu, records: &records}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	cfg := testServerConfig(t, t.TempDir())
+	registry := session.NewRegistry()
+	srv := httptest.NewServer(requestIDMiddleware(newMCPHandler(cfg, registry, 10*time.Minute)))
+	t.Cleanup(func() {
+		srv.Close()
+		registry.CloseAll()
+	})
+
+	ctx := context.Background()
+	cs := connectHTTPClient(t, ctx, srv)
+	callBash(t, ctx, cs, "true")
+	callBash(t, ctx, cs, "true")
+
+	mu.Lock()
+	defer mu.Unlock()
+	var ids []string
+	for _, rec := range records {
+		if rec["msg"] == "tool call started" && rec["tool"] == "bash" {
+			ids = append(ids, rec["request_id"])
+		}
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 bash tool call records, got %d: %v", len(ids), records)
+	}
+	if ids[0] == "" || ids[0] == ids[1] {
+		t.Fatalf("expected distinct non-empty request IDs, got %q and %q", ids[0], ids[1])
+	}
+
+	var msgs []string
+	for _, rec := range records {
+		if rec["request_id"] == ids[0] {
+			msgs = append(msgs, rec["msg"])
+		}
+	}
+	want := []string{"http request", "tool call started", "tool call finished"}
+	if strings.Join(msgs, ",") != strings.Join(want, ",") {
+		t.Errorf("records for request %s = %v, want %v", ids[0], msgs, want)
+	}
+}
+
+// encodingRecorder records whether each response was transparently
+// decompressed by the HTTP transport.
+type encodingRecorder struct {
+	mu         sync.Mutex
+	compressed int
+}
+
+func (e *encodingRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil && resp.Uncompressed {
+		e.mu.Lock()
+		e.compressed++
+		e.mu.Unlock()
+	}
+	return resp, err
+}
+
+// TestHTTPGzipToolCall verifies that a large tool result over /mcp arrives
+// gzip-encoded and is decoded correctly by the client.
+func TestHTTPGzipToolCall(t *testing.T) {
+	cfg := testServerConfig(t, t.TempDir())
+	srv := httptest.NewServer(gzipMiddleware(gzipMinBytes, newMCPHandler(cfg, session.NewRegistry(), time.Minute)))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	rec := &encodingRecorder{}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	cs, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   srv.URL,
+		HTTPClient: &http.Client{Transport: rec},
+	}, nil)
+	if err != nil {
+		t.Fatalf("client.Connect: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+
+	out := callBash(t, ctx, cs, "head -c 20000 /dev/zero | tr '\\0' x")
+	if !strings.Contains(out, strings.Repeat("x", 20000)) {
+		t.Fatalf("large output was not delivered intact: %.200s", out)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.compressed == 0 {
+		t.Error("expected at least one gzip-encoded response")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/bmatcuk/doublestar/v4"
@@ -11,8 +12,9 @@ import (
 
 // Resolver checks paths against allow/deny lists.
 type Resolver struct {
-	allowDirs    []string
-	denyPatterns []string
+	allowDirs         []string
+	denyPatterns      []string
+	denyWritePatterns []string
 }
 
 // NewResolver creates a Resolver. allowDirs are canonicalized at construction time.
@@ -49,6 +51,45 @@ func (r *Resolver) DenyPatterns() []string {
 	return r.denyPatterns
 }
 
+// WithDenyWritePatterns returns a copy of r that additionally rejects the
+// given patterns in ResolveForWrite. Paths matching them stay readable.
+// Patterns support doublestar glob syntax.
+func (r *Resolver) WithDenyWritePatterns(patterns []string) (*Resolver, error) {
+	for _, p := range patterns {
+		if !doublestar.ValidatePathPattern(p) {
+			return nil, fmt.Errorf("invalid deny-write pattern %q", p)
+		}
+	}
+	c := *r
+	c.denyWritePatterns = patterns
+	return &c, nil
+}
+
+// WithAllowDir returns a copy of r that additionally allows dir, which is
+// canonicalized like the constructor's allow dirs. A resolver with no allow
+// dirs already allows every path and is returned unchanged.
+func (r *Resolver) WithAllowDir(dir string) (*Resolver, error) {
+	if len(r.allowDirs) == 0 {
+		return r, nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("allow dir %q: %w", dir, err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("allow dir %q: %w", dir, err)
+	}
+	c := *r
+	c.allowDirs = append(slices.Clone(r.allowDirs), resolved)
+	return &c, nil
+}
+
+// DenyWritePatterns returns the deny-write pattern list.
+func (r *Resolver) DenyWritePatterns() []string {
+	return r.denyWritePatterns
+}
+
 // Resolve canonicalizes a path and checks it against allow/deny lists.
 // baseCwd is the session's current working directory, used to resolve relative paths.
 func (r *Resolver) Resolve(baseCwd string, path string) (string, error) {
@@ -80,18 +121,31 @@ func (r *Resolver) Resolve(baseCwd string, path string) (string, error) {
 	}
 
 	// Check deny list (deny overrides allow)
-	if pattern, matched := r.matchesDeny(resolved); matched {
+	if pattern, matched := matchesAny(r.denyPatterns, resolved); matched {
 		return "", fmt.Errorf("access denied: path %q matches deny pattern %q", resolved, pattern)
 	}
 
 	return resolved, nil
 }
 
-// matchesDeny checks if the resolved path or any of its parent directories
-// match a deny pattern. Returns the matching pattern and true if denied.
+// ResolveForWrite is like Resolve but additionally rejects paths matching a
+// deny-write pattern. Tools that modify files must use it instead of Resolve.
+func (r *Resolver) ResolveForWrite(baseCwd string, path string) (string, error) {
+	resolved, err := r.Resolve(baseCwd, path)
+	if err != nil {
+		return "", err
+	}
+	if pattern, matched := matchesAny(r.denyWritePatterns, resolved); matched {
+		return "", fmt.Errorf("access denied: path %q matches deny-write pattern %q", resolved, pattern)
+	}
+	return resolved, nil
+}
+
+// matchesAny checks if the resolved path or any of its parent directories
+// match one of patterns. Returns the matching pattern and true if denied.
 // Match errors are treated as a deny (fail closed).
-func (r *Resolver) matchesDeny(resolved string) (string, bool) {
-	for _, pattern := range r.denyPatterns {
+func matchesAny(patterns []string, resolved string) (string, bool) {
+	for _, pattern := range patterns {
 		// Check the path itself
 		matched, err := doublestar.PathMatch(pattern, resolved)
 		if err != nil || matched {
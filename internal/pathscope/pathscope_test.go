@@ -279,3 +279,70 @@ func TestInvalidDenyPattern(t *testing.T) {
 		t.Errorf("expected 'invalid deny pattern' error, got: %v", err)
 	}
 }
+
+func TestDenyWritePatterns(t *testing.T) {
+	tmp := t.TempDir()
+	lock := filepath.Join(tmp, "sub", "Cargo.lock")
+	os.MkdirAll(filepath.Dir(lock), 0755)
+	os.WriteFile(lock, []byte("x"), 0644)
+
+	base, err := NewResolver([]string{tmp}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := base.WithDenyWritePatterns([]string{"**/*.lock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Resolve("/", lock); err != nil {
+		t.Errorf("Resolve should allow reading: %v", err)
+	}
+	_, err = r.ResolveForWrite("/", lock)
+	if err == nil || !strings.Contains(err.Error(), "deny-write pattern") {
+		t.Errorf("ResolveForWrite should deny, got %v", err)
+	}
+	if _, err := r.ResolveForWrite("/", filepath.Join(tmp, "sub", "main.go")); err != nil {
+		t.Errorf("other paths should stay writable: %v", err)
+	}
+	if _, err := base.ResolveForWrite("/", lock); err != nil {
+		t.Errorf("original resolver should be unaffected: %v", err)
+	}
+
+	if _, err := base.WithDenyWritePatterns([]string{"[invalid"}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestWithAllowDir(t *testing.T) {
+	tmp := t.TempDir()
+	allowed := filepath.Join(tmp, "allowed")
+	extra := filepath.Join(tmp, "extra")
+	other := filepath.Join(tmp, "other")
+	for _, d := range []string{allowed, extra, other} {
+		os.MkdirAll(d, 0755)
+	}
+	base, err := NewResolver([]string{allowed}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := base.WithAllowDir(extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Resolve("/", filepath.Join(extra, "f.txt")); err != nil {
+		t.Errorf("added dir should be allowed: %v", err)
+	}
+	if _, err := r.Resolve("/", filepath.Join(other, "f.txt")); err == nil {
+		t.Error("sibling of the added dir should stay denied")
+	}
+	if _, err := base.Resolve("/", filepath.Join(extra, "f.txt")); err == nil {
+		t.Error("original resolver should be unaffected")
+	}
+
+	open, _ := NewResolver(nil, nil)
+	if r, err := open.WithAllowDir(extra); err != nil || len(r.AllowDirs()) != 0 {
+		t.Errorf("unrestricted resolver should stay unrestricted, got %v, %v", r.AllowDirs(), err)
+	}
+}
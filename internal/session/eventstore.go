@@ -12,6 +12,10 @@ import (
 // Open, Append, and After are no-ops — stream resumption is not supported.
 type SessionCleanupStore struct {
 	Registry *SessionRegistry
+
+	// OnClosed, if non-nil, is called after a registered session is closed
+	// with the number of its background tasks that were killed.
+	OnClosed func(sessionID string, killedTasks int)
 }
 
 // Open is a no-op; stream resumption is not supported.
@@ -33,6 +37,8 @@ func (s *SessionCleanupStore) After(_ context.Context, _, _ string, _ int) iter.
 // (idle timeout, client DELETE, or connection drop). It closes the
 // corresponding Boris session and removes it from the registry.
 func (s *SessionCleanupStore) SessionClosed(_ context.Context, sessionID string) error {
-	s.Registry.CloseAndRemove(sessionID)
+	if found, killed := s.Registry.closeAndRemove(sessionID); found && s.OnClosed != nil {
+		s.OnClosed(sessionID, killed)
+	}
 	return nil
 }
@@ -1,12 +1,17 @@
 package session
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // SessionRegistry maps go-sdk session IDs to Boris sessions, enabling
 // cleanup when the SDK signals session end (via EventStore.SessionClosed).
 type SessionRegistry struct {
 	mu       sync.Mutex
 	sessions map[string]*Session
+	closed   atomic.Int64 // sessions closed by CloseAndRemove
 }
 
 // NewRegistry creates an empty SessionRegistry.
@@ -27,15 +32,32 @@ func (r *SessionRegistry) Register(id string, sess *Session) {
 // CloseAndRemove closes the Boris session for the given ID and removes it
 // from the registry. If the ID is not found, this is a no-op.
 func (r *SessionRegistry) CloseAndRemove(id string) {
+	r.closeAndRemove(id)
+}
+
+// closeAndRemove is CloseAndRemove, also reporting whether id was registered
+// and how many of the session's background tasks were still running, and
+// so were killed by closing it.
+func (r *SessionRegistry) closeAndRemove(id string) (found bool, killed int) {
 	r.mu.Lock()
 	sess, ok := r.sessions[id]
 	if ok {
 		delete(r.sessions, id)
 	}
 	r.mu.Unlock()
-	if ok {
-		sess.Close()
+	if !ok {
+		return false, 0
 	}
+	killed = len(sess.RunningTasks())
+	sess.Close()
+	r.closed.Add(1)
+	return true, killed
+}
+
+// ClosedCount returns the number of sessions closed by CloseAndRemove, that
+// is, ended by an idle timeout, a client DELETE, or a dropped connection.
+func (r *SessionRegistry) ClosedCount() int64 {
+	return r.closed.Load()
 }
 
 // CloseAll closes every session in the registry and clears the map.
@@ -53,3 +75,65 @@ func (r *SessionRegistry) CloseAll() {
 		sess.Close()
 	}
 }
+
+// SessionCount returns the number of registered sessions.
+func (r *SessionRegistry) SessionCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sessions)
+}
+
+// RunningTaskCount returns the number of background tasks still running
+// across all registered sessions.
+func (r *SessionRegistry) RunningTaskCount() int {
+	n := 0
+	for _, sess := range r.snapshot() {
+		n += len(sess.RunningTasks())
+	}
+	return n
+}
+
+// Drain waits up to timeout for running background tasks in every registered
+// session to exit on their own, then closes all sessions via CloseAll,
+// force-killing whatever is left. It returns the number of tasks that were
+// still running when the timeout expired. Callers should stop accepting new
+// sessions before draining.
+func (r *SessionRegistry) Drain(timeout time.Duration) int {
+	var running []*BackgroundTask
+	for _, sess := range r.snapshot() {
+		running = append(running, sess.RunningTasks()...)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	remaining := 0
+	for i, t := range running {
+		select {
+		case <-t.Done:
+		case <-deadline.C:
+			// Count this task and any others that haven't finished yet.
+			for _, rest := range running[i:] {
+				select {
+				case <-rest.Done:
+				default:
+					remaining++
+				}
+			}
+			r.CloseAll()
+			return remaining
+		}
+	}
+	r.CloseAll()
+	return remaining
+}
+
+// snapshot returns the currently registered sessions.
+func (r *SessionRegistry) snapshot() []*Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := make([]*Session, 0, len(r.sessions))
+	for _, sess := range r.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
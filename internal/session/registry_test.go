@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestRegistryRegisterAndClose(t *testing.T) {
@@ -141,3 +142,79 @@ func TestRegistryCloseAllConcurrentWithCloseAndRemove(t *testing.T) {
 	wg.Wait()
 	// No race detector failure or panic means success.
 }
+
+func TestRegistryRunningTaskCount(t *testing.T) {
+	r := NewRegistry()
+	for i := 0; i < 2; i++ {
+		s := New("/workspace")
+		if err := s.AddTask(startSleepTask(t, fmt.Sprintf("t%d", i))); err != nil {
+			t.Fatal(err)
+		}
+		r.Register(fmt.Sprintf("sdk-%d", i), s)
+	}
+	t.Cleanup(r.CloseAll)
+
+	if n := r.RunningTaskCount(); n != 2 {
+		t.Errorf("RunningTaskCount() = %d, want 2", n)
+	}
+}
+
+func TestRegistryDrain(t *testing.T) {
+	r := NewRegistry()
+	s := New("/workspace")
+	short := startSleepTaskFor(t, "short", "0.2")
+	long := startSleepTask(t, "long")
+	for _, task := range []*BackgroundTask{short, long} {
+		if err := s.AddTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Register("sdk-1", s)
+
+	remaining := r.Drain(2 * time.Second)
+	if remaining != 1 {
+		t.Errorf("Drain() = %d, want 1 task still running at timeout", remaining)
+	}
+
+	// The short task finished on its own during the drain.
+	select {
+	case <-short.Done:
+	default:
+		t.Fatal("expected short task to be done after Drain")
+	}
+	if !short.Cmd.ProcessState.Success() {
+		t.Errorf("short task should exit cleanly, got %v", short.Cmd.ProcessState)
+	}
+
+	// The long task was force-killed when the drain timed out.
+	select {
+	case <-long.Done:
+	default:
+		t.Fatal("expected long task to be killed after Drain")
+	}
+	if long.Cmd.ProcessState.Success() {
+		t.Error("long task should have been killed, but exited cleanly")
+	}
+
+	if n := r.RunningTaskCount(); n != 0 {
+		t.Errorf("RunningTaskCount() after Drain = %d, want 0", n)
+	}
+}
+
+func TestRegistryDrainNoTasks(t *testing.T) {
+	r := NewRegistry()
+	s := New("/workspace")
+	r.Register("sdk-1", s)
+
+	start := time.Now()
+	if remaining := r.Drain(5 * time.Second); remaining != 0 {
+		t.Errorf("Drain() = %d, want 0", remaining)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Drain with no tasks took %v, expected immediate return", elapsed)
+	}
+	// Session should be closed.
+	if err := s.AddTask(&BackgroundTask{ID: "late", Done: make(chan struct{})}); err == nil {
+		t.Error("expected error adding task to closed session")
+	}
+}
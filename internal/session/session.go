@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os/exec"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -34,13 +35,17 @@ func (sb *SyncBuffer) String() string {
 
 // BackgroundTask represents a command running in the background.
 type BackgroundTask struct {
-	ID       string
-	Cmd      *exec.Cmd
-	Stdout   *SyncBuffer
-	Stderr   *SyncBuffer
-	Done     chan struct{}
-	ExitCode int
-	timedOut atomic.Bool // set when the safety-net timeout kills this task
+	ID             string
+	IdempotencyKey string // optional client-supplied key; empty if none
+	Cmd            *exec.Cmd
+	Stop           func() // optional; stops a task that has no process (Cmd is nil)
+	Stdout         *SyncBuffer
+	Stderr         *SyncBuffer
+	StdoutPath     string // set when stdout goes to this file instead of Stdout
+	StderrPath     string // set when stderr goes to this file instead of Stderr
+	Done           chan struct{}
+	ExitCode       int         // set before Done is closed; read only after <-Done
+	timedOut       atomic.Bool // set when the safety-net timeout kills this task
 }
 
 // SetTimedOut marks the task as killed by the safety-net timeout.
@@ -49,15 +54,24 @@ func (t *BackgroundTask) SetTimedOut() { t.timedOut.Store(true) }
 // TimedOut reports whether the task was killed by the safety-net timeout.
 func (t *BackgroundTask) TimedOut() bool { return t.timedOut.Load() }
 
+// DefaultMaxTasks is the default limit on concurrent background tasks per session.
+const DefaultMaxTasks = 10
+
 // Session holds per-session state including the tracked working directory,
-// a random nonce for sentinel generation, background task tracking, and
-// viewed-file tracking for view-before-edit enforcement.
+// a random nonce for sentinel generation, background task tracking,
+// viewed-file tracking for view-before-edit enforcement, and edit tokens
+// issued by grep for bulk replacement. All methods are
+// safe for concurrent use by tool handlers, timers, and task goroutines.
 type Session struct {
 	mu          sync.Mutex
 	cwd         string
 	nonce       string
 	tasks       map[string]*BackgroundTask
-	viewedFiles map[string]struct{}
+	taskKeys    map[string]string // idempotency key -> task ID
+	maxTasks    int
+	viewedFiles map[string]viewStamp
+	editTokens  map[string]editToken
+	onClose     []func()
 	closed      bool
 	closeOnce   sync.Once
 }
@@ -72,10 +86,19 @@ func New(cwd string) *Session {
 		cwd:         cwd,
 		nonce:       hex.EncodeToString(b),
 		tasks:       make(map[string]*BackgroundTask),
-		viewedFiles: make(map[string]struct{}),
+		taskKeys:    make(map[string]string),
+		maxTasks:    DefaultMaxTasks,
+		viewedFiles: make(map[string]viewStamp),
+		editTokens:  make(map[string]editToken),
 	}
 }
 
+// editToken records the query an edit token was issued for and when.
+type editToken struct {
+	query  string
+	issued time.Time
+}
+
 // Nonce returns the session's random nonce.
 func (s *Session) Nonce() string {
 	return s.nonce
@@ -100,11 +123,30 @@ func (s *Session) SetCwd(cwd string) {
 	s.cwd = cwd
 }
 
-// MarkViewed records a resolved file path as having been viewed in this session.
-func (s *Session) MarkViewed(path string) {
+// viewStamp is the modification time and size of a file when it was viewed,
+// used to detect changes made since.
+type viewStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// MarkViewed records a resolved file path as having been viewed in this
+// session while it had the given modification time and size.
+func (s *Session) MarkViewed(path string, modTime time.Time, size int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.viewedFiles[path] = struct{}{}
+	s.viewedFiles[path] = viewStamp{modTime: modTime, size: size}
+}
+
+// RefreshViewed updates the recorded modification time and size of a viewed
+// file, e.g. after a tool edits it, so the edit does not count as a change
+// since the view. Files that were never viewed are left unviewed.
+func (s *Session) RefreshViewed(path string, modTime time.Time, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.viewedFiles[path]; ok {
+		s.viewedFiles[path] = viewStamp{modTime: modTime, size: size}
+	}
 }
 
 // HasViewed reports whether the given resolved file path has been viewed in this session.
@@ -115,21 +157,126 @@ func (s *Session) HasViewed(path string) bool {
 	return ok
 }
 
+// CheckViewed reports whether path has been viewed in this session and, if
+// so, whether its current modification time or size differs from when it
+// was viewed.
+func (s *Session) CheckViewed(path string, modTime time.Time, size int64) (viewed, changed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stamp, ok := s.viewedFiles[path]
+	if !ok {
+		return false, false
+	}
+	return true, !stamp.modTime.Equal(modTime) || stamp.size != size
+}
+
+// ViewedFiles returns the resolved paths viewed in this session, sorted.
+func (s *Session) ViewedFiles() []string {
+	s.mu.Lock()
+	paths := make([]string, 0, len(s.viewedFiles))
+	for path := range s.viewedFiles {
+		paths = append(paths, path)
+	}
+	s.mu.Unlock()
+	sort.Strings(paths)
+	return paths
+}
+
+// IssueEditToken returns a new random token bound to query, an opaque
+// description of the search it authorizes. Tokens older than maxAge are
+// pruned as a side effect.
+func (s *Session) IssueEditToken(query string, maxAge time.Duration) string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate edit token: %v", err))
+	}
+	token := hex.EncodeToString(b)
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, et := range s.editTokens {
+		if now.Sub(et.issued) > maxAge {
+			delete(s.editTokens, t)
+		}
+	}
+	s.editTokens[token] = editToken{query: query, issued: now}
+	return token
+}
+
+// CheckEditToken reports whether token was issued for query within maxAge.
+func (s *Session) CheckEditToken(token, query string, maxAge time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	et, ok := s.editTokens[token]
+	return ok && et.query == query && time.Since(et.issued) <= maxAge
+}
+
+// ConsumeEditToken reports whether token was issued for query within maxAge
+// and, if so, invalidates it so that it cannot be used again. The check and
+// removal happen under one lock, so concurrent callers cannot both succeed.
+func (s *Session) ConsumeEditToken(token, query string, maxAge time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	et, ok := s.editTokens[token]
+	if !ok || et.query != query || time.Since(et.issued) > maxAge {
+		return false
+	}
+	delete(s.editTokens, token)
+	return true
+}
+
+// OnClose registers fn to run when the session is closed, after its
+// background tasks have been terminated. Hooks run in registration order.
+func (s *Session) OnClose(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onClose = append(s.onClose, fn)
+}
+
+// SetMaxTasks sets the limit on concurrent background tasks. A limit of 0
+// or less disables background tasks entirely.
+func (s *Session) SetMaxTasks(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxTasks = n
+}
+
 // AddTask stores a background task. Returns an error if the session is
-// closed or the limit is reached.
+// closed, background tasks are disabled, the limit is reached, or the task's
+// idempotency key is already held by another task.
 func (s *Session) AddTask(task *BackgroundTask) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.closed {
 		return fmt.Errorf("session is closed")
 	}
-	if len(s.tasks) >= 10 {
-		return fmt.Errorf("maximum concurrent background task limit (10) reached")
+	if task.IdempotencyKey != "" {
+		if id, ok := s.taskKeys[task.IdempotencyKey]; ok {
+			return fmt.Errorf("idempotency key %q is already used by task %s", task.IdempotencyKey, id)
+		}
+	}
+	if s.maxTasks <= 0 {
+		return fmt.Errorf("background tasks are disabled")
+	}
+	if len(s.tasks) >= s.maxTasks {
+		return fmt.Errorf("maximum concurrent background task limit (%d) reached", s.maxTasks)
 	}
 	s.tasks[task.ID] = task
+	if task.IdempotencyKey != "" {
+		s.taskKeys[task.IdempotencyKey] = task.ID
+	}
 	return nil
 }
 
+// TaskIDForKey returns the ID of the tracked task started with the given
+// idempotency key, if any.
+func (s *Session) TaskIDForKey(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.taskKeys[key]
+	return id, ok
+}
+
 // GetTask retrieves a background task by ID.
 func (s *Session) GetTask(id string) (*BackgroundTask, bool) {
 	s.mu.Lock()
@@ -138,10 +285,13 @@ func (s *Session) GetTask(id string) (*BackgroundTask, bool) {
 	return t, ok
 }
 
-// RemoveTask removes a background task by ID.
+// RemoveTask removes a background task by ID, releasing its idempotency key.
 func (s *Session) RemoveTask(id string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if t, ok := s.tasks[id]; ok && t.IdempotencyKey != "" {
+		delete(s.taskKeys, t.IdempotencyKey)
+	}
 	delete(s.tasks, id)
 }
 
@@ -152,9 +302,90 @@ func (s *Session) TaskCount() int {
 	return len(s.tasks)
 }
 
+// RunningTasks returns the background tasks that have not yet exited.
+func (s *Session) RunningTasks() []*BackgroundTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var running []*BackgroundTask
+	for _, t := range s.tasks {
+		select {
+		case <-t.Done:
+		default:
+			running = append(running, t)
+		}
+	}
+	return running
+}
+
+// KillTasks terminates every running background task the way Close does,
+// without closing the session, and removes the killed tasks from it.
+// Completed tasks awaiting retrieval are left in place. It returns the IDs
+// of the killed tasks.
+func (s *Session) KillTasks() []string {
+	s.mu.Lock()
+	var running []*BackgroundTask
+	for id, t := range s.tasks {
+		select {
+		case <-t.Done:
+			continue
+		default:
+		}
+		running = append(running, t)
+		delete(s.tasks, id)
+		if t.IdempotencyKey != "" {
+			delete(s.taskKeys, t.IdempotencyKey)
+		}
+	}
+	s.mu.Unlock()
+
+	terminateTasks(running)
+	ids := make([]string, len(running))
+	for i, t := range running {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// terminateTasks sends SIGTERM to the process group of each task that is
+// still running, waits up to 5 seconds for it to exit, then sends SIGKILL.
+// Tasks without a process are stopped through their Stop func instead.
+// Tasks are terminated concurrently; it returns once all have exited.
+func terminateTasks(tasks []*BackgroundTask) {
+	var wg sync.WaitGroup
+	for _, t := range tasks {
+		select {
+		case <-t.Done:
+			continue // already finished
+		default:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if t.Stop != nil {
+				t.Stop()
+				<-t.Done
+				return
+			}
+			pgid := t.Cmd.Process.Pid
+			_ = syscall.Kill(-pgid, syscall.SIGTERM)
+
+			// Wait up to 5 seconds for graceful exit, then SIGKILL.
+			select {
+			case <-t.Done:
+			case <-time.After(5 * time.Second):
+				_ = syscall.Kill(-pgid, syscall.SIGKILL)
+				<-t.Done
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // Close terminates all running background tasks and marks the session as
 // closed. For each running task, it sends SIGTERM to the process group,
 // waits up to 5 seconds, then sends SIGKILL if the process is still alive.
+// OnClose hooks run once all tasks have exited.
 // Close is idempotent — subsequent calls have no effect.
 func (s *Session) Close() {
 	s.closeOnce.Do(func() {
@@ -165,25 +396,15 @@ func (s *Session) Close() {
 		}
 		s.closed = true
 		s.tasks = make(map[string]*BackgroundTask)
+		s.taskKeys = make(map[string]string)
+		hooks := s.onClose
+		s.onClose = nil
 		s.mu.Unlock()
 
-		for _, t := range tasks {
-			select {
-			case <-t.Done:
-				continue // already finished
-			default:
-			}
-
-			pgid := t.Cmd.Process.Pid
-			_ = syscall.Kill(-pgid, syscall.SIGTERM)
+		terminateTasks(tasks)
 
-			// Wait up to 5 seconds for graceful exit, then SIGKILL.
-			select {
-			case <-t.Done:
-			case <-time.After(5 * time.Second):
-				_ = syscall.Kill(-pgid, syscall.SIGKILL)
-				<-t.Done
-			}
+		for _, fn := range hooks {
+			fn()
 		}
 	})
 }
@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
@@ -57,7 +58,7 @@ func TestSentinel(t *testing.T) {
 func TestViewedFiles(t *testing.T) {
 	t.Run("mark and check", func(t *testing.T) {
 		s := New("/workspace")
-		s.MarkViewed("/workspace/src/main.go")
+		s.MarkViewed("/workspace/src/main.go", time.Time{}, 0)
 		if !s.HasViewed("/workspace/src/main.go") {
 			t.Error("expected HasViewed to return true after MarkViewed")
 		}
@@ -70,10 +71,50 @@ func TestViewedFiles(t *testing.T) {
 		}
 	})
 
+	t.Run("list is sorted", func(t *testing.T) {
+		s := New("/workspace")
+		if got := s.ViewedFiles(); len(got) != 0 {
+			t.Errorf("expected no viewed files, got %v", got)
+		}
+		s.MarkViewed("/workspace/b.go", time.Time{}, 0)
+		s.MarkViewed("/workspace/a.go", time.Time{}, 0)
+		s.MarkViewed("/workspace/b.go", time.Time{}, 0)
+		if got := s.ViewedFiles(); len(got) != 2 || got[0] != "/workspace/a.go" || got[1] != "/workspace/b.go" {
+			t.Errorf("ViewedFiles() = %v, want [/workspace/a.go /workspace/b.go]", got)
+		}
+	})
+
+	t.Run("changes since view", func(t *testing.T) {
+		s := New("/workspace")
+		viewedAt := time.Unix(1000, 0)
+		if viewed, _ := s.CheckViewed("/workspace/a.go", viewedAt, 10); viewed {
+			t.Error("expected unviewed file to report viewed=false")
+		}
+		s.MarkViewed("/workspace/a.go", viewedAt, 10)
+		if viewed, changed := s.CheckViewed("/workspace/a.go", viewedAt, 10); !viewed || changed {
+			t.Errorf("same stamp: viewed=%v changed=%v, want true false", viewed, changed)
+		}
+		if _, changed := s.CheckViewed("/workspace/a.go", viewedAt.Add(time.Second), 10); !changed {
+			t.Error("expected a newer mtime to count as changed")
+		}
+		if _, changed := s.CheckViewed("/workspace/a.go", viewedAt, 11); !changed {
+			t.Error("expected a different size to count as changed")
+		}
+
+		s.RefreshViewed("/workspace/a.go", viewedAt.Add(time.Second), 11)
+		if _, changed := s.CheckViewed("/workspace/a.go", viewedAt.Add(time.Second), 11); changed {
+			t.Error("expected refreshed stamp to count as unchanged")
+		}
+		s.RefreshViewed("/workspace/b.go", viewedAt, 10)
+		if s.HasViewed("/workspace/b.go") {
+			t.Error("RefreshViewed should not mark an unviewed file as viewed")
+		}
+	})
+
 	t.Run("per-session isolation", func(t *testing.T) {
 		a := New("/workspace")
 		b := New("/workspace")
-		a.MarkViewed("/workspace/file.go")
+		a.MarkViewed("/workspace/file.go", time.Time{}, 0)
 		if b.HasViewed("/workspace/file.go") {
 			t.Error("session B should not see session A's viewed files")
 		}
@@ -87,7 +128,7 @@ func TestViewedFiles(t *testing.T) {
 			path := fmt.Sprintf("/workspace/file%d.go", i)
 			go func() {
 				defer wg.Done()
-				s.MarkViewed(path)
+				s.MarkViewed(path, time.Time{}, 0)
 			}()
 			go func() {
 				defer wg.Done()
@@ -162,13 +203,42 @@ func TestBackgroundTasks(t *testing.T) {
 			s.RemoveTask(string(rune('0' + i)))
 		}
 	})
+	t.Run("custom limit", func(t *testing.T) {
+		cs := New("/tmp")
+		cs.SetMaxTasks(2)
+		for _, id := range []string{"a", "b"} {
+			if err := cs.AddTask(&BackgroundTask{ID: id, Done: make(chan struct{})}); err != nil {
+				t.Fatalf("task %s should succeed: %v", id, err)
+			}
+		}
+		err := cs.AddTask(&BackgroundTask{ID: "c", Done: make(chan struct{})})
+		if err == nil || !strings.Contains(err.Error(), "(2)") {
+			t.Errorf("expected limit (2) error, got: %v", err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ds := New("/tmp")
+		ds.SetMaxTasks(0)
+		err := ds.AddTask(&BackgroundTask{ID: "a", Done: make(chan struct{})})
+		if err == nil || !strings.Contains(err.Error(), "disabled") {
+			t.Errorf("expected disabled error, got: %v", err)
+		}
+	})
 }
 
 // startSleepTask starts a real "sleep" process as a background task with
 // process group isolation, matching how bash.go launches background tasks.
 func startSleepTask(t *testing.T, id string) *BackgroundTask {
 	t.Helper()
-	cmd := exec.Command("sleep", "300")
+	return startSleepTaskFor(t, id, "300")
+}
+
+// startSleepTaskFor is like startSleepTask but sleeps for the given duration
+// (in sleep(1) syntax).
+func startSleepTaskFor(t *testing.T, id, duration string) *BackgroundTask {
+	t.Helper()
+	cmd := exec.Command("sleep", duration)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	if err := cmd.Start(); err != nil {
 		t.Fatalf("failed to start sleep process: %v", err)
@@ -271,6 +341,59 @@ func TestAddTaskRejectedAfterClose(t *testing.T) {
 	}
 }
 
+func TestTaskIdempotencyKeys(t *testing.T) {
+	s := New("/workspace")
+
+	if err := s.AddTask(&BackgroundTask{ID: "first", IdempotencyKey: "k", Done: make(chan struct{})}); err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := s.TaskIDForKey("k"); !ok || id != "first" {
+		t.Errorf("TaskIDForKey = %q, %v; want first, true", id, ok)
+	}
+
+	err := s.AddTask(&BackgroundTask{ID: "second", IdempotencyKey: "k", Done: make(chan struct{})})
+	if err == nil {
+		t.Fatal("expected error adding a second task with the same key")
+	}
+	if _, ok := s.GetTask("second"); ok {
+		t.Error("duplicate-key task should not be tracked")
+	}
+
+	// Removing the task releases its key
+	s.RemoveTask("first")
+	if _, ok := s.TaskIDForKey("k"); ok {
+		t.Error("key should be released when its task is removed")
+	}
+	if err := s.AddTask(&BackgroundTask{ID: "third", IdempotencyKey: "k", Done: make(chan struct{})}); err != nil {
+		t.Errorf("key should be reusable after removal: %v", err)
+	}
+}
+
+func TestOnCloseHooks(t *testing.T) {
+	s := New("/workspace")
+	task := startSleepTask(t, "hooked")
+	if err := s.AddTask(task); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	s.OnClose(func() {
+		select {
+		case <-task.Done:
+		default:
+			t.Error("hook ran before background task exited")
+		}
+		calls = append(calls, "first")
+	})
+	s.OnClose(func() { calls = append(calls, "second") })
+
+	s.Close()
+	s.Close()
+	if strings.Join(calls, ",") != "first,second" {
+		t.Errorf("hooks ran as %v, want [first second] exactly once", calls)
+	}
+}
+
 func TestCloseConcurrentSafety(t *testing.T) {
 	s := New("/workspace")
 	for i := 0; i < 3; i++ {
@@ -291,3 +414,93 @@ func TestCloseConcurrentSafety(t *testing.T) {
 	wg.Wait()
 	// No race detector failure means success.
 }
+
+func TestRunningTasks(t *testing.T) {
+	s := New("/workspace")
+	t.Cleanup(s.Close)
+
+	done := &BackgroundTask{ID: "done", Done: make(chan struct{})}
+	close(done.Done)
+	running := startSleepTask(t, "running")
+	for _, task := range []*BackgroundTask{done, running} {
+		if err := s.AddTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := s.RunningTasks()
+	if len(got) != 1 || got[0].ID != "running" {
+		t.Errorf("expected only the running task, got %d tasks", len(got))
+	}
+}
+
+func TestEditTokens(t *testing.T) {
+	s := New("/workspace")
+	token := s.IssueEditToken("q1", time.Minute)
+	if !s.CheckEditToken(token, "q1", time.Minute) {
+		t.Error("fresh token should be valid for its query")
+	}
+	if s.CheckEditToken(token, "q2", time.Minute) {
+		t.Error("token should not be valid for a different query")
+	}
+	if s.CheckEditToken("bogus", "q1", time.Minute) {
+		t.Error("unknown token should be invalid")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if s.CheckEditToken(token, "q1", time.Millisecond) {
+		t.Error("token older than maxAge should be invalid")
+	}
+	if s.ConsumeEditToken(token, "q2", time.Minute) {
+		t.Error("token should not be consumed for a different query")
+	}
+	if !s.ConsumeEditToken(token, "q1", time.Minute) {
+		t.Error("valid token should be consumed")
+	}
+	if s.CheckEditToken(token, "q1", time.Minute) || s.ConsumeEditToken(token, "q1", time.Minute) {
+		t.Error("consumed token should be invalid")
+	}
+}
+
+func TestConsumeEditTokenOnce(t *testing.T) {
+	s := New("/workspace")
+	token := s.IssueEditToken("q", time.Minute)
+	var wg sync.WaitGroup
+	var consumed atomic.Int32
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.ConsumeEditToken(token, "q", time.Minute) {
+				consumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := consumed.Load(); n != 1 {
+		t.Errorf("token consumed %d times, want 1", n)
+	}
+}
+
+func TestCloseStopsProcesslessTask(t *testing.T) {
+	s := New("/tmp")
+	stopped := make(chan struct{})
+	task := &BackgroundTask{
+		ID:   "watch",
+		Done: make(chan struct{}),
+		Stop: func() { close(stopped) },
+	}
+	if err := s.AddTask(task); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-stopped
+		close(task.Done)
+	}()
+
+	s.Close()
+	select {
+	case <-task.Done:
+	default:
+		t.Fatal("Close returned before the task's Stop took effect")
+	}
+}
@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxAuditArgChars caps each string argument recorded in the audit log so
+// that large payloads (file contents, replacement text) don't bloat it.
+const maxAuditArgChars = 256
+
+// auditMu serializes audit log writes across all sessions sharing a writer.
+var auditMu sync.Mutex
+
+// auditEntry is a single JSON line in the audit log.
+type auditEntry struct {
+	Time       time.Time      `json:"time"`
+	SessionID  string         `json:"session_id,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Tool       string         `json:"tool"`
+	Args       map[string]any `json:"args,omitempty"`
+	ErrorCode  string         `json:"error_code,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+// withAudit wraps a tool handler so that every invocation is recorded as a
+// JSON line on w. A nil writer returns the handler unchanged.
+func withAudit[In any](w io.Writer, name string, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	if w == nil {
+		return h
+	}
+	return func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		start := time.Now()
+		result, out, err := h(ctx, req, args)
+
+		entry := auditEntry{
+			Time:       start.UTC(),
+			Tool:       name,
+			Args:       sanitizeAuditArgs(args),
+			RequestID:  RequestID(ctx),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if req != nil && req.Session != nil {
+			entry.SessionID = req.Session.ID()
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		} else if result != nil && result.IsError {
+			entry.ErrorCode = resultErrorCode(result)
+		}
+		writeAuditEntry(ctx, w, entry)
+
+		return result, out, err
+	}
+}
+
+// writeAuditEntry encodes entry as a single JSON line and writes it to w.
+// Failures are logged rather than surfaced to the tool caller.
+func writeAuditEntry(ctx context.Context, w io.Writer, entry auditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		logger(ctx).Warn("failed to encode audit entry", "tool", entry.Tool, "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if _, err := w.Write(line); err != nil {
+		logger(ctx).Warn("failed to write audit entry", "tool", entry.Tool, "error", err)
+	}
+}
+
+// auditSecretKeyParts mark argument names whose values are withheld from the
+// audit log, matched case-insensitively as substrings of the name.
+var auditSecretKeyParts = []string{"token", "secret", "password", "passwd", "api_key", "apikey", "credential", "auth"}
+
+// sanitizeAuditArgs converts tool arguments to a generic map via their JSON
+// representation, redacting secret-like values and truncating long strings.
+func sanitizeAuditArgs(args any) map[string]any {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	sanitizeAuditMap(m)
+	return m
+}
+
+// sanitizeAuditMap redacts and truncates the values of m in place, descending
+// into nested objects.
+func sanitizeAuditMap(m map[string]any) {
+	for k, v := range m {
+		if isSecretAuditKey(k) {
+			m[k] = "[redacted]"
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			m[k] = truncateAuditString(v)
+		case map[string]any:
+			sanitizeAuditMap(v)
+		}
+	}
+}
+
+func isSecretAuditKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range auditSecretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateAuditString caps s at maxAuditArgChars runes, never splitting one.
+func truncateAuditString(s string) string {
+	if len(s) <= maxAuditArgChars {
+		return s
+	}
+	n := utf8.RuneCountInString(s)
+	if n <= maxAuditArgChars {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:maxAuditArgChars]) + fmt.Sprintf("... [truncated, %d chars total]", n)
+}
+
+// resultErrorCode extracts the error code produced by toolErr from an error
+// result, preferring the structured content over the "[CODE]" text prefix.
+// Returns "" if the result carries no recognizable code.
+func resultErrorCode(r *mcp.CallToolResult) string {
+	if te, ok := r.StructuredContent.(toolError); ok {
+		return te.Code
+	}
+	if len(r.Content) == 0 {
+		return ""
+	}
+	tc, ok := r.Content[0].(*mcp.TextContent)
+	if !ok || !strings.HasPrefix(tc.Text, "[") {
+		return ""
+	}
+	end := strings.Index(tc.Text, "]")
+	if end < 0 {
+		return ""
+	}
+	return tc.Text[1:end]
+}
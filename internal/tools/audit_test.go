@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+// decodeAuditLines parses each JSON line in buf into an auditEntry.
+func decodeAuditLines(t *testing.T, buf *bytes.Buffer) []auditEntry {
+	t.Helper()
+	var entries []auditEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e auditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAuditLogBash(t *testing.T) {
+	sess := session.New(t.TempDir())
+	var buf bytes.Buffer
+	h := withAudit(&buf, "bash", bashHandler(sess, testResolver(t), testConfig()))
+
+	if _, _, err := h(context.Background(), nil, BashArgs{Command: "echo audited"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := h(context.Background(), nil, BashArgs{Command: "   "}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := decodeAuditLines(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d: %s", len(entries), buf.String())
+	}
+	if entries[0].Tool != "bash" {
+		t.Errorf("tool = %q, want bash", entries[0].Tool)
+	}
+	if entries[0].Args["command"] != "echo audited" {
+		t.Errorf("args.command = %v, want %q", entries[0].Args["command"], "echo audited")
+	}
+	if entries[0].ErrorCode != "" {
+		t.Errorf("expected no error code for successful call, got %q", entries[0].ErrorCode)
+	}
+	if entries[0].Time.IsZero() {
+		t.Error("expected timestamp to be set")
+	}
+	if entries[1].ErrorCode != ErrBashEmptyCommand {
+		t.Errorf("error_code = %q, want %q", entries[1].ErrorCode, ErrBashEmptyCommand)
+	}
+}
+
+func TestAuditLogGrep(t *testing.T) {
+	tmp := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	sess := session.New(tmp)
+	var buf bytes.Buffer
+	h := withAudit(&buf, "grep", grepHandler(sess, resolver, testConfig()))
+
+	if _, _, err := h(context.Background(), nil, GrepArgs{Pattern: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := h(context.Background(), nil, GrepArgs{Pattern: "("}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := decodeAuditLines(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d: %s", len(entries), buf.String())
+	}
+	if entries[0].Tool != "grep" || entries[0].Args["pattern"] != "hello" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].ErrorCode != ErrGrepInvalidPattern {
+		t.Errorf("error_code = %q, want %q", entries[1].ErrorCode, ErrGrepInvalidPattern)
+	}
+}
+
+func TestAuditLogTruncatesLongArgs(t *testing.T) {
+	args := sanitizeAuditArgs(CreateFileArgs{Path: "a.txt", Content: strings.Repeat("x", 1000)})
+	content, _ := args["content"].(string)
+	if !strings.Contains(content, "[truncated, 1000 chars total]") {
+		t.Errorf("expected truncated content, got %q", content)
+	}
+	if args["path"] != "a.txt" {
+		t.Errorf("path = %v, want a.txt", args["path"])
+	}
+}
+
+func TestAuditLogTruncatesOnRuneBoundary(t *testing.T) {
+	args := sanitizeAuditArgs(CreateFileArgs{Path: "a.txt", Content: strings.Repeat("é", 300)})
+	content, _ := args["content"].(string)
+	if !utf8.ValidString(content) {
+		t.Fatalf("truncated content is not valid UTF-8: %q", content)
+	}
+	if want := strings.Repeat("é", maxAuditArgChars) + "... [truncated, 300 chars total]"; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
+func TestAuditLogRedactsSecrets(t *testing.T) {
+	args := sanitizeAuditArgs(map[string]any{
+		"pattern":    "needle",
+		"edit_token": "abc123",
+		"headers":    map[string]any{"Authorization": "Bearer xyz", "Accept": "text/plain"},
+	})
+	if args["edit_token"] != "[redacted]" {
+		t.Errorf("edit_token = %v, want redacted", args["edit_token"])
+	}
+	headers, _ := args["headers"].(map[string]any)
+	if headers["Authorization"] != "[redacted]" || headers["Accept"] != "text/plain" {
+		t.Errorf("headers = %v, want only Authorization redacted", headers)
+	}
+	if args["pattern"] != "needle" {
+		t.Errorf("pattern = %v, want needle", args["pattern"])
+	}
+}
+
+func TestAuditLogNilWriter(t *testing.T) {
+	sess := session.New(t.TempDir())
+	h := withAudit(nil, "bash", bashHandler(sess, testResolver(t), testConfig()))
+	result, _, err := h(context.Background(), nil, BashArgs{Command: "echo ok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resultText(result), "ok") {
+		t.Errorf("expected handler output, got: %s", resultText(result))
+	}
+}
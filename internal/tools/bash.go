@@ -6,8 +6,10 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -15,6 +17,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/mjkoo/boris/internal/pathscope"
 	"github.com/mjkoo/boris/internal/session"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -24,12 +27,17 @@ const maxOutputChars = 30000
 // BashArgs is the input schema for the bash tool.
 type BashArgs struct {
 	Command         string `json:"command" jsonschema:"the shell command to execute"`
-	Timeout         int    `json:"timeout,omitempty" jsonschema:"timeout in milliseconds (default 120000, max 600000)"`
+	Timeout         int    `json:"timeout,omitempty" jsonschema:"timeout in milliseconds (default 120000; capped at the server maximum, 600000 unless configured)"`
 	RunInBackground bool   `json:"run_in_background,omitempty" jsonschema:"run command in background, returns a task_id"`
 	Description     string `json:"description,omitempty" jsonschema:"optional human-readable description of what this command does"`
+	IdempotencyKey  string `json:"idempotency_key,omitempty" jsonschema:"background only: if a task with this key is already tracked in the session, return its task_id instead of starting another"`
+	Retries         int    `json:"retries,omitempty" jsonschema:"foreground only: re-run the command up to this many times (max 10) while it exits non-zero; timeouts are not retried"`
+	RetryBackoff    string `json:"retry_backoff,omitempty" jsonschema:"delay before the first retry, doubling after each attempt (e.g. '500ms', '2s'; default 1s)"`
+	OutputToFile    bool   `json:"output_to_file,omitempty" jsonschema:"background only: write stdout and stderr to files under the working directory instead of holding them in memory; task_output reports the paths and the end of each file"`
+	Cwd             string `json:"cwd,omitempty" jsonschema:"run this command in the given directory without changing the session working directory"`
 }
 
-func bashHandler(sess *session.Session, cfg Config) mcp.ToolHandlerFor[BashArgs, any] {
+func bashHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[BashArgs, any] {
 	// Convert CLI --timeout (seconds) to milliseconds for the default.
 	defaultTimeoutMs := cfg.DefaultTimeout * 1000
 	var regOnce sync.Once
@@ -47,24 +55,175 @@ func bashHandler(sess *session.Session, cfg Config) mcp.ToolHandlerFor[BashArgs,
 		if timeoutMs <= 0 {
 			timeoutMs = defaultTimeoutMs
 		}
-		if timeoutMs > 600000 {
-			timeoutMs = 600000
+		if maxMs := cfg.maxBashTimeoutMs(); timeoutMs > maxMs {
+			timeoutMs = maxMs
 		}
 
 		cwd := sess.Cwd()
 		sentinel := sess.Sentinel()
 
+		// A one-off cwd runs the command elsewhere while leaving the session
+		// working directory untouched, even if the command itself cds.
+		persistCwd := true
+		if args.Cwd != "" {
+			dir, err := resolver.Resolve(cwd, args.Cwd)
+			if err != nil {
+				return toolErr(ErrAccessDenied, "cwd not allowed: %v", err)
+			}
+			info, err := os.Stat(dir)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return toolErr(ErrPathNotFound, "%s does not exist", dir)
+				}
+				return toolErr(ErrIO, "could not stat %s: %v", dir, err)
+			}
+			if !info.IsDir() {
+				return toolErr(ErrInvalidInput, "%s is not a directory", dir)
+			}
+			cwd = dir
+			persistCwd = false
+		}
+
+		if args.Retries < 0 || args.Retries > maxBashRetries {
+			return toolErr(ErrInvalidInput, "retries must be between 0 and %d, got %d", maxBashRetries, args.Retries)
+		}
+		backoff := defaultRetryBackoff
+		if args.RetryBackoff != "" {
+			d, err := time.ParseDuration(args.RetryBackoff)
+			if err != nil || d < 0 {
+				return toolErr(ErrInvalidInput, "invalid retry_backoff %q: expected a non-negative duration such as 500ms or 2s", args.RetryBackoff)
+			}
+			backoff = d
+		}
+
 		if args.RunInBackground {
-			return runBackground(sess, cfg, cwd, args.Command)
+			if args.Retries > 0 {
+				return toolErr(ErrInvalidInput, "retries is not supported with run_in_background")
+			}
+			return runBackground(sess, resolver, cfg, cwd, args.Command, args.IdempotencyKey, args.OutputToFile)
+		}
+		if args.OutputToFile {
+			return toolErr(ErrInvalidInput, "output_to_file requires run_in_background")
 		}
 
-		return runForeground(ctx, req, sess, cfg, cwd, sentinel, args.Command, timeoutMs)
+		return runForeground(ctx, req, sess, cfg, cwd, sentinel, args.Command, timeoutMs, args.Retries, backoff, persistCwd)
 	}
 }
 
-func runForeground(ctx context.Context, req *mcp.CallToolRequest, sess *session.Session, cfg Config, cwd, sentinel, command string, timeoutMs int) (*mcp.CallToolResult, any, error) {
-	wrappedCmd := fmt.Sprintf("cd %s && %s ; echo ; echo '%s' ; pwd",
-		shellQuote(cwd), command, sentinel)
+// maxBashRetries caps BashArgs.Retries.
+const maxBashRetries = 10
+
+// defaultRetryBackoff is the delay before the first retry when
+// BashArgs.RetryBackoff is unset. Each later retry doubles it.
+const defaultRetryBackoff = time.Second
+
+// foregroundRun is the outcome of one foreground command execution.
+type foregroundRun struct {
+	exitCode      int
+	timedOut      bool
+	cancelled     bool
+	sentinelFound bool
+	cwd           string // working directory reported by the sentinel; empty if not observed
+	stdout        string // sentinel lines stripped
+	stderr        string
+}
+
+func runForeground(ctx context.Context, req *mcp.CallToolRequest, sess *session.Session, cfg Config, cwd, sentinel, command string, timeoutMs, retries int, backoff time.Duration, persistCwd bool) (*mcp.CallToolResult, any, error) {
+	// Retry on non-zero exit only; a timeout or cancellation ends the loop.
+	// Every attempt starts from the original cwd, so only the final
+	// attempt's directory change is kept.
+	attempts := 0
+	var run foregroundRun
+	for {
+		attempts++
+		var errResult *mcp.CallToolResult
+		run, errResult = execForeground(ctx, req, sess, cfg, cwd, sentinel, command, timeoutMs, persistCwd)
+		if errResult != nil {
+			return errResult, nil, nil
+		}
+		if run.exitCode == 0 || run.timedOut || run.cancelled || attempts > retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if persistCwd {
+			sess.SetCwd(cwd)
+		}
+		backoff *= 2
+	}
+	rawStdout, rawStderr := run.stdout, run.stderr
+
+	// Truncate output
+	stdoutStr := truncateOutput(run.stdout)
+	stderrStr := truncateOutput(run.stderr)
+
+	// Build response
+	var result strings.Builder
+	if run.timedOut {
+		fmt.Fprintf(&result, "Command timed out after %dms\n\n", timeoutMs)
+	} else if run.cancelled {
+		result.WriteString("Command cancelled by client\n\n")
+	}
+	fmt.Fprintf(&result, "exit_code: %d\n", run.exitCode)
+	if retries > 0 {
+		fmt.Fprintf(&result, "attempts: %d\n", attempts)
+	}
+	changedCwd := ""
+	if persistCwd && run.cwd != "" && run.cwd != cwd {
+		changedCwd = run.cwd
+		fmt.Fprintf(&result, "cwd: %s\n", changedCwd)
+	}
+	if stderrStr != "" {
+		fmt.Fprintf(&result, "\nstderr:\n%s", stderrStr)
+	}
+	if stdoutStr != "" {
+		fmt.Fprintf(&result, "\nstdout:\n%s", stdoutStr)
+	}
+	if !strings.HasSuffix(result.String(), "\n") {
+		result.WriteString("\n")
+	}
+	result.WriteString("\n" + outputSizeFooter(rawStdout, rawStderr))
+	if !run.sentinelFound && cfg.SentinelWarning {
+		if !strings.HasSuffix(result.String(), "\n") {
+			result.WriteString("\n")
+		}
+		result.WriteString("\n" + sentinelMissingNote)
+	}
+
+	r := &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: result.String()}},
+	}
+	if cfg.StructuredBashOutput {
+		br := newBashResult("", &run.exitCode, run.timedOut, rawStdout, rawStderr)
+		if retries > 0 {
+			br.Attempts = attempts
+		}
+		br.Cwd = changedCwd
+		attachStructured(r, br)
+	}
+	return r, nil, nil
+}
+
+// execForeground runs command once in cwd, streaming output as progress
+// notifications, and updates the session cwd from the sentinel if persistCwd
+// is set. It returns a non-nil result only if the process could not be
+// started.
+func execForeground(ctx context.Context, req *mcp.CallToolRequest, sess *session.Session, cfg Config, cwd, sentinel, command string, timeoutMs int, persistCwd bool) (foregroundRun, *mcp.CallToolResult) {
+	startErr := func(msg string, args ...any) (foregroundRun, *mcp.CallToolResult) {
+		r, _, _ := toolErr(ErrBashStartFailed, msg, args...)
+		return foregroundRun{}, r
+	}
+
+	// The command is passed to eval as a single quoted word so that a trailing
+	// backslash or unterminated heredoc cannot swallow the sentinel lines. Its
+	// exit status is saved and restored around the sentinel commands.
+	wrappedCmd := fmt.Sprintf("cd %s && eval %s ; __boris_status=$? ; echo ; echo '%s' ; pwd ; exit $__boris_status",
+		shellQuote(cwd), shellQuote(command), sentinel)
 
 	cmd := exec.Command(cfg.Shell, "-c", wrappedCmd)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -72,27 +231,39 @@ func runForeground(ctx context.Context, req *mcp.CallToolRequest, sess *session.
 	// Use pipes for streaming output
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
-		return toolErr(ErrBashStartFailed, "could not create stdout pipe: %v", err)
+		return startErr("could not create stdout pipe: %v", err)
 	}
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
-		return toolErr(ErrBashStartFailed, "could not create stderr pipe: %v", err)
+		return startErr("could not create stderr pipe: %v", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return toolErr(ErrBashStartFailed, "could not start command: %v", err)
+		return startErr("could not start command: %v", err)
 	}
 
 	pgid := cmd.Process.Pid
-	var timedOut atomic.Bool
+	var timedOut, cancelled atomic.Bool
 	var killTimer atomic.Pointer[time.Timer]
-	timer := time.AfterFunc(time.Duration(timeoutMs)*time.Millisecond, func() {
-		timedOut.Store(true)
+	// terminate sends SIGTERM to the process group, escalating to SIGKILL
+	// after a grace period. It is shared by the timeout and cancellation paths.
+	terminate := func() {
 		_ = syscall.Kill(-pgid, syscall.SIGTERM)
 		kt := time.AfterFunc(5*time.Second, func() {
 			_ = syscall.Kill(-pgid, syscall.SIGKILL)
 		})
-		killTimer.Store(kt)
+		if old := killTimer.Swap(kt); old != nil {
+			old.Stop()
+		}
+	}
+	timer := time.AfterFunc(time.Duration(timeoutMs)*time.Millisecond, func() {
+		timedOut.Store(true)
+		terminate()
+	})
+	// Client disconnects and cancellation notifications cancel ctx
+	stopCancel := context.AfterFunc(ctx, func() {
+		cancelled.Store(true)
+		terminate()
 	})
 
 	// Collect output via scanners, sending progress notifications
@@ -101,82 +272,136 @@ func runForeground(ctx context.Context, req *mcp.CallToolRequest, sess *session.
 	if req != nil && req.Params != nil {
 		progressToken = req.Params.GetProgressToken()
 	}
-	var lineCount atomic.Int64
+	var progress atomic.Int64
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		scanAndNotify(ctx, req, stdoutPipe, &stdout, progressToken, &lineCount)
+		scanAndNotify(ctx, req, stdoutPipe, &stdout, progressToken, &progress, "stdout", cfg)
 	}()
 	go func() {
 		defer wg.Done()
-		scanAndNotify(ctx, req, stderrPipe, &stderr, progressToken, &lineCount)
+		scanAndNotify(ctx, req, stderrPipe, &stderr, progressToken, &progress, "stderr", cfg)
 	}()
 	wg.Wait()
 
 	waitErr := cmd.Wait()
 	timer.Stop()
+	stopCancel()
 	if kt := killTimer.Load(); kt != nil {
 		kt.Stop()
 	}
 
-	exitCode := 0
+	run := foregroundRun{
+		timedOut:  timedOut.Load(),
+		cancelled: cancelled.Load(),
+		stderr:    stderr.String(),
+	}
 	if waitErr != nil {
 		if exitErr, ok := waitErr.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
+			run.exitCode = exitErr.ExitCode()
 		}
 	}
 
-	stdoutStr := stdout.String()
-	stderrStr := stderr.String()
-
 	// Parse sentinel from stdout to extract new cwd (before truncation)
-	stdoutStr = parseSentinel(stdoutStr, sentinel, sess)
+	run.stdout, run.cwd, run.sentinelFound = parseSentinel(stdout.String(), sentinel)
+	if persistCwd && run.cwd != "" {
+		sess.SetCwd(run.cwd)
+	}
+	return run, nil
+}
 
-	// Truncate output
-	stdoutStr = truncateOutput(stdoutStr)
-	stderrStr = truncateOutput(stderrStr)
+// bashResult is the machine-readable form of a bash or task_output result,
+// attached as a second JSON content block when Config.StructuredBashOutput
+// is set.
+type bashResult struct {
+	Status          string `json:"status,omitempty"`   // task_output only: running or completed
+	Attempts        int    `json:"attempts,omitempty"` // bash with retries only: runs made, including the first
+	Cwd             string `json:"cwd,omitempty"`      // foreground bash only: the new working directory, if the command changed it
+	ExitCode        *int   `json:"exit_code"`          // nil while a task is running
+	TimedOut        bool   `json:"timed_out"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdout_truncated"`
+	StderrTruncated bool   `json:"stderr_truncated"`
+}
 
-	// Build response
-	var result strings.Builder
-	if timedOut.Load() {
-		fmt.Fprintf(&result, "Command timed out after %dms\n\n", timeoutMs)
+// newBashResult builds a bashResult, capping stdout and stderr at
+// maxOutputChars and recording whether either was cut.
+func newBashResult(status string, exitCode *int, timedOut bool, stdout, stderr string) bashResult {
+	r := bashResult{
+		Status:   status,
+		ExitCode: exitCode,
+		TimedOut: timedOut,
+		Stdout:   stdout,
+		Stderr:   stderr,
 	}
-	fmt.Fprintf(&result, "exit_code: %d\n", exitCode)
-	if stderrStr != "" {
-		fmt.Fprintf(&result, "\nstderr:\n%s", stderrStr)
+	if len(r.Stdout) > maxOutputChars {
+		r.Stdout = r.Stdout[:maxOutputChars]
+		r.StdoutTruncated = true
 	}
-	if stdoutStr != "" {
-		fmt.Fprintf(&result, "\nstdout:\n%s", stdoutStr)
+	if len(r.Stderr) > maxOutputChars {
+		r.Stderr = r.Stderr[:maxOutputChars]
+		r.StderrTruncated = true
 	}
+	return r
+}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{&mcp.TextContent{Text: result.String()}},
-	}, nil, nil
+// attachStructured appends br to r as a JSON text content block. If br
+// cannot be marshaled, r is left as the text-only result.
+func attachStructured(r *mcp.CallToolResult, br bashResult) {
+	data, err := json.Marshal(br)
+	if err != nil {
+		return
+	}
+	r.Content = append(r.Content, &mcp.TextContent{Text: string(data)})
 }
 
 // scanAndNotify reads from r line by line, writing to buf and optionally
-// sending progress notifications for each line.
-func scanAndNotify(ctx context.Context, req *mcp.CallToolRequest, r io.Reader, buf *bytes.Buffer, progressToken any, lineCount *atomic.Int64) {
+// sending progress notifications for each line. progress is shared by the
+// stdout and stderr scanners and counts lines, or bytes with
+// Config.ProgressByteCount. With Config.ProgressStreamPrefix the message is
+// prefixed with stream, e.g. "stderr: warning". Config.DisableProgress
+// suppresses notifications entirely; output is still buffered.
+func scanAndNotify(ctx context.Context, req *mcp.CallToolRequest, r io.Reader, buf *bytes.Buffer, progressToken any, progress *atomic.Int64, stream string, cfg Config) {
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
 		buf.WriteString(line)
 		buf.WriteByte('\n')
 
-		if progressToken != nil && req.Session != nil {
-			n := lineCount.Add(1)
+		if progressToken != nil && req.Session != nil && !cfg.DisableProgress {
+			step := int64(1)
+			if cfg.ProgressByteCount {
+				step = int64(len(line) + 1)
+			}
+			n := progress.Add(step)
+			msg := line
+			if cfg.ProgressStreamPrefix {
+				msg = stream + ": " + line
+			}
 			_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
 				ProgressToken: progressToken,
 				Progress:      float64(n),
-				Message:       line,
+				Message:       msg,
 			})
 		}
 	}
 }
 
-func runBackground(sess *session.Session, cfg Config, cwd, command string) (*mcp.CallToolResult, any, error) {
+func runBackground(sess *session.Session, resolver *pathscope.Resolver, cfg Config, cwd, command, idempotencyKey string, outputToFile bool) (*mcp.CallToolResult, any, error) {
+	if cfg.MaxBackgroundTasks <= 0 {
+		return toolErr(ErrBashTaskLimit, "background tasks are disabled")
+	}
+
+	// A retried launch returns the task it already started
+	if idempotencyKey != "" {
+		if id, ok := sess.TaskIDForKey(idempotencyKey); ok {
+			return existingTaskResult(id)
+		}
+	}
+
 	// Generate a unique task ID
 	b := make([]byte, 8)
 	if _, err := rand.Read(b); err != nil {
@@ -195,22 +420,74 @@ func runBackground(sess *session.Session, cfg Config, cwd, command string) (*mcp
 	cmd.Stdout = stdoutBuf
 	cmd.Stderr = stderrBuf
 
+	// Long-running, chatty commands can write to scratch files instead, so
+	// their output is not held in memory. The child gets its own copies of
+	// the descriptors, so ours are closed once it has started.
+	var stdoutPath, stderrPath string
+	removeOutputFiles := func() {
+		for _, p := range []string{stdoutPath, stderrPath} {
+			if p != "" {
+				os.Remove(p)
+			}
+		}
+	}
+	if outputToFile {
+		dir, err := resolver.ResolveForWrite(cwd, scratchDir)
+		if err != nil {
+			return toolErr(ErrAccessDenied, "cannot write task output: %v", err)
+		}
+		outFile, err := createScratchFile(sess, dir, "task-"+taskID+"-stdout-*.txt")
+		if err != nil {
+			return toolErr(ErrIO, "could not create output file: %v", err)
+		}
+		defer outFile.Close()
+		errFile, err := createScratchFile(sess, dir, "task-"+taskID+"-stderr-*.txt")
+		if err != nil {
+			os.Remove(outFile.Name())
+			return toolErr(ErrIO, "could not create output file: %v", err)
+		}
+		defer errFile.Close()
+		cmd.Stdout, cmd.Stderr = outFile, errFile
+		stdoutPath, stderrPath = outFile.Name(), errFile.Name()
+	}
+
 	if err := cmd.Start(); err != nil {
+		removeOutputFiles()
 		return toolErr(ErrBashStartFailed, "could not start background command: %v", err)
 	}
 
+	// Lower the whole process group's priority so background builds don't
+	// starve foreground commands. Processes the shell forks from here on
+	// inherit it.
+	var niceWarning string
+	if cfg.BackgroundNiceness != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, cmd.Process.Pid, cfg.BackgroundNiceness); err != nil {
+			niceWarning = fmt.Sprintf("\nwarning: could not set niceness %d: %v", cfg.BackgroundNiceness, err)
+		}
+	}
+
 	task := &session.BackgroundTask{
-		ID:     taskID,
-		Cmd:    cmd,
-		Stdout: stdoutBuf,
-		Stderr: stderrBuf,
-		Done:   make(chan struct{}),
+		ID:             taskID,
+		IdempotencyKey: idempotencyKey,
+		Cmd:            cmd,
+		Stdout:         stdoutBuf,
+		Stderr:         stderrBuf,
+		StdoutPath:     stdoutPath,
+		StderrPath:     stderrPath,
+		Done:           make(chan struct{}),
 	}
 
 	if err := sess.AddTask(task); err != nil {
 		// Kill the process we just started since we can't track it
 		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 		_ = cmd.Wait()
+		removeOutputFiles()
+		// A concurrent launch with the same key won the race
+		if idempotencyKey != "" {
+			if id, ok := sess.TaskIDForKey(idempotencyKey); ok {
+				return existingTaskResult(id)
+			}
+		}
 		return toolErr(ErrBashTaskLimit, "could not add background task: %v", err)
 	}
 
@@ -247,6 +524,19 @@ func runBackground(sess *session.Session, cfg Config, cwd, command string) (*mcp
 	}()
 
 	text := fmt.Sprintf("task_id: %s\nCommand started in background.", taskID)
+	if outputToFile {
+		text += fmt.Sprintf("\nstdout: %s\nstderr: %s", stdoutPath, stderrPath)
+	}
+	text += niceWarning
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+	}, nil, nil
+}
+
+// existingTaskResult reports a background task that was already started with
+// the requested idempotency key.
+func existingTaskResult(taskID string) (*mcp.CallToolResult, any, error) {
+	text := fmt.Sprintf("task_id: %s\nCommand already started in background (idempotency_key matched).", taskID)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: text}},
 	}, nil, nil
@@ -254,10 +544,12 @@ func runBackground(sess *session.Session, cfg Config, cwd, command string) (*mcp
 
 // TaskOutputArgs is the input schema for the task_output tool.
 type TaskOutputArgs struct {
-	TaskID string `json:"task_id" jsonschema:"the task ID returned by a background bash command"`
+	TaskID     string `json:"task_id" jsonschema:"the task ID returned by a background bash command"`
+	Peek       bool   `json:"peek,omitempty" jsonschema:"return completed output without cleaning up the task, so it can be read again"`
+	SaveOutput bool   `json:"save_output,omitempty" jsonschema:"write the full, untruncated stdout and stderr to files under the working directory and return their paths instead of the output"`
 }
 
-func taskOutputHandler(sess *session.Session, cfg Config) mcp.ToolHandlerFor[TaskOutputArgs, any] {
+func taskOutputHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[TaskOutputArgs, any] {
 	var regOnce sync.Once
 	return func(_ context.Context, req *mcp.CallToolRequest, args TaskOutputArgs) (*mcp.CallToolResult, any, error) {
 		if cfg.RegisterSession != nil && req != nil && req.Session != nil {
@@ -269,50 +561,133 @@ func taskOutputHandler(sess *session.Session, cfg Config) mcp.ToolHandlerFor[Tas
 			return toolErr(ErrBashTaskNotFound, "task not found: %s", args.TaskID)
 		}
 
+		var saveDir string
+		if args.SaveOutput {
+			dir, err := resolver.ResolveForWrite(sess.Cwd(), scratchDir)
+			if err != nil {
+				return toolErr(ErrAccessDenied, "cannot save task output: %v", err)
+			}
+			saveDir = dir
+		}
+
 		var result strings.Builder
+		var structured bashResult
 		select {
 		case <-task.Done:
 			// Task completed
-			stdoutStr := truncateOutput(task.Stdout.String())
-			stderrStr := truncateOutput(task.Stderr.String())
-
 			if task.TimedOut() {
 				fmt.Fprintf(&result, "status: completed (killed by background task timeout)\nexit_code: %d\n", task.ExitCode)
 			} else {
 				fmt.Fprintf(&result, "status: completed\nexit_code: %d\n", task.ExitCode)
 			}
-			if stderrStr != "" {
-				fmt.Fprintf(&result, "\nstderr:\n%s", stderrStr)
-			}
-			if stdoutStr != "" {
-				fmt.Fprintf(&result, "\nstdout:\n%s", stdoutStr)
+			rawStdout, rawStderr, err := writeTaskOutput(&result, sess, saveDir, task)
+			if err != nil {
+				return toolErr(ErrIO, "could not save task output: %v", err)
 			}
+			exitCode := task.ExitCode
+			structured = newBashResult("completed", &exitCode, task.TimedOut(), rawStdout, rawStderr)
 
-			// Single-read semantics: clean up after retrieval
-			sess.RemoveTask(args.TaskID)
+			// Single-read semantics: clean up after retrieval unless the
+			// client asked to peek. A later non-peek read acknowledges it.
+			if !args.Peek {
+				sess.RemoveTask(args.TaskID)
+			}
 		default:
 			// Task still running
-			stdoutStr := truncateOutput(task.Stdout.String())
-			stderrStr := truncateOutput(task.Stderr.String())
-
 			fmt.Fprintf(&result, "status: running\n")
-			if stderrStr != "" {
-				fmt.Fprintf(&result, "\nstderr:\n%s", stderrStr)
-			}
-			if stdoutStr != "" {
-				fmt.Fprintf(&result, "\nstdout:\n%s", stdoutStr)
+			rawStdout, rawStderr, err := writeTaskOutput(&result, sess, saveDir, task)
+			if err != nil {
+				return toolErr(ErrIO, "could not save task output: %v", err)
 			}
+			structured = newBashResult("running", nil, false, rawStdout, rawStderr)
 		}
 
-		return &mcp.CallToolResult{
+		r := &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{Text: result.String()}},
-		}, nil, nil
+		}
+		if cfg.StructuredBashOutput {
+			attachStructured(r, structured)
+		}
+		return r, nil, nil
+	}
+}
+
+// writeTaskOutput appends a task's output to b and returns the stdout and
+// stderr it reported. Output held in memory is written by writeTaskStreams;
+// output already going to files is reported by path, with the end of each
+// file inline, and saveDir is not needed.
+func writeTaskOutput(b *strings.Builder, sess *session.Session, saveDir string, task *session.BackgroundTask) (stdout, stderr string, err error) {
+	if task.StdoutPath == "" {
+		stdout, stderr = task.Stdout.String(), task.Stderr.String()
+		return stdout, stderr, writeTaskStreams(b, sess, saveDir, task.ID, stdout, stderr)
+	}
+	if stderr, err = writeTaskFileTail(b, "stderr", task.StderrPath); err != nil {
+		return "", "", err
+	}
+	if stdout, err = writeTaskFileTail(b, "stdout", task.StdoutPath); err != nil {
+		return "", "", err
+	}
+	return stdout, stderr, nil
+}
+
+// writeTaskFileTail appends the path and current size of a task output file
+// to b, followed by up to its last maxOutputChars bytes, which it returns.
+func writeTaskFileTail(b *strings.Builder, name, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := info.Size()
+	fmt.Fprintf(b, "\n%s: %d bytes in %s\n", name, size, path)
+	if size == 0 {
+		return "", nil
 	}
+	data, start, err := readTail(f, size, maxOutputChars)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(b, "[bytes %d-%d of %d]\n%s", start, size, size, data)
+	return string(data), nil
 }
 
+// writeTaskStreams appends a task's stderr and stdout to b. With an empty
+// saveDir each stream is written inline, truncated; otherwise each non-empty
+// stream is saved in full to a scratch file in saveDir and only its path and
+// size are written.
+func writeTaskStreams(b *strings.Builder, sess *session.Session, saveDir, taskID, stdout, stderr string) error {
+	for _, s := range []struct{ name, data string }{{"stderr", stderr}, {"stdout", stdout}} {
+		if s.data == "" {
+			continue
+		}
+		if saveDir == "" {
+			fmt.Fprintf(b, "\n%s:\n%s", s.name, truncateOutput(s.data))
+			continue
+		}
+		path, err := writeScratchFile(sess, saveDir, "task-"+taskID+"-"+s.name+"-*.txt", s.data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "\n%s: %d bytes saved to %s\n", s.name, len(s.data), path)
+	}
+	return nil
+}
+
+// sentinelMissingNote is appended to foreground bash output when the cwd
+// sentinel never printed, e.g. after a timeout, a shell syntax error, or an
+// explicit exit. The session keeps its previous working directory.
+const sentinelMissingNote = "(working directory may be unchanged; sentinel not observed)"
+
 // parseSentinel finds the cwd sentinel in stdout, extracts the new working
-// directory, updates the session, and returns stdout with sentinel lines stripped.
-func parseSentinel(stdout, sentinel string, sess *session.Session) string {
+// directory, and returns stdout with sentinel lines stripped along with the
+// extracted directory. found is false if the sentinel
+// never appeared, e.g. because the command called exit, and stdout is then
+// returned unchanged.
+func parseSentinel(stdout, sentinel string) (_, newCwd string, found bool) {
 	lines := strings.Split(stdout, "\n")
 
 	sentinelIdx := -1
@@ -324,15 +699,12 @@ func parseSentinel(stdout, sentinel string, sess *session.Session) string {
 	}
 
 	if sentinelIdx < 0 {
-		return stdout
+		return stdout, "", false
 	}
 
 	// The line after sentinel is the pwd output
 	if sentinelIdx+1 < len(lines) {
-		newCwd := strings.TrimSpace(lines[sentinelIdx+1])
-		if newCwd != "" {
-			sess.SetCwd(newCwd)
-		}
+		newCwd = strings.TrimSpace(lines[sentinelIdx+1])
 	}
 
 	// Reconstruct output: everything before sentinel, excluding the
@@ -344,9 +716,22 @@ func parseSentinel(stdout, sentinel string, sess *session.Session) string {
 	}
 
 	if len(outputLines) == 0 {
-		return ""
+		return "", newCwd, true
+	}
+	return strings.Join(outputLines, "\n") + "\n", newCwd, true
+}
+
+// outputSizeFooter summarizes the untruncated size of a command's output,
+// e.g. "[output: stdout 120000 bytes (truncated), stderr 0 bytes]".
+func outputSizeFooter(stdout, stderr string) string {
+	return fmt.Sprintf("[output: stdout %s, stderr %s]", outputSize(stdout), outputSize(stderr))
+}
+
+func outputSize(s string) string {
+	if len(s) > maxOutputChars {
+		return fmt.Sprintf("%d bytes (truncated)", len(s))
 	}
-	return strings.Join(outputLines, "\n") + "\n"
+	return fmt.Sprintf("%d bytes", len(s))
 }
 
 // truncateOutput caps output at maxOutputChars characters.
@@ -2,16 +2,25 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/mjkoo/boris/internal/pathscope"
 	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func TestBashSimpleCommand(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo hello"})
 	if err != nil {
@@ -28,7 +37,7 @@ func TestBashSimpleCommand(t *testing.T) {
 
 func TestBashNonZeroExit(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	result, _, err := handler(context.Background(), nil, BashArgs{Command: "exit 42"})
 	if err != nil {
@@ -46,7 +55,7 @@ func TestBashNonZeroExit(t *testing.T) {
 
 func TestBashStderrCapture(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo err >&2"})
 	if err != nil {
@@ -61,7 +70,7 @@ func TestBashStderrCapture(t *testing.T) {
 func TestBashCwdTracking(t *testing.T) {
 	tmp := t.TempDir()
 	sess := session.New(tmp)
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	// cd to /tmp
 	_, _, err := handler(context.Background(), nil, BashArgs{Command: "cd /tmp"})
@@ -83,9 +92,32 @@ func TestBashCwdTracking(t *testing.T) {
 	}
 }
 
+func TestBashReportsChangedCwd(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	handler := bashHandler(sess, testResolver(t), testConfig())
+
+	result, _, err := handler(context.Background(), nil, BashArgs{Command: "cd /tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(result); !strings.Contains(text, "cwd: /tmp\n") {
+		t.Errorf("expected new cwd in result, got: %s", text)
+	}
+
+	// An unchanged cwd is not reported
+	result, _, err = handler(context.Background(), nil, BashArgs{Command: "echo hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(result); strings.Contains(text, "cwd:") {
+		t.Errorf("unchanged cwd should not be reported, got: %s", text)
+	}
+}
+
 func TestBashSentinelStripping(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo hello"})
 	if err != nil {
@@ -109,16 +141,180 @@ func TestBashSentinelNonce(t *testing.T) {
 	// Old sentinel format should not trigger parser
 	oldSentinel := "__BORIS_CWD__"
 	stdout := "output\n" + oldSentinel + "\n/fake/path\n"
-	parsed := parseSentinel(stdout, sentinel, sess)
+	parsed, _, found := parseSentinel(stdout, sentinel)
 	// Old sentinel should NOT be parsed — should remain in output
-	if !strings.Contains(parsed, oldSentinel) {
+	if found || !strings.Contains(parsed, oldSentinel) {
 		t.Errorf("old sentinel format should not be parsed, got: %s", parsed)
 	}
 }
 
+func TestBashContextCancellation(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	handler := bashHandler(sess, testResolver(t), testConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	// The marker is only written if the process group survives cancellation
+	start := time.Now()
+	result, _, err := handler(ctx, nil, BashArgs{Command: "echo started; sleep 1 && touch marker; sleep 300"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("handler took %v after cancellation", elapsed)
+	}
+	text := resultText(result)
+	if !strings.Contains(text, "cancelled") {
+		t.Errorf("expected cancellation note, got: %s", text)
+	}
+	if !strings.Contains(text, "started") {
+		t.Errorf("expected output produced before cancellation, got: %s", text)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(tmp, "marker")); err == nil {
+		t.Error("process group kept running after cancellation")
+	}
+}
+
+func TestBashExitCodeOfLastCommand(t *testing.T) {
+	handler := bashHandler(session.New(t.TempDir()), testResolver(t), testConfig())
+
+	result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo hi; false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(result); !strings.Contains(text, "exit_code: 1") {
+		t.Errorf("expected the command's exit status, got: %s", text)
+	}
+}
+
+func TestBashRetries(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	handler := bashHandler(sess, testResolver(t), testConfig())
+
+	// Fails on the first two runs, succeeds on the third
+	cmd := `n=$(cat count 2>/dev/null || echo 0); n=$((n+1)); echo $n > count; echo "attempt $n"; [ $n -ge 3 ]`
+	result, _, err := handler(context.Background(), nil, BashArgs{Command: cmd, Retries: 5, RetryBackoff: "10ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if !strings.Contains(text, "exit_code: 0\nattempts: 3\n") {
+		t.Errorf("expected success on attempt 3, got: %s", text)
+	}
+	if !strings.Contains(text, "attempt 3") || strings.Contains(text, "attempt 2") {
+		t.Errorf("expected only the final attempt's output, got: %s", text)
+	}
+
+	// Retries run out: the last failure is reported
+	os.Remove(filepath.Join(tmp, "count"))
+	result, _, _ = handler(context.Background(), nil, BashArgs{Command: cmd, Retries: 1, RetryBackoff: "0s"})
+	if text := resultText(result); !strings.Contains(text, "exit_code: 1\nattempts: 2\n") {
+		t.Errorf("expected failure after 2 attempts, got: %s", text)
+	}
+
+	// Timeouts are not retried
+	os.Remove(filepath.Join(tmp, "count"))
+	result, _, _ = handler(context.Background(), nil, BashArgs{Command: "echo x >> runs; sleep 5", Timeout: 200, Retries: 3, RetryBackoff: "0s"})
+	if text := resultText(result); !strings.Contains(text, "attempts: 1") {
+		t.Errorf("timeout should not be retried, got: %s", text)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmp, "runs")); string(data) != "x\n" {
+		t.Errorf("expected a single run, got %q", data)
+	}
+
+	for _, args := range []BashArgs{
+		{Command: "true", Retries: -1},
+		{Command: "true", Retries: maxBashRetries + 1},
+		{Command: "true", Retries: 1, RetryBackoff: "soon"},
+		{Command: "true", Retries: 1, RunInBackground: true},
+	} {
+		result, _, _ := handler(context.Background(), nil, args)
+		if !hasErrorCode(result, ErrInvalidInput) {
+			t.Errorf("%+v: expected %s, got: %s", args, ErrInvalidInput, resultText(result))
+		}
+	}
+}
+
+func TestBashWrapperSurvivesUnterminatedInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{"trailing backslash", "cd sub && echo continued \\", "continued"},
+		{"unterminated heredoc", "cd sub && cat <<EOF\nfrom heredoc", "from heredoc"},
+		{"heredoc at end", "cd sub && cat <<'EOF'\nquoted $HOME\nEOF", "quoted $HOME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			sub := filepath.Join(tmp, "sub")
+			os.Mkdir(sub, 0755)
+			sess := session.New(tmp)
+			handler := bashHandler(sess, testResolver(t), testConfig())
+
+			result, _, err := handler(context.Background(), nil, BashArgs{Command: tt.command})
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := resultText(result)
+			if !strings.Contains(text, tt.want) {
+				t.Errorf("expected %q in output, got: %s", tt.want, text)
+			}
+			if strings.Contains(text, sess.Sentinel()) || strings.Contains(text, sentinelMissingNote) {
+				t.Errorf("sentinel should be found and stripped, got: %s", text)
+			}
+			if sess.Cwd() != sub {
+				t.Errorf("cwd = %q, want %q", sess.Cwd(), sub)
+			}
+		})
+	}
+}
+
+func TestBashSentinelMissingNote(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	handler := bashHandler(sess, testResolver(t), testConfig())
+
+	result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo before; cd /; exit 0; echo after"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if !strings.HasSuffix(text, "stdout:\nbefore\n\n[output: stdout 7 bytes, stderr 0 bytes]\n\n"+sentinelMissingNote) {
+		t.Errorf("expected note after output, got: %s", text)
+	}
+	if strings.Contains(text, "after") {
+		t.Errorf("command should have stopped at exit, got: %s", text)
+	}
+	// The previous working directory is kept
+	if sess.Cwd() != tmp {
+		t.Errorf("cwd = %q, want %q", sess.Cwd(), tmp)
+	}
+
+	// A normal command carries no note
+	result, _, _ = handler(context.Background(), nil, BashArgs{Command: "echo ok"})
+	if strings.Contains(resultText(result), sentinelMissingNote) {
+		t.Errorf("unexpected note: %s", resultText(result))
+	}
+
+	// The note can be turned off
+	cfg := testConfig()
+	cfg.SentinelWarning = false
+	result, _, _ = bashHandler(sess, testResolver(t), cfg)(context.Background(), nil, BashArgs{Command: "exit 0"})
+	if strings.Contains(resultText(result), sentinelMissingNote) {
+		t.Errorf("note should be disabled, got: %s", resultText(result))
+	}
+}
+
 func TestBashTimeoutMilliseconds(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	// Timeout of 1000ms (1 second) should be enough to kill sleep 300
 	result, _, err := handler(context.Background(), nil, BashArgs{Command: "sleep 300", Timeout: 1000})
@@ -136,7 +332,7 @@ func TestBashTimeoutMilliseconds(t *testing.T) {
 
 func TestBashTimeoutMaxCap(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	// Request 900000ms (15 min), should be clamped to 600000ms (10 min)
 	// We can't actually wait that long, so just verify the command starts.
@@ -151,10 +347,33 @@ func TestBashTimeoutMaxCap(t *testing.T) {
 	}
 }
 
+func TestBashTimeoutConfiguredMax(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxBashTimeoutMs = 300
+	handler := bashHandler(session.New(t.TempDir()), testResolver(t), cfg)
+
+	for _, tt := range []struct {
+		requested int
+		want      string
+	}{
+		{requested: 10000, want: "timed out after 300ms"},
+		{requested: 200, want: "timed out after 200ms"},
+		{requested: 0, want: "timed out after 300ms"}, // the 120s default is clamped too
+	} {
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: "sleep 5", Timeout: tt.requested})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := resultText(result); !strings.Contains(text, tt.want) {
+			t.Errorf("timeout %d: expected %q, got: %s", tt.requested, tt.want, text)
+		}
+	}
+}
+
 func TestBashMissingSentinelPreservesCwd(t *testing.T) {
 	tmp := t.TempDir()
 	sess := session.New(tmp)
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	// Timeout before sentinel is printed — cwd should be preserved
 	_, _, _ = handler(context.Background(), nil, BashArgs{Command: "sleep 300", Timeout: 1000})
@@ -167,7 +386,7 @@ func TestBashMissingSentinelPreservesCwd(t *testing.T) {
 func TestBashInitialWorkdir(t *testing.T) {
 	tmp := t.TempDir()
 	sess := session.New(tmp)
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	result, _, err := handler(context.Background(), nil, BashArgs{Command: "pwd"})
 	if err != nil {
@@ -181,7 +400,7 @@ func TestBashInitialWorkdir(t *testing.T) {
 
 func TestBashEmptyCommand(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	for _, cmd := range []string{"", "  ", "\t\n"} {
 		result, _, err := handler(context.Background(), nil, BashArgs{Command: cmd})
@@ -200,7 +419,7 @@ func TestBashEmptyCommand(t *testing.T) {
 
 func TestBashSIGTERM(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	// Use a trap to verify SIGTERM is received and process exits gracefully
 	cmd := `trap 'echo got_sigterm; exit 0' TERM; sleep 300`
@@ -223,7 +442,7 @@ func TestBashSIGTERM(t *testing.T) {
 
 func TestBashOutputTruncation(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	t.Run("within limit", func(t *testing.T) {
 		result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo hello"})
@@ -234,6 +453,9 @@ func TestBashOutputTruncation(t *testing.T) {
 		if strings.Contains(text, "Truncated") {
 			t.Error("short output should not be truncated")
 		}
+		if !strings.Contains(text, "[output: stdout 6 bytes, stderr 0 bytes]") {
+			t.Errorf("expected size footer, got: %s", text)
+		}
 	})
 
 	t.Run("exceeds limit", func(t *testing.T) {
@@ -249,6 +471,9 @@ func TestBashOutputTruncation(t *testing.T) {
 		if !strings.Contains(text, "Truncated") {
 			t.Error("large output should be truncated")
 		}
+		if !strings.Contains(text, "[output: stdout 50001 bytes (truncated), stderr 0 bytes]") {
+			t.Errorf("footer should report the untruncated size, got: %s", text[len(text)-200:])
+		}
 	})
 
 	t.Run("stderr independent truncation", func(t *testing.T) {
@@ -267,13 +492,17 @@ func TestBashOutputTruncation(t *testing.T) {
 		if !strings.Contains(text, "Truncated") {
 			t.Error("stderr should be truncated")
 		}
+		// Output is read line by line, so the unterminated stderr gains a newline
+		if !strings.Contains(text, "[output: stdout 6 bytes, stderr 50001 bytes (truncated)]") {
+			t.Errorf("footer should report both sizes, got: %s", text[len(text)-200:])
+		}
 	})
 }
 
 func TestBashBackgroundCommand(t *testing.T) {
 	sess := session.New(t.TempDir())
 	t.Cleanup(sess.Close)
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	t.Run("immediate return with task_id", func(t *testing.T) {
 		result, _, err := handler(context.Background(), nil, BashArgs{
@@ -296,7 +525,7 @@ func TestBashBackgroundCommand(t *testing.T) {
 		tmp := t.TempDir()
 		bgSess := session.New(tmp)
 		t.Cleanup(bgSess.Close)
-		bgHandler := bashHandler(bgSess, testConfig())
+		bgHandler := bashHandler(bgSess, testResolver(t), testConfig())
 
 		_, _, err := bgHandler(context.Background(), nil, BashArgs{
 			Command:         "cd /tmp",
@@ -315,7 +544,7 @@ func TestBashBackgroundCommand(t *testing.T) {
 	t.Run("task limit enforcement", func(t *testing.T) {
 		limitSess := session.New(t.TempDir())
 		t.Cleanup(limitSess.Close)
-		limitHandler := bashHandler(limitSess, testConfig())
+		limitHandler := bashHandler(limitSess, testResolver(t), testConfig())
 
 		// Fill up 10 tasks
 		for i := 0; i < 10; i++ {
@@ -346,13 +575,65 @@ func TestBashBackgroundCommand(t *testing.T) {
 			t.Errorf("expected error code %s, got: %s", ErrBashTaskLimit, resultText(result))
 		}
 	})
+	t.Run("custom task limit", func(t *testing.T) {
+		limitSess := session.New(t.TempDir())
+		t.Cleanup(limitSess.Close)
+		cfg := testConfig()
+		cfg.MaxBackgroundTasks = 2
+		limitSess.SetMaxTasks(cfg.MaxBackgroundTasks)
+		limitHandler := bashHandler(limitSess, testResolver(t), cfg)
+
+		for i := 0; i < 2; i++ {
+			result, _, err := limitHandler(context.Background(), nil, BashArgs{
+				Command:         "sleep 300",
+				RunInBackground: true,
+			})
+			if err != nil {
+				t.Fatalf("task %d: %v", i, err)
+			}
+			if isErrorResult(result) {
+				t.Fatalf("task %d should succeed: %s", i, resultText(result))
+			}
+		}
+
+		result, _, err := limitHandler(context.Background(), nil, BashArgs{
+			Command:         "sleep 300",
+			RunInBackground: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, ErrBashTaskLimit) {
+			t.Errorf("expected error code %s, got: %s", ErrBashTaskLimit, resultText(result))
+		}
+	})
+
+	t.Run("background tasks disabled", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.MaxBackgroundTasks = 0
+		handler := bashHandler(session.New(t.TempDir()), testResolver(t), cfg)
+
+		result, _, err := handler(context.Background(), nil, BashArgs{
+			Command:         "sleep 300",
+			RunInBackground: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, ErrBashTaskLimit) {
+			t.Errorf("expected error code %s, got: %s", ErrBashTaskLimit, resultText(result))
+		}
+		if !strings.Contains(resultText(result), "disabled") {
+			t.Errorf("expected disabled message, got: %s", resultText(result))
+		}
+	})
 }
 
 func TestTaskOutput(t *testing.T) {
 	sess := session.New(t.TempDir())
 	t.Cleanup(sess.Close)
-	bashH := bashHandler(sess, testConfig())
-	taskH := taskOutputHandler(sess, testConfig())
+	bashH := bashHandler(sess, testResolver(t), testConfig())
+	taskH := taskOutputHandler(sess, testResolver(t), testConfig())
 
 	t.Run("running status", func(t *testing.T) {
 		result, _, _ := bashH(context.Background(), nil, BashArgs{
@@ -427,6 +708,59 @@ func TestTaskOutput(t *testing.T) {
 		}
 	})
 
+	t.Run("peek does not consume completed task", func(t *testing.T) {
+		result, _, _ := bashH(context.Background(), nil, BashArgs{
+			Command:         "echo peeked",
+			RunInBackground: true,
+		})
+		text := resultText(result)
+		taskID := ""
+		for _, line := range strings.Split(text, "\n") {
+			if strings.HasPrefix(line, "task_id: ") {
+				taskID = strings.TrimPrefix(line, "task_id: ")
+				break
+			}
+		}
+		if taskID == "" {
+			t.Fatal("no task_id in response")
+		}
+
+		// Wait for completion
+		time.Sleep(1 * time.Second)
+
+		// Peek twice: both reads should return the completed output
+		for i := 0; i < 2; i++ {
+			result, _, err := taskH(context.Background(), nil, TaskOutputArgs{TaskID: taskID, Peek: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			text = resultText(result)
+			if !strings.Contains(text, "status: completed") {
+				t.Errorf("peek %d: expected completed status, got: %s", i+1, text)
+			}
+			if !strings.Contains(text, "peeked") {
+				t.Errorf("peek %d: expected 'peeked' in output, got: %s", i+1, text)
+			}
+		}
+
+		// A regular read consumes the task
+		result, _, err := taskH(context.Background(), nil, TaskOutputArgs{TaskID: taskID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resultText(result), "peeked") {
+			t.Errorf("expected 'peeked' in consuming read, got: %s", resultText(result))
+		}
+
+		result, _, err = taskH(context.Background(), nil, TaskOutputArgs{TaskID: taskID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, ErrBashTaskNotFound) {
+			t.Errorf("expected error code %s after consuming read, got: %s", ErrBashTaskNotFound, resultText(result))
+		}
+	})
+
 	t.Run("unknown task_id", func(t *testing.T) {
 		result, _, err := taskH(context.Background(), nil, TaskOutputArgs{TaskID: "nonexistent"})
 		if err != nil {
@@ -443,7 +777,7 @@ func TestTaskOutput(t *testing.T) {
 
 func TestBashDescriptionParameter(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	result, _, err := handler(context.Background(), nil, BashArgs{
 		Command:     "echo hello",
@@ -461,11 +795,241 @@ func TestBashDescriptionParameter(t *testing.T) {
 	}
 }
 
+func TestTaskOutputSaveOutput(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	t.Cleanup(sess.Close)
+	bashH := bashHandler(sess, testResolver(t), testConfig())
+	taskH := taskOutputHandler(sess, testResolver(t), testConfig())
+
+	result, _, _ := bashH(context.Background(), nil, BashArgs{
+		Command:         "head -c 50000 /dev/zero | tr '\\0' x; echo; echo oops >&2",
+		RunInBackground: true,
+	})
+	taskID := strings.TrimPrefix(strings.SplitN(resultText(result), "\n", 2)[0], "task_id: ")
+	task, ok := sess.GetTask(taskID)
+	if !ok {
+		t.Fatalf("no task for %q", taskID)
+	}
+	select {
+	case <-task.Done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("background task did not finish")
+	}
+
+	result, _, err := taskH(context.Background(), nil, TaskOutputArgs{TaskID: taskID, SaveOutput: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if isErrorResult(result) || !strings.Contains(text, "status: completed") {
+		t.Fatalf("unexpected result: %s", text)
+	}
+
+	saved := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		var stream, path string
+		var n int
+		if _, err := fmt.Sscanf(line, "%s %d bytes saved to %s", &stream, &n, &path); err == nil {
+			saved[strings.TrimSuffix(stream, ":")] = path
+		}
+	}
+	for stream, want := range map[string]string{
+		"stdout": strings.Repeat("x", 50000) + "\n",
+		"stderr": "oops\n",
+	} {
+		path, ok := saved[stream]
+		if !ok {
+			t.Fatalf("no saved %s path in result: %s", stream, text)
+		}
+		if filepath.Dir(path) != filepath.Join(tmp, scratchDir) {
+			t.Errorf("%s file %s should be under %s", stream, path, filepath.Join(tmp, scratchDir))
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s file has %d bytes, want %d", stream, len(data), len(want))
+		}
+	}
+	if strings.Contains(text, "xxxx") {
+		t.Error("saved output should not also be returned inline")
+	}
+}
+
+func TestBackgroundOutputToFile(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	t.Cleanup(sess.Close)
+	bashH := bashHandler(sess, testResolver(t), testConfig())
+	taskH := taskOutputHandler(sess, testResolver(t), testConfig())
+
+	result, _, _ := bashH(context.Background(), nil, BashArgs{Command: "echo hi", OutputToFile: true})
+	if !hasErrorCode(result, ErrInvalidInput) {
+		t.Errorf("expected INVALID_INPUT without run_in_background, got: %s", resultText(result))
+	}
+
+	result, _, _ = bashH(context.Background(), nil, BashArgs{
+		Command:         "while :; do seq 1 5000; echo warn >&2; sleep 0.05; done",
+		RunInBackground: true,
+		OutputToFile:    true,
+	})
+	text := resultText(result)
+	taskID := strings.TrimPrefix(strings.SplitN(text, "\n", 2)[0], "task_id: ")
+	task, ok := sess.GetTask(taskID)
+	if !ok {
+		t.Fatalf("no task for %q: %s", taskID, text)
+	}
+	if filepath.Dir(task.StdoutPath) != filepath.Join(tmp, scratchDir) {
+		t.Errorf("stdout file %s should be under %s", task.StdoutPath, filepath.Join(tmp, scratchDir))
+	}
+	if !strings.Contains(text, "stdout: "+task.StdoutPath) || !strings.Contains(text, "stderr: "+task.StderrPath) {
+		t.Errorf("launch result should report the output paths: %s", text)
+	}
+
+	size := func() int64 {
+		info, err := os.Stat(task.StdoutPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	waitForGrowth := func(than int64) int64 {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			if n := size(); n > than {
+				return n
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("output file did not grow past %d bytes", than)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	first := waitForGrowth(maxOutputChars)
+	waitForGrowth(first)
+	if task.Stdout.String() != "" {
+		t.Error("output written to a file should not also be buffered in memory")
+	}
+
+	result, _, err := taskH(context.Background(), nil, TaskOutputArgs{TaskID: taskID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = resultText(result)
+	if isErrorResult(result) || !strings.Contains(text, "status: running") {
+		t.Fatalf("unexpected result: %s", text)
+	}
+	if !strings.Contains(text, "bytes in "+task.StdoutPath+"\n[bytes ") {
+		t.Errorf("expected the stdout path and tail: %s", text)
+	}
+	if !strings.Contains(text, "bytes in "+task.StderrPath+"\n[bytes ") || !strings.Contains(text, "warn\n") {
+		t.Errorf("expected the stderr path and tail: %s", text)
+	}
+	if len(text) > 2*maxOutputChars+1000 {
+		t.Errorf("task_output returned %d bytes; only the tail of each file should be inline", len(text))
+	}
+	// The tail starts on a line boundary, so every stdout line is whole.
+	stdoutTail := text[strings.Index(text, "bytes in "+task.StdoutPath):]
+	for _, line := range strings.Split(strings.TrimSpace(stdoutTail), "\n")[2:] {
+		if _, err := strconv.Atoi(line); err != nil {
+			t.Fatalf("unexpected line %q in stdout tail", line)
+		}
+	}
+}
+
+func TestTaskOutputSaveOutputDenied(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	t.Cleanup(sess.Close)
+	resolver, err := testResolver(t).WithDenyWritePatterns([]string{"**/" + scratchDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bashH := bashHandler(sess, testResolver(t), testConfig())
+	taskH := taskOutputHandler(sess, resolver, testConfig())
+
+	result, _, _ := bashH(context.Background(), nil, BashArgs{Command: "echo hi", RunInBackground: true})
+	taskID := strings.TrimPrefix(strings.SplitN(resultText(result), "\n", 2)[0], "task_id: ")
+
+	result, _, _ = taskH(context.Background(), nil, TaskOutputArgs{TaskID: taskID, SaveOutput: true})
+	if !hasErrorCode(result, ErrAccessDenied) {
+		t.Errorf("expected ACCESS_DENIED, got: %s", resultText(result))
+	}
+	if _, ok := sess.GetTask(taskID); !ok {
+		t.Error("a rejected save should not clean up the task")
+	}
+}
+
+func TestBackgroundOutputToFileDenied(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	t.Cleanup(sess.Close)
+	resolver, err := testResolver(t).WithDenyWritePatterns([]string{"**/" + scratchDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bashH := bashHandler(sess, resolver, testConfig())
+
+	result, _, _ := bashH(context.Background(), nil, BashArgs{Command: "echo hi", RunInBackground: true, OutputToFile: true})
+	if !hasErrorCode(result, ErrAccessDenied) {
+		t.Errorf("expected ACCESS_DENIED, got: %s", resultText(result))
+	}
+	if _, err := os.Stat(filepath.Join(tmp, scratchDir)); !os.IsNotExist(err) {
+		t.Errorf("denied output_to_file should not create %s: %v", scratchDir, err)
+	}
+}
+
+func TestBackgroundOutputToFileCleanupOnFailure(t *testing.T) {
+	scratchFiles := func(t *testing.T, tmp string) []string {
+		t.Helper()
+		matches, _ := filepath.Glob(filepath.Join(tmp, scratchDir, "task-*"))
+		return matches
+	}
+
+	t.Run("start failure", func(t *testing.T) {
+		tmp := t.TempDir()
+		sess := session.New(tmp)
+		t.Cleanup(sess.Close)
+		cfg := testConfig()
+		cfg.Shell = filepath.Join(tmp, "no-such-shell")
+
+		result, _, _ := bashHandler(sess, testResolver(t), cfg)(context.Background(), nil, BashArgs{Command: "echo hi", RunInBackground: true, OutputToFile: true})
+		if !hasErrorCode(result, ErrBashStartFailed) {
+			t.Fatalf("expected %s, got: %s", ErrBashStartFailed, resultText(result))
+		}
+		if files := scratchFiles(t, tmp); len(files) != 0 {
+			t.Errorf("output files left behind: %v", files)
+		}
+	})
+
+	t.Run("task limit", func(t *testing.T) {
+		tmp := t.TempDir()
+		sess := session.New(tmp)
+		t.Cleanup(sess.Close)
+		sess.SetMaxTasks(1)
+		bashH := bashHandler(sess, testResolver(t), testConfig())
+
+		result, _, _ := bashH(context.Background(), nil, BashArgs{Command: "sleep 10", RunInBackground: true})
+		if isErrorResult(result) {
+			t.Fatal(resultText(result))
+		}
+		result, _, _ = bashH(context.Background(), nil, BashArgs{Command: "echo hi", RunInBackground: true, OutputToFile: true})
+		if !hasErrorCode(result, ErrBashTaskLimit) {
+			t.Fatalf("expected %s, got: %s", ErrBashTaskLimit, resultText(result))
+		}
+		if files := scratchFiles(t, tmp); len(files) != 0 {
+			t.Errorf("output files left behind: %v", files)
+		}
+	})
+}
+
 func TestBackgroundTaskOutputRace(t *testing.T) {
 	sess := session.New(t.TempDir())
 	t.Cleanup(sess.Close)
-	bashH := bashHandler(sess, testConfig())
-	taskH := taskOutputHandler(sess, testConfig())
+	bashH := bashHandler(sess, testResolver(t), testConfig())
+	taskH := taskOutputHandler(sess, testResolver(t), testConfig())
 
 	// Start a background command that produces continuous output
 	result, _, err := bashH(context.Background(), nil, BashArgs{
@@ -512,7 +1076,7 @@ func TestBashRegistrationCallback(t *testing.T) {
 		var callCount int
 		cfg := testConfig()
 		cfg.RegisterSession = func(id string) { callCount++ }
-		handler := bashHandler(sess, cfg)
+		handler := bashHandler(sess, testResolver(t), cfg)
 
 		// First call — callback should fire (req is nil so it won't, we need to simulate)
 		// With nil req, registration is skipped (STDIO-like)
@@ -527,7 +1091,7 @@ func TestBashRegistrationCallback(t *testing.T) {
 		t.Cleanup(sess.Close)
 		cfg := testConfig()
 		// RegisterSession is nil (default/STDIO mode)
-		handler := bashHandler(sess, cfg)
+		handler := bashHandler(sess, testResolver(t), cfg)
 
 		// Should not panic.
 		result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo ok"})
@@ -546,7 +1110,7 @@ func TestTaskOutputRegistrationCallback(t *testing.T) {
 		sess := session.New(t.TempDir())
 		t.Cleanup(sess.Close)
 		cfg := testConfig()
-		handler := taskOutputHandler(sess, cfg)
+		handler := taskOutputHandler(sess, testResolver(t), cfg)
 
 		// Should not panic even with nil RegisterSession.
 		result, _, err := handler(context.Background(), nil, TaskOutputArgs{TaskID: "nonexistent"})
@@ -565,8 +1129,8 @@ func TestBashBackgroundTimeout(t *testing.T) {
 		t.Cleanup(sess.Close)
 		cfg := testConfig()
 		cfg.BackgroundTaskTimeout = 1 // 1 second
-		bashH := bashHandler(sess, cfg)
-		taskH := taskOutputHandler(sess, cfg)
+		bashH := bashHandler(sess, testResolver(t), cfg)
+		taskH := taskOutputHandler(sess, testResolver(t), cfg)
 
 		result, _, err := bashH(context.Background(), nil, BashArgs{
 			Command:         "sleep 300",
@@ -608,8 +1172,8 @@ func TestBashBackgroundTimeout(t *testing.T) {
 		t.Cleanup(sess.Close)
 		cfg := testConfig()
 		cfg.BackgroundTaskTimeout = 300 // 5 minutes — should not fire
-		bashH := bashHandler(sess, cfg)
-		taskH := taskOutputHandler(sess, cfg)
+		bashH := bashHandler(sess, testResolver(t), cfg)
+		taskH := taskOutputHandler(sess, testResolver(t), cfg)
 
 		result, _, err := bashH(context.Background(), nil, BashArgs{
 			Command:         "echo fast",
@@ -651,8 +1215,8 @@ func TestBashBackgroundTimeout(t *testing.T) {
 		t.Cleanup(sess.Close)
 		cfg := testConfig()
 		// BackgroundTaskTimeout is 0 by default in testConfig — no timer
-		bashH := bashHandler(sess, cfg)
-		taskH := taskOutputHandler(sess, cfg)
+		bashH := bashHandler(sess, testResolver(t), cfg)
+		taskH := taskOutputHandler(sess, testResolver(t), cfg)
 
 		result, _, err := bashH(context.Background(), nil, BashArgs{
 			Command:         "echo done",
@@ -691,7 +1255,7 @@ func TestBashBackgroundTimeout(t *testing.T) {
 
 func TestBashForegroundTimeoutKillTimerStopped(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	// Use a command that traps SIGTERM and exits cleanly. The foreground
 	// timeout fires SIGTERM, the process exits, and the inner 5s SIGKILL
@@ -718,8 +1282,8 @@ func TestBashBackgroundTimeoutKillTimerStopped(t *testing.T) {
 	t.Cleanup(sess.Close)
 	cfg := testConfig()
 	cfg.BackgroundTaskTimeout = 1 // 1 second
-	bashH := bashHandler(sess, cfg)
-	taskH := taskOutputHandler(sess, cfg)
+	bashH := bashHandler(sess, testResolver(t), cfg)
+	taskH := taskOutputHandler(sess, testResolver(t), cfg)
 
 	// Start a background command that traps SIGTERM and exits cleanly.
 	result, _, err := bashH(context.Background(), nil, BashArgs{
@@ -759,7 +1323,7 @@ func TestBashBackgroundTimeoutKillTimerStopped(t *testing.T) {
 
 func TestBashIsErrorForOperationalErrors(t *testing.T) {
 	sess := session.New(t.TempDir())
-	handler := bashHandler(sess, testConfig())
+	handler := bashHandler(sess, testResolver(t), testConfig())
 
 	// Empty command should be IsError, not Go error
 	result, _, err := handler(context.Background(), nil, BashArgs{Command: ""})
@@ -782,3 +1346,378 @@ func TestBashIsErrorForOperationalErrors(t *testing.T) {
 		t.Error("non-zero exit code should not set IsError")
 	}
 }
+
+// structuredResult unmarshals the JSON block attached by StructuredBashOutput.
+func structuredResult(t *testing.T, r *mcp.CallToolResult) bashResult {
+	t.Helper()
+	if len(r.Content) != 2 {
+		t.Fatalf("expected 2 content blocks, got %d", len(r.Content))
+	}
+	tc, ok := r.Content[1].(*mcp.TextContent)
+	if !ok {
+		t.Fatalf("second content block is %T, want *mcp.TextContent", r.Content[1])
+	}
+	var br bashResult
+	if err := json.Unmarshal([]byte(tc.Text), &br); err != nil {
+		t.Fatalf("unmarshal structured result %q: %v", tc.Text, err)
+	}
+	return br
+}
+
+func TestBashStructuredOutput(t *testing.T) {
+	cfg := testConfig()
+	cfg.StructuredBashOutput = true
+
+	t.Run("disabled by default", func(t *testing.T) {
+		handler := bashHandler(session.New(t.TempDir()), testResolver(t), testConfig())
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo hi"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Content) != 1 {
+			t.Errorf("expected only the text block, got %d blocks", len(result.Content))
+		}
+	})
+
+	t.Run("foreground", func(t *testing.T) {
+		handler := bashHandler(session.New(t.TempDir()), testResolver(t), cfg)
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: "echo out; echo err >&2; exit 3"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resultText(result), "exit_code: 3") {
+			t.Errorf("text block should keep the default format, got: %s", resultText(result))
+		}
+		br := structuredResult(t, result)
+		if br.ExitCode == nil || *br.ExitCode != 3 {
+			t.Errorf("exit_code = %v, want 3", br.ExitCode)
+		}
+		if br.Stdout != "out\n" || br.Stderr != "err\n" {
+			t.Errorf("stdout = %q, stderr = %q", br.Stdout, br.Stderr)
+		}
+		if br.TimedOut || br.StdoutTruncated || br.StderrTruncated || br.Status != "" {
+			t.Errorf("unexpected flags: %+v", br)
+		}
+	})
+
+	t.Run("timed out and truncated", func(t *testing.T) {
+		handler := bashHandler(session.New(t.TempDir()), testResolver(t), cfg)
+		result, _, err := handler(context.Background(), nil, BashArgs{
+			Command: "head -c 40000 /dev/zero | tr '\\0' x; sleep 10",
+			Timeout: 500,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		br := structuredResult(t, result)
+		if !br.TimedOut {
+			t.Error("expected timed_out")
+		}
+		if !br.StdoutTruncated || len(br.Stdout) != maxOutputChars {
+			t.Errorf("stdout_truncated = %v, len(stdout) = %d", br.StdoutTruncated, len(br.Stdout))
+		}
+	})
+
+	t.Run("task_output", func(t *testing.T) {
+		sess := session.New(t.TempDir())
+		t.Cleanup(sess.Close)
+		bashH := bashHandler(sess, testResolver(t), cfg)
+		taskH := taskOutputHandler(sess, testResolver(t), cfg)
+
+		result, _, _ := bashH(context.Background(), nil, BashArgs{
+			Command:         "echo started; sleep 0.5; echo done",
+			RunInBackground: true,
+		})
+		text := resultText(result)
+		taskID := strings.TrimSpace(strings.TrimPrefix(strings.Split(text, "\n")[0], "task_id: "))
+
+		result, _, err := taskH(context.Background(), nil, TaskOutputArgs{TaskID: taskID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		br := structuredResult(t, result)
+		if br.Status != "running" || br.ExitCode != nil {
+			t.Errorf("running task: status = %q, exit_code = %v", br.Status, br.ExitCode)
+		}
+
+		time.Sleep(time.Second)
+		result, _, err = taskH(context.Background(), nil, TaskOutputArgs{TaskID: taskID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		br = structuredResult(t, result)
+		if br.Status != "completed" || br.ExitCode == nil || *br.ExitCode != 0 {
+			t.Errorf("completed task: status = %q, exit_code = %v", br.Status, br.ExitCode)
+		}
+		if br.Stdout != "started\ndone\n" {
+			t.Errorf("stdout = %q", br.Stdout)
+		}
+	})
+}
+
+// extractTaskID returns the task_id reported in a background launch result.
+func extractTaskID(t *testing.T, text string) string {
+	t.Helper()
+	for _, line := range strings.Split(text, "\n") {
+		if id, ok := strings.CutPrefix(line, "task_id: "); ok {
+			return id
+		}
+	}
+	t.Fatalf("no task_id in response: %s", text)
+	return ""
+}
+
+func TestBashBackgroundIdempotencyKey(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	t.Cleanup(sess.Close)
+	handler := bashHandler(sess, testResolver(t), testConfig())
+
+	// Each process that actually starts appends a line to the counter file
+	args := BashArgs{
+		Command:         "echo started >> counter; sleep 60",
+		RunInBackground: true,
+		IdempotencyKey:  "build-1",
+	}
+	first, _, err := handler(context.Background(), nil, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := handler(context.Background(), nil, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id1 := extractTaskID(t, resultText(first))
+	id2 := extractTaskID(t, resultText(second))
+	if id1 != id2 {
+		t.Errorf("retry returned task_id %q, want %q", id2, id1)
+	}
+	if !strings.Contains(resultText(second), "already started") {
+		t.Errorf("expected retry to report existing task, got: %s", resultText(second))
+	}
+	if n := sess.TaskCount(); n != 1 {
+		t.Errorf("expected 1 tracked task, got %d", n)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	data, err := os.ReadFile(filepath.Join(tmp, "counter"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "started"); n != 1 {
+		t.Errorf("expected a single process to start, got %d", n)
+	}
+
+	// A different key starts a new task
+	args.IdempotencyKey = "build-2"
+	third, _, _ := handler(context.Background(), nil, args)
+	if extractTaskID(t, resultText(third)) == id1 {
+		t.Error("different key should start a new task")
+	}
+}
+
+// TestBashConcurrentCwdAccess is meant for -race: foreground commands update
+// the session cwd while other goroutines read it and poll a background task.
+func TestBashConcurrentCwdAccess(t *testing.T) {
+	tmp := t.TempDir()
+	dirs := []string{filepath.Join(tmp, "a"), filepath.Join(tmp, "b")}
+	for _, d := range dirs {
+		os.Mkdir(d, 0755)
+	}
+	sess := session.New(tmp)
+	t.Cleanup(sess.Close)
+	handler := bashHandler(sess, testResolver(t), testConfig())
+	taskOutput := taskOutputHandler(sess, testResolver(t), testConfig())
+
+	result, _, err := handler(context.Background(), nil, BashArgs{Command: "for i in 1 2 3 4 5; do echo $i; sleep 0.05; done", RunInBackground: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskID := strings.TrimPrefix(strings.SplitN(resultText(result), "\n", 2)[0], "task_id: ")
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = sess.Cwd()
+			}
+		}
+	}()
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				taskOutput(context.Background(), nil, TaskOutputArgs{TaskID: taskID, Peek: true})
+			}
+		}
+	}()
+
+	var writers sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		writers.Add(1)
+		go func(dir string) {
+			defer writers.Done()
+			result, _, err := handler(context.Background(), nil, BashArgs{Command: "cd " + dir})
+			if err != nil || isErrorResult(result) {
+				t.Errorf("cd %s failed: %v %s", dir, err, resultText(result))
+			}
+		}(dirs[i%len(dirs)])
+	}
+	writers.Wait()
+	close(stop)
+	readers.Wait()
+
+	if cwd := sess.Cwd(); cwd != dirs[0] && cwd != dirs[1] {
+		t.Errorf("cwd = %q, want one of %v", cwd, dirs)
+	}
+}
+
+func TestBashOneOffCwd(t *testing.T) {
+	tmp := t.TempDir()
+	sub := filepath.Join(tmp, "sub")
+	os.Mkdir(sub, 0755)
+	os.WriteFile(filepath.Join(tmp, "file.txt"), []byte("x"), 0644)
+	sess := session.New(tmp)
+	resolver, err := pathscope.NewResolver([]string{tmp}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := bashHandler(sess, resolver, testConfig())
+
+	t.Run("runs in the given directory", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: "pwd", Cwd: "sub"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := resultText(result); !strings.Contains(text, sub+"\n") {
+			t.Errorf("expected pwd %s, got: %s", sub, text)
+		}
+		if sess.Cwd() != tmp {
+			t.Errorf("session cwd changed to %s, want %s", sess.Cwd(), tmp)
+		}
+	})
+
+	t.Run("cd inside the command does not persist", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: "cd / && pwd", Cwd: sub})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := resultText(result); strings.Contains(text, "cwd:") {
+			t.Errorf("one-off cwd should not report a cwd change, got: %s", text)
+		}
+		if sess.Cwd() != tmp {
+			t.Errorf("session cwd changed to %s, want %s", sess.Cwd(), tmp)
+		}
+	})
+
+	t.Run("retries keep the session cwd", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: "cd .. && false", Cwd: "sub", Retries: 1, RetryBackoff: "1ms"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resultText(result), "attempts: 2") {
+			t.Errorf("expected 2 attempts, got: %s", resultText(result))
+		}
+		if sess.Cwd() != tmp {
+			t.Errorf("session cwd changed to %s, want %s", sess.Cwd(), tmp)
+		}
+	})
+
+	t.Run("background task runs in the given directory", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: "pwd > where.txt", Cwd: "sub", RunInBackground: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(result) {
+			t.Fatal(resultText(result))
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			data, err := os.ReadFile(filepath.Join(sub, "where.txt"))
+			if err == nil && strings.TrimSpace(string(data)) == sub {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("background task did not write its cwd in %s", sub)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if sess.Cwd() != tmp {
+			t.Errorf("session cwd changed to %s, want %s", sess.Cwd(), tmp)
+		}
+	})
+
+	t.Run("rejects bad directories", func(t *testing.T) {
+		tests := []struct {
+			cwd  string
+			code string
+		}{
+			{"missing", ErrPathNotFound},
+			{"file.txt", ErrInvalidInput},
+			{"/", ErrAccessDenied},
+		}
+		for _, tt := range tests {
+			result, _, err := handler(context.Background(), nil, BashArgs{Command: "true", Cwd: tt.cwd})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasErrorCode(result, tt.code) {
+				t.Errorf("cwd %q: expected %s, got: %s", tt.cwd, tt.code, resultText(result))
+			}
+		}
+	})
+}
+
+func TestBashBackgroundNiceness(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("reads niceness from /proc")
+	}
+	sess := session.New(t.TempDir())
+	t.Cleanup(sess.Close)
+	cfg := testConfig()
+	cfg.BackgroundNiceness = 7
+	handler := bashHandler(sess, testResolver(t), cfg)
+
+	// The sleep gives the server time to renice the group; the child forked
+	// after it must inherit the niceness. Field 19 of /proc/self/stat is nice.
+	result, _, err := handler(context.Background(), nil, BashArgs{
+		Command:         "sleep 0.2; cut -d' ' -f19 /proc/self/stat",
+		RunInBackground: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if isErrorResult(result) || strings.Contains(text, "warning:") {
+		t.Fatalf("unexpected result: %s", text)
+	}
+	taskID := strings.TrimPrefix(strings.SplitN(text, "\n", 2)[0], "task_id: ")
+	task, ok := sess.GetTask(taskID)
+	if !ok {
+		t.Fatalf("no task for %q", taskID)
+	}
+	select {
+	case <-task.Done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("background task did not finish")
+	}
+	if got := strings.TrimSpace(task.Stdout.String()); got != "7" {
+		t.Errorf("expected niceness 7, got %q (stderr: %s)", got, task.Stderr.String())
+	}
+
+	// Foreground commands are unaffected
+	result, _, _ = handler(context.Background(), nil, BashArgs{Command: "echo nice=$(cut -d' ' -f19 /proc/self/stat)"})
+	if text := resultText(result); !strings.Contains(text, "nice=") || strings.Contains(text, "nice=7") {
+		t.Errorf("expected foreground command at the default niceness, got: %s", text)
+	}
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// CountLinesArgs is the input schema for the count_lines tool.
+type CountLinesArgs struct {
+	Path  string   `json:"path,omitempty" jsonschema:"the file to count"`
+	Paths []string `json:"paths,omitempty" jsonschema:"files to count; totals are reported when more than one is given"`
+}
+
+// lineCounts holds wc-style counts for one file.
+type lineCounts struct {
+	lines, words, bytes int64
+}
+
+func countLinesHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[CountLinesArgs, any] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, args CountLinesArgs) (*mcp.CallToolResult, any, error) {
+		return doCountLines(sess, resolver, cfg, args)
+	}
+}
+
+func doCountLines(sess *session.Session, resolver *pathscope.Resolver, cfg Config, args CountLinesArgs) (*mcp.CallToolResult, any, error) {
+	paths := args.Paths
+	if args.Path != "" {
+		paths = append([]string{args.Path}, paths...)
+	}
+	if len(paths) == 0 {
+		return toolErr(ErrInvalidInput, "path or paths is required")
+	}
+
+	var (
+		rows    []string
+		notes   []string
+		total   lineCounts
+		counted int
+	)
+	for _, p := range paths {
+		resolved, err := resolver.Resolve(sess.Cwd(), p)
+		if err != nil {
+			return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return toolErr(ErrPathNotFound, "%s does not exist", resolved)
+			}
+			return toolErr(ErrIO, "could not stat %s: %v", resolved, err)
+		}
+		if info.IsDir() {
+			return toolErr(ErrInvalidInput, "%s is a directory", resolved)
+		}
+		if info.Size() > cfg.MaxFileSize {
+			notes = append(notes, fmt.Sprintf("%s: skipped, %d bytes exceeds maximum %d bytes", p, info.Size(), cfg.MaxFileSize))
+			continue
+		}
+		data, err := os.ReadFile(resolved)
+		if err != nil {
+			return toolErr(ErrIO, "could not read %s: %v", resolved, err)
+		}
+		if isBinaryHeader(data[:min(len(data), 512)]) {
+			notes = append(notes, fmt.Sprintf("%s: skipped, binary file", p))
+			continue
+		}
+
+		c := countText(data)
+		rows = append(rows, formatCounts(c, p))
+		total.lines += c.lines
+		total.words += c.words
+		total.bytes += c.bytes
+		counted++
+	}
+
+	if counted > 1 {
+		rows = append(rows, formatCounts(total, "total"))
+	}
+	if len(notes) > 0 {
+		if len(rows) > 0 {
+			rows = append(rows, "")
+		}
+		rows = append(rows, notes...)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(rows, "\n")}},
+	}, nil, nil
+}
+
+// countText counts newlines, whitespace-separated words, and bytes, matching
+// wc: a final line without a trailing newline is not counted as a line.
+func countText(data []byte) lineCounts {
+	return lineCounts{
+		lines: int64(bytes.Count(data, []byte{'\n'})),
+		words: int64(len(bytes.Fields(data))),
+		bytes: int64(len(data)),
+	}
+}
+
+func formatCounts(c lineCounts, label string) string {
+	return fmt.Sprintf("%8d %8d %8d %s", c.lines, c.words, c.bytes, label)
+}
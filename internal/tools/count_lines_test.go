@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func countLinesSetup(t *testing.T) (string, *session.Session, *pathscope.Resolver) {
+	t.Helper()
+	tmp := t.TempDir()
+	files := map[string]string{
+		"a.txt":     "hello world\nsecond line here\n",     // 2 lines, 5 words, 29 bytes
+		"b.txt":     "one\ntwo three\n\nno newline at end", // 3 lines, 7 words, 32 bytes
+		"empty.txt": "",
+		"bin.dat":   "abc\x00def\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	return tmp, session.New(tmp), resolver
+}
+
+func callCountLines(t *testing.T, sess *session.Session, resolver *pathscope.Resolver, cfg Config, args CountLinesArgs) string {
+	t.Helper()
+	result, _, err := countLinesHandler(sess, resolver, cfg)(context.Background(), nil, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	return resultText(result)
+}
+
+func TestCountLinesSingleFile(t *testing.T) {
+	_, sess, resolver := countLinesSetup(t)
+
+	got := callCountLines(t, sess, resolver, testConfig(), CountLinesArgs{Path: "a.txt"})
+	if want := formatCounts(lineCounts{2, 5, 29}, "a.txt"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = callCountLines(t, sess, resolver, testConfig(), CountLinesArgs{Path: "empty.txt"})
+	if want := formatCounts(lineCounts{}, "empty.txt"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCountLinesTotals(t *testing.T) {
+	_, sess, resolver := countLinesSetup(t)
+
+	got := callCountLines(t, sess, resolver, testConfig(), CountLinesArgs{Paths: []string{"a.txt", "b.txt"}})
+	want := strings.Join([]string{
+		formatCounts(lineCounts{2, 5, 29}, "a.txt"),
+		formatCounts(lineCounts{3, 7, 32}, "b.txt"),
+		formatCounts(lineCounts{5, 12, 61}, "total"),
+	}, "\n")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCountLinesSkipsBinaryAndOversized(t *testing.T) {
+	tmp, sess, resolver := countLinesSetup(t)
+	cfg := testConfig()
+	cfg.MaxFileSize = 30
+	os.WriteFile(filepath.Join(tmp, "big.txt"), []byte(strings.Repeat("x\n", 20)), 0644)
+
+	got := callCountLines(t, sess, resolver, cfg, CountLinesArgs{Path: "a.txt", Paths: []string{"bin.dat", "big.txt"}})
+	if !strings.HasPrefix(got, formatCounts(lineCounts{2, 5, 29}, "a.txt")+"\n") {
+		t.Errorf("expected a.txt counts first, got:\n%s", got)
+	}
+	if strings.Contains(got, "total") {
+		t.Errorf("total should be omitted when only one file was counted, got:\n%s", got)
+	}
+	if !strings.Contains(got, "bin.dat: skipped, binary file") {
+		t.Errorf("expected binary note, got:\n%s", got)
+	}
+	if !strings.Contains(got, "big.txt: skipped, 40 bytes exceeds maximum 30 bytes") {
+		t.Errorf("expected size note, got:\n%s", got)
+	}
+}
+
+func TestCountLinesErrors(t *testing.T) {
+	tmp, sess, _ := countLinesSetup(t)
+	resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+	handler := countLinesHandler(sess, resolver, testConfig())
+
+	tests := []struct {
+		name string
+		args CountLinesArgs
+		code string
+	}{
+		{"no paths", CountLinesArgs{}, ErrInvalidInput},
+		{"missing", CountLinesArgs{Path: "nope.txt"}, ErrPathNotFound},
+		{"directory", CountLinesArgs{Path: "."}, ErrInvalidInput},
+		{"outside scope", CountLinesArgs{Paths: []string{"a.txt", "/etc/hostname"}}, ErrAccessDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _, err := handler(context.Background(), nil, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasErrorCode(result, tt.code) {
+				t.Errorf("expected %s, got: %s", tt.code, resultText(result))
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mjkoo/boris/internal/pathscope"
 	"github.com/mjkoo/boris/internal/session"
@@ -13,32 +15,59 @@ import (
 
 // CreateFileArgs is the input schema for the create_file tool.
 type CreateFileArgs struct {
-	Path    string `json:"path" jsonschema:"file path to create or overwrite"`
-	Content string `json:"content" jsonschema:"file content"`
+	Path          string `json:"path" jsonschema:"file path to create or overwrite"`
+	Content       string `json:"content,omitempty" jsonschema:"file content"`
+	ContentBase64 string `json:"content_base64,omitempty" jsonschema:"file content as standard base64, for binary files such as images; mutually exclusive with content"`
 }
 
+// createPreviewLines is the number of leading lines echoed back after a
+// successful create_file.
+const createPreviewLines = 5
+
 func createFileHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[CreateFileArgs, any] {
 	return func(_ context.Context, _ *mcp.CallToolRequest, args CreateFileArgs) (*mcp.CallToolResult, any, error) {
-		return doCreateFile(sess, resolver, cfg, args.Path, args.Content)
+		content := args.Content
+		if args.ContentBase64 != "" {
+			if args.Content != "" {
+				return toolErr(ErrInvalidInput, "content and content_base64 are mutually exclusive")
+			}
+			data, err := base64.StdEncoding.DecodeString(args.ContentBase64)
+			if err != nil {
+				return toolErr(ErrInvalidInput, "invalid content_base64: %v", err)
+			}
+			content = string(data)
+		}
+		return doCreateFile(sess, resolver, cfg, args.Path, content)
 	}
 }
 
 func doCreateFile(sess *session.Session, resolver *pathscope.Resolver, cfg Config, path, content string) (*mcp.CallToolResult, any, error) {
-	if int64(len(content)) > cfg.MaxFileSize {
-		return toolErr(ErrFileTooLarge, "content is %d bytes, exceeds maximum %d bytes", len(content), cfg.MaxFileSize)
+	if limit := cfg.createFileLimit(); int64(len(content)) > limit {
+		return toolErr(ErrFileTooLarge, "content is %d bytes, exceeds maximum %d bytes", len(content), limit)
 	}
 
-	resolved, err := resolver.Resolve(sess.Cwd(), path)
+	resolved, err := resolver.ResolveForWrite(sess.Cwd(), path)
 	if err != nil {
 		return toolErr(ErrAccessDenied, "path not allowed: %v", err)
 	}
 
+	// Keep the previous content for the edit diff
+	var before string
+	if cfg.EditDiffs {
+		if data, err := os.ReadFile(resolved); err == nil {
+			before = string(data)
+		}
+	}
+
 	// Check view-before-edit for overwrites of existing files
 	if cfg.RequireViewBeforeEdit {
 		if _, statErr := os.Stat(resolved); statErr == nil {
 			// File exists — this is an overwrite, check if it was viewed
-			if !sess.HasViewed(resolved) {
-				return toolErr(ErrFileNotViewed, "file %s must be viewed before overwriting. Use the view tool first.", resolved)
+			switch viewed, changed := viewState(sess, resolved); {
+			case !viewed:
+				return toolErr(ErrFileNotViewed, "file %s must be viewed before overwriting. %s", resolved, viewHint(cfg, resolved))
+			case changed:
+				return toolErr(ErrFileNotViewed, "file %s changed since it was viewed; view it again before overwriting. %s", resolved, viewHint(cfg, resolved))
 			}
 		}
 	}
@@ -53,8 +82,30 @@ func doCreateFile(sess *session.Session, resolver *pathscope.Resolver, cfg Confi
 	if err := os.WriteFile(resolved, []byte(content), 0644); err != nil {
 		return toolErr(ErrIO, "could not write %s: %v", resolved, err)
 	}
+	refreshViewed(sess, resolved)
 
-	text := fmt.Sprintf("Created %s (%d bytes)", resolved, len(content))
+	if isBinaryHeader([]byte(content[:min(len(content), 512)])) {
+		text := fmt.Sprintf("Created %s (%d bytes, binary)", resolved, len(content))
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: appendEditDiff(cfg, text, resolved, before, content)}},
+		}, nil, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	// Remove trailing empty line from final newline
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	text := fmt.Sprintf("Created %s (%d bytes, %d lines)", resolved, len(content), len(lines))
+	if len(lines) > 0 {
+		head := lines[:min(len(lines), createPreviewLines)]
+		text += "\n\n" + formatLines(head, 1)
+		if rest := len(lines) - len(head); rest > 0 {
+			text += fmt.Sprintf("... (%d more lines)", rest)
+		}
+	}
+	text = appendEditDiff(cfg, text, resolved, before, content)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: text}},
 	}, nil, nil
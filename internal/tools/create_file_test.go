@@ -1,7 +1,13 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
 	"os"
 	"path/filepath"
 	"strings"
@@ -176,6 +182,9 @@ func TestCreateFileViewBeforeEdit(t *testing.T) {
 		if !hasErrorCode(result, ErrFileNotViewed) {
 			t.Errorf("expected error code %s, got: %s", ErrFileNotViewed, resultText(result))
 		}
+		if !strings.Contains(resultText(result), "Hint: call view with path") {
+			t.Errorf("expected view hint, got: %s", resultText(result))
+		}
 
 		// File should be unchanged
 		data, _ := os.ReadFile(file)
@@ -243,3 +252,162 @@ func TestCreateFileViewBeforeEdit(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateFilePreview(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := createFileHandler(sess, resolver, testConfig())
+
+	t.Run("long file", func(t *testing.T) {
+		content := "l1\nl2\nl3\nl4\nl5\nl6\nl7\n"
+		result, _, err := handler(context.Background(), nil, CreateFileArgs{
+			Path:    filepath.Join(tmp, "long.txt"),
+			Content: content,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := resultText(result)
+		if !strings.Contains(text, "(21 bytes, 7 lines)") {
+			t.Errorf("expected byte and line counts, got:\n%s", text)
+		}
+		if !strings.Contains(text, "1\tl1\n2\tl2\n3\tl3\n4\tl4\n5\tl5\n") {
+			t.Errorf("expected numbered head preview, got:\n%s", text)
+		}
+		if strings.Contains(text, "l6") {
+			t.Errorf("preview should stop after %d lines, got:\n%s", createPreviewLines, text)
+		}
+		if !strings.HasSuffix(text, "... (2 more lines)") {
+			t.Errorf("expected remaining line count, got:\n%s", text)
+		}
+	})
+
+	t.Run("short file without trailing newline", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, CreateFileArgs{
+			Path:    filepath.Join(tmp, "short.txt"),
+			Content: "a\nb",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := resultText(result)
+		if !strings.Contains(text, "(3 bytes, 2 lines)") {
+			t.Errorf("expected byte and line counts, got:\n%s", text)
+		}
+		if !strings.HasSuffix(text, "1\ta\n2\tb\n") {
+			t.Errorf("expected full preview, got:\n%s", text)
+		}
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, CreateFileArgs{
+			Path:    filepath.Join(tmp, "empty.txt"),
+			Content: "",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := resultText(result); !strings.HasSuffix(text, "(0 bytes, 0 lines)") {
+			t.Errorf("expected counts without preview, got:\n%s", text)
+		}
+	})
+}
+
+func TestCreateFileBase64(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+	cfg := testConfig()
+	cfg.MaxFileSize = 1024
+	handler := createFileHandler(sess, resolver, cfg)
+
+	var buf bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	pngData := buf.Bytes()
+	encoded := base64.StdEncoding.EncodeToString(pngData)
+
+	t.Run("round trips a png", func(t *testing.T) {
+		file := filepath.Join(tmp, "img", "dot.png")
+		result, _, _ := handler(context.Background(), nil, CreateFileArgs{Path: file, ContentBase64: encoded})
+		if isErrorResult(result) {
+			t.Fatalf("unexpected error: %s", resultText(result))
+		}
+		if want := fmt.Sprintf("(%d bytes, binary)", len(pngData)); !strings.Contains(resultText(result), want) {
+			t.Errorf("expected %q in result, got: %s", want, resultText(result))
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, pngData) {
+			t.Fatal("written bytes differ from the decoded payload")
+		}
+		decoded, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r, _, _, _ := decoded.At(1, 1).RGBA(); r != 0xffff {
+			t.Errorf("pixel (1,1) red = %#x, want 0xffff", r)
+		}
+	})
+
+	for _, tt := range []struct {
+		name string
+		args CreateFileArgs
+		code string
+	}{
+		{"invalid base64", CreateFileArgs{Path: filepath.Join(tmp, "bad.bin"), ContentBase64: "not base64!"}, ErrInvalidInput},
+		{"both content fields", CreateFileArgs{Path: filepath.Join(tmp, "both.bin"), Content: "x", ContentBase64: encoded}, ErrInvalidInput},
+		{"too large", CreateFileArgs{Path: filepath.Join(tmp, "big.bin"), ContentBase64: base64.StdEncoding.EncodeToString(make([]byte, 2048))}, ErrFileTooLarge},
+		{"out of scope", CreateFileArgs{Path: "/etc/dot.png", ContentBase64: encoded}, ErrAccessDenied},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _, _ := handler(context.Background(), nil, tt.args)
+			if !hasErrorCode(result, tt.code) {
+				t.Errorf("expected %s, got: %s", tt.code, resultText(result))
+			}
+			if _, err := os.Stat(tt.args.Path); err == nil {
+				t.Errorf("%s should not have been written", tt.args.Path)
+			}
+		})
+	}
+}
+
+func TestCreateFileSeparateSizeLimits(t *testing.T) {
+	tmp := t.TempDir()
+	file := filepath.Join(tmp, "data.txt")
+	content := strings.Repeat("x", 199) + "\n"
+	os.WriteFile(file, []byte(content), 0644)
+
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	cfg := testConfig()
+	cfg.MaxFileSize = 50
+	cfg.MaxViewFileSize = 1000
+	cfg.MaxCreateFileSize = 100
+
+	viewResult, _, _ := viewHandler(sess, resolver, cfg)(context.Background(), nil, ViewArgs{Path: file})
+	if isErrorResult(viewResult) {
+		t.Fatalf("file within the view limit should be viewable: %s", resultText(viewResult))
+	}
+
+	result, _, _ := createFileHandler(sess, resolver, cfg)(context.Background(), nil, CreateFileArgs{Path: file, Content: content})
+	if !hasErrorCode(result, ErrFileTooLarge) {
+		t.Fatalf("expected %s on overwrite, got: %s", ErrFileTooLarge, resultText(result))
+	}
+	if !strings.Contains(resultText(result), "maximum 100 bytes") {
+		t.Errorf("error should report the create limit, got: %s", resultText(result))
+	}
+
+	// Without a per-tool limit, view falls back to MaxFileSize
+	cfg.MaxViewFileSize = 0
+	viewResult, _, _ = viewHandler(sess, resolver, cfg)(context.Background(), nil, ViewArgs{Path: file})
+	if !hasErrorCode(viewResult, ErrFileTooLarge) {
+		t.Errorf("expected %s from the MaxFileSize fallback, got: %s", ErrFileTooLarge, resultText(viewResult))
+	}
+}
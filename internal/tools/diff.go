@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffEdits bounds the edit distance the Myers search will explore. The
+// search keeps one frontier per edit step, so memory grows quadratically with
+// the number of edits; beyond this the differing region is reported as a
+// single replacement instead of a minimal diff.
+const maxDiffEdits = 4000
+
+// diffOp is one line of an edit script: kind is ' ' (unchanged), '-' (only
+// in a), or '+' (only in b). aLine and bLine are the 0-indexed positions in
+// each input before the op is applied.
+type diffOp struct {
+	kind         byte
+	text         string
+	aLine, bLine int
+}
+
+// splitLinesKeepEnds splits s into lines that keep their trailing "\n", so a
+// missing newline at end of file shows up as a difference.
+func splitLinesKeepEnds(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns a line-level edit script turning a into b. Common prefix
+// and suffix are stripped first, and the remainder is diffed with Myers'
+// algorithm.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: ' ', text: a[i], aLine: i, bLine: i})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		ai, bi := len(a)-suffix+i, len(b)-suffix+i
+		ops = append(ops, diffOp{kind: ' ', text: a[ai], aLine: ai, bLine: bi})
+	}
+	return ops
+}
+
+// myersDiff computes a shortest edit script between a and b, whose first
+// lines sit at aBase and bBase in the original inputs.
+func myersDiff(a, b []string, aBase, bBase int) []diffOp {
+	n, m := len(a), len(b)
+	maxD := min(n+m, maxDiffEdits)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	// trace[d] holds the frontier after step d for diagonals -d..d
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+
+	if !found {
+		// Too many edits for a minimal diff; replace the whole region
+		ops := make([]diffOp, 0, n+m)
+		for i, line := range a {
+			ops = append(ops, diffOp{kind: '-', text: line, aLine: aBase + i, bLine: bBase})
+		}
+		for j, line := range b {
+			ops = append(ops, diffOp{kind: '+', text: line, aLine: aBase + n, bLine: bBase + j})
+		}
+		return ops
+	}
+
+	// Walk back from (n, m), collecting ops in reverse
+	var rev []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1] // diagonals -(d-1)..d-1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, diffOp{kind: ' ', text: a[x], aLine: aBase + x, bLine: bBase + y})
+		}
+		if prevK == k+1 {
+			y--
+			rev = append(rev, diffOp{kind: '+', text: b[y], aLine: aBase + x, bLine: bBase + y})
+		} else {
+			x--
+			rev = append(rev, diffOp{kind: '-', text: a[x], aLine: aBase + x, bLine: bBase + y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		rev = append(rev, diffOp{kind: ' ', text: a[x], aLine: aBase + x, bLine: bBase + y})
+	}
+
+	ops := make([]diffOp, len(rev))
+	for i, op := range rev {
+		ops[len(rev)-1-i] = op
+	}
+	return ops
+}
+
+// unifiedDiff renders an edit script as a unified diff with the given number
+// of context lines. It returns "" if there are no changes.
+func unifiedDiff(nameA, nameB string, ops []diffOp, context int) string {
+	var b strings.Builder
+	i := 0
+	for i < len(ops) {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		// Hunks are only split where the gap exceeds 2*context, so the
+		// leading context never overlaps the previous hunk
+		start := max(i-context, 0)
+
+		// Extend the hunk while the gap to the next change is small enough
+		// that the two context regions would touch
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			break
+		}
+		stop := min(end+context, len(ops))
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		writeHunk(&b, ops[start:stop])
+		i = stop
+	}
+	return b.String()
+}
+
+// writeHunk writes one "@@" header and its lines.
+func writeHunk(b *strings.Builder, hunk []diffOp) {
+	var aCount, bCount int
+	for _, op := range hunk {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(hunk[0].aLine, aCount), hunkRange(hunk[0].bLine, bCount))
+	for _, op := range hunk {
+		b.WriteByte(op.kind)
+		b.WriteString(op.text)
+		if !strings.HasSuffix(op.text, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk's start and length the way diff -u does: the
+// length is omitted when it is 1, and an empty range names the line before.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DiffFilesArgs is the input schema for the diff_files tool.
+type DiffFilesArgs struct {
+	PathA   string `json:"path_a" jsonschema:"the original file,required"`
+	PathB   string `json:"path_b" jsonschema:"the file to compare against path_a,required"`
+	Context *int   `json:"context,omitempty" jsonschema:"number of unchanged lines to show around each change (default 3)"`
+}
+
+const defaultDiffContext = 3
+
+func diffFilesHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[DiffFilesArgs, any] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, args DiffFilesArgs) (*mcp.CallToolResult, any, error) {
+		return doDiffFiles(sess, resolver, cfg, args)
+	}
+}
+
+func doDiffFiles(sess *session.Session, resolver *pathscope.Resolver, cfg Config, args DiffFilesArgs) (*mcp.CallToolResult, any, error) {
+	if args.PathA == "" || args.PathB == "" {
+		return toolErr(ErrInvalidInput, "path_a and path_b are required")
+	}
+	diffContext := defaultDiffContext
+	if args.Context != nil {
+		if *args.Context < 0 {
+			return toolErr(ErrInvalidInput, "context must not be negative, got %d", *args.Context)
+		}
+		diffContext = *args.Context
+	}
+
+	var contents [2][]byte
+	for i, p := range []string{args.PathA, args.PathB} {
+		data, result := readDiffInput(sess, resolver, cfg, p)
+		if result != nil {
+			return result, nil, nil
+		}
+		contents[i] = data
+	}
+
+	if bytes.Equal(contents[0], contents[1]) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Files %s and %s are identical", args.PathA, args.PathB)}},
+		}, nil, nil
+	}
+	if isBinaryHeader(contents[0][:min(len(contents[0]), 512)]) || isBinaryHeader(contents[1][:min(len(contents[1]), 512)]) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Binary files %s and %s differ", args.PathA, args.PathB)}},
+		}, nil, nil
+	}
+
+	ops := diffLines(splitLinesKeepEnds(string(contents[0])), splitLinesKeepEnds(string(contents[1])))
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: unifiedDiff(args.PathA, args.PathB, ops, diffContext)}},
+	}, nil, nil
+}
+
+// readDiffInput resolves and reads one side of a diff. On failure it returns
+// the tool error result to send back instead of the data.
+func readDiffInput(sess *session.Session, resolver *pathscope.Resolver, cfg Config, path string) ([]byte, *mcp.CallToolResult) {
+	fail := func(code, msg string, args ...any) ([]byte, *mcp.CallToolResult) {
+		r, _, _ := toolErr(code, msg, args...)
+		return nil, r
+	}
+
+	resolved, err := resolver.Resolve(sess.Cwd(), path)
+	if err != nil {
+		return fail(ErrAccessDenied, "path not allowed: %v", err)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fail(ErrPathNotFound, "%s does not exist", resolved)
+		}
+		return fail(ErrIO, "could not stat %s: %v", resolved, err)
+	}
+	if info.IsDir() {
+		return fail(ErrInvalidInput, "%s is a directory", resolved)
+	}
+	if info.Size() > cfg.MaxFileSize {
+		return fail(ErrFileTooLarge, "file %s is %d bytes, exceeds maximum %d bytes", resolved, info.Size(), cfg.MaxFileSize)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return fail(ErrIO, "could not read %s: %v", resolved, err)
+	}
+	return data, nil
+}
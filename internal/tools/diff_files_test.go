@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func callDiffFiles(t *testing.T, files map[string]string, args DiffFilesArgs) *mcp.CallToolResult {
+	t.Helper()
+	tmp := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+	result, _, err := diffFilesHandler(session.New(tmp), resolver, testConfig())(context.Background(), nil, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestDiffFilesUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context *int
+		want    string
+	}{
+		{
+			name: "single change",
+			a:    "one\ntwo\nthree\n",
+			b:    "one\n2\nthree\n",
+			want: "--- a.txt\n+++ b.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "insertion and deletion",
+			a:    "a\nb\nc\nd\n",
+			b:    "a\nc\nd\ne\n",
+			want: "--- a.txt\n+++ b.txt\n@@ -1,4 +1,4 @@\n a\n-b\n c\n d\n+e\n",
+		},
+		{
+			name:    "separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:       "X\n2\n3\n4\n5\n6\n7\n8\nY\n",
+			context: intPtr(1),
+			want:    "--- a.txt\n+++ b.txt\n@@ -1,2 +1,2 @@\n-1\n+X\n 2\n@@ -8,2 +8,2 @@\n 8\n-9\n+Y\n",
+		},
+		{
+			name:    "zero context",
+			a:       "a\nb\nc\n",
+			b:       "a\nc\n",
+			context: intPtr(0),
+			want:    "--- a.txt\n+++ b.txt\n@@ -2 +1,0 @@\n-b\n",
+		},
+		{
+			name: "missing trailing newline",
+			a:    "a\nb\n",
+			b:    "a\nb",
+			want: "--- a.txt\n+++ b.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name: "empty original",
+			a:    "",
+			b:    "new\n",
+			want: "--- a.txt\n+++ b.txt\n@@ -0,0 +1 @@\n+new\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := callDiffFiles(t, map[string]string{"a.txt": tt.a, "b.txt": tt.b},
+				DiffFilesArgs{PathA: "a.txt", PathB: "b.txt", Context: tt.context})
+			if isErrorResult(result) {
+				t.Fatalf("unexpected error: %s", resultText(result))
+			}
+			if got := resultText(result); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffFilesIdentical(t *testing.T) {
+	result := callDiffFiles(t, map[string]string{"a.txt": "same\n", "b.txt": "same\n"},
+		DiffFilesArgs{PathA: "a.txt", PathB: "b.txt"})
+	if got := resultText(result); got != "Files a.txt and b.txt are identical" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDiffFilesErrors(t *testing.T) {
+	files := map[string]string{"a.txt": "x\n"}
+
+	result := callDiffFiles(t, files, DiffFilesArgs{PathA: "a.txt", PathB: "missing.txt"})
+	if !hasErrorCode(result, ErrPathNotFound) {
+		t.Errorf("expected %s, got: %s", ErrPathNotFound, resultText(result))
+	}
+
+	result = callDiffFiles(t, files, DiffFilesArgs{PathA: "a.txt", PathB: "/etc/hostname"})
+	if !hasErrorCode(result, ErrAccessDenied) {
+		t.Errorf("expected %s, got: %s", ErrAccessDenied, resultText(result))
+	}
+
+	result = callDiffFiles(t, files, DiffFilesArgs{PathA: "a.txt", PathB: "a.txt", Context: intPtr(-1)})
+	if !hasErrorCode(result, ErrInvalidInput) {
+		t.Errorf("expected %s, got: %s", ErrInvalidInput, resultText(result))
+	}
+}
+
+func TestDiffLinesMinimal(t *testing.T) {
+	a := splitLinesKeepEnds("a\nb\nc\na\nb\nb\na\n")
+	b := splitLinesKeepEnds("c\nb\na\nb\na\nc\n")
+	ops := diffLines(a, b)
+
+	// Myers' classic example has an edit distance of 5
+	edits := 0
+	var gotA, gotB strings.Builder
+	for _, op := range ops {
+		if op.kind != ' ' {
+			edits++
+		}
+		if op.kind != '+' {
+			gotA.WriteString(op.text)
+		}
+		if op.kind != '-' {
+			gotB.WriteString(op.text)
+		}
+	}
+	if edits != 5 {
+		t.Errorf("expected 5 edits, got %d", edits)
+	}
+	if gotA.String() != strings.Join(a, "") || gotB.String() != strings.Join(b, "") {
+		t.Errorf("edit script does not reproduce inputs:\na=%q\nb=%q", gotA.String(), gotB.String())
+	}
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// editDiff describes a successful edit of path as a unified diff between
+// before and after for Config.EditDiffs, capped at maxOutputChars. Binary
+// content yields a note instead of a diff.
+func editDiff(path, before, after string) string {
+	header := "Diff:"
+	if isBinaryHeader([]byte(before[:min(len(before), 512)])) || isBinaryHeader([]byte(after[:min(len(after), 512)])) {
+		return header + " binary file, not shown"
+	}
+	if before == after {
+		return header + " no changes"
+	}
+	ops := diffLines(splitLinesKeepEnds(before), splitLinesKeepEnds(after))
+	return header + "\n" + truncateOutput(unifiedDiff(path+" (before)", path+" (after)", ops, defaultDiffContext))
+}
+
+// appendEditDiff adds the edit diff to a successful edit result's text when
+// cfg.EditDiffs is set.
+func appendEditDiff(cfg Config, text, path, before, after string) string {
+	if !cfg.EditDiffs {
+		return text
+	}
+	return fmt.Sprintf("%s\n\n%s", strings.TrimRight(text, "\n"), editDiff(path, before, after))
+}
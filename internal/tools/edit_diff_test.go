@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func TestEditDiffs(t *testing.T) {
+	cfg := testConfig()
+	cfg.EditDiffs = true
+
+	t.Run("outside a repository diffs against the previous content", func(t *testing.T) {
+		tmp := t.TempDir()
+		path := filepath.Join(tmp, "f.txt")
+		os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644)
+		sess := session.New(tmp)
+		resolver := testResolver(t)
+
+		r, _, err := doStrReplace(sess, resolver, cfg, "f.txt", "two", "TWO", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := resultText(r)
+		if isErrorResult(r) {
+			t.Fatal(text)
+		}
+		want := "Diff:\n--- " + path + " (before)\n+++ " + path + " (after)\n@@ -1,3 +1,3 @@\n one\n-two\n+TWO\n three\n"
+		if !strings.HasSuffix(text, want) {
+			t.Errorf("expected diff %q, got:\n%s", want, text)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		tmp := t.TempDir()
+		os.WriteFile(filepath.Join(tmp, "f.txt"), []byte("one\n"), 0644)
+		r, _, err := doStrReplace(session.New(tmp), testResolver(t), testConfig(), "f.txt", "one", "ONE", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(resultText(r), "\n--- ") {
+			t.Errorf("unexpected diff without EditDiffs: %s", resultText(r))
+		}
+	})
+
+	t.Run("inside a repository shows only this edit", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git not installed")
+		}
+		tmp := t.TempDir()
+		git := func(args ...string) {
+			t.Helper()
+			cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+			cmd.Dir = tmp
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, out)
+			}
+		}
+		git("init", "-q")
+		os.MkdirAll(filepath.Join(tmp, "sub"), 0755)
+		path := filepath.Join(tmp, "sub", "f.txt")
+		os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644)
+		git("add", ".")
+		git("commit", "-q", "-m", "baseline")
+		sess := session.New(tmp)
+		resolver := testResolver(t)
+
+		// The first edit is uncommitted; the second edit's diff leaves it out
+		if r, _, _ := doStrReplace(sess, resolver, cfg, "sub/f.txt", "one", "ONE", false); isErrorResult(r) {
+			t.Fatal(resultText(r))
+		}
+		r, _, err := doStrReplace(sess, resolver, cfg, "sub/f.txt", "three", "THREE", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "Diff:\n--- " + path + " (before)\n+++ " + path + " (after)\n@@ -1,3 +1,3 @@\n ONE\n two\n-three\n+THREE\n"
+		if text := resultText(r); !strings.HasSuffix(text, want) {
+			t.Errorf("expected diff %q, got:\n%s", want, text)
+		}
+	})
+
+	t.Run("large diffs are truncated", func(t *testing.T) {
+		tmp := t.TempDir()
+		sess := session.New(tmp)
+		content := strings.Repeat("a fairly long line of new content\n", 2000)
+		r, _, err := doCreateFile(sess, testResolver(t), cfg, "big.txt", content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := resultText(r)
+		if !strings.Contains(text, "[Truncated: output was") {
+			t.Errorf("expected a truncation note, got %d chars", len(text))
+		}
+		if len(text) > maxOutputChars+1000 {
+			t.Errorf("result is %d chars, want about %d", len(text), maxOutputChars)
+		}
+	})
+}
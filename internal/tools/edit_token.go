@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// editTokenTTL is how long a grep edit token remains valid for
+// search_replace_files.
+const editTokenTTL = 10 * time.Minute
+
+// editQuery identifies the set of files a grep or search_replace_files call
+// selects. An edit token is only accepted for the query it was issued for.
+// The fields after CaseInsensitive are grep filters that search_replace_files
+// lacks: a grep narrowed by any of them showed fewer files or lines than a
+// replacement would touch, so its token matches no search_replace_files call.
+type editQuery struct {
+	Pattern         string `json:"pattern"`
+	Root            string `json:"root"`
+	Include         string `json:"include,omitempty"`
+	Type            string `json:"type,omitempty"`
+	CaseInsensitive bool   `json:"case_insensitive,omitempty"`
+	SkipHidden      bool   `json:"skip_hidden,omitempty"`
+	Multiline       bool   `json:"multiline,omitempty"`
+	StartLine       int    `json:"start_line,omitempty"`
+	EndLine         int    `json:"end_line,omitempty"`
+	MaxFiles        int    `json:"max_files,omitempty"`
+	MaxMatches      int    `json:"max_matches,omitempty"`
+	ModifiedWithin  string `json:"modified_within,omitempty"`
+	ChangedSince    string `json:"changed_since,omitempty"`
+	Binary          bool   `json:"binary,omitempty"`
+	NullData        bool   `json:"null_data,omitempty"`
+}
+
+// key returns the query's canonical string form, with the search path
+// resolved against the session cwd so that equivalent spellings match.
+func (q editQuery) key(sess *session.Session, resolver *pathscope.Resolver) string {
+	if q.Root == "" {
+		q.Root = "."
+	}
+	if resolved, err := resolver.Resolve(sess.Cwd(), q.Root); err == nil {
+		q.Root = resolved
+	}
+	data, _ := json.Marshal(q)
+	return string(data)
+}
+
+// appendEditToken issues an edit token for q and appends it to the first text
+// block of a successful grep result. The result is copied rather than
+// modified, since it may be held by the grep cache. Error results are
+// returned unchanged.
+func appendEditToken(sess *session.Session, resolver *pathscope.Resolver, q editQuery, result *mcp.CallToolResult) *mcp.CallToolResult {
+	if result == nil || result.IsError || len(result.Content) == 0 {
+		return result
+	}
+	tc, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		return result
+	}
+	token := sess.IssueEditToken(q.key(sess, resolver), editTokenTTL)
+	out := *result
+	out.Content = append([]mcp.Content{&mcp.TextContent{
+		Text: tc.Text + fmt.Sprintf("\n[edit_token: %s]", token),
+	}}, result.Content[1:]...)
+	return &out
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// FindAndViewArgs is the input schema for the find_and_view tool.
+type FindAndViewArgs struct {
+	Pattern   string    `json:"pattern" jsonschema:"glob pattern selecting the file to view,required"`
+	Path      string    `json:"path,omitempty" jsonschema:"the directory to search in (defaults to cwd)"`
+	ViewRange ViewRange `json:"view_range,omitempty" jsonschema:"optional line range [start end] (1-indexed; negative values count from the end, -1 = last line)"`
+	First     bool      `json:"first,omitempty" jsonschema:"when several files match, view the most recently modified one instead of failing"`
+}
+
+// maxAmbiguousMatches caps the candidates listed when find_and_view matches
+// more than one file.
+const maxAmbiguousMatches = 20
+
+func findAndViewHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[FindAndViewArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
+	return func(ctx context.Context, _ *mcp.CallToolRequest, args FindAndViewArgs) (*mcp.CallToolResult, any, error) {
+		return doFindAndView(ctx, sess, resolver, cfg, excludeDirs, args)
+	}
+}
+
+func doFindAndView(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, cfg Config, excludeDirs map[string]bool, args FindAndViewArgs) (*mcp.CallToolResult, any, error) {
+	matches, errResult := globMatches(ctx, sess, resolver, globParams{
+		pattern:     args.Pattern,
+		path:        args.Path,
+		filterType:  "file",
+		excludeDirs: excludeDirs,
+	})
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(matches) == 0 {
+		return toolErr(ErrPathNotFound, "no files match %s", args.Pattern)
+	}
+	if len(matches) > 1 && !args.First {
+		var b strings.Builder
+		for i, m := range matches {
+			if i == maxAmbiguousMatches {
+				fmt.Fprintf(&b, "\n... and %d more", len(matches)-i)
+				break
+			}
+			b.WriteString("\n" + m.relPath)
+		}
+		return toolErr(ErrInvalidInput, "%d files match %s; narrow the pattern or set first to view the newest:%s", len(matches), args.Pattern, b.String())
+	}
+
+	match := matches[0]
+	result, extra, err := doView(ctx, sess, resolver, cfg, match.resolved, args.ViewRange, viewOptions{})
+	if err != nil || result == nil || result.IsError {
+		return result, extra, err
+	}
+	// Name the file that was picked ahead of its content
+	if len(result.Content) > 0 {
+		if tc, ok := result.Content[0].(*mcp.TextContent); ok {
+			tc.Text = match.relPath + ":\n" + tc.Text
+			return result, extra, err
+		}
+	}
+	result.Content = append([]mcp.Content{&mcp.TextContent{Text: match.relPath}}, result.Content...)
+	return result, extra, err
+}
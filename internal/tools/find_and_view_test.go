@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func findAndViewSetup(t *testing.T) (string, *session.Session) {
+	t.Helper()
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "logs"), 0755)
+	os.WriteFile(filepath.Join(tmp, "config.yaml"), []byte("name: demo\nport: 8080\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "logs", "old.log"), []byte("old run\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "logs", "new.log"), []byte("line 1\nline 2\nline 3\n"), 0644)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(tmp, "logs", "old.log"), old, old)
+	return tmp, session.New(tmp)
+}
+
+func TestFindAndViewUniqueMatch(t *testing.T) {
+	tmp, sess := findAndViewSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := findAndViewHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, FindAndViewArgs{Pattern: "*.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	text := resultText(result)
+	if !strings.HasPrefix(text, "config.yaml:\n") {
+		t.Errorf("output should name the file first, got: %s", text)
+	}
+	if !strings.Contains(text, "2\tport: 8080") {
+		t.Errorf("output should contain numbered content, got: %s", text)
+	}
+	if !sess.HasViewed(filepath.Join(tmp, "config.yaml")) {
+		t.Error("viewed file should be marked for view-before-edit")
+	}
+}
+
+func TestFindAndViewAmbiguous(t *testing.T) {
+	_, sess := findAndViewSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := findAndViewHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, FindAndViewArgs{Pattern: "logs/*.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(result, ErrInvalidInput) {
+		t.Fatalf("expected %s, got: %s", ErrInvalidInput, resultText(result))
+	}
+	text := resultText(result)
+	if !strings.Contains(text, "logs/new.log") || !strings.Contains(text, "logs/old.log") {
+		t.Errorf("error should list the candidates, got: %s", text)
+	}
+
+	// first picks the newest and honors view_range
+	result, _, err = handler(context.Background(), nil, FindAndViewArgs{Pattern: "logs/*.log", First: true, ViewRange: []int{-1, -1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	text = resultText(result)
+	if !strings.HasPrefix(text, "logs/new.log:\n") || !strings.Contains(text, "3\tline 3") || strings.Contains(text, "line 2") {
+		t.Errorf("expected last line of logs/new.log, got: %s", text)
+	}
+}
+
+func TestFindAndViewNoMatch(t *testing.T) {
+	_, sess := findAndViewSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := findAndViewHandler(sess, resolver, testConfig())
+
+	// Directories never match
+	for _, pattern := range []string{"*.toml", "logs"} {
+		result, _, err := handler(context.Background(), nil, FindAndViewArgs{Pattern: pattern})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, ErrPathNotFound) {
+			t.Errorf("%s: expected %s, got: %s", pattern, ErrPathNotFound, resultText(result))
+		}
+	}
+}
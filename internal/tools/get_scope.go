@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// GetScopeArgs is the input schema for the get_scope tool. It takes no arguments.
+type GetScopeArgs struct{}
+
+func getScopeHandler(sess *session.Session, resolver *pathscope.Resolver) mcp.ToolHandlerFor[GetScopeArgs, any] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, _ GetScopeArgs) (*mcp.CallToolResult, any, error) {
+		var b strings.Builder
+		fmt.Fprintf(&b, "cwd: %s\n", sess.Cwd())
+		writeScopeList(&b, "allow_dirs", resolver.AllowDirs(), "(none; all paths allowed)")
+		writeScopeList(&b, "deny_patterns", resolver.DenyPatterns(), "(none)")
+		if patterns := resolver.DenyWritePatterns(); len(patterns) > 0 {
+			writeScopeList(&b, "deny_write_patterns", patterns, "")
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: strings.TrimSuffix(b.String(), "\n")}},
+		}, nil, nil
+	}
+}
+
+// writeScopeList writes a labelled list, one indented entry per line, or the
+// empty text on the label line when there are no entries.
+func writeScopeList(b *strings.Builder, label string, entries []string, empty string) {
+	if len(entries) == 0 {
+		fmt.Fprintf(b, "%s: %s\n", label, empty)
+		return
+	}
+	fmt.Fprintf(b, "%s:\n", label)
+	for _, e := range entries {
+		fmt.Fprintf(b, "  %s\n", e)
+	}
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func TestGetScopeReportsResolverConfig(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "src")
+	resolver, err := pathscope.NewResolver([]string{tmp}, []string{"**/.env", src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := session.New(tmp)
+	handler := getScopeHandler(sess, resolver)
+
+	result, _, err := handler(context.Background(), nil, GetScopeArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+
+	var want strings.Builder
+	want.WriteString("cwd: " + tmp + "\nallow_dirs:\n")
+	for _, d := range resolver.AllowDirs() {
+		want.WriteString("  " + d + "\n")
+	}
+	want.WriteString("deny_patterns:\n")
+	for _, p := range resolver.DenyPatterns() {
+		want.WriteString("  " + p + "\n")
+	}
+	if got := resultText(result); got != strings.TrimSuffix(want.String(), "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want.String())
+	}
+	if !strings.Contains(resultText(result), "  **/.env") {
+		t.Errorf("expected deny pattern in output, got:\n%s", resultText(result))
+	}
+
+	// cwd tracks the session
+	sess.SetCwd(src)
+	result, _, _ = handler(context.Background(), nil, GetScopeArgs{})
+	if !strings.HasPrefix(resultText(result), "cwd: "+src+"\n") {
+		t.Errorf("expected updated cwd, got:\n%s", resultText(result))
+	}
+}
+
+func TestGetScopeUnrestricted(t *testing.T) {
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := getScopeHandler(session.New("/workspace"), resolver)
+
+	result, _, err := handler(context.Background(), nil, GetScopeArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "cwd: /workspace\nallow_dirs: (none; all paths allowed)\ndeny_patterns: (none)"
+	if got := resultText(result); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// gitignoreStack manages a stack of gitignore matchers for nested directory
+// traversal. Each directory visited during a walk pushes its .gitignore and
+// any extra ignore files (if present); patterns are matched relative to the
+// directory that defines them.
+type gitignoreStack struct {
+	stack []gitignoreLevel
+	files []string // ignore file names read at each level, lowest precedence first
+}
+
+// gitignoreLevel holds the parsed patterns from a single directory's ignore
+// files.
+type gitignoreLevel struct {
+	dir      string
+	patterns []gitignorePattern
+}
+
+// gitignorePattern is a single compiled gitignore pattern.
+type gitignorePattern struct {
+	re      *regexp.Regexp // matches a slash-separated path relative to the level's dir
+	negate  bool
+	dirOnly bool
+}
+
+// newGitignoreStack returns a stack that reads .gitignore at each level,
+// followed by extraFiles (e.g. .ignore and .rgignore) with the same syntax.
+// Later files take precedence, since the last matching pattern wins.
+func newGitignoreStack(extraFiles []string) *gitignoreStack {
+	return &gitignoreStack{files: append([]string{".gitignore"}, extraFiles...)}
+}
+
+func (g *gitignoreStack) push(dir string) {
+	var patterns []gitignorePattern
+	for _, name := range g.files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			// No such ignore file at this level
+			continue
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			if p, ok := parseGitignoreLine(scanner.Text()); ok {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+
+	g.stack = append(g.stack, gitignoreLevel{dir: dir, patterns: patterns})
+}
+
+func (g *gitignoreStack) pop() {
+	if len(g.stack) > 0 {
+		g.stack = g.stack[:len(g.stack)-1]
+	}
+}
+
+// isIgnored reports whether path is ignored by the patterns on the stack.
+// Patterns are applied in order from the outermost .gitignore inward, and the
+// last matching pattern wins, so a child's negation can re-include a file.
+func (g *gitignoreStack) isIgnored(path string, isDir bool) bool {
+	ignored := false
+	for _, level := range g.stack {
+		if len(level.patterns) == 0 {
+			continue
+		}
+		relPath, err := filepath.Rel(level.dir, path)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			continue
+		}
+		relPath = filepath.ToSlash(relPath)
+		for _, p := range level.patterns {
+			if p.dirOnly && !isDir {
+				continue
+			}
+			if p.re.MatchString(relPath) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// parseGitignoreLine compiles one line of a .gitignore file. It reports
+// ok=false for blank lines, comments, and patterns that cannot be compiled.
+//
+// Matching follows the gitignore spec:
+//   - A leading "!" negates the pattern; "\!" and "\#" escape a literal.
+//   - A trailing "/" matches directories only.
+//   - A "/" at the start or in the middle anchors the pattern to the
+//     .gitignore's directory; otherwise it matches a name at any depth.
+//   - A leading "**/" matches in all directories, a trailing "/**" matches
+//     everything inside, and "/**/" matches zero or more directories.
+func parseGitignoreLine(line string) (gitignorePattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are ignored unless escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignorePattern{}, false
+	}
+
+	var p gitignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return gitignorePattern{}, false
+	}
+
+	re, err := regexp.Compile(gitignoreRegexp(line, anchored))
+	if err != nil {
+		return gitignorePattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// gitignoreRegexp translates a gitignore pattern (without negation, trailing
+// slash, or leading slash) into an anchored regular expression over
+// slash-separated relative paths.
+func gitignoreRegexp(pattern string, anchored bool) string {
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	segs := strings.Split(pattern, "/")
+	for i, seg := range segs {
+		last := i == len(segs)-1
+		if seg == "**" {
+			if last {
+				b.WriteString(".*")
+			} else {
+				b.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+		b.WriteString(globSegmentRegexp(seg))
+		if !last {
+			b.WriteString("/")
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// globSegmentRegexp translates a single path segment containing "*", "?",
+// "[...]" and backslash escapes into a regular expression that never matches
+// "/". A "**" that is not a whole segment behaves like "*".
+func globSegmentRegexp(seg string) string {
+	var b strings.Builder
+	rs := []rune(seg)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		switch c {
+		case '*':
+			b.WriteString("[^/]*")
+			for i+1 < len(rs) && rs[i+1] == '*' {
+				i++
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(rs) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(string(rs[i])))
+		case '[':
+			end := slices.Index(rs[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := string(rs[i+1 : i+1+end])
+			i += end + 1
+			b.WriteString("[")
+			if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+				b.WriteString("^/")
+				class = class[1:]
+			}
+			b.WriteString(strings.ReplaceAll(class, "[", `\[`))
+			b.WriteString("]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
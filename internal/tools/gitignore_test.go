@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func TestGitignorePatternMatching(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		// Unanchored names match at any depth
+		{"build", "build", true, true},
+		{"build", "src/build", true, true},
+		{"build", "src/build", false, true},
+		{"*.log", "a/b/debug.log", false, true},
+
+		// Leading slash anchors to the .gitignore's directory
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+
+		// Trailing slash matches directories only, at any depth
+		{"build/", "build", true, true},
+		{"build/", "src/nested/build", true, true},
+		{"build/", "build", false, false},
+
+		// A slash in the middle anchors too
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "src/docs/a.md", false, false},
+		{"docs/*.md", "docs/sub/a.md", false, false},
+
+		// ** semantics
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/b", false, true},
+		{"a/**/b", "a/x/y/b", true, true},
+		{"a/**/b", "c/a/x/b", false, false},
+		{"a/**/b", "a/x/bb", false, false},
+		{"**/foo", "foo", false, true},
+		{"**/foo", "x/y/foo", false, true},
+		{"lib/**", "lib/x/y.go", false, true},
+		{"lib/**", "lib", true, false},
+		{"**/vendor/**", "src/vendor/dep/main.go", false, true},
+
+		// Wildcards never cross directory boundaries
+		{"foo*bar", "foo/bar", false, false},
+		{"foo?bar", "foo/bar", false, false},
+		{"[a-c].txt", "b.txt", false, true},
+		{"[!a-c].txt", "d.txt", false, true},
+		{"[!a-c].txt", "a.txt", false, false},
+
+		// Escapes
+		{`\#notes`, "#notes", false, true},
+		{`\!keep`, "!keep", false, true},
+
+		// Non-ASCII patterns match whole characters
+		{"café.txt", "docs/café.txt", false, true},
+		{"caf?.txt", "café.txt", false, true},
+		{"日本/*.md", "日本/読む.md", false, true},
+		{"[éè].txt", "è.txt", false, true},
+		{`\é`, "é", false, true},
+	}
+	for _, tt := range tests {
+		p, ok := parseGitignoreLine(tt.pattern)
+		if !ok {
+			t.Errorf("pattern %q did not compile", tt.pattern)
+			continue
+		}
+		got := (!p.dirOnly || tt.isDir) && p.re.MatchString(tt.path)
+		if got != tt.want {
+			t.Errorf("pattern %q vs %q (dir=%v): got %v, want %v", tt.pattern, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestGitignoreSkipsCommentsAndBlanks(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "/"} {
+		if _, ok := parseGitignoreLine(line); ok {
+			t.Errorf("line %q should not produce a pattern", line)
+		}
+	}
+	p, ok := parseGitignoreLine("!important.log  ")
+	if !ok || !p.negate || !p.re.MatchString("important.log") {
+		t.Errorf("negated pattern with trailing spaces not parsed correctly")
+	}
+}
+
+func TestGitignoreStackNested(t *testing.T) {
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "src"), 0755)
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("/build\n*.log\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "src", ".gitignore"), []byte("/gen\n!keep.log\n"), 0644)
+
+	gi := newGitignoreStack(nil)
+	gi.push(tmp)
+	gi.push(filepath.Join(tmp, "src"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{filepath.Join(tmp, "build"), true, true},
+		{filepath.Join(tmp, "src", "build"), true, false},
+		// Anchored patterns are relative to the .gitignore that defines them
+		{filepath.Join(tmp, "src", "gen"), true, true},
+		{filepath.Join(tmp, "gen"), true, false},
+		{filepath.Join(tmp, "src", "debug.log"), false, true},
+		{filepath.Join(tmp, "src", "keep.log"), false, false},
+	}
+	for _, tt := range tests {
+		if got := gi.isIgnored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("isIgnored(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExtraIgnoreFiles(t *testing.T) {
+	tmp := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("*.log\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, ".ignore"), []byte("secret.txt\n"), 0644)
+	// .rgignore is read last, so it can re-include what .gitignore excludes
+	os.WriteFile(filepath.Join(tmp, ".rgignore"), []byte("!keep.log\n"), 0644)
+	for _, name := range []string{"a.txt", "secret.txt", "debug.log", "keep.log"} {
+		os.WriteFile(filepath.Join(tmp, name), []byte("hit\n"), 0644)
+	}
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+
+	search := func(cfg Config) (grepped, globbed []string) {
+		t.Helper()
+		r, _, err := grepHandler(sess, resolver, cfg)(context.Background(), nil, GrepArgs{Pattern: "hit"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		grepped = strings.Split(resultText(r), "\n")
+		r, _, err = globHandler(sess, resolver, cfg)(context.Background(), nil, GlobArgs{Pattern: "*.{txt,log}"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		globbed = strings.Split(resultText(r), "\n")
+		sort.Strings(grepped)
+		sort.Strings(globbed)
+		return grepped, globbed
+	}
+
+	cfg := testConfig()
+	cfg.ExtraIgnoreFiles = []string{".ignore", ".rgignore"}
+	grepped, globbed := search(cfg)
+	want := []string{"a.txt", "keep.log"}
+	if !slices.Equal(grepped, want) {
+		t.Errorf("grep found %q, want %q", grepped, want)
+	}
+	if !slices.Equal(globbed, want) {
+		t.Errorf("glob found %q, want %q", globbed, want)
+	}
+
+	// Without extra ignore files only .gitignore applies
+	grepped, globbed = search(testConfig())
+	want = []string{"a.txt", "secret.txt"}
+	if !slices.Equal(grepped, want) {
+		t.Errorf("grep without extra ignore files found %q, want %q", grepped, want)
+	}
+	if !slices.Equal(globbed, want) {
+		t.Errorf("glob without extra ignore files found %q, want %q", globbed, want)
+	}
+}
@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxGitIndexEntries caps how many index entries gitDirty will stat. Larger
+// repositories report no dirty status rather than slowing down view.
+const maxGitIndexEntries = 10000
+
+// gitSummary returns a one-line description of the git repository rooted at
+// dir, such as "[git] branch: main (uncommitted changes)". It reports ok=false
+// if dir is not a repository root. Only the repository files are read; the
+// git binary is not required.
+func gitSummary(dir string) (string, bool) {
+	gitDir, ok := findGitDir(dir)
+	if !ok {
+		return "", false
+	}
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", false
+	}
+
+	var line string
+	ref := strings.TrimSpace(string(head))
+	if branch, ok := strings.CutPrefix(ref, "ref: refs/heads/"); ok {
+		line = "[git] branch: " + branch
+	} else if other, ok := strings.CutPrefix(ref, "ref: "); ok {
+		line = "[git] ref: " + other
+	} else {
+		line = "[git] detached HEAD at " + ref[:min(12, len(ref))]
+	}
+
+	switch dirty, err := gitDirty(dir, gitDir); {
+	case err != nil:
+		// Status unknown; report the branch only
+	case dirty:
+		line += " (uncommitted changes)"
+	default:
+		line += " (no changes to tracked files)"
+	}
+	return line, true
+}
+
+// findGitDir locates the git directory for a repository rooted at dir. It
+// handles both a .git directory and a .git file pointing elsewhere, as used
+// by worktrees and submodules.
+func findGitDir(dir string) (string, bool) {
+	dotGit := filepath.Join(dir, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", false
+	}
+	if info.IsDir() {
+		return dotGit, true
+	}
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", false
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return "", false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+	return target, true
+}
+
+// gitDirty reports whether any tracked file differs from the index, using the
+// same cheap stat comparison (size and mtime) that git performs before
+// rehashing. Merge conflicts and deleted files count as changes; untracked
+// files are not considered. A false positive is possible for files that were
+// touched but not modified.
+func gitDirty(workTree, gitDir string) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "index"))
+	if err != nil {
+		return false, err
+	}
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return false, errors.New("invalid index header")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version != 2 && version != 3 {
+		return false, errors.New("unsupported index version")
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	if count > maxGitIndexEntries {
+		return false, errors.New("index too large")
+	}
+
+	hashLen := 20
+	if config, err := os.ReadFile(filepath.Join(gitDir, "config")); err == nil &&
+		bytes.Contains(config, []byte("objectformat = sha256")) {
+		hashLen = 32
+	}
+
+	const (
+		flagExtended    = 0x4000
+		flagStageMask   = 0x3000
+		flagNameMask    = 0x0fff
+		extSkipWorktree = 0x4000
+		extIntentToAdd  = 0x2000
+		modeGitlink     = 0160000
+	)
+
+	pos := 12
+	for i := uint32(0); i < count; i++ {
+		fixed := 40 + hashLen + 2
+		if pos+fixed > len(data) {
+			return false, errors.New("truncated index")
+		}
+		e := data[pos:]
+		mtime := binary.BigEndian.Uint32(e[8:12])
+		mode := binary.BigEndian.Uint32(e[24:28])
+		size := binary.BigEndian.Uint32(e[36:40])
+		flags := binary.BigEndian.Uint16(e[40+hashLen:])
+		var ext uint16
+		if flags&flagExtended != 0 {
+			if pos+fixed+2 > len(data) {
+				return false, errors.New("truncated index")
+			}
+			ext = binary.BigEndian.Uint16(e[fixed:])
+			fixed += 2
+		}
+		nameLen := int(flags & flagNameMask)
+		if nameLen == flagNameMask {
+			nameLen = bytes.IndexByte(e[fixed:], 0)
+		}
+		if nameLen < 0 || pos+fixed+nameLen > len(data) {
+			return false, errors.New("truncated index")
+		}
+		name := string(e[fixed : fixed+nameLen])
+		// Entries are NUL-padded to a multiple of 8 bytes
+		pos += (fixed + nameLen + 8) &^ 7
+
+		if flags&flagStageMask != 0 {
+			return true, nil // unresolved merge conflict
+		}
+		if ext&(extSkipWorktree|extIntentToAdd) != 0 || mode&0170000 == modeGitlink {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(workTree, filepath.FromSlash(name)))
+		if err != nil {
+			return true, nil // deleted
+		}
+		if uint32(info.Size()) != size || uint32(info.ModTime().Unix()) != mtime {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+// writeGitRepo creates a minimal repository structure at dir with the given
+// HEAD contents and a version 2 index recording the current stat data of
+// the named files.
+func writeGitRepo(t *testing.T, dir, head string, tracked ...string) {
+	t.Helper()
+	gitDir := filepath.Join(dir, ".git")
+	if err := os.MkdirAll(gitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte(head+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("DIRC")
+	binary.Write(&buf, binary.BigEndian, uint32(2))
+	binary.Write(&buf, binary.BigEndian, uint32(len(tracked)))
+	for _, name := range tracked {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := buf.Len()
+		mtime := uint32(info.ModTime().Unix())
+		for _, v := range []uint32{mtime, 0, mtime, 0, 0, 0, 0100644, 0, 0, uint32(info.Size())} {
+			binary.Write(&buf, binary.BigEndian, v)
+		}
+		buf.Write(make([]byte, 20)) // object hash
+		binary.Write(&buf, binary.BigEndian, uint16(len(name)))
+		buf.WriteString(name)
+		entryLen := buf.Len() - start
+		buf.Write(make([]byte, (entryLen+8)&^7-entryLen))
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "index"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func viewGitInfo(t *testing.T, dir string) string {
+	t.Helper()
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := viewHandler(session.New(dir), resolver, testConfig())
+	result, _, err := handler(context.Background(), nil, ViewArgs{Path: dir, GitInfo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resultText(result)
+}
+
+func TestViewGitInfoBranchAndStatus(t *testing.T) {
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "src"), 0755)
+	os.WriteFile(filepath.Join(tmp, "README.md"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "src", "main.go"), []byte("package main\n"), 0644)
+	writeGitRepo(t, tmp, "ref: refs/heads/feature/login", "README.md", "src/main.go")
+
+	text := viewGitInfo(t, tmp)
+	if first := strings.SplitN(text, "\n", 2)[0]; first != "[git] branch: feature/login (no changes to tracked files)" {
+		t.Errorf("unexpected git line %q", first)
+	}
+	if !strings.Contains(text, "src/") {
+		t.Errorf("listing should follow the git line, got:\n%s", text)
+	}
+
+	// Modifying a tracked file changes its size
+	os.WriteFile(filepath.Join(tmp, "README.md"), []byte("hello, world\n"), 0644)
+	if text := viewGitInfo(t, tmp); !strings.HasPrefix(text, "[git] branch: feature/login (uncommitted changes)\n") {
+		t.Errorf("expected uncommitted changes after edit, got:\n%s", text)
+	}
+
+	// Deleting a tracked file
+	writeGitRepo(t, tmp, "ref: refs/heads/main", "README.md", "src/main.go")
+	os.Remove(filepath.Join(tmp, "src", "main.go"))
+	if text := viewGitInfo(t, tmp); !strings.HasPrefix(text, "[git] branch: main (uncommitted changes)\n") {
+		t.Errorf("expected uncommitted changes after delete, got:\n%s", text)
+	}
+}
+
+func TestViewGitInfoDetachedAndWithoutIndex(t *testing.T) {
+	tmp := t.TempDir()
+	writeGitRepo(t, tmp, "0123456789abcdef0123456789abcdef01234567")
+	os.Remove(filepath.Join(tmp, ".git", "index"))
+
+	// Without an index the status is unknown and only HEAD is reported
+	if text := viewGitInfo(t, tmp); !strings.HasPrefix(text, "[git] detached HEAD at 0123456789ab\n") {
+		t.Errorf("unexpected output:\n%s", text)
+	}
+}
+
+func TestViewGitInfoWorktreeFile(t *testing.T) {
+	tmp := t.TempDir()
+	realGitDir := filepath.Join(tmp, "repo.git")
+	work := filepath.Join(tmp, "work")
+	os.MkdirAll(realGitDir, 0755)
+	os.MkdirAll(work, 0755)
+	os.WriteFile(filepath.Join(realGitDir, "HEAD"), []byte("ref: refs/heads/wt\n"), 0644)
+	os.WriteFile(filepath.Join(work, ".git"), []byte("gitdir: ../repo.git\n"), 0644)
+
+	if text := viewGitInfo(t, work); !strings.HasPrefix(text, "[git] branch: wt\n") {
+		t.Errorf("unexpected output:\n%s", text)
+	}
+}
+
+func TestViewGitInfoNotARepo(t *testing.T) {
+	tmp := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("a"), 0644)
+
+	if text := viewGitInfo(t, tmp); strings.Contains(text, "[git]") {
+		t.Errorf("non-repository should have no git line, got:\n%s", text)
+	}
+
+	// Without git_info, repositories are listed as before
+	writeGitRepo(t, tmp, "ref: refs/heads/main")
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	result, _, _ := viewHandler(session.New(tmp), resolver, testConfig())(context.Background(), nil, ViewArgs{Path: tmp})
+	if strings.Contains(resultText(result), "[git]") {
+		t.Errorf("git line should require git_info, got:\n%s", resultText(result))
+	}
+}
+
+// TestViewGitInfoRealRepository checks the index parser against an index
+// written by git itself, when git is installed.
+func TestViewGitInfoRealRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmp := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = tmp
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "trunk")
+	os.MkdirAll(filepath.Join(tmp, "pkg"), 0755)
+	os.WriteFile(filepath.Join(tmp, "pkg", "a_rather_long_file_name.go"), []byte("package pkg\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "b.txt"), []byte("b\n"), 0644)
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	if text := viewGitInfo(t, tmp); !strings.HasPrefix(text, "[git] branch: trunk (no changes to tracked files)\n") {
+		t.Errorf("unexpected output for clean repo:\n%s", text)
+	}
+
+	// Same size, later mtime
+	later := time.Now().Add(time.Hour)
+	os.WriteFile(filepath.Join(tmp, "b.txt"), []byte("c\n"), 0644)
+	os.Chtimes(filepath.Join(tmp, "b.txt"), later, later)
+	if text := viewGitInfo(t, tmp); !strings.HasPrefix(text, "[git] branch: trunk (uncommitted changes)\n") {
+		t.Errorf("unexpected output for modified repo:\n%s", text)
+	}
+}
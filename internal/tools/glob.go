@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -16,9 +17,10 @@ import (
 
 // GlobArgs is the input schema for the glob tool (normal MCP mode).
 type GlobArgs struct {
-	Pattern string `json:"pattern" jsonschema:"the glob pattern to match files against,required"`
-	Path    string `json:"path,omitempty" jsonschema:"the directory to search in (defaults to cwd)"`
-	Type    string `json:"type,omitempty" jsonschema:"filter by type: file or directory"`
+	Pattern         string `json:"pattern" jsonschema:"the glob pattern to match files against; an absolute pattern (e.g. /repo/src/**/*.go) searches from its literal directory prefix and ignores path,required"`
+	Path            string `json:"path,omitempty" jsonschema:"the directory to search in (defaults to cwd)"`
+	Type            string `json:"type,omitempty" jsonschema:"filter by type: file or directory"`
+	ResolveSymlinks bool   `json:"resolve_symlinks,omitempty" jsonschema:"report symlinked files by their real path instead of the link path"`
 }
 
 // GlobCompatArgs is the input schema for the glob tool in --anthropic-compat mode.
@@ -29,16 +31,20 @@ type GlobCompatArgs struct {
 
 // globParams holds the normalized parameters for glob.
 type globParams struct {
-	pattern    string
-	path       string
-	filterType string // "", "file", or "directory"
+	pattern         string
+	path            string
+	filterType      string // "", "file", or "directory"
+	resolveSymlinks bool   // report file symlinks by their real path
+	excludeDirs     map[string]bool
+	ignoreFiles     []string // ignore files read alongside .gitignore
 }
 
 func normalizeGlobArgs(args GlobArgs) globParams {
 	return globParams{
-		pattern:    args.Pattern,
-		path:       args.Path,
-		filterType: args.Type,
+		pattern:         args.Pattern,
+		path:            args.Path,
+		filterType:      args.Type,
+		resolveSymlinks: args.ResolveSymlinks,
 	}
 }
 
@@ -49,27 +55,85 @@ func normalizeGlobCompatArgs(args GlobCompatArgs) globParams {
 	}
 }
 
-func globHandler(sess *session.Session, resolver *pathscope.Resolver) mcp.ToolHandlerFor[GlobArgs, any] {
+func globHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[GlobArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
 	return func(ctx context.Context, _ *mcp.CallToolRequest, args GlobArgs) (*mcp.CallToolResult, any, error) {
-		return doGlob(ctx, sess, resolver, normalizeGlobArgs(args))
+		p := normalizeGlobArgs(args)
+		p.excludeDirs = excludeDirs
+		p.ignoreFiles = cfg.ExtraIgnoreFiles
+		return doGlob(ctx, sess, resolver, p)
 	}
 }
 
-func globCompatHandler(sess *session.Session, resolver *pathscope.Resolver) mcp.ToolHandlerFor[GlobCompatArgs, any] {
+func globCompatHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[GlobCompatArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
 	return func(ctx context.Context, _ *mcp.CallToolRequest, args GlobCompatArgs) (*mcp.CallToolResult, any, error) {
-		return doGlob(ctx, sess, resolver, normalizeGlobCompatArgs(args))
+		p := normalizeGlobCompatArgs(args)
+		p.excludeDirs = excludeDirs
+		p.ignoreFiles = cfg.ExtraIgnoreFiles
+		return doGlob(ctx, sess, resolver, p)
 	}
 }
 
 const globMaxOutputChars = 30000
 
 func doGlob(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, p globParams) (*mcp.CallToolResult, any, error) {
+	results, errResult := globMatches(ctx, sess, resolver, p)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(results) == 0 {
+		r, _, _ := globNoFiles()
+		return withPagination(r, 0, 0, 0), nil, nil
+	}
+
+	// Join paths and truncate at last complete line
+	var out strings.Builder
+	returned := 0
+	for i, r := range results {
+		line := r.relPath
+		if i > 0 {
+			line = "\n" + line
+		}
+		if out.Len()+len(line) > globMaxOutputChars {
+			break
+		}
+		out.WriteString(line)
+		returned++
+	}
+
+	output := out.String()
+	if returned < len(results) {
+		output += "\n... output truncated (exceeded 30,000 characters)"
+	}
+
+	return withPagination(&mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: output}},
+	}, len(results), 0, returned), nil, nil
+}
+
+// globMatch is one entry matched by a glob.
+type globMatch struct {
+	relPath  string // as reported: relative to the search root, or absolute
+	resolved string // canonical path that passed scope checks
+	modTime  int64
+}
+
+// globMatches validates p and returns the entries matching it, newest first.
+// A missing or non-directory search root yields no matches. Invalid input is
+// reported as a non-nil error result.
+func globMatches(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, p globParams) ([]globMatch, *mcp.CallToolResult) {
+	fail := func(code, msg string, args ...any) ([]globMatch, *mcp.CallToolResult) {
+		r, _, _ := toolErr(code, msg, args...)
+		return nil, r
+	}
+
 	// Validate pattern
 	if p.pattern == "" {
-		return toolErr(ErrInvalidInput, "pattern must not be empty")
+		return fail(ErrInvalidInput, "pattern must not be empty")
 	}
-	if !doublestar.ValidatePattern(p.pattern) {
-		return toolErr(ErrGlobInvalidPattern, "invalid glob pattern: %s", p.pattern)
+	if err := globPatternError(p.pattern); err != nil {
+		return fail(ErrGlobInvalidPattern, "invalid glob pattern %q: %v", p.pattern, err)
 	}
 
 	// Validate type filter
@@ -77,7 +141,15 @@ func doGlob(ctx context.Context, sess *session.Session, resolver *pathscope.Reso
 	case "", "file", "directory":
 		// valid
 	default:
-		return toolErr(ErrGlobInvalidType, "invalid type %q; valid values: file, directory", p.filterType)
+		return fail(ErrGlobInvalidType, "invalid type %q; valid values: file, directory", p.filterType)
+	}
+
+	// An absolute pattern carries its own search root: its literal directory
+	// prefix replaces path, and matches are reported as absolute paths
+	absBase := ""
+	if filepath.IsAbs(p.pattern) {
+		absBase, p.pattern = splitGlobBase(p.pattern)
+		p.path = absBase
 	}
 
 	// Check path scoping on the search root
@@ -86,29 +158,91 @@ func doGlob(ctx context.Context, sess *session.Session, resolver *pathscope.Reso
 		if p.path == "" {
 			resolvedRoot = sess.Cwd()
 		} else {
-			return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+			return fail(ErrAccessDenied, "path not allowed: %v", err)
 		}
 	}
 
 	info, err := os.Lstat(resolvedRoot)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return globNoFiles()
+			return nil, nil
 		}
-		return toolErr(ErrIO, "could not stat %s: %v", p.path, err)
+		return fail(ErrIO, "could not stat %s: %v", p.path, err)
 	}
 	if !info.IsDir() {
-		return globNoFiles()
+		return nil, nil
 	}
 
-	// Walk and collect results
-	type globResult struct {
-		relPath string
-		modTime int64
+	var results []globMatch
+
+	// Only descend into the directories the pattern can match under, so
+	// {src,test}/**/*.go does not walk the rest of the tree
+	keep := globBaseFilter(globPatternBases(p.pattern))
+
+	err = walkGlobEntriesFiltered(ctx, resolvedRoot, p.excludeDirs, p.ignoreFiles, keep, func(entryPath, relPath, name string, isDir bool) {
+		if absBase != "" {
+			// The remainder of an absolute pattern is anchored at its base
+			// directory; it never falls back to matching base names
+			if matched, err := doublestar.Match(p.pattern, filepath.ToSlash(relPath)); err != nil || !matched {
+				return
+			}
+		} else if !matchesGlobPattern(p.pattern, relPath, name) {
+			return
+		}
+
+		// Apply type filter
+		if (isDir && p.filterType == "file") || (!isDir && p.filterType == "directory") {
+			return
+		}
+
+		// Path scoping: silently skip denied entries
+		resolvedFile, err := resolver.Resolve(sess.Cwd(), entryPath)
+		if err != nil {
+			return
+		}
+
+		fInfo, err := os.Lstat(resolvedFile)
+		if err != nil {
+			return
+		}
+
+		if !isDir && p.resolveSymlinks && isSymlink(entryPath) {
+			relPath = resolvedFile
+		} else if absBase != "" {
+			relPath = filepath.Join(absBase, relPath)
+		}
+
+		results = append(results, globMatch{
+			relPath:  relPath,
+			resolved: resolvedFile,
+			modTime:  fInfo.ModTime().Unix(),
+		})
+	})
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return fail(ErrIO, "could not walk directory %s: %v", p.path, err)
 	}
 
-	gi := newGitignoreStack()
-	var results []globResult
+	// Sort by mtime descending (newest first)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].modTime > results[j].modTime
+	})
+	return results, nil
+}
+
+// walkGlobEntries recursively walks root the way glob does, calling visit
+// for every file and directory not skipped by excluded or .gitignore rules.
+// File symlinks are reported as files; directory symlinks and broken
+// symlinks are skipped entirely. The walk stops early with ctx.Err() if ctx
+// is cancelled.
+func walkGlobEntries(ctx context.Context, root string, excluded map[string]bool, ignoreFiles []string, visit func(entryPath, relPath, name string, isDir bool)) error {
+	return walkGlobEntriesFiltered(ctx, root, excluded, ignoreFiles, nil, visit)
+}
+
+// walkGlobEntriesFiltered is walkGlobEntries with an additional keep
+// predicate: entries whose relative path it rejects are neither visited nor
+// descended into. A nil keep accepts everything.
+func walkGlobEntriesFiltered(ctx context.Context, root string, excluded map[string]bool, ignoreFiles []string, keep func(relPath string) bool, visit func(entryPath, relPath, name string, isDir bool)) error {
+	gi := newGitignoreStack(ignoreFiles)
 
 	var walkFn func(dir string) error
 	walkFn = func(dir string) error {
@@ -138,8 +272,8 @@ func doGlob(ctx context.Context, sess *session.Session, resolver *pathscope.Reso
 			name := entry.Name()
 			entryPath := filepath.Join(dir, name)
 
-			// Skip .git and node_modules
-			if name == ".git" || name == "node_modules" {
+			// Skip .git, node_modules, and configured directories
+			if excluded[name] {
 				continue
 			}
 
@@ -168,98 +302,25 @@ func doGlob(ctx context.Context, sess *session.Session, resolver *pathscope.Reso
 				continue
 			}
 
-			if isDir {
-				// Check if directory matches pattern (for directory type filter)
-				relPath, err := filepath.Rel(resolvedRoot, entryPath)
-				if err == nil && matchesGlobPattern(p.pattern, relPath, name) && (p.filterType == "" || p.filterType == "directory") {
-					resolvedFile, err := resolver.Resolve(sess.Cwd(), entryPath)
-					if err == nil {
-						fInfo, err := os.Lstat(resolvedFile)
-						if err == nil {
-							results = append(results, globResult{
-								relPath: relPath,
-								modTime: fInfo.ModTime().Unix(),
-							})
-						}
-					}
-				}
-				// Recurse into directory
-				if err := walkFn(entryPath); err != nil {
-					return err
-				}
-				continue
-			}
-
-			// File (regular or file symlink)
-			relPath, err := filepath.Rel(resolvedRoot, entryPath)
+			relPath, err := filepath.Rel(root, entryPath)
 			if err != nil {
 				continue
 			}
-
-			if !matchesGlobPattern(p.pattern, relPath, name) {
-				continue
-			}
-
-			// Apply type filter
-			if p.filterType == "directory" {
-				continue
-			}
-
-			// Path scoping: silently skip denied files
-			resolvedFile, err := resolver.Resolve(sess.Cwd(), entryPath)
-			if err != nil {
+			if keep != nil && !keep(relPath) {
 				continue
 			}
+			visit(entryPath, relPath, name, isDir)
 
-			fInfo, err := os.Lstat(resolvedFile)
-			if err != nil {
-				continue
+			if isDir {
+				if err := walkFn(entryPath); err != nil {
+					return err
+				}
 			}
-
-			results = append(results, globResult{
-				relPath: relPath,
-				modTime: fInfo.ModTime().Unix(),
-			})
 		}
 		return nil
 	}
 
-	if err := walkFn(resolvedRoot); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
-		return toolErr(ErrIO, "could not walk directory %s: %v", p.path, err)
-	}
-
-	if len(results) == 0 {
-		return globNoFiles()
-	}
-
-	// Sort by mtime descending (newest first)
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].modTime > results[j].modTime
-	})
-
-	// Join paths and truncate at last complete line
-	var out strings.Builder
-	truncated := false
-	for i, r := range results {
-		line := r.relPath
-		if i > 0 {
-			line = "\n" + line
-		}
-		if out.Len()+len(line) > globMaxOutputChars {
-			truncated = true
-			break
-		}
-		out.WriteString(line)
-	}
-
-	output := out.String()
-	if truncated {
-		output += "\n... output truncated (exceeded 30,000 characters)"
-	}
-
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{&mcp.TextContent{Text: output}},
-	}, nil, nil
+	return walkFn(root)
 }
 
 func globNoFiles() (*mcp.CallToolResult, any, error) {
@@ -279,3 +340,191 @@ func matchesGlobPattern(pattern, relPath, baseName string) bool {
 	}
 	return false
 }
+
+// splitGlobBase splits pattern at the last slash before its first meta
+// character, like doublestar.SplitPattern. Unlike SplitPattern, which only
+// unescapes meta characters, every backslash escape in the returned base is
+// removed so that it names the directory on disk. The base is "." if the
+// pattern has no literal directory prefix.
+func splitGlobBase(pattern string) (base, rest string) {
+	splitIdx := -1
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '\\' {
+			i++
+		} else if c == '/' {
+			splitIdx = i
+		} else if c == '*' || c == '?' || c == '[' || c == '{' {
+			break
+		}
+	}
+	switch {
+	case splitIdx < 0:
+		return ".", pattern
+	case splitIdx == 0:
+		return "/", pattern[1:]
+	}
+	return unescapeGlob(pattern[:splitIdx]), pattern[splitIdx+1:]
+}
+
+// unescapeGlob removes the backslash from every escape sequence in s.
+func unescapeGlob(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// maxGlobBraceExpansions caps how many alternatives globPatternBases will
+// expand a pattern into before falling back to a full walk.
+const maxGlobBraceExpansions = 256
+
+// globPatternBases returns the literal directory prefixes (relative, slash
+// separated) under which pattern can match, one per brace alternative. It
+// returns nil if any alternative can match anywhere, in which case the whole
+// tree must be walked.
+func globPatternBases(pattern string) []string {
+	alts := expandBraces(pattern, maxGlobBraceExpansions)
+	if alts == nil {
+		return nil
+	}
+	var bases []string
+	for _, alt := range alts {
+		base, _ := splitGlobBase(alt)
+		if base == "." || base == "" {
+			return nil
+		}
+		bases = append(bases, base)
+	}
+	return bases
+}
+
+// globBaseFilter returns a walk predicate accepting paths that lead to, equal,
+// or lie under one of bases. It returns nil (accept everything) for no bases.
+func globBaseFilter(bases []string) func(relPath string) bool {
+	if len(bases) == 0 {
+		return nil
+	}
+	return func(relPath string) bool {
+		rel := filepath.ToSlash(relPath)
+		for _, base := range bases {
+			if rel == base || strings.HasPrefix(rel, base+"/") || strings.HasPrefix(base, rel+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// expandBraces expands {a,b} alternatives in a glob pattern, including nested
+// and path-spanning ones, into the list of brace-free patterns. Backslash
+// escapes are preserved. It returns nil if the pattern has unbalanced braces
+// or would expand to more than limit alternatives.
+func expandBraces(pattern string, limit int) []string {
+	// Find the first unescaped top-level brace group
+	open := -1
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			open = i
+		}
+		if open >= 0 {
+			break
+		}
+	}
+	if open < 0 {
+		return []string{pattern}
+	}
+
+	// Split its body on top-level commas
+	depth := 0
+	start := open + 1
+	var options []string
+	closeIdx := -1
+	for i := open + 1; i < len(pattern) && closeIdx < 0; i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				options = append(options, pattern[start:i])
+				closeIdx = i
+			} else {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				options = append(options, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if closeIdx < 0 {
+		return nil
+	}
+
+	prefix, suffix := pattern[:open], pattern[closeIdx+1:]
+	var out []string
+	for _, opt := range options {
+		expanded := expandBraces(prefix+opt+suffix, limit-len(out))
+		if expanded == nil || len(out)+len(expanded) > limit {
+			return nil
+		}
+		out = append(out, expanded...)
+	}
+	return out
+}
+
+// globPatternError returns nil if pattern is a valid doublestar pattern, or
+// an error explaining the most likely mistake: an unbalanced brace, an
+// unclosed or empty character class, or a trailing backslash.
+func globPatternError(pattern string) error {
+	if doublestar.ValidatePattern(pattern) {
+		return nil
+	}
+	var openBraces []int
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i == len(pattern)-1 {
+				return errors.New("trailing backslash escapes nothing")
+			}
+			i++
+		case '{':
+			openBraces = append(openBraces, i)
+		case '}':
+			if len(openBraces) == 0 {
+				return fmt.Errorf("unmatched '}' at offset %d", i)
+			}
+			openBraces = openBraces[:len(openBraces)-1]
+		case '[':
+			j := i + 1
+			if j < len(pattern) && (pattern[j] == '!' || pattern[j] == '^') {
+				j++
+			}
+			if j < len(pattern) && pattern[j] == ']' {
+				return fmt.Errorf("empty character class at offset %d", i)
+			}
+			end := strings.IndexByte(pattern[j:], ']')
+			if end < 0 {
+				return fmt.Errorf("unclosed '[' at offset %d; use \\[ to match a literal bracket", i)
+			}
+			i = j + end
+		}
+	}
+	if len(openBraces) > 0 {
+		return fmt.Errorf("unclosed '{' at offset %d", openBraces[len(openBraces)-1])
+	}
+	return errors.New("malformed pattern")
+}
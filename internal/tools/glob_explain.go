@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// GlobExplainArgs is the input schema for the glob_explain tool.
+type GlobExplainArgs struct {
+	Pattern string `json:"pattern" jsonschema:"the glob pattern to analyze; the filesystem is not touched"`
+}
+
+func globExplainHandler() mcp.ToolHandlerFor[GlobExplainArgs, any] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, args GlobExplainArgs) (*mcp.CallToolResult, any, error) {
+		return doGlobExplain(args)
+	}
+}
+
+func doGlobExplain(args GlobExplainArgs) (*mcp.CallToolResult, any, error) {
+	if args.Pattern == "" {
+		return toolErr(ErrInvalidInput, "pattern must not be empty")
+	}
+	if err := globPatternError(args.Pattern); err != nil {
+		return toolErr(ErrGlobInvalidPattern, "invalid glob pattern %q: %v", args.Pattern, err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "pattern: %s\n", args.Pattern)
+	alts := expandBraces(args.Pattern, maxGlobBraceExpansions)
+	if alts == nil {
+		fmt.Fprintf(&b, "expands to more than %d patterns; glob matches it without expanding and walks the whole tree\n", maxGlobBraceExpansions)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: b.String()}},
+		}, nil, nil
+	}
+	if len(alts) == 1 {
+		b.WriteString("expands to 1 pattern:\n")
+	} else {
+		fmt.Fprintf(&b, "expands to %d patterns:\n", len(alts))
+	}
+	for _, alt := range alts {
+		fmt.Fprintf(&b, "\n%s\n", alt)
+		segments := strings.Split(alt, "/")
+		for i, seg := range segments {
+			fmt.Fprintf(&b, "  %-12s %s\n", seg, explainGlobSegment(seg, i == len(segments)-1))
+		}
+		if !strings.Contains(alt, "/") {
+			b.WriteString("  (no '/', so it is also matched against base names at any depth)\n")
+		}
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: b.String()}},
+	}, nil, nil
+}
+
+// explainGlobSegment describes what one slash-separated segment of an
+// expanded (brace-free) pattern matches. last reports whether it is the
+// final segment, which names the file rather than a directory.
+func explainGlobSegment(seg string, last bool) string {
+	kind := "directory"
+	if last {
+		kind = "file or directory name"
+	}
+	if seg == "" {
+		return "root or empty segment"
+	}
+	if seg == "**" {
+		if last {
+			return "any path, at any depth"
+		}
+		return "any number of directories, including none"
+	}
+
+	var wild []string
+	for i := 0; i < len(seg); i++ {
+		switch seg[i] {
+		case '\\':
+			i++
+		case '*':
+			wild = append(wild, "'*' any run of characters")
+		case '?':
+			wild = append(wild, "'?' any single character")
+		case '[':
+			end := strings.IndexByte(seg[i+1:], ']')
+			if end < 0 {
+				continue
+			}
+			class := seg[i : i+end+2]
+			if len(class) > 2 && (class[1] == '!' || class[1] == '^') {
+				wild = append(wild, fmt.Sprintf("'%s' one character not in the set", class))
+			} else {
+				wild = append(wild, fmt.Sprintf("'%s' one character from the set", class))
+			}
+			i += end + 1
+		}
+	}
+	if len(wild) == 0 {
+		return "literal " + kind
+	}
+	return kind + " with " + strings.Join(wild, ", ")
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGlobExplain(t *testing.T) {
+	call := func(t *testing.T, pattern string) (string, bool) {
+		t.Helper()
+		r, _, err := globExplainHandler()(context.Background(), nil, GlobExplainArgs{Pattern: pattern})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resultText(r), isErrorResult(r)
+	}
+
+	t.Run("braces expand to alternatives", func(t *testing.T) {
+		text, isErr := call(t, "*.{ts,tsx}")
+		if isErr {
+			t.Fatal(text)
+		}
+		if !strings.Contains(text, "expands to 2 patterns:") {
+			t.Errorf("expected 2 patterns, got:\n%s", text)
+		}
+		for _, want := range []string{"\n*.ts\n", "\n*.tsx\n", "base names at any depth"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("expected %q in:\n%s", want, text)
+			}
+		}
+	})
+
+	t.Run("segments are explained", func(t *testing.T) {
+		text, isErr := call(t, "src/**/test_?.go")
+		if isErr {
+			t.Fatal(text)
+		}
+		for _, want := range []string{"literal directory", "any number of directories, including none", "'?' any single character"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("expected %q in:\n%s", want, text)
+			}
+		}
+		if strings.Contains(text, "base names") {
+			t.Errorf("pattern with '/' should not match base names:\n%s", text)
+		}
+	})
+
+	t.Run("invalid pattern reports the error", func(t *testing.T) {
+		r, _, err := globExplainHandler()(context.Background(), nil, GlobExplainArgs{Pattern: "*.{ts,tsx"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(r, ErrGlobInvalidPattern) {
+			t.Fatalf("expected %s, got: %s", ErrGlobInvalidPattern, resultText(r))
+		}
+		if !strings.Contains(resultText(r), "unclosed '{'") {
+			t.Errorf("expected unclosed brace explanation, got: %s", resultText(r))
+		}
+	})
+}
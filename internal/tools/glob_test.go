@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -27,13 +28,13 @@ func globTestSetup(t *testing.T) (string, *session.Session, *pathscope.Resolver)
 }
 
 func callGlob(sess *session.Session, resolver *pathscope.Resolver, args GlobArgs) (*mcp.CallToolResult, error) {
-	handler := globHandler(sess, resolver)
+	handler := globHandler(sess, resolver, testConfig())
 	r, _, err := handler(context.Background(), nil, args)
 	return r, err
 }
 
 func callGlobCompat(sess *session.Session, resolver *pathscope.Resolver, args GlobCompatArgs) (*mcp.CallToolResult, error) {
-	handler := globCompatHandler(sess, resolver)
+	handler := globCompatHandler(sess, resolver, testConfig())
 	r, _, err := handler(context.Background(), nil, args)
 	return r, err
 }
@@ -168,6 +169,64 @@ func TestGlobBraceExpansion(t *testing.T) {
 	}
 }
 
+func TestGlobBraceExpansionAcrossDirectories(t *testing.T) {
+	tmp, sess, resolver := globTestSetup(t)
+	for _, f := range []string{
+		filepath.Join("src", "main.go"),
+		filepath.Join("src", "pkg", "util.go"),
+		filepath.Join("test", "main_test.go"),
+		filepath.Join("docs", "example.go"),
+		filepath.Join("other", "src", "nested.go"),
+		filepath.Join("src", "README.md"),
+	} {
+		os.MkdirAll(filepath.Join(tmp, filepath.Dir(f)), 0755)
+		os.WriteFile(filepath.Join(tmp, f), []byte("code"), 0644)
+	}
+	// A root .gitignore still applies when only src and test are walked
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("src/pkg/\n"), 0644)
+
+	r, err := callGlob(sess, resolver, GlobArgs{Pattern: "{src,test}/**/*.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	for _, want := range []string{filepath.Join("src", "main.go"), filepath.Join("test", "main_test.go")} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %s, got: %s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"docs", "nested.go", "util.go", "README.md"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("%s should not match, got: %s", unwanted, text)
+		}
+	}
+}
+
+func TestGlobPatternBases(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", nil},
+		{"**/*.go", nil},
+		{"src/**/*.go", []string{"src"}},
+		{"{src,test}/**/*.go", []string{"src", "test"}},
+		{"{src/a,lib}/*.go", []string{"src/a", "lib"}},
+		{"src/{a,b{1,2}}/*", []string{"src/a", "src/b1", "src/b2"}},
+		{"{src,*.md}", nil},
+		{`src\{x}/*.go`, []string{"src{x}"}},
+		{`\{a\}/x`, []string{"{a}"}},
+		{`a\\b/*.go`, []string{`a\b`}},
+		{`a\,b/*.go`, []string{"a,b"}},
+	}
+	for _, tt := range tests {
+		got := globPatternBases(tt.pattern)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("globPatternBases(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
 func TestGlobCharacterClass(t *testing.T) {
 	tmp, sess, resolver := globTestSetup(t)
 	os.WriteFile(filepath.Join(tmp, "Makefile"), []byte("all:"), 0644)
@@ -374,6 +433,67 @@ func TestGlobFileSymlinkIncluded(t *testing.T) {
 	}
 }
 
+func TestGlobResolveSymlinks(t *testing.T) {
+	tmp, sess, resolver := globTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, "data"), 0755)
+	os.WriteFile(filepath.Join(tmp, "data", "real.txt"), []byte("x"), 0644)
+	os.Symlink(filepath.Join(tmp, "data", "real.txt"), filepath.Join(tmp, "link.txt"))
+	realPath, err := filepath.EvalSymlinks(filepath.Join(tmp, "data", "real.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := callGlob(sess, resolver, GlobArgs{Pattern: "link.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != "link.txt" {
+		t.Errorf("without resolve_symlinks, expected link path, got: %q", text)
+	}
+
+	r, err = callGlob(sess, resolver, GlobArgs{Pattern: "link.txt", ResolveSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != realPath {
+		t.Errorf("with resolve_symlinks, expected %q, got: %q", realPath, text)
+	}
+
+	// Regular files are still reported relative to the search root
+	r, err = callGlob(sess, resolver, GlobArgs{Pattern: "data/*.txt", ResolveSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != filepath.Join("data", "real.txt") {
+		t.Errorf("regular file should keep its relative path, got: %q", text)
+	}
+}
+
+func TestGlobExcludeDirs(t *testing.T) {
+	tmp, sess, resolver := globTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, "target", "debug"), 0755)
+	os.WriteFile(filepath.Join(tmp, "target", "debug", "out.rs"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmp, "main.rs"), []byte("x"), 0644)
+
+	cfg := testConfig()
+	cfg.ExcludeDirs = []string{"target"}
+	r, _, err := globHandler(sess, resolver, cfg)(context.Background(), nil, GlobArgs{Pattern: "**/*.rs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != "main.rs" {
+		t.Errorf("excluded directory should be skipped, got: %q", text)
+	}
+
+	r, err = callGlob(sess, resolver, GlobArgs{Pattern: "**/*.rs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); !strings.Contains(text, filepath.Join("target", "debug", "out.rs")) {
+		t.Errorf("directory should be walked when not excluded, got: %q", text)
+	}
+}
+
 // --- 4.3: Broken symlink silently skipped ---
 
 func TestGlobBrokenSymlinkSkipped(t *testing.T) {
@@ -559,6 +679,90 @@ func TestGlobSearchRootOutsideAllowedDirs(t *testing.T) {
 	}
 }
 
+func TestGlobAbsolutePattern(t *testing.T) {
+	tmp, sess, resolver := globTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, "repo", "src", "pkg"), 0755)
+	os.WriteFile(filepath.Join(tmp, "repo", "src", "main.go"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmp, "repo", "src", "pkg", "util.go"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmp, "repo", "src", "notes.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmp, "repo", "other.go"), []byte("x"), 0644)
+	sess.SetCwd(t.TempDir()) // the pattern, not the cwd, picks the root
+
+	r, err := callGlob(sess, resolver, GlobArgs{Pattern: filepath.Join(tmp, "repo", "src", "**", "*.go")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(r) {
+		t.Fatalf("unexpected error: %s", resultText(r))
+	}
+	lines := strings.Split(resultText(r), "\n")
+	sort.Strings(lines)
+	want := []string{
+		filepath.Join(tmp, "repo", "src", "main.go"),
+		filepath.Join(tmp, "repo", "src", "pkg", "util.go"),
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %v, want %v", lines, want)
+	}
+}
+
+func TestGlobAbsolutePatternAnchored(t *testing.T) {
+	tmp, sess, resolver := globTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, "src", "deep"), 0755)
+	os.WriteFile(filepath.Join(tmp, "src", "a.go"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmp, "src", "deep", "b.go"), []byte("x"), 0644)
+
+	// Without a slash in the remainder, a relative pattern would also match
+	// base names at any depth; an absolute one must not
+	r, err := callGlob(sess, resolver, GlobArgs{Pattern: filepath.Join(tmp, "src", "*.go")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resultText(r), filepath.Join(tmp, "src", "a.go"); got != want {
+		t.Errorf("got %q, want only %q", got, want)
+	}
+}
+
+func TestGlobEscapedBaseDirectory(t *testing.T) {
+	tmp, sess, resolver := globTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, `{a}`), 0755)
+	os.MkdirAll(filepath.Join(tmp, `b\c`), 0755)
+	os.WriteFile(filepath.Join(tmp, `{a}`, "x.go"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmp, `b\c`, "y.go"), []byte("x"), 0644)
+
+	for pattern, want := range map[string]string{
+		`\{a\}/*.go`: filepath.Join(`{a}`, "x.go"),
+		`b\\c/*.go`:  filepath.Join(`b\c`, "y.go"),
+	} {
+		r, err := callGlob(sess, resolver, GlobArgs{Pattern: pattern})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resultText(r); got != want {
+			t.Errorf("pattern %q: got %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestGlobAbsolutePatternOutsideAllowedDirs(t *testing.T) {
+	tmp := t.TempDir()
+	allowed := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "secret.go"), []byte("x"), 0644)
+	sess := session.New(allowed)
+	resolver, err := pathscope.NewResolver([]string{allowed}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := callGlob(sess, resolver, GlobArgs{Pattern: filepath.Join(tmp, "*.go")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(r, ErrAccessDenied) {
+		t.Errorf("expected error code %s, got: %s", ErrAccessDenied, resultText(r))
+	}
+}
+
 // --- 6.1: Empty pattern returns IsError ---
 
 func TestGlobEmptyPatternError(t *testing.T) {
@@ -593,6 +797,35 @@ func TestGlobMalformedPatternError(t *testing.T) {
 	}
 }
 
+func TestGlobMalformedPatternMessages(t *testing.T) {
+	_, sess, resolver := globTestSetup(t)
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"src/{a,b", "unclosed '{' at offset 4"},
+		{"src/a,b}.go", "unmatched '}' at offset 7"},
+		{"file[0-9.txt", "unclosed '[' at offset 4; use \\[ to match a literal bracket"},
+		{"x[]y", "empty character class at offset 1"},
+		{"x[!]", "empty character class at offset 1"},
+		{`dir\`, "trailing backslash escapes nothing"},
+	}
+	for _, tt := range tests {
+		r, err := callGlob(sess, resolver, GlobArgs{Pattern: tt.pattern})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(r, ErrGlobInvalidPattern) {
+			t.Errorf("%q: expected %s, got: %s", tt.pattern, ErrGlobInvalidPattern, resultText(r))
+			continue
+		}
+		if !strings.HasSuffix(resultText(r), tt.want) {
+			t.Errorf("%q: got %q, want message ending %q", tt.pattern, resultText(r), tt.want)
+		}
+	}
+}
+
 // --- 6.3: Type filter "file" returns only files ---
 
 func TestGlobTypeFilterFile(t *testing.T) {
@@ -869,6 +1102,47 @@ func TestGlobGitignoreAnchoredPattern(t *testing.T) {
 	}
 }
 
+func TestGlobGitignoreDirOnlyAndDoublestar(t *testing.T) {
+	tmp, sess, resolver := globTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("build/\na/**/b.txt\n"), 0644)
+	for _, f := range []string{
+		filepath.Join("build", "out.txt"),
+		filepath.Join("src", "build", "out.txt"),
+		filepath.Join("a", "b.txt"),
+		filepath.Join("a", "x", "y", "b.txt"),
+		filepath.Join("c", "a", "b.txt"),
+		filepath.Join("src", "keep.txt"),
+	} {
+		os.MkdirAll(filepath.Join(tmp, filepath.Dir(f)), 0755)
+		os.WriteFile(filepath.Join(tmp, f), []byte("x"), 0644)
+	}
+
+	r, err := callGlob(sess, resolver, GlobArgs{Pattern: "**/*.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	found := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		found[line] = true
+	}
+	for _, f := range []string{
+		filepath.Join("build", "out.txt"),
+		filepath.Join("src", "build", "out.txt"),
+		filepath.Join("a", "b.txt"),
+		filepath.Join("a", "x", "y", "b.txt"),
+	} {
+		if found[f] {
+			t.Errorf("%s should be ignored, got: %s", f, text)
+		}
+	}
+	for _, f := range []string{filepath.Join("c", "a", "b.txt"), filepath.Join("src", "keep.txt")} {
+		if !found[f] {
+			t.Errorf("%s should not be ignored, got: %s", f, text)
+		}
+	}
+}
+
 // --- 7.5: Context cancellation tests ---
 
 func TestGlobContextCancellationStopsWalk(t *testing.T) {
@@ -884,7 +1158,7 @@ func TestGlobContextCancellationStopsWalk(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	handler := globHandler(sess, resolver)
+	handler := globHandler(sess, resolver, testConfig())
 	done := make(chan struct{})
 	go func() {
 		handler(ctx, nil, GlobArgs{Pattern: "**/*.txt"})
@@ -904,3 +1178,39 @@ func TestGlobContextCancellationStopsWalk(t *testing.T) {
 // These tests verify the new tool appears in tool lists.
 // Tests for exact tool list contents are handled by TestIntegrationGlobInDefaultToolList
 // and TestIntegrationGlobInCompatToolList above.
+
+func TestGlobPaginationMeta(t *testing.T) {
+	tmp, sess, resolver := globTestSetup(t)
+	for i := range 3 {
+		os.WriteFile(filepath.Join(tmp, fmt.Sprintf("small%d.go", i)), nil, 0644)
+	}
+	// 600 names of 60+ characters overflow the 30,000 character output cap
+	long := filepath.Join(tmp, "long")
+	os.MkdirAll(long, 0755)
+	for i := range 600 {
+		os.WriteFile(filepath.Join(long, fmt.Sprintf("%s%03d.txt", strings.Repeat("x", 55), i)), nil, 0644)
+	}
+
+	r, err := callGlob(sess, resolver, GlobArgs{Pattern: "*.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Meta["pagination"], (paginationMeta{Total: 3, Returned: 3}); got != want {
+		t.Errorf("pagination = %+v, want %+v", got, want)
+	}
+
+	r, err = callGlob(sess, resolver, GlobArgs{Pattern: "long/*.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := r.Meta["pagination"].(paginationMeta)
+	if !ok {
+		t.Fatalf("no pagination metadata in %#v", r.Meta)
+	}
+	if got.Total != 600 || !got.HasMore || got.Returned >= 600 || got.Returned == 0 {
+		t.Errorf("pagination = %+v, want a truncated page of 600", got)
+	}
+	if !strings.Contains(resultText(r), "output truncated") {
+		t.Errorf("expected truncation note")
+	}
+}
@@ -4,17 +4,20 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
-	ignore "github.com/sabhiram/go-gitignore"
 
 	"github.com/mjkoo/boris/internal/pathscope"
 	"github.com/mjkoo/boris/internal/session"
@@ -23,19 +26,45 @@ import (
 
 // GrepArgs is the input schema for the grep tool (normal MCP mode).
 type GrepArgs struct {
-	Pattern          string `json:"pattern" jsonschema:"the regex pattern to search for in file contents,required"`
-	Path             string `json:"path,omitempty" jsonschema:"file or directory to search in (defaults to cwd)"`
-	Include          string `json:"include,omitempty" jsonschema:"glob pattern to filter files (e.g. '*.js' or '*.{ts,tsx}')"`
-	Type             string `json:"type,omitempty" jsonschema:"file type to search (e.g. js, py, go, ts)"`
-	OutputMode       string `json:"output_mode,omitempty" jsonschema:"output mode: content, files_with_matches (default), or count"`
-	CaseInsensitive  bool   `json:"case_insensitive,omitempty" jsonschema:"case-insensitive search"`
-	LineNumbers      *bool  `json:"line_numbers,omitempty" jsonschema:"show line numbers in content mode (default true)"`
-	Multiline        bool   `json:"multiline,omitempty" jsonschema:"enable multiline mode where . matches newlines"`
-	HeadLimit        int    `json:"head_limit,omitempty" jsonschema:"limit output to first N results (0 = unlimited)"`
-	Offset           int    `json:"offset,omitempty" jsonschema:"skip first N results before applying head_limit"`
-	ContextBefore    *int   `json:"context_before,omitempty" jsonschema:"number of lines to show before each match"`
-	ContextAfter     *int   `json:"context_after,omitempty" jsonschema:"number of lines to show after each match"`
-	Context          *int   `json:"context,omitempty" jsonschema:"number of lines to show before and after each match"`
+	Pattern          string  `json:"pattern" jsonschema:"the regex pattern to search for in file contents,required"`
+	Path             string  `json:"path,omitempty" jsonschema:"file or directory to search in (defaults to cwd)"`
+	Include          string  `json:"include,omitempty" jsonschema:"glob pattern to filter files (e.g. '*.js' or '*.{ts,tsx}')"`
+	Type             string  `json:"type,omitempty" jsonschema:"file type to search (e.g. js, py, go, ts)"`
+	OutputMode       string  `json:"output_mode,omitempty" jsonschema:"output mode: content, files_with_matches (default), or count"`
+	CaseInsensitive  bool    `json:"case_insensitive,omitempty" jsonschema:"case-insensitive search"`
+	LineNumbers      *bool   `json:"line_numbers,omitempty" jsonschema:"show line numbers in content mode (default true)"`
+	Multiline        bool    `json:"multiline,omitempty" jsonschema:"enable multiline mode where . matches newlines"`
+	HeadLimit        int     `json:"head_limit,omitempty" jsonschema:"limit output to first N results (0 = unlimited)"`
+	Offset           int     `json:"offset,omitempty" jsonschema:"skip first N results before applying head_limit"`
+	StartLine        int     `json:"start_line,omitempty" jsonschema:"single-file searches only: ignore matches before this 1-indexed line; reported line numbers stay absolute"`
+	EndLine          int     `json:"end_line,omitempty" jsonschema:"single-file searches only: ignore matches after this 1-indexed line (0 = end of file)"`
+	MaxMatches       int     `json:"max_matches,omitempty" jsonschema:"in content mode, stop after this many matching lines across all files; unlike head_limit, context lines and separators are not counted (0 = unlimited)"`
+	ContextBefore    *int    `json:"context_before,omitempty" jsonschema:"number of lines to show before each match"`
+	ContextAfter     *int    `json:"context_after,omitempty" jsonschema:"number of lines to show after each match"`
+	Context          *int    `json:"context,omitempty" jsonschema:"number of lines to show before and after each match"`
+	Highlight        bool    `json:"highlight,omitempty" jsonschema:"wrap matched text with markers in content mode"`
+	HighlightOpen    string  `json:"highlight_open,omitempty" jsonschema:"marker inserted before each match when highlighting (default «)"`
+	HighlightClose   string  `json:"highlight_close,omitempty" jsonschema:"marker inserted after each match when highlighting (default »)"`
+	Replace          *string `json:"replace,omitempty" jsonschema:"preview a substitution: show matching lines with matches replaced ($1 expands capture groups); files are not modified unless in_place is set"`
+	Binary           bool    `json:"binary,omitempty" jsonschema:"search binary files instead of skipping them; NUL bytes match \\x00 in patterns and are shown as the two characters \\0"`
+	NullData         bool    `json:"null_data,omitempty" jsonschema:"treat input as NUL-separated records instead of newline-separated lines; output numbers are record indices"`
+	ResolveSymlinks  bool    `json:"resolve_symlinks,omitempty" jsonschema:"report symlinked files by their real path instead of the link path"`
+	MaxFiles         int     `json:"max_files,omitempty" jsonschema:"stop a directory search after searching this many files (0 = unlimited)"`
+	GroupByFile      bool    `json:"group_by_file,omitempty" jsonschema:"in content mode, print each file path once as a header followed by its lines, instead of prefixing every line with the path"`
+	Summarize        bool    `json:"summarize,omitempty" jsonschema:"compact content output: one line per file with its match count and first matching line; implies output_mode content"`
+	ModifiedWithin   string  `json:"modified_within,omitempty" jsonschema:"in directory searches, only search files modified within this long ago (e.g. '30m', '2h', '7d')"`
+	Content          string  `json:"content,omitempty" jsonschema:"search this text instead of the filesystem; path, include, and type are ignored and results are reported as <content>"`
+	ResourceLink     bool    `json:"resource_link,omitempty" jsonschema:"if the output exceeds 30000 bytes, write it to a file under the working directory and return a resource link to it instead of inline text (ignored in read-only mode)"`
+	ChangedSince     string  `json:"changed_since,omitempty" jsonschema:"only search tracked files that differ from this git ref (as listed by git diff --name-only), e.g. main or HEAD~3; the search path must be inside a git repository"`
+	Hidden           *bool   `json:"hidden,omitempty" jsonschema:"search files and directories whose names start with '.' during directory walks (default true); an explicit path is always searched"`
+	Columns          bool    `json:"columns,omitempty" jsonschema:"in content mode, add the 1-indexed byte column of the first match on each matching line (path:line:col:content)"`
+	EditToken        bool    `json:"edit_token,omitempty" jsonschema:"also return an edit_token authorizing a search_replace_files call with the same pattern, path, include, type, and case_insensitive; no call accepts it if other file or line filters are set"`
+	InPlace          bool    `json:"in_place,omitempty" jsonschema:"write the replace substitution into every file matching pattern, path, include, and type, line by line as the preview shows it, and return a per-file change summary instead of matches; cannot be combined with other file or line filters; only available when the server allows it"`
+	Force            bool    `json:"force,omitempty" jsonschema:"with in_place, edit files that have not been viewed even when view-before-edit is required"`
+	PadLineNumbers   bool    `json:"pad_line_numbers,omitempty" jsonschema:"in content mode, right-align line numbers to the width of the largest line number shown for each file, as view does"`
+	RelativeTo       string  `json:"relative_to,omitempty" jsonschema:"report file paths relative to this directory instead of the search path (e.g. '.' for the working directory)"`
+	FunctionContext  bool    `json:"function_context,omitempty" jsonschema:"in content mode, also show the function, class, or section header enclosing each group of lines, however far above, marked with '=' (path=line=header) as git grep -p does; applies to recognized code and markdown files"`
+	JSONLines        bool    `json:"json_lines,omitempty" jsonschema:"in content mode, print each match or context line as a JSON object on its own line ({path, line, column, text, is_context}) so large results can be parsed incrementally; notes become {note} objects"`
 }
 
 // GrepCompatArgs is the input schema for the grep tool in --anthropic-compat mode.
@@ -65,14 +94,51 @@ type grepParams struct {
 	outputMode      string
 	caseInsensitive bool
 	lineNumbers     bool
+	padLineNumbers  bool // content mode right-aligns line numbers within each file
+	columns         bool // content mode adds the byte column of each line's first match
 	multiline       bool
 	headLimit       int
 	offset          int
+	maxMatches      int // content mode stops after this many matching lines (0 = unlimited)
+	startLine       int // single-file search ignores matches before this line (0 = from the start)
+	endLine         int // single-file search ignores matches after this line (0 = to the end)
 	contextBefore   int
 	contextAfter    int
 	maxFileSize     int64
+	highlight       bool
+	highlightOpen   string
+	highlightClose  string
+	replace         *string // preview replacement for match lines (nil = none)
+	nullData        bool    // split records on NUL instead of newline
+	binary          bool    // search binary files, escaping NUL bytes
+	normalizeEOL    bool    // treat CRLF and lone CR as line endings
+	resolveSymlinks bool    // report file symlinks by their real path
+	relativeTo      string  // directory reported paths are relative to (empty = the search root)
+	excludeDirs     map[string]bool
+	ignoreFiles     []string // ignore files read alongside .gitignore in directory walks
+	skipHidden      bool   // directory walks skip entries whose names start with "."
+	maxFiles        int    // directory search stops after this many files (0 = unlimited)
+	content         string // inline text to search instead of the filesystem
+	modifiedWithin  string
+	changedSince    string          // git ref; only files differing from it are searched
+	changedFiles    map[string]bool // resolved paths of files changed since changedSince
+	groupByFile     bool      // content mode prints the path once per file as a header
+	summarize       bool      // content mode prints one count + first match line per file
+	jsonLines       bool      // content mode prints one JSON object per line, without separators
+	functionContext bool      // content mode shows the header of the function enclosing each group
+	modifiedSince   time.Time // directory search skips files older than this (zero = no filter)
+	deps            *grepDeps // records searched paths for the result cache (nil = not caching)
 }
 
+// contentDisplayPath is the synthetic path reported for matches in inline content.
+const contentDisplayPath = "<content>"
+
+// Default markers placed around matched text when highlighting.
+const (
+	defaultHighlightOpen  = "«"
+	defaultHighlightClose = "»"
+)
+
 func normalizeGrepArgs(args GrepArgs) grepParams {
 	p := grepParams{
 		pattern:         args.Pattern,
@@ -82,13 +148,43 @@ func normalizeGrepArgs(args GrepArgs) grepParams {
 		outputMode:      args.OutputMode,
 		caseInsensitive: args.CaseInsensitive,
 		lineNumbers:     true,
+		columns:         args.Columns,
+		padLineNumbers:  args.PadLineNumbers,
 		multiline:       args.Multiline,
 		headLimit:       args.HeadLimit,
 		offset:          args.Offset,
+		maxMatches:      args.MaxMatches,
+		startLine:       args.StartLine,
+		endLine:         args.EndLine,
+		highlight:       args.Highlight,
+		highlightOpen:   args.HighlightOpen,
+		highlightClose:  args.HighlightClose,
+		replace:         args.Replace,
+		nullData:        args.NullData,
+		binary:          args.Binary,
+		resolveSymlinks: args.ResolveSymlinks,
+		relativeTo:      args.RelativeTo,
+		maxFiles:        args.MaxFiles,
+		content:         args.Content,
+		modifiedWithin:  args.ModifiedWithin,
+		changedSince:    args.ChangedSince,
+		groupByFile:     args.GroupByFile,
+		summarize:       args.Summarize,
+		jsonLines:       args.JSONLines,
+		functionContext: args.FunctionContext,
+	}
+	if p.highlightOpen == "" {
+		p.highlightOpen = defaultHighlightOpen
+	}
+	if p.highlightClose == "" {
+		p.highlightClose = defaultHighlightClose
 	}
 	if args.LineNumbers != nil {
 		p.lineNumbers = *args.LineNumbers
 	}
+	if args.Hidden != nil {
+		p.skipHidden = !*args.Hidden
+	}
 	// Context: explicit before/after override shorthand
 	if args.Context != nil {
 		p.contextBefore = *args.Context
@@ -137,19 +233,125 @@ func normalizeGrepCompatArgs(args GrepCompatArgs) grepParams {
 	return p
 }
 
-func grepHandler(sess *session.Session, resolver *pathscope.Resolver, maxFileSize int64) mcp.ToolHandlerFor[GrepArgs, any] {
+func grepHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[GrepArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
+	var cache *grepCache
+	if cfg.EnableGrepCache {
+		cache = newGrepCache()
+	}
 	return func(ctx context.Context, _ *mcp.CallToolRequest, args GrepArgs) (*mcp.CallToolResult, any, error) {
+		if args.InPlace {
+			return grepInPlace(ctx, sess, resolver, cfg, args)
+		}
 		p := normalizeGrepArgs(args)
-		p.maxFileSize = maxFileSize
-		return doGrep(ctx, sess, resolver, p)
+		p.maxFileSize = cfg.MaxFileSize
+		p.normalizeEOL = cfg.NormalizeLineEndings
+		p.excludeDirs = excludeDirs
+		p.ignoreFiles = cfg.ExtraIgnoreFiles
+		result, out, err := cachedGrep(ctx, cache, sess, resolver, p, args)
+		// Linking writes a scratch file, so read-only servers return the
+		// output inline instead
+		if args.ResourceLink && !cfg.ReadOnly {
+			result = linkLargeGrepOutput(sess, resolver, result)
+		}
+		if args.EditToken && args.Content == "" {
+			result = appendEditToken(sess, resolver, editQuery{
+				Pattern:         args.Pattern,
+				Root:            args.Path,
+				Include:         args.Include,
+				Type:            args.Type,
+				CaseInsensitive: args.CaseInsensitive,
+				SkipHidden:      args.Hidden != nil && !*args.Hidden,
+				Multiline:       args.Multiline,
+				StartLine:       args.StartLine,
+				EndLine:         args.EndLine,
+				MaxFiles:        args.MaxFiles,
+				MaxMatches:      args.MaxMatches,
+				ModifiedWithin:  args.ModifiedWithin,
+				ChangedSince:    args.ChangedSince,
+				Binary:          args.Binary,
+				NullData:        args.NullData,
+			}, result)
+		}
+		return result, out, err
 	}
 }
 
-func grepCompatHandler(sess *session.Session, resolver *pathscope.Resolver, maxFileSize int64) mcp.ToolHandlerFor[GrepCompatArgs, any] {
+// grepInPlace applies a grep replace to the matching files instead of
+// previewing it. The edit itself is done by search_replace_files, so files
+// are written atomically, view-before-edit applies, and files the
+// substitution leaves unchanged are not rewritten.
+func grepInPlace(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, cfg Config, args GrepArgs) (*mcp.CallToolResult, any, error) {
+	if cfg.ReadOnly || !cfg.AllowGrepInPlace {
+		return toolErr(ErrAccessDenied, "in_place is disabled on this server. Hint: omit in_place to preview the replacement.")
+	}
+	if args.Replace == nil {
+		return toolErr(ErrInvalidInput, "in_place requires replace")
+	}
+	if args.Content != "" {
+		return toolErr(ErrInvalidInput, "in_place cannot be combined with content")
+	}
+	// The edit selects files by pattern, path, include, and type alone;
+	// options that would narrow the preview some other way are refused
+	// rather than silently widening what gets written.
+	if unsupported := inPlaceUnsupported(args); len(unsupported) > 0 {
+		return toolErr(ErrInvalidInput, "in_place cannot be combined with %s. Hint: preview without in_place, or narrow the edit with path, include, or type.", strings.Join(unsupported, ", "))
+	}
+	// A grep cannot present an edit token, and issuing one to itself would
+	// defeat the point of requiring it.
+	if cfg.RequireEditToken {
+		return toolErr(ErrEditTokenRequired, "in_place is unavailable while edit tokens are required. Hint: run grep with edit_token set, then call search_replace_files with the returned token.")
+	}
+	return doSearchReplaceFiles(ctx, sess, resolver, cfg, SearchReplaceFilesArgs{
+		Pattern:         args.Pattern,
+		Path:            args.Path,
+		Include:         args.Include,
+		Type:            args.Type,
+		CaseInsensitive: args.CaseInsensitive,
+		NewStr:          *args.Replace,
+		Force:           args.Force,
+	}, replaceOptions{perLine: true})
+}
+
+// inPlaceUnsupported lists the options set in args that change which files
+// or lines a grep covers but that an in_place edit cannot honor.
+func inPlaceUnsupported(args GrepArgs) []string {
+	var names []string
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"hidden", args.Hidden != nil && !*args.Hidden},
+		{"start_line", args.StartLine != 0},
+		{"end_line", args.EndLine != 0},
+		{"multiline", args.Multiline},
+		{"max_files", args.MaxFiles != 0},
+		{"max_matches", args.MaxMatches != 0},
+		{"modified_within", args.ModifiedWithin != ""},
+		{"changed_since", args.ChangedSince != ""},
+		{"binary", args.Binary},
+		{"null_data", args.NullData},
+	} {
+		if o.set {
+			names = append(names, o.name)
+		}
+	}
+	return names
+}
+
+func grepCompatHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[GrepCompatArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
+	var cache *grepCache
+	if cfg.EnableGrepCache {
+		cache = newGrepCache()
+	}
 	return func(ctx context.Context, _ *mcp.CallToolRequest, args GrepCompatArgs) (*mcp.CallToolResult, any, error) {
 		p := normalizeGrepCompatArgs(args)
-		p.maxFileSize = maxFileSize
-		return doGrep(ctx, sess, resolver, p)
+		p.maxFileSize = cfg.MaxFileSize
+		p.normalizeEOL = cfg.NormalizeLineEndings
+		p.excludeDirs = excludeDirs
+		p.ignoreFiles = cfg.ExtraIgnoreFiles
+		return cachedGrep(ctx, cache, sess, resolver, p, args)
 	}
 }
 
@@ -226,6 +428,9 @@ func doGrep(ctx context.Context, sess *session.Session, resolver *pathscope.Reso
 	// Validate output_mode
 	if p.outputMode == "" {
 		p.outputMode = "files_with_matches"
+		if p.replace != nil || p.summarize {
+			p.outputMode = "content"
+		}
 	}
 	switch p.outputMode {
 	case "content", "files_with_matches", "count":
@@ -233,10 +438,51 @@ func doGrep(ctx context.Context, sess *session.Session, resolver *pathscope.Reso
 	default:
 		return toolErr(ErrGrepInvalidOutputMode, "invalid output_mode %q; valid values: content, files_with_matches, count", p.outputMode)
 	}
+	if p.replace != nil && p.outputMode != "content" {
+		return toolErr(ErrInvalidInput, "replace requires output_mode content, got %q", p.outputMode)
+	}
+	if p.summarize && p.outputMode != "content" {
+		return toolErr(ErrInvalidInput, "summarize requires output_mode content, got %q", p.outputMode)
+	}
+	if p.summarize && p.replace != nil {
+		return toolErr(ErrInvalidInput, "summarize and replace cannot be combined")
+	}
+	if p.functionContext && p.outputMode != "content" {
+		return toolErr(ErrInvalidInput, "function_context requires output_mode content, got %q", p.outputMode)
+	}
+	if p.jsonLines && p.outputMode != "content" {
+		return toolErr(ErrInvalidInput, "json_lines requires output_mode content, got %q", p.outputMode)
+	}
+	if p.jsonLines && (p.summarize || p.groupByFile) {
+		return toolErr(ErrInvalidInput, "json_lines cannot be combined with summarize or group_by_file")
+	}
+
+	if p.maxFiles < 0 {
+		return toolErr(ErrInvalidInput, "max_files must not be negative, got %d", p.maxFiles)
+	}
+	if p.startLine < 0 || p.endLine < 0 {
+		return toolErr(ErrInvalidInput, "start_line and end_line must not be negative")
+	}
+
+	if p.modifiedWithin != "" {
+		d, err := parseAge(p.modifiedWithin)
+		if err != nil {
+			return toolErr(ErrInvalidInput, "invalid modified_within %q: %v", p.modifiedWithin, err)
+		}
+		p.modifiedSince = time.Now().Add(-d)
+	}
+
+	// Validate include up front: a malformed glob would otherwise silently
+	// match nothing
+	if p.include != "" && p.content == "" {
+		if err := globPatternError(p.include); err != nil {
+			return toolErr(ErrInvalidInput, "invalid include pattern %q: %v", p.include, err)
+		}
+	}
 
 	// Validate type
 	var typePatterns []string
-	if p.fileType != "" {
+	if p.fileType != "" && p.content == "" {
 		var err error
 		typePatterns, err = resolveType(p.fileType)
 		if err != nil {
@@ -258,6 +504,10 @@ func doGrep(ctx context.Context, sess *session.Session, resolver *pathscope.Reso
 		return toolErr(ErrGrepInvalidPattern, "invalid regex pattern: %v", err)
 	}
 
+	if p.content != "" {
+		return grepContent(re, p)
+	}
+
 	// Resolve search path
 	searchPath := p.path
 	if searchPath == "" {
@@ -297,10 +547,56 @@ func doGrep(ctx context.Context, sess *session.Session, resolver *pathscope.Reso
 		}
 	}
 
+	if p.relativeTo != "" {
+		base, err := resolver.Resolve(sess.Cwd(), p.relativeTo)
+		if err != nil {
+			return toolErr(ErrAccessDenied, "relative_to not allowed: %v", err)
+		}
+		p.relativeTo = base
+	}
+
+	if p.changedSince != "" {
+		gitDir := resolvedRoot
+		if !info.IsDir() {
+			gitDir = filepath.Dir(resolvedRoot)
+		}
+		changed, err := gitChangedFiles(ctx, gitDir, p.changedSince)
+		if err != nil {
+			return toolErr(ErrInvalidInput, "changed_since: %v", err)
+		}
+		p.changedFiles = changed
+	}
+
 	if info.IsDir() {
+		if p.startLine > 0 || p.endLine > 0 {
+			return toolErr(ErrInvalidInput, "start_line and end_line only apply to single-file searches; %s is a directory", searchPath)
+		}
 		return grepDirectory(ctx, resolver, sess, re, resolvedRoot, p, typePatterns)
 	}
-	return grepSingleFile(re, resolvedRoot, p.path, p, false)
+	if p.deps != nil {
+		p.deps.add(resolvedRoot)
+	}
+	if p.changedFiles != nil && !p.changedFiles[resolvedRoot] {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: ""}},
+		}, nil, nil
+	}
+	displayPath := p.path
+	if p.resolveSymlinks && isSymlink(searchPath) {
+		displayPath = resolvedRoot
+	} else if p.relativeTo != "" {
+		displayPath = relativeDisplayPath(p.relativeTo, resolvedRoot)
+	}
+	return grepSingleFile(re, resolvedRoot, displayPath, p, false)
+}
+
+// relativeDisplayPath reports path relative to base, falling back to path
+// itself if no relative path exists.
+func relativeDisplayPath(base, path string) string {
+	if rel, err := filepath.Rel(base, path); err == nil {
+		return rel
+	}
+	return path
 }
 
 // grepSingleFile searches a single file.
@@ -311,6 +607,16 @@ func grepSingleFile(re *regexp.Regexp, filePath, displayPath string, p grepParam
 		displayPath = filePath
 	}
 
+	// Opening or reading a FIFO, device, or socket can block forever
+	if info, err := os.Stat(filePath); err == nil && !info.Mode().IsRegular() {
+		if isPartOfDirSearch {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: ""}},
+			}, nil, nil
+		}
+		return toolErr(ErrInvalidInput, "%s is a %s, not a regular file", displayPath, fileKind(info.Mode()))
+	}
+
 	// Check file size before multiline read to prevent OOM
 	if p.multiline && p.maxFileSize > 0 {
 		info, err := os.Stat(filePath)
@@ -341,7 +647,7 @@ func grepSingleFile(re *regexp.Regexp, filePath, displayPath string, p grepParam
 	header := make([]byte, 512)
 	n, _ := f.Read(header)
 	header = header[:n]
-	if isBinaryHeader(header) {
+	if !p.nullData && !p.binary && isBinaryHeader(header) {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{Text: ""}},
 		}, nil, nil
@@ -352,16 +658,28 @@ func grepSingleFile(re *regexp.Regexp, filePath, displayPath string, p grepParam
 		return toolErr(ErrIO, "could not seek %s: %v", displayPath, err)
 	}
 
-	if p.multiline {
+	if p.multiline && !p.nullData {
 		return grepFileMultiline(re, f, displayPath, p)
 	}
 	return grepFileLineByLine(re, f, displayPath, p)
 }
 
-// grepFileLineByLine searches file line by line.
-func grepFileLineByLine(re *regexp.Regexp, f *os.File, displayPath string, p grepParams) (*mcp.CallToolResult, any, error) {
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+// grepContent searches the inline text in p.content, reporting results under
+// contentDisplayPath. The filesystem is not touched.
+func grepContent(re *regexp.Regexp, p grepParams) (*mcp.CallToolResult, any, error) {
+	if p.multiline && !p.nullData {
+		if p.maxFileSize > 0 && int64(len(p.content)) > p.maxFileSize {
+			return toolErr(ErrFileTooLarge, "content is %d bytes, exceeds maximum %d bytes for multiline grep", len(p.content), p.maxFileSize)
+		}
+		return grepFileMultiline(re, strings.NewReader(p.content), contentDisplayPath, p)
+	}
+	return grepFileLineByLine(re, strings.NewReader(p.content), contentDisplayPath, p)
+}
+
+// grepFileLineByLine searches file line by line, or record by record when
+// p.nullData is set.
+func grepFileLineByLine(re *regexp.Regexp, r io.Reader, displayPath string, p grepParams) (*mcp.CallToolResult, any, error) {
+	scanner := newRecordScanner(r, p.nullData, p.normalizeEOL)
 
 	var allLines []string
 	var matchLineNums []int
@@ -369,22 +687,59 @@ func grepFileLineByLine(re *regexp.Regexp, f *os.File, displayPath string, p gre
 	lineNum := 0
 	for scanner.Scan() {
 		lineNum++
+		// Lines past the window are only needed as trailing context
+		if p.endLine > 0 && lineNum > p.endLine+p.contextAfter {
+			break
+		}
 		line := scanner.Text()
 		allLines = append(allLines, line)
-		if re.MatchString(line) {
+		if inLineWindow(lineNum, p) && re.MatchString(line) {
 			matchLineNums = append(matchLineNums, lineNum)
 		}
 	}
 
-	return buildFileResult(displayPath, allLines, matchLineNums, p)
+	result, out, err := buildFileResult(re, displayPath, allLines, matchLineNums, p)
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) && err == nil && result != nil && !result.IsError {
+		appendGrepNote(result, longLineNote(displayPath, lineNum+1), p)
+	}
+	return result, out, err
+}
+
+// longLineNote reports that the search of path stopped at lineNum, a line
+// longer than a line-by-line search can read.
+func longLineNote(path string, lineNum int) string {
+	return fmt.Sprintf("[Stopped searching %s at line %d: line exceeds %d bytes]", path, lineNum, maxScanLineBytes)
+}
+
+// appendGrepNote appends a bracketed note to the text of a grep result, as a
+// {"note": ...} object in json_lines mode.
+func appendGrepNote(r *mcp.CallToolResult, note string, p grepParams) {
+	if len(r.Content) == 0 {
+		r.Content = []mcp.Content{&mcp.TextContent{}}
+	}
+	tc, ok := r.Content[0].(*mcp.TextContent)
+	if !ok {
+		return
+	}
+	sep := "\n\n"
+	if p.jsonLines {
+		note, sep = jsonNoteLines(note), "\n"
+	}
+	if tc.Text != "" {
+		tc.Text += sep
+	}
+	tc.Text += note
 }
 
 // grepFileMultiline searches file content as a whole string.
-func grepFileMultiline(re *regexp.Regexp, f *os.File, displayPath string, p grepParams) (*mcp.CallToolResult, any, error) {
-	data, err := readAllFile(f)
+func grepFileMultiline(re *regexp.Regexp, r io.Reader, displayPath string, p grepParams) (*mcp.CallToolResult, any, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
 		return toolErr(ErrIO, "could not read %s: %v", displayPath, err)
 	}
+	if p.normalizeEOL {
+		data = normalizeLineEndings(data)
+	}
 	content := string(data)
 
 	lines := strings.Split(content, "\n")
@@ -395,7 +750,7 @@ func grepFileMultiline(re *regexp.Regexp, f *os.File, displayPath string, p grep
 
 	matches := re.FindAllStringIndex(content, -1)
 	if len(matches) == 0 {
-		return buildFileResult(displayPath, lines, nil, p)
+		return buildFileResult(re, displayPath, lines, nil, p)
 	}
 
 	// Map byte ranges to line numbers
@@ -416,11 +771,20 @@ func grepFileMultiline(re *regexp.Regexp, f *os.File, displayPath string, p grep
 
 	var matchLineNums []int
 	for l := range matchLineSet {
-		matchLineNums = append(matchLineNums, l)
+		if inLineWindow(l, p) {
+			matchLineNums = append(matchLineNums, l)
+		}
 	}
 	sort.Ints(matchLineNums)
 
-	return buildFileResult(displayPath, lines, matchLineNums, p)
+	return buildFileResult(re, displayPath, lines, matchLineNums, p)
+}
+
+// inLineWindow reports whether 1-indexed line ln lies within the
+// start_line/end_line window. A window past the end of the file, or with
+// start after end, contains no lines of it.
+func inLineWindow(ln int, p grepParams) bool {
+	return ln >= p.startLine && (p.endLine == 0 || ln <= p.endLine)
 }
 
 // byteOffsetToLine converts a byte offset in content to a 1-indexed line number.
@@ -442,7 +806,7 @@ func byteOffsetToLine(content string, offset int) int {
 
 // buildFileResult constructs results from matched line numbers.
 // matchLineNums are 1-indexed.
-func buildFileResult(displayPath string, allLines []string, matchLineNums []int, p grepParams) (*mcp.CallToolResult, any, error) {
+func buildFileResult(re *regexp.Regexp, displayPath string, allLines []string, matchLineNums []int, p grepParams) (*mcp.CallToolResult, any, error) {
 	matchCount := len(matchLineNums)
 
 	// Apply offset/head_limit for non-content modes on a single file
@@ -485,7 +849,8 @@ func buildFileResult(displayPath string, allLines []string, matchLineNums []int,
 				Content: []mcp.Content{&mcp.TextContent{Text: ""}},
 			}, nil, nil
 		}
-		lines := formatContentLines(displayPath, allLines, matchLineNums, p)
+		allLines, matchLineNums = limitMatches(allLines, matchLineNums, p.maxMatches)
+		lines := formatContentLines(re, displayPath, allLines, matchLineNums, p)
 		// Apply offset/head_limit on all output lines (match + context + separators)
 		if p.offset > 0 {
 			if p.offset >= len(lines) {
@@ -506,6 +871,18 @@ func buildFileResult(displayPath string, allLines []string, matchLineNums []int,
 	panic("unreachable: invalid output_mode " + p.outputMode)
 }
 
+// limitMatches keeps the first max of matchLineNums (1-indexed). When matches
+// are dropped, allLines is cut just before the first dropped match so that the
+// trailing context of the last kept match does not run into it. A
+// non-positive max keeps everything.
+func limitMatches(allLines []string, matchLineNums []int, max int) ([]string, []int) {
+	if max <= 0 || len(matchLineNums) <= max {
+		return allLines, matchLineNums
+	}
+	next := matchLineNums[max]
+	return allLines[:min(next-1, len(allLines))], matchLineNums[:max]
+}
+
 // outputGroup represents a contiguous range of lines to output (match + context).
 type outputGroup struct {
 	startLine int // 1-indexed
@@ -514,7 +891,14 @@ type outputGroup struct {
 
 // formatContentLines formats match and context lines for content output mode.
 // Includes `--` separators between non-contiguous groups within the file.
-func formatContentLines(displayPath string, allLines []string, matchLineNums []int, p grepParams) []string {
+// With p.groupByFile, the path is emitted once as a header line and omitted
+// from each match and context line. With p.summarize, the file is reduced to
+// a single summary line and context is ignored.
+func formatContentLines(re *regexp.Regexp, displayPath string, allLines []string, matchLineNums []int, p grepParams) []string {
+	if p.summarize {
+		return []string{summarizeMatches(re, displayPath, allLines, matchLineNums, p)}
+	}
+
 	totalLines := len(allLines)
 	matchSet := map[int]bool{}
 	for _, ln := range matchLineNums {
@@ -542,27 +926,77 @@ func formatContentLines(displayPath string, allLines []string, matchLineNums []i
 		}
 	}
 
+	// Padding to the last line shown keeps trailing context lines aligned
+	// with the matches.
+	width := 0
+	if p.padLineNumbers && len(groups) > 0 {
+		width = len(strconv.Itoa(groups[len(groups)-1].endLine))
+	}
+
+	// With function context, a group is preceded by the header enclosing its
+	// first line unless that header is already shown, in this group or the
+	// one before.
+	var headers []int
+	if p.functionContext {
+		if header := functionHeaderPattern(displayPath); header != nil {
+			nearest := enclosingHeaders(header, allLines)
+			headers = make([]int, len(groups))
+			prevEnd := 0
+			for gi, g := range groups {
+				if h := nearest[g.startLine]; h > prevEnd && h < g.startLine {
+					headers[gi] = h
+				}
+				prevEnd = g.endLine
+			}
+		}
+	}
+
 	var result []string
+	if p.jsonLines {
+		for gi, g := range groups {
+			if headers != nil && headers[gi] > 0 {
+				h := headers[gi]
+				data, _ := json.Marshal(grepLineJSON{Path: displayPath, Line: h, Text: escapeNUL(allLines[h-1]), IsContext: true, IsFunction: true})
+				result = append(result, string(data))
+			}
+			for ln := g.startLine; ln <= g.endLine; ln++ {
+				result = append(result, grepJSONLine(re, displayPath, ln, allLines[ln-1], !matchSet[ln], p))
+			}
+		}
+		return result
+	}
+	prefix := displayPath
+	if p.groupByFile {
+		result = append(result, displayPath)
+		prefix = ""
+	}
 	for gi, g := range groups {
 		if gi > 0 {
 			result = append(result, "--")
 		}
+		if headers != nil && headers[gi] > 0 {
+			// Function header: filepath=linenum=content, as git grep -p prints it
+			h := headers[gi]
+			result = append(result, formatGrepLine(prefix, "=", h, width, 0, allLines[h-1], p.lineNumbers))
+		}
 		for ln := g.startLine; ln <= g.endLine; ln++ {
 			line := allLines[ln-1]
 			if matchSet[ln] {
-				// Match line: filepath:linenum:content
-				if p.lineNumbers {
-					result = append(result, fmt.Sprintf("%s:%d:%s", displayPath, ln, line))
-				} else {
-					result = append(result, fmt.Sprintf("%s:%s", displayPath, line))
+				col := 0
+				if p.columns {
+					col = matchColumn(re, line)
 				}
+				switch {
+				case p.replace != nil:
+					line = re.ReplaceAllString(line, *p.replace)
+				case p.highlight:
+					line = highlightMatches(re, line, p.highlightOpen, p.highlightClose)
+				}
+				// Match line: filepath:linenum[:col]:content
+				result = append(result, formatGrepLine(prefix, ":", ln, width, col, line, p.lineNumbers))
 			} else {
 				// Context line: filepath-linenum-content
-				if p.lineNumbers {
-					result = append(result, fmt.Sprintf("%s-%d-%s", displayPath, ln, line))
-				} else {
-					result = append(result, fmt.Sprintf("%s-%s", displayPath, line))
-				}
+				result = append(result, formatGrepLine(prefix, "-", ln, width, 0, line, p.lineNumbers))
 			}
 		}
 	}
@@ -570,18 +1004,135 @@ func formatContentLines(displayPath string, allLines []string, matchLineNums []i
 	return result
 }
 
-// grepDirectory searches all files in a directory recursively.
-func grepDirectory(ctx context.Context, resolver *pathscope.Resolver, sess *session.Session, re *regexp.Regexp, rootPath string, p grepParams, typePatterns []string) (*mcp.CallToolResult, any, error) {
-	// Gitignore support
-	gi := newGitignoreStack()
+// grepLineJSON is one content-mode output line in json_lines mode.
+type grepLineJSON struct {
+	Path       string `json:"path"`
+	Line       int    `json:"line"`
+	Column     int    `json:"column,omitempty"`
+	Text       string `json:"text"`
+	IsContext  bool   `json:"is_context"`
+	IsFunction bool   `json:"is_function,omitempty"` // enclosing function header shown by function_context
+}
 
-	// Track visited real paths for symlink cycle detection
-	visited := map[string]bool{}
-	realRoot, err := filepath.EvalSymlinks(rootPath)
-	if err == nil {
-		visited[realRoot] = true
+// grepJSONLine renders one match or context line as a single-line JSON
+// object. Match text gets the same replace and highlight treatment as plain
+// content output; columns are reported only with p.columns.
+func grepJSONLine(re *regexp.Regexp, displayPath string, lineNum int, text string, isContext bool, p grepParams) string {
+	entry := grepLineJSON{Path: displayPath, Line: lineNum, Text: text, IsContext: isContext}
+	if !isContext {
+		if p.columns {
+			entry.Column = matchColumn(re, text)
+		}
+		switch {
+		case p.replace != nil:
+			entry.Text = re.ReplaceAllString(text, *p.replace)
+		case p.highlight:
+			entry.Text = highlightMatches(re, text, p.highlightOpen, p.highlightClose)
+		}
+	}
+	entry.Text = escapeNUL(entry.Text)
+	data, _ := json.Marshal(entry)
+	return string(data)
+}
+
+// jsonNoteLines converts the non-empty lines of a plain-text note block into
+// {"note": ...} objects, one per line, for json_lines output.
+func jsonNoteLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			continue
+		}
+		data, _ := json.Marshal(struct {
+			Note string `json:"note"`
+		}{line})
+		lines = append(lines, string(data))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// summarizeMatches renders a file's matches as one line giving the match
+// count and the first matching line, e.g. "main.go: 3 matches, first at 12: func main() {".
+func summarizeMatches(re *regexp.Regexp, displayPath string, allLines []string, matchLineNums []int, p grepParams) string {
+	noun := "matches"
+	if len(matchLineNums) == 1 {
+		noun = "match"
+	}
+	first := matchLineNums[0]
+	line := allLines[first-1]
+	if p.highlight {
+		line = highlightMatches(re, line, p.highlightOpen, p.highlightClose)
+	}
+	line = escapeNUL(line)
+	if p.lineNumbers {
+		return fmt.Sprintf("%s: %d %s, first at %d: %s", displayPath, len(matchLineNums), noun, first, line)
+	}
+	return fmt.Sprintf("%s: %d %s, first: %s", displayPath, len(matchLineNums), noun, line)
+}
+
+// formatGrepLine renders one content-mode output line as
+// prefix<sep>linenum<sep>col<sep>text, omitting the prefix when it is empty
+// (grouped output), the line number when lineNumbers is false, and the
+// column when col is 0. The line number is right-aligned to width digits.
+func formatGrepLine(prefix, sep string, lineNum, width, col int, text string, lineNumbers bool) string {
+	var fields []string
+	if prefix != "" {
+		fields = append(fields, prefix)
+	}
+	if lineNumbers {
+		fields = append(fields, fmt.Sprintf("%*d", width, lineNum))
 	}
+	if col > 0 {
+		fields = append(fields, strconv.Itoa(col))
+	}
+	return strings.Join(append(fields, escapeNUL(text)), sep)
+}
+
+// escapeNUL shows the NUL bytes of a binary-file line as the two characters
+// \0. Matching runs on the raw line, so patterns can still match \x00.
+func escapeNUL(s string) string {
+	if !strings.Contains(s, "\x00") {
+		return s
+	}
+	return strings.ReplaceAll(s, "\x00", `\0`)
+}
 
+// matchColumn returns the 1-indexed byte column at which re first matches
+// line. In multiline mode a match that starts on an earlier line may not
+// match the line on its own; such lines report column 1.
+func matchColumn(re *regexp.Regexp, line string) int {
+	if loc := re.FindStringIndex(line); loc != nil {
+		return loc[0] + 1
+	}
+	return 1
+}
+
+// highlightMatches wraps each non-empty match of re within line with the
+// open and close markers. In multiline mode only matches that fall within a
+// single line are highlighted.
+func highlightMatches(re *regexp.Regexp, line, open, close string) string {
+	spans := re.FindAllStringIndex(line, -1)
+	if len(spans) == 0 {
+		return line
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range spans {
+		if m[0] == m[1] {
+			continue
+		}
+		b.WriteString(line[last:m[0]])
+		b.WriteString(open)
+		b.WriteString(line[m[0]:m[1]])
+		b.WriteString(close)
+		last = m[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// grepDirectory searches all files in a directory recursively.
+func grepDirectory(ctx context.Context, resolver *pathscope.Resolver, sess *session.Session, re *regexp.Regexp, rootPath string, p grepParams, typePatterns []string) (*mcp.CallToolResult, any, error) {
 	type fileResult struct {
 		displayPath string
 		lines       []string // for content mode (already formatted)
@@ -595,6 +1146,300 @@ func grepDirectory(ctx context.Context, resolver *pathscope.Resolver, sess *sess
 	// Counting for head_limit/offset
 	totalMatches := 0
 	collected := 0
+
+	// Counting for max_matches
+	matchesLeft := p.maxMatches
+
+	// Counting for max_files
+	searched := 0
+	capped := false
+
+	// Set when head_limit or max_matches ends the walk with files left
+	stoppedEarly := false
+
+	// Files too large to load for multiline search
+	var oversized []string
+
+	// Notes for files whose search stopped at an overlong line
+	var cutShort []string
+
+	opts := walkOptions{
+		include:      p.include,
+		typePatterns: typePatterns,
+		excludeDirs:  p.excludeDirs,
+		ignoreFiles:  p.ignoreFiles,
+		skipHidden:   p.skipHidden,
+	}
+	if p.deps != nil {
+		opts.visitDir = p.deps.addDir
+	}
+	err := walkSearchFiles(ctx, resolver, sess, rootPath, opts, func(entry fs.DirEntry, relPath, resolvedFile string) bool {
+		if p.changedFiles != nil && !p.changedFiles[resolvedFile] {
+			return true
+		}
+		// Files outside the modified_within window are skipped silently
+		if !p.modifiedSince.IsZero() {
+			info, err := os.Stat(resolvedFile)
+			if err != nil || info.ModTime().Before(p.modifiedSince) {
+				return true
+			}
+		}
+
+		if p.maxMatches > 0 && matchesLeft <= 0 {
+			stoppedEarly = true
+			return false
+		}
+		if p.maxFiles > 0 && searched >= p.maxFiles {
+			capped = true
+			return false
+		}
+		searched++
+		if p.deps != nil {
+			p.deps.add(resolvedFile)
+		}
+
+		// Search the file
+		fileLines, matchLineNums, matchCount, err := searchFile(re, resolvedFile, p)
+		if errors.Is(err, errFileTooLarge) {
+			oversized = append(oversized, relPath)
+			return true
+		}
+		if errors.Is(err, bufio.ErrTooLong) {
+			// Matches before the long line still count
+			cutShort = append(cutShort, longLineNote(relPath, len(fileLines)+1))
+			err = nil
+		}
+		if err != nil || matchCount == 0 {
+			return true
+		}
+		displayPath := relPath
+		if p.resolveSymlinks && isSymlink(filepath.Join(rootPath, relPath)) {
+			displayPath = resolvedFile
+		} else if p.relativeTo != "" {
+			displayPath = relativeDisplayPath(p.relativeTo, filepath.Join(rootPath, relPath))
+		}
+
+		switch p.outputMode {
+		case "files_with_matches":
+			// Collect ALL matching files; offset applied after mtime sort
+			info, err := entry.Info()
+			var mtime int64
+			if err == nil {
+				mtime = info.ModTime().Unix()
+			}
+			results = append(results, fileResult{
+				displayPath: displayPath,
+				hasMatch:    true,
+				modTime:     mtime,
+			})
+
+		case "count":
+			// One matching file past the page shows there is more; the
+			// walk stops there rather than counting the rest
+			totalMatches++
+			if p.headLimit > 0 && collected >= p.headLimit {
+				stoppedEarly = true
+				return false
+			}
+			if totalMatches <= p.offset {
+				return true
+			}
+			results = append(results, fileResult{
+				displayPath: displayPath,
+				count:       matchCount,
+				hasMatch:    true,
+			})
+			collected++
+
+		case "content":
+			if p.maxMatches > 0 {
+				if len(matchLineNums) > matchesLeft {
+					stoppedEarly = true
+				}
+				fileLines, matchLineNums = limitMatches(fileLines, matchLineNums, matchesLeft)
+				matchesLeft -= len(matchLineNums)
+			}
+			formatted := formatContentLines(re, displayPath, fileLines, matchLineNums, p)
+			results = append(results, fileResult{
+				displayPath: displayPath,
+				hasMatch:    true,
+				lines:       formatted,
+			})
+		}
+		return true
+	})
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return toolErr(ErrIO, "could not walk directory %s: %v", rootPath, err)
+	}
+
+	// Build output (may be partial if context was cancelled)
+	var output strings.Builder
+	var total, returned int
+	switch p.outputMode {
+	case "files_with_matches":
+		// Sort by mtime (newest first)
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].modTime > results[j].modTime
+		})
+		total = len(results)
+		// Apply offset after sorting
+		if p.offset > 0 {
+			if p.offset >= len(results) {
+				results = nil
+			} else {
+				results = results[p.offset:]
+			}
+		}
+		// Apply head_limit after offset
+		if p.headLimit > 0 && len(results) > p.headLimit {
+			results = results[:p.headLimit]
+		}
+		returned = len(results)
+		for i, r := range results {
+			if i > 0 {
+				output.WriteString("\n")
+			}
+			output.WriteString(r.displayPath)
+		}
+
+	case "count":
+		total, returned = totalMatches, len(results)
+		for i, r := range results {
+			if i > 0 {
+				output.WriteString("\n")
+			}
+			fmt.Fprintf(&output, "%s:%d", r.displayPath, r.count)
+		}
+
+	case "content":
+		// Collect all output lines (match + context + inter-file separators).
+		// Grouped output separates files with a blank line, as ripgrep does;
+		// summaries are already one line per file and need no separator.
+		// JSON lines stand alone and need no separator either.
+		fileSep := "--"
+		if p.groupByFile {
+			fileSep = ""
+		}
+		var allOutputLines []string
+		first := true
+		for _, r := range results {
+			if !r.hasMatch || len(r.lines) == 0 {
+				continue
+			}
+			if !first && !p.summarize && !p.jsonLines {
+				allOutputLines = append(allOutputLines, fileSep)
+			}
+			first = false
+			allOutputLines = append(allOutputLines, r.lines...)
+		}
+		// Apply offset/head_limit on all output lines uniformly
+		total = len(allOutputLines)
+		if p.offset > 0 {
+			if p.offset >= len(allOutputLines) {
+				allOutputLines = nil
+			} else {
+				allOutputLines = allOutputLines[p.offset:]
+			}
+		}
+		if p.headLimit > 0 && len(allOutputLines) > p.headLimit {
+			allOutputLines = allOutputLines[:p.headLimit]
+		}
+		returned = len(allOutputLines)
+		output.WriteString(strings.Join(allOutputLines, "\n"))
+	}
+
+	var notes strings.Builder
+	if capped {
+		fmt.Fprintf(&notes, "[Search stopped after %d files (max_files); results may be incomplete]", p.maxFiles)
+	}
+	if len(oversized) > 0 {
+		if notes.Len() > 0 {
+			notes.WriteString("\n\n")
+		}
+		writeOversizedNotes(&notes, oversized, p.maxFileSize)
+	}
+	for i, note := range cutShort {
+		if notes.Len() > 0 {
+			notes.WriteString("\n")
+			if i == 0 {
+				notes.WriteString("\n")
+			}
+		}
+		if i == maxOversizedNotes {
+			fmt.Fprintf(&notes, "[Stopped searching %d more files at lines over %d bytes]", len(cutShort)-i, maxScanLineBytes)
+			break
+		}
+		notes.WriteString(note)
+	}
+	if notes.Len() > 0 {
+		text, sep := notes.String(), "\n\n"
+		if p.jsonLines {
+			text, sep = jsonNoteLines(text), "\n"
+		}
+		if output.Len() > 0 {
+			output.WriteString(sep)
+		}
+		output.WriteString(text)
+	}
+
+	result := withPagination(&mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: output.String()}},
+	}, total, p.offset, returned)
+	if capped || stoppedEarly {
+		// total counts only the files searched before the walk stopped
+		markHasMore(result)
+	}
+	return result, nil, nil
+}
+
+// maxOversizedNotes caps the per-file notes for files skipped by multiline
+// search; the rest are summarized in a single line.
+const maxOversizedNotes = 20
+
+// writeOversizedNotes writes one note per file skipped because it exceeds the
+// multiline size limit.
+func writeOversizedNotes(b *strings.Builder, paths []string, limit int64) {
+	for i, path := range paths {
+		if i == maxOversizedNotes {
+			fmt.Fprintf(b, "\n[Skipped %d more files over %d bytes]", len(paths)-i, limit)
+			return
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "[Skipped %s: exceeds maximum %d bytes for multiline grep]", path, limit)
+	}
+}
+
+// walkOptions selects the files walkSearchFiles visits.
+type walkOptions struct {
+	include      string           // glob matched against relative path or base name
+	typePatterns []string         // base name globs from the type filter
+	excludeDirs  map[string]bool  // entry names skipped entirely
+	ignoreFiles  []string         // ignore files honored alongside .gitignore
+	skipHidden   bool             // skip dot-prefixed entries
+	visitDir     func(dir string) // if set, called for every directory entered, including rootPath
+}
+
+// walkSearchFiles recursively walks rootPath the way grep does: it honors
+// .gitignore and opts.ignoreFiles, skips entries named in opts.excludeDirs
+// (and dot-prefixed entries when opts.skipHidden is set), follows symlinks
+// with cycle detection, applies the include and type filters, and silently
+// skips paths denied by the resolver. visit is called for each remaining
+// file with its path relative to rootPath and its resolved path; returning
+// false stops the walk. The walk stops early with ctx.Err() if ctx is
+// cancelled.
+func walkSearchFiles(ctx context.Context, resolver *pathscope.Resolver, sess *session.Session, rootPath string, opts walkOptions, visit func(entry fs.DirEntry, relPath, resolvedFile string) bool) error {
+	// Gitignore support
+	gi := newGitignoreStack(opts.ignoreFiles)
+
+	// Track visited real paths for symlink cycle detection
+	visited := map[string]bool{}
+	realRoot, err := filepath.EvalSymlinks(rootPath)
+	if err == nil {
+		visited[realRoot] = true
+	}
+
 	limitReached := false
 
 	var walkFn func(dir string) error
@@ -609,6 +1454,10 @@ func grepDirectory(ctx context.Context, resolver *pathscope.Resolver, sess *sess
 		default:
 		}
 
+		if opts.visitDir != nil {
+			opts.visitDir(dir)
+		}
+
 		// Load gitignore at this level
 		gi.push(dir)
 		defer gi.pop()
@@ -632,8 +1481,11 @@ func grepDirectory(ctx context.Context, resolver *pathscope.Resolver, sess *sess
 			name := entry.Name()
 			entryPath := filepath.Join(dir, name)
 
-			// Skip .git and node_modules
-			if name == ".git" || name == "node_modules" {
+			// Skip .git, node_modules, and configured directories
+			if opts.excludeDirs[name] {
+				continue
+			}
+			if opts.skipHidden && strings.HasPrefix(name, ".") {
 				continue
 			}
 
@@ -683,6 +1535,11 @@ func grepDirectory(ctx context.Context, resolver *pathscope.Resolver, sess *sess
 				continue
 			}
 
+			// FIFOs, sockets, and devices can block or never end; skip them
+			if !entry.Type().IsRegular() {
+				continue
+			}
+
 			// Compute relative path early (needed for include matching and display)
 			relPath, err := filepath.Rel(rootPath, entryPath)
 			if err != nil {
@@ -690,10 +1547,10 @@ func grepDirectory(ctx context.Context, resolver *pathscope.Resolver, sess *sess
 			}
 
 			// File: apply filters
-			if !matchesInclude(relPath, name, p.include) {
+			if !matchesInclude(relPath, name, opts.include) {
 				continue
 			}
-			if !matchesType(name, typePatterns) {
+			if !matchesType(name, opts.typePatterns) {
 				continue
 			}
 
@@ -703,123 +1560,15 @@ func grepDirectory(ctx context.Context, resolver *pathscope.Resolver, sess *sess
 				continue
 			}
 
-			// Search the file
-			fileLines, matchLineNums, matchCount, err := searchFile(re, resolvedFile, p)
-			if err != nil || matchCount == 0 {
-				continue
-			}
-
-			switch p.outputMode {
-			case "files_with_matches":
-				// Collect ALL matching files; offset applied after mtime sort
-				info, err := entry.Info()
-				var mtime int64
-				if err == nil {
-					mtime = info.ModTime().Unix()
-				}
-				results = append(results, fileResult{
-					displayPath: relPath,
-					hasMatch:    true,
-					modTime:     mtime,
-})
-
-			case "count":
-				totalMatches++
-				if totalMatches <= p.offset {
-					continue
-				}
-				results = append(results, fileResult{
-					displayPath: relPath,
-					count:       matchCount,
-					hasMatch:    true,
-				})
-				collected++
-				if p.headLimit > 0 && collected >= p.headLimit {
-					limitReached = true
-				}
-
-			case "content":
-				formatted := formatContentLines(relPath, fileLines, matchLineNums, p)
-				results = append(results, fileResult{
-					displayPath: relPath,
-					hasMatch:    true,
-					lines:       formatted,
-				})
+			if !visit(entry, relPath, resolvedFile) {
+				limitReached = true
+				return nil
 			}
 		}
 		return nil
 	}
 
-	if err := walkFn(rootPath); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
-		return toolErr(ErrIO, "could not walk directory %s: %v", rootPath, err)
-	}
-
-	// Build output (may be partial if context was cancelled)
-	var output strings.Builder
-	switch p.outputMode {
-	case "files_with_matches":
-		// Sort by mtime (newest first)
-		sort.Slice(results, func(i, j int) bool {
-			return results[i].modTime > results[j].modTime
-		})
-		// Apply offset after sorting
-		if p.offset > 0 {
-			if p.offset >= len(results) {
-				results = nil
-			} else {
-				results = results[p.offset:]
-			}
-		}
-		// Apply head_limit after offset
-		if p.headLimit > 0 && len(results) > p.headLimit {
-			results = results[:p.headLimit]
-		}
-		for i, r := range results {
-			if i > 0 {
-				output.WriteString("\n")
-			}
-			output.WriteString(r.displayPath)
-		}
-
-	case "count":
-		for i, r := range results {
-			if i > 0 {
-				output.WriteString("\n")
-			}
-			fmt.Fprintf(&output, "%s:%d", r.displayPath, r.count)
-		}
-
-	case "content":
-		// Collect all output lines (match + context + inter-file separators)
-		var allOutputLines []string
-		first := true
-		for _, r := range results {
-			if !r.hasMatch || len(r.lines) == 0 {
-				continue
-			}
-			if !first {
-				allOutputLines = append(allOutputLines, "--")
-			}
-			first = false
-			allOutputLines = append(allOutputLines, r.lines...)
-		}
-		// Apply offset/head_limit on all output lines uniformly
-		if p.offset > 0 {
-			if p.offset >= len(allOutputLines) {
-				allOutputLines = nil
-			} else {
-				allOutputLines = allOutputLines[p.offset:]
-			}
-		}
-		if p.headLimit > 0 && len(allOutputLines) > p.headLimit {
-			allOutputLines = allOutputLines[:p.headLimit]
-		}
-		output.WriteString(strings.Join(allOutputLines, "\n"))
-	}
-
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{&mcp.TextContent{Text: output.String()}},
-	}, nil, nil
+	return walkFn(rootPath)
 }
 
 // searchFile searches a single file and returns its lines, match line numbers, and count.
@@ -828,8 +1577,7 @@ func searchFile(re *regexp.Regexp, filePath string, p grepParams) ([]string, []i
 	if p.multiline && p.maxFileSize > 0 {
 		info, err := os.Stat(filePath)
 		if err == nil && info.Size() > p.maxFileSize {
-			// Silently skip oversized files in directory walk
-			return nil, nil, 0, nil
+			return nil, nil, 0, errFileTooLarge
 		}
 	}
 
@@ -843,7 +1591,7 @@ func searchFile(re *regexp.Regexp, filePath string, p grepParams) ([]string, []i
 	header := make([]byte, 512)
 	n, _ := f.Read(header)
 	header = header[:n]
-	if isBinaryHeader(header) {
+	if !p.nullData && !p.binary && isBinaryHeader(header) {
 		return nil, nil, 0, nil
 	}
 
@@ -851,15 +1599,14 @@ func searchFile(re *regexp.Regexp, filePath string, p grepParams) ([]string, []i
 		return nil, nil, 0, err
 	}
 
-	if p.multiline {
-		return searchFileMultiline(re, f)
+	if p.multiline && !p.nullData {
+		return searchFileMultiline(re, f, p.normalizeEOL)
 	}
-	return searchFileLineByLine(re, f)
+	return searchFileLineByLine(re, f, p.nullData, p.normalizeEOL)
 }
 
-func searchFileLineByLine(re *regexp.Regexp, f *os.File) ([]string, []int, int, error) {
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+func searchFileLineByLine(re *regexp.Regexp, r io.Reader, nullData, normalizeEOL bool) ([]string, []int, int, error) {
+	scanner := newRecordScanner(r, nullData, normalizeEOL)
 
 	var allLines []string
 	var matchLineNums []int
@@ -874,14 +1621,22 @@ func searchFileLineByLine(re *regexp.Regexp, f *os.File) ([]string, []int, int,
 		}
 	}
 
+	// A line over the scanner limit ends the search; report it with
+	// the lines read so far.
+	if err := scanner.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return allLines, matchLineNums, len(matchLineNums), err
+	}
 	return allLines, matchLineNums, len(matchLineNums), nil
 }
 
-func searchFileMultiline(re *regexp.Regexp, f *os.File) ([]string, []int, int, error) {
-	data, err := readAllFile(f)
+func searchFileMultiline(re *regexp.Regexp, r io.Reader, normalizeEOL bool) ([]string, []int, int, error) {
+	data, err := readAllFile(r)
 	if err != nil {
 		return nil, nil, 0, err
 	}
+	if normalizeEOL {
+		data = normalizeLineEndings(data)
+	}
 	content := string(data)
 
 	lines := strings.Split(content, "\n")
@@ -917,9 +1672,43 @@ func searchFileMultiline(re *regexp.Regexp, f *os.File) ([]string, []int, int, e
 	return lines, matchLineNums, len(matchLineNums), nil
 }
 
-func readAllFile(f *os.File) ([]byte, error) {
+// maxScanLineBytes is the longest line or record a line-by-line search can
+// read; the search of a file stops at the first longer one.
+const maxScanLineBytes = 1024 * 1024
+
+// newRecordScanner returns a scanner over r that yields newline-separated
+// lines, or NUL-separated records when nullData is set. With normalizeEOL,
+// lines may also end in CRLF or a lone CR.
+func newRecordScanner(r io.Reader, nullData, normalizeEOL bool) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScanLineBytes)
+	switch {
+	case nullData:
+		scanner.Split(scanNullRecords)
+	case normalizeEOL:
+		scanner.Split(scanNormalizedLines)
+	}
+	return scanner
+}
+
+// scanNullRecords is a bufio.SplitFunc that splits input on NUL bytes.
+// A trailing NUL does not produce an empty final record.
+func scanNullRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func readAllFile(r io.Reader) ([]byte, error) {
 	var buf bytes.Buffer
-	_, err := buf.ReadFrom(f)
+	_, err := buf.ReadFrom(r)
 	return buf.Bytes(), err
 }
 
@@ -958,6 +1747,12 @@ func matchesType(name string, typePatterns []string) bool {
 	return false
 }
 
+// isSymlink reports whether path is itself a symbolic link.
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
 // isSymlinkDir checks if a path is a symlink pointing to a directory.
 func isSymlinkDir(path string) bool {
 	target, err := filepath.EvalSymlinks(path)
@@ -981,100 +1776,3 @@ func (f fakeDirEntry) Name() string               { return f.name }
 func (f fakeDirEntry) IsDir() bool                 { return f.info.IsDir() }
 func (f fakeDirEntry) Type() fs.FileMode           { return f.info.Mode().Type() }
 func (f fakeDirEntry) Info() (fs.FileInfo, error)   { return f.info, nil }
-
-// gitignoreStack manages a stack of gitignore matchers for nested directory traversal.
-// It uses sabhiram/go-gitignore for pattern compilation and matching, while keeping
-// our own stack management for nested .gitignore files during directory walks.
-type gitignoreStack struct {
-	stack []gitignoreLevel
-}
-
-// gitignoreLevel holds the parsed patterns from a single .gitignore file.
-type gitignoreLevel struct {
-	dir      string
-	patterns []gitignoreLevelPattern
-}
-
-// gitignoreLevelPattern holds a single parsed gitignore pattern with its matcher.
-type gitignoreLevelPattern struct {
-	matcher *ignore.GitIgnore
-	negate  bool
-	dirOnly bool
-}
-
-func newGitignoreStack() *gitignoreStack {
-	return &gitignoreStack{}
-}
-
-func (g *gitignoreStack) push(dir string) {
-	gitignorePath := filepath.Join(dir, ".gitignore")
-	data, err := os.ReadFile(gitignorePath)
-	if err != nil {
-		// No .gitignore at this level
-		g.stack = append(g.stack, gitignoreLevel{dir: dir})
-		return
-	}
-
-	var patterns []gitignoreLevelPattern
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		var negate bool
-		if strings.HasPrefix(line, "!") {
-			negate = true
-			line = line[1:]
-		}
-
-		var dirOnly bool
-		if strings.HasSuffix(line, "/") {
-			dirOnly = true
-			line = strings.TrimSuffix(line, "/")
-		}
-
-		// Compile the pattern using the library
-		matcher := ignore.CompileIgnoreLines(line)
-
-		patterns = append(patterns, gitignoreLevelPattern{
-			matcher: matcher,
-			negate:  negate,
-			dirOnly: dirOnly,
-		})
-	}
-
-	g.stack = append(g.stack, gitignoreLevel{dir: dir, patterns: patterns})
-}
-
-func (g *gitignoreStack) pop() {
-	if len(g.stack) > 0 {
-		g.stack = g.stack[:len(g.stack)-1]
-	}
-}
-
-func (g *gitignoreStack) isIgnored(path string, isDir bool) bool {
-	// Process all gitignore levels, child overrides parent (last match wins)
-	ignored := false
-	for _, level := range g.stack {
-		for _, p := range level.patterns {
-			if p.dirOnly && !isDir {
-				continue
-			}
-			// Match against relative path from the gitignore's directory
-			relPath, err := filepath.Rel(level.dir, path)
-			if err != nil {
-				continue
-			}
-			if p.matcher.MatchesPath(relPath) {
-				if p.negate {
-					ignored = false
-				} else {
-					ignored = true
-				}
-			}
-		}
-	}
-	return ignored
-}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxGrepCacheEntries bounds the number of results kept per session.
+const maxGrepCacheEntries = 64
+
+// grepCache maps a grep query (arguments plus cwd) to its result. An entry
+// is served only while every file and directory it depended on still has
+// the modification time recorded when the search ran.
+type grepCache struct {
+	mu      sync.Mutex
+	entries map[string]grepCacheEntry
+}
+
+type grepCacheEntry struct {
+	result *mcp.CallToolResult
+	mtimes map[string]time.Time
+}
+
+func newGrepCache() *grepCache {
+	return &grepCache{entries: make(map[string]grepCacheEntry)}
+}
+
+// get returns the cached result for key if none of its dependencies changed.
+// Stale entries are dropped.
+func (c *grepCache) get(key string) (*mcp.CallToolResult, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	for path, mtime := range entry.mtimes {
+		if statMtime(path) != mtime {
+			c.mu.Lock()
+			delete(c.entries, key)
+			c.mu.Unlock()
+			return nil, false
+		}
+	}
+	return entry.result, true
+}
+
+func (c *grepCache) put(key string, result *mcp.CallToolResult, deps *grepDeps) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxGrepCacheEntries {
+		// Evict an arbitrary entry; the cache only needs to catch repeats
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = grepCacheEntry{result: result, mtimes: deps.mtimes}
+}
+
+// grepDeps records the modification times of everything a search read:
+// each searched file, every directory the walk entered, and the .gitignore
+// and extra ignore files in those directories. A directory's mtime changes whenever an entry is
+// added, removed, or renamed in it, so files the include, type, or hidden
+// filters skipped (which are selected by name alone) and files created
+// after the search are noticed without recording each one. Mtimes are
+// taken before a file is read, so a change made during the search
+// invalidates the entry.
+type grepDeps struct {
+	ignoreFiles []string // extra ignore file names honored by the walk
+	mtimes      map[string]time.Time
+}
+
+func newGrepDeps(ignoreFiles []string) *grepDeps {
+	return &grepDeps{ignoreFiles: ignoreFiles, mtimes: make(map[string]time.Time)}
+}
+
+func (d *grepDeps) add(path string) {
+	if _, ok := d.mtimes[path]; !ok {
+		d.mtimes[path] = statMtime(path)
+	}
+}
+
+// addDir records dir and the ignore files the walk reads in it.
+func (d *grepDeps) addDir(dir string) {
+	d.add(dir)
+	d.add(filepath.Join(dir, ".gitignore"))
+	for _, name := range d.ignoreFiles {
+		d.add(filepath.Join(dir, name))
+	}
+}
+
+// statMtime returns the modification time of path, or the zero time if it
+// cannot be stat'ed (so a missing file stays valid only while missing).
+func statMtime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// cachedGrep runs doGrep through cache, keyed by the raw tool arguments and
+// the session cwd. A nil cache disables caching. Searches of inline content,
+// with modified_within (which depends on the current time), or with
+// changed_since (which depends on git state) bypass the cache, as do errors
+// and searches cut short by cancellation.
+func cachedGrep(ctx context.Context, cache *grepCache, sess *session.Session, resolver *pathscope.Resolver, p grepParams, args any) (*mcp.CallToolResult, any, error) {
+	if cache == nil || p.content != "" || p.modifiedWithin != "" || p.changedSince != "" {
+		return doGrep(ctx, sess, resolver, p)
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return doGrep(ctx, sess, resolver, p)
+	}
+	key := sess.Cwd() + "\x00" + string(data)
+	if result, ok := cache.get(key); ok {
+		return result, nil, nil
+	}
+
+	p.deps = newGrepDeps(p.ignoreFiles)
+	result, out, err := doGrep(ctx, sess, resolver, p)
+	if err == nil && result != nil && !result.IsError && ctx.Err() == nil {
+		cache.put(key, result, p.deps)
+	}
+	return result, out, err
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrepCache(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	file := filepath.Join(tmp, "main.go")
+	os.WriteFile(file, []byte("func alpha() {}\n"), 0644)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(file, old, old)
+
+	cfg := testConfig()
+	cfg.EnableGrepCache = true
+	handler := grepHandler(sess, resolver, cfg)
+	grep := func() string {
+		t.Helper()
+		result, _, err := handler(context.Background(), nil, GrepArgs{Pattern: "func", OutputMode: "content"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(result) {
+			t.Fatalf("unexpected error: %s", resultText(result))
+		}
+		return resultText(result)
+	}
+
+	if got := grep(); !strings.Contains(got, "alpha") {
+		t.Fatalf("first query should find alpha, got %q", got)
+	}
+
+	// Rewrite the file but restore its mtime: the cached result is served
+	os.WriteFile(file, []byte("func beta() {}\n"), 0644)
+	os.Chtimes(file, old, old)
+	if got := grep(); !strings.Contains(got, "alpha") {
+		t.Errorf("second identical query should return the cached result, got %q", got)
+	}
+
+	// Touching the file invalidates the entry
+	now := time.Now()
+	os.Chtimes(file, now, now)
+	if got := grep(); !strings.Contains(got, "beta") || strings.Contains(got, "alpha") {
+		t.Errorf("query after touch should see new content, got %q", got)
+	}
+}
+
+func TestGrepCacheNewFile(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmp, "sub", "a.go"), []byte("needle\n"), 0644)
+
+	cfg := testConfig()
+	cfg.EnableGrepCache = true
+	handler := grepHandler(sess, resolver, cfg)
+
+	result, _, _ := handler(context.Background(), nil, GrepArgs{Pattern: "needle"})
+	if got := resultText(result); got != "sub/a.go" {
+		t.Fatalf("got %q, want sub/a.go", got)
+	}
+
+	os.WriteFile(filepath.Join(tmp, "sub", "b.go"), []byte("needle\n"), 0644)
+	result, _, _ = handler(context.Background(), nil, GrepArgs{Pattern: "needle"})
+	if got := resultText(result); !strings.Contains(got, "sub/b.go") {
+		t.Errorf("adding a file should invalidate the cache, got %q", got)
+	}
+}
+
+func TestGrepCacheNewFileInFilteredDir(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("needle\n"), 0644)
+	os.MkdirAll(filepath.Join(tmp, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmp, "sub", "notes.txt"), []byte("needle\n"), 0644)
+	os.MkdirAll(filepath.Join(tmp, "empty"), 0755)
+
+	cfg := testConfig()
+	cfg.EnableGrepCache = true
+	handler := grepHandler(sess, resolver, cfg)
+	grep := func() string {
+		t.Helper()
+		result, _, _ := handler(context.Background(), nil, GrepArgs{Pattern: "needle", Include: "*.go"})
+		return resultText(result)
+	}
+
+	if got := grep(); got != "a.go" {
+		t.Fatalf("got %q, want a.go", got)
+	}
+
+	// sub held only filtered-out files, so nothing in it was searched
+	os.WriteFile(filepath.Join(tmp, "sub", "b.go"), []byte("needle\n"), 0644)
+	if got := grep(); !strings.Contains(got, "sub/b.go") {
+		t.Errorf("a file added under a directory with only filtered files should invalidate the cache, got %q", got)
+	}
+
+	os.WriteFile(filepath.Join(tmp, "empty", "c.go"), []byte("needle\n"), 0644)
+	if got := grep(); !strings.Contains(got, "empty/c.go") {
+		t.Errorf("a file added under an empty directory should invalidate the cache, got %q", got)
+	}
+}
+
+func TestGrepCacheExtraIgnoreFile(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("needle\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "b.go"), []byte("needle\n"), 0644)
+	ignore := filepath.Join(tmp, ".rgignore")
+	os.WriteFile(ignore, []byte("b.go\n"), 0644)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(ignore, old, old)
+
+	cfg := testConfig()
+	cfg.EnableGrepCache = true
+	cfg.ExtraIgnoreFiles = []string{".ignore", ".rgignore"}
+	handler := grepHandler(sess, resolver, cfg)
+	grep := func() string {
+		t.Helper()
+		// The include filter keeps the ignore file itself out of the search
+		result, _, _ := handler(context.Background(), nil, GrepArgs{Pattern: "needle", Include: "*.go"})
+		return resultText(result)
+	}
+
+	if got := grep(); got != "a.go" {
+		t.Fatalf("got %q, want a.go", got)
+	}
+
+	// Editing the ignore file in place changes neither directory nor files searched
+	os.WriteFile(ignore, []byte("a.go\n"), 0644)
+	if got := grep(); got != "b.go" {
+		t.Errorf("editing .rgignore should invalidate the cache, got %q", got)
+	}
+}
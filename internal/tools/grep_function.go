@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+// cFunctionHeader matches a C-family function definition: an unindented line
+// that names something and opens a parameter list without ending in ';'.
+var cFunctionHeader = regexp.MustCompile(`^[A-Za-z_][\w\s\*&:<>,~]*\([^;]*$`)
+
+// functionHeaders maps grep file type names (see typeGlobs) to the pattern
+// marking a function, class, or section header in that language, used by
+// function_context to find the enclosing definition of a match.
+var functionHeaders = map[string]*regexp.Regexp{
+	"go":       regexp.MustCompile(`^func\b|^type\s+\w+\s+(struct|interface)\b`),
+	"py":       regexp.MustCompile(`^\s*(async\s+)?(def|class)\s`),
+	"js":       regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?(function\b|class\s)`),
+	"ts":       regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(abstract\s+)?(async\s+)?(function\b|class\s|interface\s)`),
+	"rust":     regexp.MustCompile(`^\s*(pub(\([\w:]+\))?\s+)?(async\s+)?(unsafe\s+)?(fn|impl|struct|enum|trait|mod)\b`),
+	"java":     regexp.MustCompile(`^\s*((public|protected|private|static|final|abstract|synchronized)\s+)+[\w<>\[\], ]+\(|^\s*((public|protected|private|static|final|abstract)\s+)*(class|interface|enum|record)\s`),
+	"c":        cFunctionHeader,
+	"cpp":      cFunctionHeader,
+	"markdown": regexp.MustCompile(`^#{1,6}\s`),
+}
+
+// functionHeaderPattern returns the header pattern for the language of path,
+// judged by its file name, or nil if the language is not recognized.
+func functionHeaderPattern(path string) *regexp.Regexp {
+	base := filepath.Base(path)
+	for name, re := range functionHeaders {
+		if matchesType(base, typeGlobs[name]) {
+			return re
+		}
+	}
+	return nil
+}
+
+// enclosingHeaders returns, for each 1-indexed line of allLines, the number
+// of the nearest header line at or above it according to header, or 0 if
+// there is none. Index 0 is unused.
+func enclosingHeaders(header *regexp.Regexp, allLines []string) []int {
+	nearest := make([]int, len(allLines)+1)
+	for i, line := range allLines {
+		nearest[i+1] = nearest[i]
+		if header.MatchString(line) {
+			nearest[i+1] = i + 1
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGrepFunctionContext(t *testing.T) {
+	grep := func(t *testing.T, files map[string]string, args GrepArgs) string {
+		t.Helper()
+		tmp, sess, resolver := grepTestSetup(t)
+		for name, content := range files {
+			os.WriteFile(filepath.Join(tmp, name), []byte(content), 0644)
+		}
+		args.Pattern = "needle"
+		args.OutputMode = "content"
+		args.FunctionContext = true
+		r, err := callGrep(sess, resolver, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(r) {
+			t.Fatal(resultText(r))
+		}
+		return resultText(r)
+	}
+
+	goSrc := "package main\n\nimport \"fmt\"\n\nfunc helper(x int) int {\n\ty := x * 2\n\tz := y + 1\n\treturn z + needle\n}\n\nfunc other() {\n\tfmt.Println(\"needle\")\n}\n"
+	pySrc := "class Greeter:\n    def __init__(self):\n        self.name = \"x\"\n\n    def greet(self):\n        msg = \"hello\"\n        return msg + \" needle\"\n"
+
+	t.Run("go", func(t *testing.T) {
+		got := grep(t, map[string]string{"main.go": goSrc}, GrepArgs{})
+		want := strings.Join([]string{
+			"main.go=5=func helper(x int) int {",
+			"main.go:8:\treturn z + needle",
+			"--",
+			"main.go=11=func other() {",
+			"main.go:12:\tfmt.Println(\"needle\")",
+		}, "\n")
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("python", func(t *testing.T) {
+		got := grep(t, map[string]string{"app.py": pySrc}, GrepArgs{Context: intPtr(1)})
+		want := "app.py=5=    def greet(self):\napp.py-6-        msg = \"hello\"\napp.py:7:        return msg + \" needle\""
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("header already in context is not repeated", func(t *testing.T) {
+		got := grep(t, map[string]string{"app.py": pySrc}, GrepArgs{ContextBefore: intPtr(2)})
+		if strings.Contains(got, "app.py=") {
+			t.Errorf("unexpected header line:\n%s", got)
+		}
+	})
+
+	t.Run("unrecognized file type", func(t *testing.T) {
+		got := grep(t, map[string]string{"notes.txt": "def nothing\nneedle\n"}, GrepArgs{})
+		if got != "notes.txt:2:needle" {
+			t.Errorf("got %q, want only the match", got)
+		}
+	})
+
+	t.Run("json lines", func(t *testing.T) {
+		got := grep(t, map[string]string{"app.py": pySrc}, GrepArgs{JSONLines: true})
+		want := `{"path":"app.py","line":5,"text":"    def greet(self):","is_context":true,"is_function":true}` + "\n" +
+			`{"path":"app.py","line":7,"text":"        return msg + \" needle\"","is_context":false}`
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
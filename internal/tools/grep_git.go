@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// gitChangedFiles returns the resolved paths of tracked files in the git
+// repository containing dir that differ from ref in the working tree, as
+// reported by git diff --name-only. Files deleted since ref are omitted. It
+// fails if dir is not inside a repository or ref does not name a commit.
+func gitChangedFiles(ctx context.Context, dir, ref string) (map[string]bool, error) {
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid git ref %q", ref)
+	}
+	top, err := runGit(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %v", dir, err)
+	}
+	top = strings.TrimSpace(top)
+	if _, err := runGit(ctx, top, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git ref %q", ref)
+	}
+	out, err := runGit(ctx, top, "diff", "--name-only", "-z", ref, "--")
+	if err != nil {
+		return nil, fmt.Errorf("git diff %s failed: %v", ref, err)
+	}
+
+	changed := map[string]bool{}
+	for _, name := range strings.Split(out, "\x00") {
+		if name == "" {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(filepath.Join(top, name))
+		if err != nil {
+			continue // deleted since ref
+		}
+		changed[resolved] = true
+	}
+	return changed, nil
+}
+
+// gitSafeArgs disable the repository settings that make git run programs of
+// the repository's choosing, so that inspecting an untrusted repository (as
+// under --readonly) does not execute code from it.
+var gitSafeArgs = []string{"-c", "core.fsmonitor=false", "-c", "core.hooksPath=/dev/null"}
+
+// runGit runs git with args in dir and returns its stdout. On failure the
+// error includes git's stderr.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	gitArgs := append(slices.Clone(gitSafeArgs), "-C", dir)
+	cmd := exec.CommandContext(ctx, "git", append(gitArgs, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestGrepChangedSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmp, sess, resolver := grepTestSetup(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = tmp
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "trunk")
+	os.MkdirAll(filepath.Join(tmp, "sub"), 0755)
+	for _, name := range []string{"a.go", "b.go", filepath.Join("sub", "c.go")} {
+		os.WriteFile(filepath.Join(tmp, name), []byte("needle\n"), 0644)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "baseline")
+	git("tag", "base")
+
+	// b.go changes in a commit; sub/c.go changes in the working tree
+	os.WriteFile(filepath.Join(tmp, "b.go"), []byte("needle v2\n"), 0644)
+	git("commit", "-q", "-am", "change b")
+	os.WriteFile(filepath.Join(tmp, "sub", "c.go"), []byte("needle v3\n"), 0644)
+
+	grep := func(args GrepArgs) string {
+		t.Helper()
+		args.Pattern = "needle"
+		r, err := callGrep(sess, resolver, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(r) {
+			t.Fatalf("unexpected error: %s", resultText(r))
+		}
+		return resultText(r)
+	}
+	sorted := func(s string) string {
+		lines := strings.Split(s, "\n")
+		sort.Strings(lines)
+		return strings.Join(lines, ",")
+	}
+
+	if got := sorted(grep(GrepArgs{ChangedSince: "base"})); got != "b.go,"+filepath.Join("sub", "c.go") {
+		t.Errorf("changed since base: got %q", got)
+	}
+	if got := grep(GrepArgs{ChangedSince: "HEAD"}); got != filepath.Join("sub", "c.go") {
+		t.Errorf("changed since HEAD: got %q", got)
+	}
+	if got := grep(GrepArgs{ChangedSince: "HEAD", Path: "sub"}); got != "c.go" {
+		t.Errorf("changed since HEAD under sub: got %q", got)
+	}
+	if got := grep(GrepArgs{ChangedSince: "HEAD", Path: "a.go"}); got != "" {
+		t.Errorf("unchanged explicit file should not match, got %q", got)
+	}
+
+	r, _ := callGrep(sess, resolver, GrepArgs{Pattern: "needle", ChangedSince: "no-such-ref"})
+	if !hasErrorCode(r, ErrInvalidInput) || !strings.Contains(resultText(r), `unknown git ref "no-such-ref"`) {
+		t.Errorf("expected unknown ref error, got: %s", resultText(r))
+	}
+	r, _ = callGrep(sess, resolver, GrepArgs{Pattern: "needle", ChangedSince: "--output=x"})
+	if !hasErrorCode(r, ErrInvalidInput) {
+		t.Errorf("expected option-like ref to be rejected, got: %s", resultText(r))
+	}
+}
+
+func TestGrepChangedSinceIgnoresRepoPrograms(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmp, sess, resolver := grepTestSetup(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = tmp
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "trunk")
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("needle\n"), 0644)
+	git("add", ".")
+	git("commit", "-q", "-m", "baseline")
+
+	// A repository-supplied fsmonitor hook runs on every index refresh
+	marker := filepath.Join(t.TempDir(), "ran")
+	hook := filepath.Join(t.TempDir(), "fsmonitor.sh")
+	os.WriteFile(hook, []byte("#!/bin/sh\ntouch "+marker+"\n"), 0755)
+	git("config", "core.fsmonitor", hook)
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("needle v2\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "needle", ChangedSince: "HEAD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultText(r); got != "a.go" {
+		t.Errorf("got %q, want a.go", got)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("repository fsmonitor hook was executed")
+	}
+}
+
+func TestGrepChangedSinceNotRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("needle\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "needle", ChangedSince: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(r, ErrInvalidInput) || !strings.Contains(resultText(r), "is not in a git repository") {
+		t.Errorf("expected not-a-repository error, got: %s", resultText(r))
+	}
+}
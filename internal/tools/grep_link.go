@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// grepLinkThreshold is the output size above which grep with resource_link
+// writes its results to a file instead of returning them inline.
+const grepLinkThreshold = maxOutputChars
+
+// linkLargeGrepOutput replaces the text of a successful grep result larger
+// than grepLinkThreshold with a short summary and a resource link to a file
+// holding the full output. The file is removed when the session closes. If
+// the file cannot be written inside the path scope, result is returned as is.
+func linkLargeGrepOutput(sess *session.Session, resolver *pathscope.Resolver, result *mcp.CallToolResult) *mcp.CallToolResult {
+	if result == nil || result.IsError || len(result.Content) == 0 {
+		return result
+	}
+	tc, ok := result.Content[0].(*mcp.TextContent)
+	if !ok || len(tc.Text) <= grepLinkThreshold {
+		return result
+	}
+
+	dir, err := resolver.ResolveForWrite(sess.Cwd(), scratchDir)
+	if err != nil {
+		return result
+	}
+	path, err := writeScratchFile(sess, dir, "grep-*.txt", tc.Text)
+	if err != nil {
+		return result
+	}
+
+	size := int64(len(tc.Text))
+	return &mcp.CallToolResult{
+		Meta: result.Meta,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Output is %d bytes; written to %s. Use view with view_range to read it.", size, path)},
+			&mcp.ResourceLink{
+				URI:      "file://" + path,
+				Name:     filepath.Base(path),
+				MIMEType: "text/plain",
+				Size:     &size,
+			},
+		},
+	}
+}
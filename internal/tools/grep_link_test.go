@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestGrepResourceLink(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	t.Cleanup(sess.Close)
+	os.WriteFile(filepath.Join(tmp, "big.txt"), []byte(strings.Repeat("needle in a haystack\n", 3000)), 0644)
+	os.WriteFile(filepath.Join(tmp, "small.txt"), []byte("pin\n"), 0644)
+	handler := grepHandler(sess, resolver, testConfig())
+
+	t.Run("small output stays inline", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, GrepArgs{Pattern: "pin", OutputMode: "content", ResourceLink: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Content) != 1 || resultText(result) != "small.txt:1:pin" {
+			t.Errorf("expected inline output, got %d blocks: %s", len(result.Content), resultText(result))
+		}
+	})
+
+	t.Run("large output is linked", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, GrepArgs{Pattern: "needle", OutputMode: "content", ResourceLink: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(result) || len(result.Content) != 2 {
+			t.Fatalf("expected summary and resource link, got: %s", resultText(result))
+		}
+		link, ok := result.Content[1].(*mcp.ResourceLink)
+		if !ok {
+			t.Fatalf("second block is %T, want *mcp.ResourceLink", result.Content[1])
+		}
+		path := strings.TrimPrefix(link.URI, "file://")
+		if filepath.Dir(path) != filepath.Join(tmp, scratchDir) {
+			t.Errorf("output file %s should be under %s", path, filepath.Join(tmp, scratchDir))
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), "big.txt:"); n != 3000 {
+			t.Errorf("output file should hold all 3000 matches, got %d", n)
+		}
+		if link.Size == nil || *link.Size != int64(len(data)) {
+			t.Errorf("link size = %v, want %d", link.Size, len(data))
+		}
+		if !strings.Contains(resultText(result), path) {
+			t.Errorf("summary should name the file, got: %s", resultText(result))
+		}
+
+		// The output file is not picked up by later searches
+		result, _, _ = handler(context.Background(), nil, GrepArgs{Pattern: "needle"})
+		if got := resultText(result); got != "big.txt" {
+			t.Errorf("later search should only find big.txt, got %q", got)
+		}
+	})
+
+	t.Run("without the option output is inline", func(t *testing.T) {
+		result, _, _ := handler(context.Background(), nil, GrepArgs{Pattern: "needle", OutputMode: "content"})
+		if len(result.Content) != 1 || !strings.Contains(resultText(result), "big.txt:1:needle") {
+			t.Errorf("expected inline output, got %d blocks", len(result.Content))
+		}
+	})
+}
+
+func TestGrepResourceLinkReadOnly(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	t.Cleanup(sess.Close)
+	os.WriteFile(filepath.Join(tmp, "big.txt"), []byte(strings.Repeat("needle in a haystack\n", 3000)), 0644)
+	cfg := testConfig()
+	cfg.ReadOnly = true
+
+	result, _, err := grepHandler(sess, resolver, cfg)(context.Background(), nil, GrepArgs{Pattern: "needle", OutputMode: "content", ResourceLink: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) || len(result.Content) != 1 || !strings.Contains(resultText(result), "big.txt:3000:needle") {
+		t.Errorf("expected inline output in read-only mode, got %d blocks", len(result.Content))
+	}
+	if _, err := os.Stat(filepath.Join(tmp, scratchDir)); !os.IsNotExist(err) {
+		t.Errorf("read-only grep should not create %s: %v", scratchDir, err)
+	}
+}
@@ -2,10 +2,13 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -27,13 +30,13 @@ func grepTestSetup(t *testing.T) (string, *session.Session, *pathscope.Resolver)
 }
 
 func callGrep(sess *session.Session, resolver *pathscope.Resolver, args GrepArgs) (*mcp.CallToolResult, error) {
-	handler := grepHandler(sess, resolver, 10*1024*1024)
+	handler := grepHandler(sess, resolver, testConfig())
 	r, _, err := handler(context.Background(), nil, args)
 	return r, err
 }
 
 func callGrepCompat(sess *session.Session, resolver *pathscope.Resolver, args GrepCompatArgs) (*mcp.CallToolResult, error) {
-	handler := grepCompatHandler(sess, resolver, 10*1024*1024)
+	handler := grepCompatHandler(sess, resolver, testConfig())
 	r, _, err := handler(context.Background(), nil, args)
 	return r, err
 }
@@ -861,6 +864,51 @@ func TestGrepSymlinkedFileSearched(t *testing.T) {
 	}
 }
 
+func TestGrepResolveSymlinks(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, "data"), 0755)
+	os.WriteFile(filepath.Join(tmp, "data", "real.txt"), []byte("match\n"), 0644)
+	os.Symlink(filepath.Join(tmp, "data", "real.txt"), filepath.Join(tmp, "link.txt"))
+	realPath, err := filepath.EvalSymlinks(filepath.Join(tmp, "data", "real.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "match", Include: "link.txt", OutputMode: "content"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != "link.txt:1:match" {
+		t.Errorf("without resolve_symlinks, expected link path, got: %q", text)
+	}
+
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "match", Include: "link.txt", OutputMode: "content", ResolveSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != realPath+":1:match" {
+		t.Errorf("with resolve_symlinks, expected real path, got: %q", text)
+	}
+
+	// Single-file search through the link
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "match", Path: "link.txt", ResolveSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != realPath {
+		t.Errorf("single-file search: expected %q, got: %q", realPath, text)
+	}
+
+	// Regular files keep their relative path
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "match", Include: "data/*.txt", ResolveSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != filepath.Join("data", "real.txt") {
+		t.Errorf("regular file should keep its relative path, got: %q", text)
+	}
+}
+
 // --- 3.8: Path scoping tests ---
 
 func TestGrepSearchRootOutsideAllowList(t *testing.T) {
@@ -1703,8 +1751,10 @@ func TestGrepGitignoreAnchoredPattern(t *testing.T) {
 	}
 	text := resultText(r)
 	// build/ at root should be ignored
-	if strings.Contains(text, "build/out.txt") && !strings.Contains(text, "src/build") {
-		// This is fine — build/out.txt at root is excluded
+	for _, line := range strings.Split(text, "\n") {
+		if line == filepath.Join("build", "out.txt") {
+			t.Errorf("build/out.txt should be ignored by anchored /build, got: %s", text)
+		}
 	}
 	// src/build/ should NOT be ignored (anchored pattern only applies at root)
 	if !strings.Contains(text, filepath.Join("src", "build", "out.txt")) {
@@ -1735,6 +1785,55 @@ func TestGrepGitignoreDoublestarVendor(t *testing.T) {
 	}
 }
 
+func TestGrepGitignoreDirOnlyNested(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("build/\n"), 0644)
+	os.MkdirAll(filepath.Join(tmp, "build"), 0755)
+	os.WriteFile(filepath.Join(tmp, "build", "out.txt"), []byte("match\n"), 0644)
+	os.MkdirAll(filepath.Join(tmp, "src", "pkg", "build"), 0755)
+	os.WriteFile(filepath.Join(tmp, "src", "pkg", "build", "out.txt"), []byte("match\n"), 0644)
+	// A file named build is not matched by a directory-only pattern
+	os.WriteFile(filepath.Join(tmp, "src", "build"), []byte("match\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "match"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	if strings.Contains(text, "out.txt") {
+		t.Errorf("build/ should ignore build directories at every level, got: %s", text)
+	}
+	if !strings.Contains(text, filepath.Join("src", "build")) {
+		t.Errorf("file named build should not be ignored by build/, got: %s", text)
+	}
+}
+
+func TestGrepGitignoreDoublestarMiddle(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("a/**/b\n"), 0644)
+	for _, dir := range []string{"a", filepath.Join("a", "x", "y"), filepath.Join("c", "a", "x")} {
+		os.MkdirAll(filepath.Join(tmp, dir), 0755)
+		os.WriteFile(filepath.Join(tmp, dir, "b"), []byte("match\n"), 0644)
+	}
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "match"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	found := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		found[line] = true
+	}
+	if found[filepath.Join("a", "b")] || found[filepath.Join("a", "x", "y", "b")] {
+		t.Errorf("a/b and a/x/y/b should be ignored by a/**/b, got: %s", text)
+	}
+	// The pattern is anchored, so c/a/x/b is not matched
+	if !found[filepath.Join("c", "a", "x", "b")] {
+		t.Errorf("c/a/x/b should not be ignored, got: %s", text)
+	}
+}
+
 func TestGrepGitignoreNestedNegation(t *testing.T) {
 	tmp, sess, resolver := grepTestSetup(t)
 	// Root ignores all .log files
@@ -1780,7 +1879,7 @@ func TestGrepContextCancellationStopsWalk(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	handler := grepHandler(sess, resolver, 10*1024*1024)
+	handler := grepHandler(sess, resolver, testConfig())
 	done := make(chan struct{})
 	go func() {
 		handler(ctx, nil, GrepArgs{
@@ -1806,7 +1905,9 @@ func TestGrepMultilineSingleFileOversized(t *testing.T) {
 	os.WriteFile(filepath.Join(tmp, "big.txt"), []byte(bigContent), 0644)
 
 	// Use a handler with maxFileSize=1000 (smaller than file)
-	handler := grepHandler(sess, resolver, 1000)
+	cfg := testConfig()
+	cfg.MaxFileSize = 1000
+	handler := grepHandler(sess, resolver, cfg)
 	r, _, err := handler(context.Background(), nil, GrepArgs{
 		Pattern:    "match",
 		Path:       "big.txt",
@@ -1832,7 +1933,9 @@ func TestGrepMultilineDirectorySkipsOversized(t *testing.T) {
 	os.WriteFile(filepath.Join(tmp, "small.txt"), []byte("match\n"), 0644)
 
 	// Use a handler with maxFileSize=1000 (smaller than big.txt but bigger than small.txt)
-	handler := grepHandler(sess, resolver, 1000)
+	cfg := testConfig()
+	cfg.MaxFileSize = 1000
+	handler := grepHandler(sess, resolver, cfg)
 	r, _, err := handler(context.Background(), nil, GrepArgs{
 		Pattern:    "match",
 		OutputMode: "files_with_matches",
@@ -1842,13 +1945,29 @@ func TestGrepMultilineDirectorySkipsOversized(t *testing.T) {
 		t.Fatal(err)
 	}
 	text := resultText(r)
-	// big.txt should be silently skipped
-	if strings.Contains(text, "big.txt") {
-		t.Errorf("oversized file should be silently skipped in directory walk, got: %s", text)
+	// big.txt should be skipped with a note rather than listed as a match
+	want := "small.txt\n\n[Skipped big.txt: exceeds maximum 1000 bytes for multiline grep]"
+	if text != want {
+		t.Errorf("got %q, want %q", text, want)
+	}
+}
+
+func TestGrepMultilineOversizedNotesCapped(t *testing.T) {
+	paths := make([]string, maxOversizedNotes+3)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("f%d.txt", i)
 	}
-	// small.txt should be found
-	if !strings.Contains(text, "small.txt") {
-		t.Errorf("small file should be found, got: %s", text)
+	var b strings.Builder
+	writeOversizedNotes(&b, paths, 1000)
+	lines := strings.Split(b.String(), "\n")
+	if len(lines) != maxOversizedNotes+1 {
+		t.Fatalf("expected %d note lines, got %d:\n%s", maxOversizedNotes+1, len(lines), b.String())
+	}
+	if lines[0] != "[Skipped f0.txt: exceeds maximum 1000 bytes for multiline grep]" {
+		t.Errorf("first note = %q", lines[0])
+	}
+	if last := lines[len(lines)-1]; last != "[Skipped 3 more files over 1000 bytes]" {
+		t.Errorf("summary note = %q", last)
 	}
 }
 
@@ -1858,7 +1977,9 @@ func TestGrepNonMultilineIgnoresFileSize(t *testing.T) {
 	os.WriteFile(filepath.Join(tmp, "big.txt"), []byte(bigContent), 0644)
 
 	// Non-multiline grep should work fine regardless of file size limit
-	handler := grepHandler(sess, resolver, 1000)
+	cfg := testConfig()
+	cfg.MaxFileSize = 1000
+	handler := grepHandler(sess, resolver, cfg)
 	r, _, err := handler(context.Background(), nil, GrepArgs{
 		Pattern:    "match",
 		Path:       "big.txt",
@@ -1877,6 +1998,1298 @@ func TestGrepNonMultilineIgnoresFileSize(t *testing.T) {
 	}
 }
 
-// Helper functions
-func intPtr(v int) *int   { return &v }
-func boolPtr(v bool) *bool { return &v }
+// --- 3.18: Highlight tests ---
+
+func TestGrepHighlightMatches(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "test.txt"), []byte("foo bar foo\nctx line\nbaz\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:      "foo",
+		Path:         "test.txt",
+		OutputMode:   "content",
+		Highlight:    true,
+		ContextAfter: intPtr(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "test.txt:1:«foo» bar «foo»\ntest.txt-2-ctx line"
+	if got := resultText(r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGrepColumns(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "test.txt"), []byte("foo first\nthe foo middle\nctx\nhéllo foo\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:      "foo",
+		Path:         "test.txt",
+		OutputMode:   "content",
+		Columns:      true,
+		ContextAfter: intPtr(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "é" is two bytes, so the match after it starts at byte column 8
+	want := "test.txt:1:1:foo first\ntest.txt:2:5:the foo middle\ntest.txt-3-ctx\ntest.txt:4:8:héllo foo"
+	if got := resultText(r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGrepHidden(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, ".env"), []byte("SECRET=1\n"), 0644)
+	os.MkdirAll(filepath.Join(tmp, ".config"), 0755)
+	os.WriteFile(filepath.Join(tmp, ".config", "app.ini"), []byte("SECRET=2\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "app.env"), []byte("SECRET=3\n"), 0644)
+
+	grep := func(args GrepArgs) string {
+		t.Helper()
+		args.Pattern = "SECRET"
+		r, err := callGrep(sess, resolver, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(r) {
+			t.Fatalf("unexpected error: %s", resultText(r))
+		}
+		return resultText(r)
+	}
+
+	// Default and hidden=true both search dotfiles
+	for _, args := range []GrepArgs{{}, {Hidden: boolPtr(true)}} {
+		got := grep(args)
+		for _, want := range []string{".env", filepath.Join(".config", "app.ini"), "app.env"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("hidden=%v: expected %s in output, got %q", args.Hidden, want, got)
+			}
+		}
+	}
+
+	// hidden=false skips dotfiles and dot-directories during the walk
+	if got := grep(GrepArgs{Hidden: boolPtr(false)}); got != "app.env" {
+		t.Errorf("hidden=false: got %q, want only app.env", got)
+	}
+
+	// An explicit path is searched regardless
+	if got := grep(GrepArgs{Hidden: boolPtr(false), Path: ".env"}); got != ".env" {
+		t.Errorf("explicit .env path: got %q", got)
+	}
+}
+
+func TestGrepBinary(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "prog.bin"), []byte("\x7fELF\x00\x01\x00version-string 1.2\x00\x00\nmore\x00\n"), 0644)
+
+	grep := func(args GrepArgs) string {
+		t.Helper()
+		if args.Pattern == "" {
+			args.Pattern = "version-string"
+		}
+		r, err := callGrep(sess, resolver, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(r) {
+			t.Fatalf("unexpected error: %s", resultText(r))
+		}
+		return resultText(r)
+	}
+
+	// Binary files are skipped by default, in a walk and as an explicit path
+	if got := grep(GrepArgs{}); got != "" {
+		t.Errorf("default directory search should skip binary, got %q", got)
+	}
+	if got := grep(GrepArgs{Path: "prog.bin", OutputMode: "content"}); got != "" {
+		t.Errorf("default file search should skip binary, got %q", got)
+	}
+
+	// With binary set, matches are found and NUL bytes are escaped
+	if got := grep(GrepArgs{Binary: true}); got != "prog.bin" {
+		t.Errorf("binary directory search: got %q, want prog.bin", got)
+	}
+	want := `prog.bin:1:` + "\x7f" + `ELF\0` + "\x01" + `\0version-string 1.2\0\0`
+	if got := grep(GrepArgs{Binary: true, Path: "prog.bin", OutputMode: "content"}); got != want {
+		t.Errorf("binary file search:\ngot  %q\nwant %q", got, want)
+	}
+	if got := grep(GrepArgs{Binary: true, OutputMode: "content"}); got != want {
+		t.Errorf("binary directory content search:\ngot  %q\nwant %q", got, want)
+	}
+
+	// Patterns match the raw NUL bytes, not their escaped form
+	if got := grep(GrepArgs{Binary: true, Pattern: `ELF\x00\x01`, Path: "prog.bin", OutputMode: "content"}); got != want {
+		t.Errorf("NUL pattern file search:\ngot  %q\nwant %q", got, want)
+	}
+	if got := grep(GrepArgs{Binary: true, Pattern: `ELF\x00\x01`, OutputMode: "content"}); got != want {
+		t.Errorf("NUL pattern directory search:\ngot  %q\nwant %q", got, want)
+	}
+	if got := grep(GrepArgs{Binary: true, Pattern: `ELF\\0`}); got != "" {
+		t.Errorf("escaped form should not match, got %q", got)
+	}
+}
+
+func TestGrepLongLineNote(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	long := strings.Repeat("x", maxScanLineBytes+1)
+	os.WriteFile(filepath.Join(tmp, "big.txt"), []byte("needle one\n"+long+"\nneedle two\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "needle", Path: "big.txt", OutputMode: "content"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	if !strings.Contains(text, "big.txt:1:needle one") {
+		t.Errorf("file search should keep matches before the long line, got %q", text)
+	}
+	if !strings.Contains(text, "[Stopped searching big.txt at line 2: line exceeds") {
+		t.Errorf("file search should note the long line, got %q", text)
+	}
+
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "needle", OutputMode: "content"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = resultText(r)
+	if !strings.Contains(text, "big.txt:1:needle one") {
+		t.Errorf("directory search should keep matches before the long line, got %q", text)
+	}
+	if !strings.Contains(text, "[Stopped searching big.txt at line 2: line exceeds") {
+		t.Errorf("directory search should note the long line, got %q", text)
+	}
+}
+
+func TestGrepInvalidInclude(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("needle\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "needle", Include: "*.{go,ts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(r, ErrInvalidInput) {
+		t.Fatalf("expected %s for malformed include, got: %s", ErrInvalidInput, resultText(r))
+	}
+	if want := `invalid include pattern "*.{go,ts": unclosed '{' at offset 2`; !strings.HasSuffix(resultText(r), want) {
+		t.Errorf("got %q, want suffix %q", resultText(r), want)
+	}
+}
+
+func TestGrepHighlightOnlyMatchedPortion(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "test.txt"), []byte("value = compute(42)\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:    `\d+`,
+		Path:       "test.txt",
+		OutputMode: "content",
+		Highlight:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "test.txt:1:value = compute(«42»)"
+	if got := resultText(r); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGrepHighlightCustomMarkers(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "test.txt"), []byte("a1b2\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:        `[0-9]`,
+		Path:           "test.txt",
+		OutputMode:     "content",
+		Highlight:      true,
+		HighlightOpen:  "[[",
+		HighlightClose: "]]",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "test.txt:1:a[[1]]b[[2]]"
+	if got := resultText(r); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGrepHighlightDisabledByDefault(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "test.txt"), []byte("foo\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:    "foo",
+		Path:       "test.txt",
+		OutputMode: "content",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultText(r); got != "test.txt:1:foo" {
+		t.Errorf("got %q, want no markers", got)
+	}
+}
+
+func TestGrepHighlightDirectorySearch(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("x foo y\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:    "foo",
+		OutputMode: "content",
+		Highlight:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultText(r); got != "a.txt:1:x «foo» y" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// --- 3.19: Replace preview tests ---
+
+func strPtr(v string) *string { return &v }
+
+func TestGrepReplacePreviewCaptureGroups(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("func oldName() {}\nx := 1\n"), 0644)
+	os.MkdirAll(filepath.Join(tmp, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmp, "sub", "b.go"), []byte("// call oldHelper here\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern: `old(\w+)`,
+		Replace: strPtr("new$1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	if !strings.Contains(text, "a.go:1:func newName() {}") {
+		t.Errorf("expected replaced line for a.go, got:\n%s", text)
+	}
+	if !strings.Contains(text, filepath.Join("sub", "b.go")+":1:// call newHelper here") {
+		t.Errorf("expected replaced line for sub/b.go, got:\n%s", text)
+	}
+	if strings.Contains(text, "x := 1") {
+		t.Errorf("non-matching lines should not appear, got:\n%s", text)
+	}
+
+	// Files must not be modified
+	data, _ := os.ReadFile(filepath.Join(tmp, "a.go"))
+	if !strings.Contains(string(data), "oldName") {
+		t.Errorf("replace preview modified a.go: %s", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(tmp, "sub", "b.go"))
+	if !strings.Contains(string(data), "oldHelper") {
+		t.Errorf("replace preview modified sub/b.go: %s", data)
+	}
+}
+
+func TestGrepReplacePreviewMultipleMatchesPerLine(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "test.txt"), []byte("a=1, b=2\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern: `(\w)=(\d)`,
+		Path:    "test.txt",
+		Replace: strPtr("${2}=${1}"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultText(r); got != "test.txt:1:1=a, 2=b" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestGrepReplacePreviewEmptyReplacement(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "test.txt"), []byte("keep DROP keep\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern: `DROP `,
+		Path:    "test.txt",
+		Replace: strPtr(""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultText(r); got != "test.txt:1:keep keep" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestGrepReplaceRequiresContentMode(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "test.txt"), []byte("foo\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:    "foo",
+		OutputMode: "count",
+		Replace:    strPtr("bar"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(r, ErrInvalidInput) {
+		t.Errorf("expected %s, got: %s", ErrInvalidInput, resultText(r))
+	}
+}
+
+// --- 3.20: Null-data tests ---
+
+func TestGrepNullDataRecords(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "records.bin"), []byte("alpha\nfirst\x00beta second\x00gamma\nalpha again\x00"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:    `alpha`,
+		Path:       "records.bin",
+		OutputMode: "content",
+		NullData:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	if !strings.Contains(text, "records.bin:1:alpha\nfirst") {
+		t.Errorf("expected record 1 with embedded newline, got:\n%s", text)
+	}
+	if !strings.Contains(text, "records.bin:3:gamma\nalpha again") {
+		t.Errorf("expected record 3, got:\n%s", text)
+	}
+	if strings.Contains(text, "beta") {
+		t.Errorf("non-matching record should not appear, got:\n%s", text)
+	}
+}
+
+func TestGrepNullDataCount(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "records.bin"), []byte("x1\x00y\x00x2"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{
+		Pattern:    `x\d`,
+		Path:       "records.bin",
+		OutputMode: "count",
+		NullData:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != "records.bin:2" {
+		t.Errorf("expected records.bin:2, got %q", text)
+	}
+}
+
+func TestGrepNullDataNotSkippedAsBinary(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "records.bin"), []byte("needle\x00hay\x00"), 0644)
+
+	// Without null_data the NUL bytes mark the file as binary
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: `needle`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != "" {
+		t.Errorf("expected binary file to be skipped, got %q", text)
+	}
+
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: `needle`, NullData: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); !strings.Contains(text, "records.bin") {
+		t.Errorf("expected records.bin with null_data, got %q", text)
+	}
+}
+
+// --- 3.21: Line ending tests ---
+
+func TestGrepNormalizesLineEndings(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "crlf.txt"), []byte("foo one\r\nbar\r\nfoo two\r\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "cr.txt"), []byte("foo three\rbar\r"), 0644)
+
+	for _, multiline := range []bool{false, true} {
+		r, err := callGrep(sess, resolver, GrepArgs{
+			Pattern:    `foo \w+`,
+			OutputMode: "content",
+			Context:    intPtr(1),
+			Multiline:  multiline,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := resultText(r)
+		if strings.Contains(text, "\r") {
+			t.Errorf("multiline=%v: CR leaked into output: %q", multiline, text)
+		}
+		for _, want := range []string{"crlf.txt:1:foo one", "crlf.txt:3:foo two", "cr.txt:1:foo three", "cr.txt-2-bar"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("multiline=%v: expected %q in output, got:\n%s", multiline, want, text)
+			}
+		}
+	}
+}
+
+func TestGrepLineEndingsNotNormalizedWhenDisabled(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "crlf.txt"), []byte("foo one\r\nbar\r\n"), 0644)
+
+	cfg := testConfig()
+	cfg.NormalizeLineEndings = false
+	handler := grepHandler(sess, resolver, cfg)
+	r, _, err := handler(context.Background(), nil, GrepArgs{
+		Pattern:    `foo`,
+		Path:       "crlf.txt",
+		OutputMode: "content",
+		Multiline:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); !strings.Contains(text, "foo one\r") {
+		t.Errorf("expected raw CR without normalization, got %q", text)
+	}
+}
+
+// --- 3.22: Configurable directory exclusion tests ---
+
+func TestGrepExcludeDirs(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, ".venv", "lib"), 0755)
+	os.WriteFile(filepath.Join(tmp, ".venv", "lib", "x.py"), []byte("needle\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "main.py"), []byte("needle\n"), 0644)
+
+	cfg := testConfig()
+	cfg.ExcludeDirs = []string{".venv"}
+	r, _, err := grepHandler(sess, resolver, cfg)(context.Background(), nil, GrepArgs{Pattern: "needle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); text != "main.py" {
+		t.Errorf("excluded directory should be skipped, got: %q", text)
+	}
+
+	// Without the extra exclusion the directory is searched again
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "needle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); !strings.Contains(text, filepath.Join(".venv", "lib", "x.py")) {
+		t.Errorf("directory should be searched when not excluded, got: %q", text)
+	}
+}
+
+// --- 3.23: max_files tests ---
+
+func TestGrepMaxFiles(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		os.WriteFile(filepath.Join(tmp, name), []byte("match\n"), 0644)
+	}
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "match", OutputMode: "count", MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	// Directory entries are visited in lexical order
+	if !strings.Contains(text, "a.txt:1") || !strings.Contains(text, "b.txt:1") {
+		t.Errorf("expected first two files, got:\n%s", text)
+	}
+	if strings.Contains(text, "c.txt") || strings.Contains(text, "d.txt") {
+		t.Errorf("walk should stop after 2 files, got:\n%s", text)
+	}
+	if !strings.Contains(text, "[Search stopped after 2 files (max_files); results may be incomplete]") {
+		t.Errorf("expected cap note, got:\n%s", text)
+	}
+
+	// The cap counts files searched, not files matched
+	os.WriteFile(filepath.Join(tmp, "0.txt"), []byte("nothing\n"), 0644)
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "match", MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = resultText(r)
+	if !strings.Contains(text, "a.txt") || strings.Contains(text, "b.txt") {
+		t.Errorf("non-matching file should count toward max_files, got:\n%s", text)
+	}
+
+	// No note when the limit is not reached
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "match", MaxFiles: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(r); strings.Contains(text, "max_files") {
+		t.Errorf("unexpected cap note when all files were searched, got:\n%s", text)
+	}
+
+	r, _ = callGrep(sess, resolver, GrepArgs{Pattern: "match", MaxFiles: -1})
+	if !hasErrorCode(r, ErrInvalidInput) {
+		t.Errorf("expected %s for negative max_files, got: %s", ErrInvalidInput, resultText(r))
+	}
+}
+
+// --- 3.24: inline content tests ---
+
+func TestGrepContent(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	// A file on disk that would match must not be searched
+	os.WriteFile(filepath.Join(tmp, "disk.txt"), []byte("error on disk\n"), 0644)
+	content := "ok\nerror: first\nok\nerror: second\ndone\n"
+
+	t.Run("files_with_matches", func(t *testing.T) {
+		r, err := callGrep(sess, resolver, GrepArgs{Pattern: "error", Content: content})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := resultText(r); text != "<content>" {
+			t.Errorf("got %q, want <content>", text)
+		}
+	})
+
+	t.Run("count", func(t *testing.T) {
+		r, _ := callGrep(sess, resolver, GrepArgs{Pattern: "error", Content: content, OutputMode: "count"})
+		if text := resultText(r); !strings.Contains(text, "2") || strings.Contains(text, "disk") {
+			t.Errorf("expected count of 2 for content only, got %q", text)
+		}
+	})
+
+	t.Run("content with context", func(t *testing.T) {
+		r, _ := callGrep(sess, resolver, GrepArgs{Pattern: "second", Content: content, OutputMode: "content", Context: intPtr(1)})
+		text := resultText(r)
+		for _, want := range []string{"3-ok", "4:error: second", "5-done"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("expected %q in output, got:\n%s", want, text)
+			}
+		}
+		if strings.Contains(text, "first") {
+			t.Errorf("line outside context should not appear, got:\n%s", text)
+		}
+	})
+
+	t.Run("multiline", func(t *testing.T) {
+		r, _ := callGrep(sess, resolver, GrepArgs{Pattern: `first\nok`, Content: content, OutputMode: "content", Multiline: true})
+		text := resultText(r)
+		if !strings.Contains(text, "2:error: first") || !strings.Contains(text, "3:ok") {
+			t.Errorf("expected multiline match across lines 2-3, got:\n%s", text)
+		}
+	})
+
+	t.Run("path, include, and type ignored", func(t *testing.T) {
+		r, err := callGrep(sess, resolver, GrepArgs{Pattern: "error", Content: content, Path: "does-not-exist", Include: "*.go", Type: "bogus"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(r) || resultText(r) != "<content>" {
+			t.Errorf("expected <content> match, got %q", resultText(r))
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		r, _ := callGrep(sess, resolver, GrepArgs{Pattern: "absent", Content: content})
+		if isErrorResult(r) || strings.Contains(resultText(r), "<content>") {
+			t.Errorf("expected no matches, got %q", resultText(r))
+		}
+	})
+}
+
+// --- 3.25: modified_within tests ---
+
+func TestGrepModifiedWithin(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	old := time.Now().Add(-72 * time.Hour)
+	for _, name := range []string{"old.txt", filepath.Join("sub", "old.go")} {
+		path := filepath.Join(tmp, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("match\n"), 0644)
+		os.Chtimes(path, old, old)
+	}
+	os.WriteFile(filepath.Join(tmp, "new.txt"), []byte("match\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "sub", "new.go"), []byte("match\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "match", ModifiedWithin: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	if strings.Contains(text, "old") {
+		t.Errorf("files older than the window should be skipped, got:\n%s", text)
+	}
+	if !strings.Contains(text, "new.txt") || !strings.Contains(text, filepath.Join("sub", "new.go")) {
+		t.Errorf("recent files should be searched, got:\n%s", text)
+	}
+
+	// Day suffix reaches the older files
+	r, _ = callGrep(sess, resolver, GrepArgs{Pattern: "match", ModifiedWithin: "7d", OutputMode: "count"})
+	if text := resultText(r); !strings.Contains(text, "old.txt") {
+		t.Errorf("7d window should include old.txt, got:\n%s", text)
+	}
+
+	// Skipped files do not count toward max_files
+	r, _ = callGrep(sess, resolver, GrepArgs{Pattern: "match", ModifiedWithin: "1h", MaxFiles: 2})
+	if text := resultText(r); strings.Contains(text, "max_files") {
+		t.Errorf("only two recent files exist, cap should not trigger, got:\n%s", text)
+	}
+
+	r, _ = callGrep(sess, resolver, GrepArgs{Pattern: "match", ModifiedWithin: "recently"})
+	if !hasErrorCode(r, ErrInvalidInput) {
+		t.Errorf("expected %s for bad duration, got: %s", ErrInvalidInput, resultText(r))
+	}
+}
+
+// --- 3.26: group_by_file tests ---
+
+func TestGrepGroupByFile(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("match one\nfiller\nmatch two\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "b.txt"), []byte("nothing\nmatch three\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "match", OutputMode: "content", GroupByFile: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	for _, header := range []string{"a.txt", "b.txt"} {
+		count := 0
+		for _, line := range strings.Split(text, "\n") {
+			if line == header {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Errorf("expected header %q exactly once, got %d in:\n%s", header, count, text)
+		}
+	}
+	for _, want := range []string{"1:match one", "3:match two", "2:match three"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "a.txt:") || strings.Contains(text, "b.txt:") {
+		t.Errorf("grouped lines should omit the path, got:\n%s", text)
+	}
+	if !strings.Contains(text, "3:match two\n\nb.txt\n") {
+		t.Errorf("files should be separated by a blank line, got:\n%s", text)
+	}
+}
+
+func TestGrepGroupByFileContext(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("before\nmatch\nafter\nx\ny\nz\nmatch\n"), 0644)
+
+	ctx := 1
+	r, _ := callGrep(sess, resolver, GrepArgs{Pattern: "match", OutputMode: "content", GroupByFile: true, Context: &ctx})
+	want := "a.txt\n1-before\n2:match\n3-after\n--\n6-z\n7:match"
+	if text := resultText(r); !strings.Contains(text, want) {
+		t.Errorf("expected context nested under header:\n%s\ngot:\n%s", want, text)
+	}
+
+	// Without line numbers only the content remains under the header
+	off := false
+	r, _ = callGrep(sess, resolver, GrepArgs{Pattern: "match", OutputMode: "content", GroupByFile: true, LineNumbers: &off})
+	if text := resultText(r); !strings.Contains(text, "a.txt\nmatch\n--\nmatch") {
+		t.Errorf("expected bare lines under header, got:\n%s", text)
+	}
+}
+
+// --- 3.27: summarize tests ---
+
+func TestGrepSummarize(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("skip\nTODO first\nTODO second\nTODO third\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "b.txt"), []byte("TODO only\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "c.txt"), []byte("nothing here\n"), 0644)
+
+	ctx := 2
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "TODO", Summarize: true, Context: &ctx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(r)
+	lines := strings.Split(text, "\n")
+	sort.Strings(lines)
+	want := []string{
+		"a.txt: 3 matches, first at 2: TODO first",
+		"b.txt: 1 match, first at 1: TODO only",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", text, strings.Join(want, "\n"))
+	}
+
+	// Single file, without line numbers
+	off := false
+	r, _ = callGrep(sess, resolver, GrepArgs{Pattern: "TODO", Path: "a.txt", Summarize: true, LineNumbers: &off})
+	if got := resultText(r); got != "a.txt: 3 matches, first: TODO first" {
+		t.Errorf("single file summary = %q", got)
+	}
+
+	r, _ = callGrep(sess, resolver, GrepArgs{Pattern: "TODO", Summarize: true, OutputMode: "count"})
+	if !hasErrorCode(r, ErrInvalidInput) {
+		t.Errorf("expected %s for summarize with count mode, got: %s", ErrInvalidInput, resultText(r))
+	}
+}
+
+// Helper functions
+func intPtr(v int) *int   { return &v }
+func boolPtr(v bool) *bool { return &v }
+
+func TestGrepMaxMatches(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("foo 1\nx\nfoo 2\ny\nfoo 3\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "b.txt"), []byte("foo 4\n"), 0644)
+
+	grep := func(args GrepArgs) string {
+		t.Helper()
+		args.Pattern = "foo"
+		args.OutputMode = "content"
+		r, err := callGrep(sess, resolver, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resultText(r)
+	}
+
+	t.Run("counts match lines only", func(t *testing.T) {
+		got := grep(GrepArgs{Path: "a.txt", Context: intPtr(1), MaxMatches: 2})
+		want := "a.txt:1:foo 1\na.txt-2-x\na.txt:3:foo 2\na.txt-4-y"
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("head_limit counts output lines", func(t *testing.T) {
+		got := grep(GrepArgs{Path: "a.txt", Context: intPtr(1), HeadLimit: 2})
+		want := "a.txt:1:foo 1\na.txt-2-x"
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("trailing context stops before the next match", func(t *testing.T) {
+		got := grep(GrepArgs{Path: "a.txt", ContextAfter: intPtr(3), MaxMatches: 2})
+		want := "a.txt:1:foo 1\na.txt-2-x\na.txt:3:foo 2\na.txt-4-y"
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("across files", func(t *testing.T) {
+		got := grep(GrepArgs{MaxMatches: 3})
+		want := "a.txt:1:foo 1\n--\na.txt:3:foo 2\n--\na.txt:5:foo 3"
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+		got = grep(GrepArgs{MaxMatches: 4})
+		if !strings.Contains(got, "b.txt:1:foo 4") {
+			t.Errorf("expected the fourth match from b.txt, got:\n%s", got)
+		}
+	})
+}
+
+func TestGrepSymlinkEscapingScope(t *testing.T) {
+	allowed := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "passwd"), []byte("root:x:0:0\n"), 0644)
+	os.WriteFile(filepath.Join(allowed, "ok.txt"), []byte("root is fine\n"), 0644)
+	os.Symlink(filepath.Join(outside, "passwd"), filepath.Join(allowed, "link"))
+	os.Symlink(filepath.Join(allowed, "ok.txt"), filepath.Join(allowed, "inside-link"))
+
+	sess := session.New(allowed)
+	resolver, err := pathscope.NewResolver([]string{allowed}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "root", Path: "link", OutputMode: "content"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(r, ErrAccessDenied) {
+		t.Errorf("expected %s, got: %s", ErrAccessDenied, resultText(r))
+	}
+	if strings.Contains(resultText(r), "root:x") {
+		t.Error("content outside the allowed directories leaked")
+	}
+
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "root", Path: "inside-link", OutputMode: "content"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(r) || !strings.Contains(resultText(r), "root is fine") {
+		t.Errorf("in-scope symlink should be searched, got: %s", resultText(r))
+	}
+}
+
+func TestGrepLineWindow(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "big.txt"), []byte("foo 1\nbar\nfoo 3\nfoo 4\nbaz\nfoo 6\n"), 0644)
+
+	grep := func(args GrepArgs) *mcp.CallToolResult {
+		t.Helper()
+		args.Pattern = "foo"
+		if args.Path == "" {
+			args.Path = "big.txt"
+		}
+		if args.OutputMode == "" {
+			args.OutputMode = "content"
+		}
+		r, err := callGrep(sess, resolver, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("matches only within the window", func(t *testing.T) {
+		got := resultText(grep(GrepArgs{StartLine: 2, EndLine: 4}))
+		want := "big.txt:3:foo 3\nbig.txt:4:foo 4"
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("open-ended window", func(t *testing.T) {
+		got := resultText(grep(GrepArgs{StartLine: 5, OutputMode: "count"}))
+		if got != "big.txt:1" {
+			t.Errorf("got %q, want big.txt:1", got)
+		}
+	})
+
+	t.Run("multiline", func(t *testing.T) {
+		got := resultText(grep(GrepArgs{EndLine: 2, Multiline: true}))
+		if got != "big.txt:1:foo 1" {
+			t.Errorf("got %q, want only line 1", got)
+		}
+	})
+
+	t.Run("context may extend past the window", func(t *testing.T) {
+		got := resultText(grep(GrepArgs{StartLine: 4, EndLine: 4, ContextAfter: intPtr(1)}))
+		want := "big.txt:4:foo 4\nbig.txt-5-baz"
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("out of range window is empty", func(t *testing.T) {
+		for _, args := range []GrepArgs{{StartLine: 100}, {StartLine: 4, EndLine: 2}} {
+			r := grep(args)
+			if isErrorResult(r) || resultText(r) != "" {
+				t.Errorf("%+v: expected empty result, got: %s", args, resultText(r))
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if r := grep(GrepArgs{StartLine: -1}); !hasErrorCode(r, ErrInvalidInput) {
+			t.Errorf("negative start_line: got %s", resultText(r))
+		}
+		if r := grep(GrepArgs{Path: ".", StartLine: 2}); !hasErrorCode(r, ErrInvalidInput) {
+			t.Errorf("directory search: got %s", resultText(r))
+		}
+	})
+}
+
+func TestGrepInPlace(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("oldName()\noldName()\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "sub", "b.go"), []byte("x := oldName\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "noop.go"), []byte("newName already\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "other.go"), []byte("unrelated\n"), 0644)
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(filepath.Join(tmp, "noop.go"), past, past)
+	replace := "newName"
+	args := GrepArgs{Pattern: `(old|new)Name`, Replace: &replace, InPlace: true}
+
+	call := func(cfg Config, args GrepArgs) *mcp.CallToolResult {
+		t.Helper()
+		r, _, err := grepHandler(sess, resolver, cfg)(context.Background(), nil, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		r := call(testConfig(), args)
+		if !hasErrorCode(r, ErrAccessDenied) {
+			t.Errorf("expected ACCESS_DENIED, got: %s", resultText(r))
+		}
+		readOnly := testConfig()
+		readOnly.AllowGrepInPlace = true
+		readOnly.ReadOnly = true
+		if r := call(readOnly, args); !hasErrorCode(r, ErrAccessDenied) {
+			t.Errorf("expected ACCESS_DENIED in read-only mode, got: %s", resultText(r))
+		}
+		if got := readString(t, filepath.Join(tmp, "a.go")); got != "oldName()\noldName()\n" {
+			t.Errorf("a.go modified while disabled: %q", got)
+		}
+	})
+
+	cfg := testConfig()
+	cfg.AllowGrepInPlace = true
+
+	t.Run("requires replace", func(t *testing.T) {
+		r := call(cfg, GrepArgs{Pattern: "oldName", InPlace: true})
+		if !hasErrorCode(r, ErrInvalidInput) {
+			t.Errorf("expected INVALID_INPUT, got: %s", resultText(r))
+		}
+	})
+
+	t.Run("view before edit", func(t *testing.T) {
+		strict := cfg
+		strict.RequireViewBeforeEdit = true
+		r := call(strict, args)
+		if !hasErrorCode(r, ErrFileNotViewed) {
+			t.Errorf("expected FILE_NOT_VIEWED, got: %s", resultText(r))
+		}
+		if got := readString(t, filepath.Join(tmp, "a.go")); got != "oldName()\noldName()\n" {
+			t.Errorf("a.go modified without being viewed: %q", got)
+		}
+	})
+
+	t.Run("edits matching files", func(t *testing.T) {
+		r := call(cfg, args)
+		text := resultText(r)
+		if isErrorResult(r) {
+			t.Fatalf("unexpected error: %s", text)
+		}
+		want := "Replaced 3 occurrences in 2 files\na.go: 2\n" + filepath.Join("sub", "b.go") + ": 1"
+		if text != want {
+			t.Errorf("got summary:\n%s\nwant:\n%s", text, want)
+		}
+		if got := readString(t, filepath.Join(tmp, "a.go")); got != "newName()\nnewName()\n" {
+			t.Errorf("a.go = %q", got)
+		}
+		if got := readString(t, filepath.Join(tmp, "sub", "b.go")); got != "x := newName\n" {
+			t.Errorf("sub/b.go = %q", got)
+		}
+		info, err := os.Stat(filepath.Join(tmp, "noop.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(past) {
+			t.Error("noop.go was rewritten although the replacement left it unchanged")
+		}
+	})
+
+	t.Run("rejects filters it cannot honor", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmp, "c.go"), []byte("oldName\n"), 0644)
+		for name, extra := range map[string]GrepArgs{
+			"hidden":          {Hidden: boolPtr(false)},
+			"start_line":      {StartLine: 2},
+			"multiline":       {Multiline: true},
+			"max_files":       {MaxFiles: 1},
+			"modified_within": {ModifiedWithin: "1h"},
+			"changed_since":   {ChangedSince: "HEAD"},
+		} {
+			extra.Pattern, extra.Replace, extra.InPlace = "oldName", &replace, true
+			r := call(cfg, extra)
+			if !hasErrorCode(r, ErrInvalidInput) || !strings.Contains(resultText(r), name) {
+				t.Errorf("%s: expected INVALID_INPUT naming it, got: %s", name, resultText(r))
+			}
+		}
+		if got := readString(t, filepath.Join(tmp, "c.go")); got != "oldName\n" {
+			t.Errorf("c.go modified by a rejected call: %q", got)
+		}
+	})
+
+	t.Run("replaces line by line like the preview", func(t *testing.T) {
+		dir := filepath.Join(tmp, "lines")
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "ws.txt"), []byte("a  \n\nb \r\n"), 0644)
+		empty := ""
+		r := call(cfg, GrepArgs{Pattern: `\s+$`, Path: "lines", Replace: &empty, InPlace: true})
+		if isErrorResult(r) {
+			t.Fatalf("unexpected error: %s", resultText(r))
+		}
+		// A whole-file (?m) replace would also swallow the blank line
+		if got := readString(t, filepath.Join(dir, "ws.txt")); got != "a\n\nb\r\n" {
+			t.Errorf("ws.txt = %q, want %q", got, "a\n\nb\r\n")
+		}
+	})
+
+	t.Run("exempt from the tool timeout", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmp, "d.go"), []byte("oldName\n"), 0644)
+		h := grepWithTimeout(grepHandler(sess, resolver, cfg), time.Nanosecond)
+		r, _, err := h(context.Background(), nil, GrepArgs{Pattern: "oldName", Path: "d.go", Replace: &replace, InPlace: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(r) {
+			t.Fatalf("unexpected error: %s", resultText(r))
+		}
+		if got := readString(t, filepath.Join(tmp, "d.go")); got != "newName\n" {
+			t.Errorf("d.go = %q", got)
+		}
+	})
+}
+
+func TestGrepPadLineNumbers(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	lines := make([]string, 120)
+	for i := range lines {
+		lines[i] = "filler"
+	}
+	lines[4], lines[119] = "hit", "hit"
+	os.WriteFile(filepath.Join(tmp, "long.txt"), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "short.txt"), []byte("filler\nhit\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "hit", OutputMode: "content", PadLineNumbers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(resultText(r), "\n")
+	sort.Strings(got)
+	want := []string{"--", "--", "long.txt:  5:hit", "long.txt:120:hit", "short.txt:2:hit"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Context lines share the width of the file's matches
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "hit", Path: "long.txt", OutputMode: "content", PadLineNumbers: true, ContextBefore: intPtr(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"long.txt-  4-filler", "long.txt:  5:hit", "--", "long.txt-119-filler", "long.txt:120:hit"}
+	if got := resultText(r); got != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// Default output is unpadded
+	r, err = callGrep(sess, resolver, GrepArgs{Pattern: "hit", Path: "long.txt", OutputMode: "content"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultText(r); got != "long.txt:5:hit\n--\nlong.txt:120:hit" {
+		t.Errorf("default output changed: %q", got)
+	}
+}
+
+func TestGrepRelativeTo(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.MkdirAll(filepath.Join(tmp, "a", "b"), 0755)
+	os.WriteFile(filepath.Join(tmp, "a", "x.txt"), []byte("hit\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "a", "b", "y.txt"), []byte("hit\n"), 0644)
+
+	grepPaths := func(args GrepArgs) []string {
+		t.Helper()
+		args.Pattern = "hit"
+		r, err := callGrep(sess, resolver, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(r) {
+			t.Fatalf("unexpected error: %s", resultText(r))
+		}
+		got := strings.Split(resultText(r), "\n")
+		sort.Strings(got)
+		return got
+	}
+
+	tests := []struct {
+		name string
+		args GrepArgs
+		want []string
+	}{
+		{"outer root", GrepArgs{Path: "a", RelativeTo: "."}, []string{"a/b/y.txt", "a/x.txt"}},
+		{"nested root", GrepArgs{Path: "a/b", RelativeTo: "."}, []string{"a/b/y.txt"}},
+		{"base below root", GrepArgs{Path: "a", RelativeTo: "a/b"}, []string{"../x.txt", "y.txt"}},
+		{"absolute base", GrepArgs{Path: "a/b", RelativeTo: tmp}, []string{"a/b/y.txt"}},
+		{"default is search root", GrepArgs{Path: "a/b"}, []string{"y.txt"}},
+		{"single file", GrepArgs{Path: "a/b/y.txt", RelativeTo: "a", OutputMode: "content"}, []string{"b/y.txt:1:hit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grepPaths(tt.args); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("single file under symlinked cwd", func(t *testing.T) {
+		link := filepath.Join(t.TempDir(), "link")
+		if err := os.Symlink(filepath.Join(tmp, "a"), link); err != nil {
+			t.Fatal(err)
+		}
+		r, err := callGrep(session.New(link), resolver, GrepArgs{Pattern: "hit", Path: "b/y.txt", RelativeTo: ".", OutputMode: "content"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resultText(r), "b/y.txt:1:hit"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("base out of scope", func(t *testing.T) {
+		scoped, err := pathscope.NewResolver([]string{tmp}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := callGrep(sess, scoped, GrepArgs{Pattern: "hit", RelativeTo: "/"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(r, ErrAccessDenied) {
+			t.Errorf("expected ACCESS_DENIED, got: %s", resultText(r))
+		}
+	})
+}
+
+func TestGrepFIFO(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	if err := syscall.Mkfifo(filepath.Join(tmp, "pipe"), 0644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+	os.WriteFile(filepath.Join(tmp, "file.txt"), []byte("hit\n"), 0644)
+
+	grepWithin := func(args GrepArgs) *mcp.CallToolResult {
+		t.Helper()
+		done := make(chan *mcp.CallToolResult, 1)
+		go func() {
+			r, _ := callGrep(sess, resolver, args)
+			done <- r
+		}()
+		select {
+		case r := <-done:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatalf("grep %q blocked on a FIFO", args.Path)
+			return nil
+		}
+	}
+
+	r := grepWithin(GrepArgs{Pattern: "hit", Path: "pipe"})
+	if !hasErrorCode(r, ErrInvalidInput) || !strings.Contains(resultText(r), "named pipe") {
+		t.Errorf("expected INVALID_INPUT naming a named pipe, got: %s", resultText(r))
+	}
+
+	// Directory searches skip the FIFO
+	r = grepWithin(GrepArgs{Pattern: "hit"})
+	if got := resultText(r); got != "file.txt" {
+		t.Errorf("expected only file.txt, got: %q", got)
+	}
+}
+
+func TestGrepPaginationMeta(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		os.WriteFile(filepath.Join(tmp, name), []byte("hit\n"), 0644)
+	}
+
+	tests := []struct {
+		name string
+		args GrepArgs
+		want paginationMeta
+	}{
+		{"files first page", GrepArgs{HeadLimit: 2}, paginationMeta{Total: 3, Offset: 0, Returned: 2, HasMore: true}},
+		{"files last page", GrepArgs{HeadLimit: 2, Offset: 2}, paginationMeta{Total: 3, Offset: 2, Returned: 1, HasMore: false}},
+		{"files unlimited", GrepArgs{}, paginationMeta{Total: 3, Returned: 3}},
+		// The count walk stops at the first matching file past the page
+		{"count first page", GrepArgs{OutputMode: "count", HeadLimit: 1}, paginationMeta{Total: 2, Returned: 1, HasMore: true}},
+		{"count last page", GrepArgs{OutputMode: "count", HeadLimit: 1, Offset: 2}, paginationMeta{Total: 3, Offset: 2, Returned: 1}},
+		// Content output is paged by line, including the "--" between files
+		{"content first page", GrepArgs{OutputMode: "content", HeadLimit: 3}, paginationMeta{Total: 5, Returned: 3, HasMore: true}},
+		{"content all", GrepArgs{OutputMode: "content", HeadLimit: 5}, paginationMeta{Total: 5, Returned: 5}},
+		// Limits that stop the walk early leave more to find
+		{"max_files", GrepArgs{MaxFiles: 1}, paginationMeta{Total: 1, Returned: 1, HasMore: true}},
+		{"max_files covers all", GrepArgs{MaxFiles: 3}, paginationMeta{Total: 3, Returned: 3}},
+		{"max_matches", GrepArgs{OutputMode: "content", MaxMatches: 1}, paginationMeta{Total: 1, Returned: 1, HasMore: true}},
+		{"max_matches covers all", GrepArgs{OutputMode: "content", MaxMatches: 3}, paginationMeta{Total: 5, Returned: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args.Pattern = "hit"
+			r, err := callGrep(sess, resolver, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := r.Meta["pagination"].(paginationMeta)
+			if !ok {
+				t.Fatalf("no pagination metadata in %#v", r.Meta)
+			}
+			if got != tt.want {
+				t.Errorf("pagination = %+v, want %+v", got, tt.want)
+			}
+			// The max_files note follows the results after a blank line
+			text, _, _ := strings.Cut(resultText(r), "\n\n[")
+			if lines := strings.Split(text, "\n"); len(lines) != got.Returned {
+				t.Errorf("returned = %d but output has %d lines", got.Returned, len(lines))
+			}
+		})
+	}
+}
+
+func TestGrepJSONLines(t *testing.T) {
+	tmp, sess, resolver := grepTestSetup(t)
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("one\nneedle a\nthree\nfour\nfive\nneedle b\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "b.txt"), []byte("needle c\n"), 0644)
+
+	r, err := callGrep(sess, resolver, GrepArgs{Pattern: "needle", OutputMode: "content", Context: intPtr(1), JSONLines: true, Columns: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(r) {
+		t.Fatal(resultText(r))
+	}
+	var got []grepLineJSON
+	for _, line := range strings.Split(resultText(r), "\n") {
+		var entry grepLineJSON
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", line, err)
+		}
+		got = append(got, entry)
+	}
+	want := []grepLineJSON{
+		{Path: "a.txt", Line: 1, Text: "one", IsContext: true},
+		{Path: "a.txt", Line: 2, Column: 1, Text: "needle a"},
+		{Path: "a.txt", Line: 3, Text: "three", IsContext: true},
+		{Path: "a.txt", Line: 5, Text: "five", IsContext: true},
+		{Path: "a.txt", Line: 6, Column: 1, Text: "needle b"},
+		{Path: "b.txt", Line: 1, Column: 1, Text: "needle c"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(got), len(want), resultText(r))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if !strings.Contains(resultText(r), `"is_context":false`) {
+		t.Errorf("match lines should carry is_context false explicitly:\n%s", resultText(r))
+	}
+
+	t.Run("notes are JSON too", func(t *testing.T) {
+		r, err := callGrep(sess, resolver, GrepArgs{Pattern: "needle", OutputMode: "content", JSONLines: true, MaxFiles: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(resultText(r), "\n")
+		var note struct {
+			Note string `json:"note"`
+		}
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &note); err != nil || !strings.Contains(note.Note, "max_files") {
+			t.Errorf("expected a max_files note object, got: %s", resultText(r))
+		}
+	})
+
+	t.Run("requires content mode", func(t *testing.T) {
+		r, err := callGrep(sess, resolver, GrepArgs{Pattern: "needle", JSONLines: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(r, ErrInvalidInput) {
+			t.Errorf("expected %s, got: %s", ErrInvalidInput, resultText(r))
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Limits on the closest-line search in notFoundHint, so a failed edit on a
+// large file stays cheap.
+const (
+	maxHintLines     = 20000   // file lines considered
+	maxHintLineChars = 500     // longer lines (and old_str lines) are skipped
+	maxHintCells     = 4000000 // total edit-distance cells computed
+)
+
+// viewHint returns the hint appended to FILE_NOT_VIEWED errors, naming the
+// tool call that resolves it in the current mode.
+func viewHint(cfg Config, path string) string {
+	if cfg.AnthropicCompat {
+		return fmt.Sprintf("Hint: call str_replace_editor with command view and path %s, then retry.", path)
+	}
+	return fmt.Sprintf("Hint: call view with path %s, then retry.", path)
+}
+
+// notFoundHint explains why oldStr was not found in content, if it can tell:
+// a line-ending or whitespace mismatch, or else the line most similar to the
+// first line of oldStr. It returns "" if there is nothing useful to say.
+func notFoundHint(content, oldStr string) string {
+	if strings.Contains(content, "\r\n") && !strings.Contains(oldStr, "\r") &&
+		strings.Contains(strings.ReplaceAll(content, "\r\n", "\n"), oldStr) {
+		return "Hint: the file uses CRLF line endings; old_str matches if each line ends with \\r\\n."
+	}
+	if collapsed := collapseWhitespace(oldStr); collapsed != "" && strings.Contains(collapseWhitespace(content), collapsed) {
+		return "Hint: old_str matches if whitespace is ignored; check indentation (tabs vs spaces) and trailing spaces."
+	}
+
+	var target string
+	for _, line := range strings.Split(oldStr, "\n") {
+		if t := strings.TrimSpace(line); t != "" {
+			target = t
+			break
+		}
+	}
+	if target == "" || len(target) > maxHintLineChars {
+		return ""
+	}
+
+	bestLine, bestDist := 0, -1
+	budget := maxHintCells
+	for i, line := range strings.Split(content, "\n") {
+		if i >= maxHintLines {
+			break
+		}
+		t := strings.TrimSpace(line)
+		if t == "" || len(t) > maxHintLineChars {
+			continue
+		}
+		budget -= len(t) * len(target)
+		if budget < 0 {
+			break
+		}
+		if d := levenshtein(t, target); bestDist < 0 || d < bestDist {
+			bestLine, bestDist = i+1, d
+		}
+	}
+	// Only suggest lines that share most of their text with the target
+	if bestDist < 0 || bestDist > len(target)/2 {
+		return ""
+	}
+	line := strings.TrimRight(strings.Split(content, "\n")[bestLine-1], "\r")
+	return fmt.Sprintf("Hint: the closest line to the start of old_str is line %d: %s", bestLine, line)
+}
+
+// collapseWhitespace replaces each run of whitespace with a single space and
+// trims the ends.
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.FieldsFunc(s, unicode.IsSpace), " ")
+}
+
+// levenshtein returns the byte-wise edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -1,12 +1,16 @@
 package tools_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/mjkoo/boris/internal/pathscope"
 	"github.com/mjkoo/boris/internal/session"
@@ -439,6 +443,9 @@ func TestIntegrationAnthropicCompatViewBeforeEdit(t *testing.T) {
 	if !strings.Contains(contentText(res), "FILE_NOT_VIEWED") {
 		t.Errorf("expected FILE_NOT_VIEWED error, got: %s", contentText(res))
 	}
+	if !strings.Contains(contentText(res), "Hint: call str_replace_editor with command view") {
+		t.Errorf("expected compat-mode view hint, got: %s", contentText(res))
+	}
 
 	// View via str_replace_editor view command
 	res, err = clientSession.CallTool(ctx, &mcp.CallToolParams{
@@ -724,6 +731,55 @@ func TestIntegrationDisableTools(t *testing.T) {
 		}
 	})
 
+	t.Run("readonly mode", func(t *testing.T) {
+		for _, compat := range []bool{false, true} {
+			tmp := t.TempDir()
+			server := mcp.NewServer(&mcp.Implementation{Name: "boris-test", Version: "test"}, nil)
+			sess := session.New(tmp)
+			t.Cleanup(sess.Close)
+			resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+
+			tools.RegisterAll(server, resolver, sess, tools.Config{
+				MaxFileSize:     10 * 1024 * 1024,
+				DefaultTimeout:  30,
+				Shell:           "/bin/sh",
+				AnthropicCompat: compat,
+				ReadOnly:        true,
+			})
+
+			ctx := context.Background()
+			t1, t2 := mcp.NewInMemoryTransports()
+			if _, err := server.Connect(ctx, t1, nil); err != nil {
+				t.Fatal(err)
+			}
+			client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+			clientSession, err := client.Connect(ctx, t2, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clientSession.Close()
+
+			toolList, err := clientSession.ListTools(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			toolNames := make(map[string]bool)
+			for _, tool := range toolList.Tools {
+				toolNames[tool.Name] = true
+			}
+			for _, name := range []string{"bash", "task_output", "str_replace", "create_file", "str_replace_editor", "search_replace_files", "touch"} {
+				if toolNames[name] {
+					t.Errorf("compat=%v: %s should be absent in readonly mode", compat, name)
+				}
+			}
+			for _, name := range []string{"view", "grep", "glob", "get_scope", "list_directory"} {
+				if !toolNames[name] {
+					t.Errorf("compat=%v: %s should remain available in readonly mode", compat, name)
+				}
+			}
+		}
+	})
+
 	t.Run("unknown tool name validation", func(t *testing.T) {
 		err := tools.ValidateDisableTools(
 			map[string]struct{}{"nonexistent": {}},
@@ -758,6 +814,78 @@ func TestIntegrationDisableTools(t *testing.T) {
 	})
 }
 
+func TestIntegrationEnableTools(t *testing.T) {
+	listTools := func(t *testing.T, cfg tools.Config) map[string]bool {
+		t.Helper()
+		tmp := t.TempDir()
+		server := mcp.NewServer(&mcp.Implementation{Name: "boris-test", Version: "test"}, nil)
+		sess := session.New(tmp)
+		t.Cleanup(sess.Close)
+		resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+
+		cfg.MaxFileSize = 10 * 1024 * 1024
+		cfg.DefaultTimeout = 30
+		cfg.Shell = "/bin/sh"
+		tools.RegisterAll(server, resolver, sess, cfg)
+
+		ctx := context.Background()
+		t1, t2 := mcp.NewInMemoryTransports()
+		if _, err := server.Connect(ctx, t1, nil); err != nil {
+			t.Fatal(err)
+		}
+		client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+		clientSession, err := client.Connect(ctx, t2, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { clientSession.Close() })
+
+		toolList, err := clientSession.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		toolNames := make(map[string]bool)
+		for _, tool := range toolList.Tools {
+			toolNames[tool.Name] = true
+		}
+		return toolNames
+	}
+
+	t.Run("only enabled tools register", func(t *testing.T) {
+		got := listTools(t, tools.Config{
+			EnableTools: map[string]struct{}{"grep": {}, "view": {}},
+		})
+		if len(got) != 2 || !got["grep"] || !got["view"] {
+			t.Errorf("expected exactly grep and view, got %v", got)
+		}
+	})
+
+	t.Run("bash brings its companions", func(t *testing.T) {
+		got := listTools(t, tools.Config{
+			EnableTools: map[string]struct{}{"bash": {}},
+		})
+		if len(got) != 3 || !got["bash"] || !got["task_output"] || !got["kill_all_tasks"] {
+			t.Errorf("expected bash, task_output, and kill_all_tasks, got %v", got)
+		}
+	})
+
+	t.Run("anthropic-compat str_replace_editor", func(t *testing.T) {
+		got := listTools(t, tools.Config{
+			AnthropicCompat: true,
+			EnableTools:     map[string]struct{}{"str_replace_editor": {}},
+		})
+		if len(got) != 1 || !got["str_replace_editor"] {
+			t.Errorf("expected only str_replace_editor, got %v", got)
+		}
+	})
+
+	t.Run("validated like disable-tools", func(t *testing.T) {
+		if err := tools.ValidateDisableTools(map[string]struct{}{"nope": {}}, false); err == nil {
+			t.Error("expected an error for an unknown tool name")
+		}
+	})
+}
+
 func TestIntegrationServerInstructions(t *testing.T) {
 	tmp := t.TempDir()
 
@@ -813,6 +941,228 @@ func TestIntegrationServerInstructions(t *testing.T) {
 	}
 }
 
+// connectIntegration registers all tools for a fresh session rooted at tmp
+// and returns a client session connected over in-memory transports.
+func connectIntegration(t *testing.T, tmp string, cfg tools.Config) *mcp.ClientSession {
+	t.Helper()
+	return connectIntegrationWithClient(t, tmp, cfg, nil)
+}
+
+// connectIntegrationWithClient is connectIntegration with client options,
+// e.g. to observe notifications.
+func connectIntegrationWithClient(t *testing.T, tmp string, cfg tools.Config, opts *mcp.ClientOptions) *mcp.ClientSession {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "boris-test",
+		Version: "test",
+	}, nil)
+
+	sess := session.New(tmp)
+	t.Cleanup(sess.Close)
+	resolver, err := pathscope.NewResolver([]string{tmp}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools.RegisterAll(server, resolver, sess, cfg)
+
+	ctx := context.Background()
+	t1, t2 := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, t1, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, opts)
+	clientSession, err := client.Connect(ctx, t2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { clientSession.Close() })
+	return clientSession
+}
+
+func TestIntegrationBashProgressFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name                    string
+		byteCount, streamPrefix bool
+		wantMessages            []string
+		wantProgress            []float64
+	}{
+		{"default", false, false, []string{"out", "err"}, []float64{1, 2}},
+		{"bytes and prefix", true, true, []string{"stdout: out", "stderr: err"}, []float64{4, 8}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []*mcp.ProgressNotificationParams
+			cs := connectIntegrationWithClient(t, t.TempDir(), tools.Config{
+				MaxFileSize:          10 * 1024 * 1024,
+				DefaultTimeout:       30,
+				Shell:                "/bin/sh",
+				ProgressByteCount:    tt.byteCount,
+				ProgressStreamPrefix: tt.streamPrefix,
+			}, &mcp.ClientOptions{
+				ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+					mu.Lock()
+					defer mu.Unlock()
+					got = append(got, req.Params)
+				},
+			})
+
+			// The sleep orders the two streams; the trailing sentinel lines
+			// are reported too and are ignored here
+			params := &mcp.CallToolParams{
+				Meta:      mcp.Meta{"progressToken": "tok"},
+				Name:      "bash",
+				Arguments: map[string]any{"command": "echo out; sleep 0.2; echo err >&2; sleep 0.2"},
+			}
+			if _, err := cs.CallTool(context.Background(), params); err != nil {
+				t.Fatal(err)
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				mu.Lock()
+				n := len(got)
+				mu.Unlock()
+				if n >= 2 || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(got) < 2 {
+				t.Fatalf("expected at least 2 notifications, got %d", len(got))
+			}
+			for i := range tt.wantMessages {
+				if got[i].Message != tt.wantMessages[i] || got[i].Progress != tt.wantProgress[i] {
+					t.Errorf("notification %d = (%q, %v), want (%q, %v)", i, got[i].Message, got[i].Progress, tt.wantMessages[i], tt.wantProgress[i])
+				}
+				if got[i].ProgressToken != "tok" {
+					t.Errorf("notification %d token = %v", i, got[i].ProgressToken)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrationBashDisableProgress(t *testing.T) {
+	var mu sync.Mutex
+	var got []*mcp.ProgressNotificationParams
+	cs := connectIntegrationWithClient(t, t.TempDir(), tools.Config{
+		MaxFileSize:     10 * 1024 * 1024,
+		DefaultTimeout:  30,
+		Shell:           "/bin/sh",
+		DisableProgress: true,
+	}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, req.Params)
+		},
+	})
+
+	params := &mcp.CallToolParams{
+		Meta:      mcp.Meta{"progressToken": "tok"},
+		Name:      "bash",
+		Arguments: map[string]any{"command": "echo out; echo err >&2"},
+	}
+	res, err := cs.CallTool(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := contentText(res); !strings.Contains(text, "out") || !strings.Contains(text, "err") {
+		t.Errorf("output should still be returned, got: %s", text)
+	}
+
+	// Give any stray notification time to arrive
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 0 {
+		t.Errorf("expected no progress notifications, got %d: %+v", len(got), got[0])
+	}
+}
+
+func TestIntegrationAuditLog(t *testing.T) {
+	tmp := t.TempDir()
+	var buf bytes.Buffer
+	cs := connectIntegration(t, tmp, tools.Config{
+		MaxFileSize:    10 * 1024 * 1024,
+		DefaultTimeout: 30,
+		Shell:          "/bin/sh",
+		AuditLog:       &buf,
+	})
+
+	ctx := context.Background()
+	for _, params := range []*mcp.CallToolParams{
+		{Name: "bash", Arguments: map[string]interface{}{"command": "echo hi > a.txt"}},
+		{Name: "grep", Arguments: map[string]interface{}{"pattern": "hi"}},
+	} {
+		if _, err := cs.CallTool(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 audit lines, got %d: %s", len(lines), buf.String())
+	}
+	for i, want := range []string{"bash", "grep"} {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %v", lines[i], err)
+		}
+		if entry["tool"] != want {
+			t.Errorf("line %d: tool = %v, want %s", i, entry["tool"], want)
+		}
+		if _, ok := entry["duration_ms"]; !ok {
+			t.Errorf("line %d: missing duration_ms", i)
+		}
+	}
+}
+
+func TestIntegrationStructuredError(t *testing.T) {
+	tmp := t.TempDir()
+	cs := connectIntegration(t, tmp, tools.Config{
+		MaxFileSize:    10 * 1024 * 1024,
+		DefaultTimeout: 30,
+		Shell:          "/bin/sh",
+	})
+
+	res, err := cs.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "view",
+		Arguments: map[string]interface{}{"path": filepath.Join(tmp, "missing.txt")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError {
+		t.Fatal("expected error result")
+	}
+	text := contentText(res)
+	if !strings.HasPrefix(text, "["+tools.ErrPathNotFound+"] ") {
+		t.Errorf("text should keep the code prefix, got: %s", text)
+	}
+
+	// Round-trip the structured content as a client would see it
+	data, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var structured struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &structured); err != nil {
+		t.Fatalf("structured content %s: %v", data, err)
+	}
+	if structured.Code != tools.ErrPathNotFound {
+		t.Errorf("structured code = %q, want %q", structured.Code, tools.ErrPathNotFound)
+	}
+	if want := strings.TrimPrefix(text, "["+tools.ErrPathNotFound+"] "); structured.Message != want {
+		t.Errorf("structured message = %q, want %q", structured.Message, want)
+	}
+}
+
 func contentText(r *mcp.CallToolResult) string {
 	if r == nil || len(r.Content) == 0 {
 		return ""
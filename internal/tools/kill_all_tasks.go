@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// KillAllTasksArgs is the input schema for the kill_all_tasks tool. It takes no arguments.
+type KillAllTasksArgs struct{}
+
+func killAllTasksHandler(sess *session.Session) mcp.ToolHandlerFor[KillAllTasksArgs, any] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, _ KillAllTasksArgs) (*mcp.CallToolResult, any, error) {
+		ids := sess.KillTasks()
+		text := "No running background tasks"
+		if len(ids) > 0 {
+			sort.Strings(ids)
+			noun := "tasks"
+			if len(ids) == 1 {
+				noun = "task"
+			}
+			text = fmt.Sprintf("Killed %d background %s: %s", len(ids), noun, strings.Join(ids, ", "))
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: text}},
+		}, nil, nil
+	}
+}
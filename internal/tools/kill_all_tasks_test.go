@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func TestKillAllTasks(t *testing.T) {
+	sess := session.New(t.TempDir())
+	t.Cleanup(sess.Close)
+	bashH := bashHandler(sess, testResolver(t), testConfig())
+	taskH := taskOutputHandler(sess, testResolver(t), testConfig())
+	killH := killAllTasksHandler(sess)
+
+	startTask := func(command string) string {
+		t.Helper()
+		result, _, err := bashH(context.Background(), nil, BashArgs{Command: command, RunInBackground: true})
+		if err != nil || isErrorResult(result) {
+			t.Fatalf("start %q: %v %s", command, err, resultText(result))
+		}
+		return strings.TrimPrefix(strings.SplitN(resultText(result), "\n", 2)[0], "task_id: ")
+	}
+
+	done := startTask("echo finished")
+	doneTask, _ := sess.GetTask(done)
+	<-doneTask.Done
+	var running []*session.BackgroundTask
+	for i := 0; i < 3; i++ {
+		id := startTask("sleep 60")
+		task, _ := sess.GetTask(id)
+		running = append(running, task)
+	}
+
+	result, _, err := killH(context.Background(), nil, KillAllTasksArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if !strings.HasPrefix(text, "Killed 3 background tasks: ") {
+		t.Errorf("unexpected summary: %s", text)
+	}
+	for _, task := range running {
+		select {
+		case <-task.Done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("task %s still running after kill_all_tasks", task.ID)
+		}
+		if !strings.Contains(text, task.ID) {
+			t.Errorf("summary should list %s: %s", task.ID, text)
+		}
+		if _, ok := sess.GetTask(task.ID); ok {
+			t.Errorf("killed task %s should be removed from the session", task.ID)
+		}
+	}
+
+	// The completed task is still retrievable
+	result, _, _ = taskH(context.Background(), nil, TaskOutputArgs{TaskID: done})
+	if !strings.Contains(resultText(result), "finished") {
+		t.Errorf("completed task output should survive kill_all_tasks, got: %s", resultText(result))
+	}
+
+	// The session remains usable
+	result, _, _ = bashH(context.Background(), nil, BashArgs{Command: "echo still-alive"})
+	if !strings.Contains(resultText(result), "still-alive") {
+		t.Errorf("bash should work after kill_all_tasks, got: %s", resultText(result))
+	}
+	startTask("sleep 60")
+
+	result, _, _ = killH(context.Background(), nil, KillAllTasksArgs{})
+	if got := resultText(result); !strings.HasPrefix(got, "Killed 1 background task: ") {
+		t.Errorf("unexpected summary: %s", got)
+	}
+	result, _, _ = killH(context.Background(), nil, KillAllTasksArgs{})
+	if got := resultText(result); got != "No running background tasks" {
+		t.Errorf("got %q with no running tasks", got)
+	}
+}
@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ListDirectoryArgs is the input schema for the list_directory tool.
+type ListDirectoryArgs struct {
+	Path  string `json:"path,omitempty" jsonschema:"the directory to list (defaults to cwd)"`
+	Depth int    `json:"depth,omitempty" jsonschema:"how many levels to descend; 1 lists only direct children (default 1, max 10)"`
+}
+
+const (
+	defaultListDepth  = 1
+	maxListDepth      = 10
+	maxListDirEntries = 10000
+)
+
+// dirEntry is one element of the list_directory JSON output.
+type dirEntry struct {
+	Name          string `json:"name"` // slash-separated path relative to the listed directory
+	Type          string `json:"type"` // file, directory, symlink, or other
+	Size          int64  `json:"size"`
+	Mtime         string `json:"mtime"` // RFC 3339
+	SymlinkTarget string `json:"symlink_target,omitempty"`
+}
+
+func listDirectoryHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[ListDirectoryArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
+	return func(ctx context.Context, _ *mcp.CallToolRequest, args ListDirectoryArgs) (*mcp.CallToolResult, any, error) {
+		return doListDirectory(ctx, sess, resolver, excludeDirs, cfg.ExtraIgnoreFiles, args)
+	}
+}
+
+func doListDirectory(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, excludeDirs map[string]bool, ignoreFiles []string, args ListDirectoryArgs) (*mcp.CallToolResult, any, error) {
+	depth := args.Depth
+	if depth < 0 || depth > maxListDepth {
+		return toolErr(ErrInvalidInput, "depth must be between 1 and %d, got %d", maxListDepth, depth)
+	}
+	if depth == 0 {
+		depth = defaultListDepth
+	}
+
+	root, err := resolver.Resolve(sess.Cwd(), args.Path)
+	if err != nil {
+		return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return toolErr(ErrPathNotFound, "%s does not exist", root)
+		}
+		return toolErr(ErrIO, "could not stat %s: %v", root, err)
+	}
+	if !info.IsDir() {
+		return toolErr(ErrInvalidInput, "%s is not a directory", root)
+	}
+
+	entries := []dirEntry{}
+	truncated := false
+	gi := newGitignoreStack(ignoreFiles)
+
+	var walk func(dir string, level int) error
+	walk = func(dir string, level int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		gi.push(dir)
+		defer gi.pop()
+
+		children, err := os.ReadDir(dir)
+		if err != nil {
+			if dir == root {
+				return err
+			}
+			return nil // silently skip unreadable subdirectories
+		}
+		for _, child := range children {
+			name := child.Name()
+			entryPath := filepath.Join(dir, name)
+			if excludeDirs[name] || gi.isIgnored(entryPath, child.IsDir()) {
+				continue
+			}
+			// Path scoping: silently skip denied entries
+			if _, err := resolver.Resolve(sess.Cwd(), entryPath); err != nil {
+				continue
+			}
+			if len(entries) >= maxListDirEntries {
+				truncated = true
+				return nil
+			}
+
+			fInfo, err := child.Info()
+			if err != nil {
+				continue
+			}
+			rel, _ := filepath.Rel(root, entryPath)
+			e := dirEntry{
+				Name:  filepath.ToSlash(rel),
+				Size:  fInfo.Size(),
+				Mtime: fInfo.ModTime().UTC().Format(time.RFC3339),
+			}
+			switch {
+			case fInfo.Mode()&os.ModeSymlink != 0:
+				e.Type = "symlink"
+				e.SymlinkTarget, _ = os.Readlink(entryPath)
+			case fInfo.IsDir():
+				e.Type = "directory"
+			case fInfo.Mode().IsRegular():
+				e.Type = "file"
+			default:
+				e.Type = "other"
+			}
+			entries = append(entries, e)
+
+			// Symlinked directories are reported but not descended into
+			if e.Type == "directory" && level < depth {
+				if err := walk(entryPath, level+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err = walk(root, 1)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return toolErr(ErrIO, "could not list directory %s: %v", root, err)
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return toolErr(ErrIO, "could not encode entries: %v", err)
+	}
+	content := []mcp.Content{&mcp.TextContent{Text: string(data)}}
+	if truncated {
+		content = append(content, &mcp.TextContent{Text: fmt.Sprintf("[Truncated: listing stopped after %d entries]", maxListDirEntries)})
+	}
+	return &mcp.CallToolResult{Content: content}, nil, nil
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func listDirectorySetup(t *testing.T) (string, *session.Session) {
+	t.Helper()
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "src", "pkg"), 0755)
+	os.MkdirAll(filepath.Join(tmp, "node_modules", "dep"), 0755)
+	os.WriteFile(filepath.Join(tmp, "README.md"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(tmp, "src", "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "src", "pkg", "util.go"), []byte("package pkg\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "debug.log"), []byte("noise"), 0644)
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("*.log\n"), 0644)
+	os.Symlink("README.md", filepath.Join(tmp, "link.md"))
+	return tmp, session.New(tmp)
+}
+
+func callListDirectory(t *testing.T, sess *session.Session, resolver *pathscope.Resolver, args ListDirectoryArgs) map[string]dirEntry {
+	t.Helper()
+	handler := listDirectoryHandler(sess, resolver, testConfig())
+	result, _, err := handler(context.Background(), nil, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	var entries []dirEntry
+	if err := json.Unmarshal([]byte(resultText(result)), &entries); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, resultText(result))
+	}
+	byName := make(map[string]dirEntry, len(entries))
+	for _, e := range entries {
+		byName[e.Name] = e
+	}
+	return byName
+}
+
+func TestListDirectoryEntries(t *testing.T) {
+	_, sess := listDirectorySetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+
+	entries := callListDirectory(t, sess, resolver, ListDirectoryArgs{})
+	want := map[string]string{
+		".gitignore": "file",
+		"README.md":  "file",
+		"src":        "directory",
+		"link.md":    "symlink",
+	}
+	if len(entries) != len(want) {
+		t.Errorf("expected %d entries, got %v", len(want), entries)
+	}
+	for name, typ := range want {
+		e, ok := entries[name]
+		if !ok {
+			t.Errorf("missing entry %s", name)
+			continue
+		}
+		if e.Type != typ {
+			t.Errorf("%s: type = %q, want %q", name, e.Type, typ)
+		}
+		if _, err := time.Parse(time.RFC3339, e.Mtime); err != nil {
+			t.Errorf("%s: bad mtime %q", name, e.Mtime)
+		}
+	}
+	if e := entries["README.md"]; e.Size != 5 {
+		t.Errorf("README.md size = %d, want 5", e.Size)
+	}
+	if e := entries["link.md"]; e.SymlinkTarget != "README.md" {
+		t.Errorf("link.md symlink_target = %q, want README.md", e.SymlinkTarget)
+	}
+	for _, skipped := range []string{"debug.log", "node_modules"} {
+		if _, ok := entries[skipped]; ok {
+			t.Errorf("%s should be skipped", skipped)
+		}
+	}
+}
+
+func TestListDirectoryDepth(t *testing.T) {
+	_, sess := listDirectorySetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+
+	entries := callListDirectory(t, sess, resolver, ListDirectoryArgs{Depth: 2})
+	if _, ok := entries["src/main.go"]; !ok {
+		t.Errorf("depth 2 should include src/main.go, got %v", entries)
+	}
+	if e, ok := entries["src/pkg"]; !ok || e.Type != "directory" {
+		t.Errorf("depth 2 should include src/pkg as a directory, got %v", entries)
+	}
+	if _, ok := entries["src/pkg/util.go"]; ok {
+		t.Error("depth 2 should not include src/pkg/util.go")
+	}
+
+	entries = callListDirectory(t, sess, resolver, ListDirectoryArgs{Path: "src", Depth: 3})
+	if _, ok := entries["pkg/util.go"]; !ok {
+		t.Errorf("names should be relative to the listed directory, got %v", entries)
+	}
+}
+
+func TestListDirectoryScopingAndErrors(t *testing.T) {
+	tmp, sess := listDirectorySetup(t)
+	resolver, _ := pathscope.NewResolver([]string{tmp}, []string{filepath.Join(tmp, "src")})
+
+	entries := callListDirectory(t, sess, resolver, ListDirectoryArgs{})
+	if _, ok := entries["src"]; ok {
+		t.Error("denied directory should be omitted")
+	}
+
+	handler := listDirectoryHandler(sess, resolver, testConfig())
+	for _, tt := range []struct {
+		args ListDirectoryArgs
+		code string
+	}{
+		{ListDirectoryArgs{Path: "missing"}, ErrPathNotFound},
+		{ListDirectoryArgs{Path: "README.md"}, ErrInvalidInput},
+		{ListDirectoryArgs{Path: "/"}, ErrAccessDenied},
+		{ListDirectoryArgs{Depth: maxListDepth + 1}, ErrInvalidInput},
+	} {
+		result, _, err := handler(context.Background(), nil, tt.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, tt.code) {
+			t.Errorf("%+v: expected %s, got: %s", tt.args, tt.code, resultText(result))
+		}
+	}
+}
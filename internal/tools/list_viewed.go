@@ -0,0 +1,24 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ListViewedArgs is the input schema for the list_viewed tool. It takes no arguments.
+type ListViewedArgs struct{}
+
+func listViewedHandler(sess *session.Session) mcp.ToolHandlerFor[ListViewedArgs, any] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, _ ListViewedArgs) (*mcp.CallToolResult, any, error) {
+		text := "No files viewed in this session"
+		if paths := sess.ViewedFiles(); len(paths) > 0 {
+			text = strings.Join(paths, "\n")
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: text}},
+		}, nil, nil
+	}
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func TestListViewed(t *testing.T) {
+	tmp := t.TempDir()
+	file := filepath.Join(tmp, "test.txt")
+	os.WriteFile(file, []byte("hello world\n"), 0644)
+
+	sess := session.New(tmp)
+	resolver := testResolver(t)
+	cfg := testConfig()
+	cfg.RequireViewBeforeEdit = true
+	list := func() string {
+		t.Helper()
+		result, _, err := listViewedHandler(sess)(context.Background(), nil, ListViewedArgs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resultText(result)
+	}
+
+	if got := list(); got != "No files viewed in this session" {
+		t.Errorf("before view: got %q", got)
+	}
+	edit := StrReplaceArgs{Path: "test.txt", OldStr: "hello", NewStr: "goodbye"}
+	result, _, err := strReplaceHandler(sess, resolver, cfg)(context.Background(), nil, edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(result, ErrFileNotViewed) {
+		t.Fatalf("expected %s before view, got: %s", ErrFileNotViewed, resultText(result))
+	}
+
+	result, _, err = viewHandler(sess, resolver, cfg)(context.Background(), nil, ViewArgs{Path: "test.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatal(resultText(result))
+	}
+	if got := list(); got != file {
+		t.Errorf("after view: got %q, want %q", got, file)
+	}
+
+	result, _, err = strReplaceHandler(sess, resolver, cfg)(context.Background(), nil, edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Errorf("expected edit to succeed after view, got: %s", resultText(result))
+	}
+}
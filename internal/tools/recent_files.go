@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RecentFilesArgs is the input schema for the recent_files tool.
+type RecentFilesArgs struct {
+	Path           string `json:"path,omitempty" jsonschema:"the directory to search in (defaults to cwd)"`
+	Limit          int    `json:"limit,omitempty" jsonschema:"maximum number of files to return (default 20)"`
+	ModifiedWithin string `json:"modified_within,omitempty" jsonschema:"only include files modified within this long ago (e.g. '30m', '2h', '7d')"`
+}
+
+const defaultRecentFilesLimit = 20
+
+func recentFilesHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[RecentFilesArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
+	return func(ctx context.Context, _ *mcp.CallToolRequest, args RecentFilesArgs) (*mcp.CallToolResult, any, error) {
+		return doRecentFiles(ctx, sess, resolver, excludeDirs, cfg.ExtraIgnoreFiles, args)
+	}
+}
+
+func doRecentFiles(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, excludeDirs map[string]bool, ignoreFiles []string, args RecentFilesArgs) (*mcp.CallToolResult, any, error) {
+	limit := args.Limit
+	if limit < 0 {
+		return toolErr(ErrInvalidInput, "limit must not be negative, got %d", limit)
+	}
+	if limit == 0 {
+		limit = defaultRecentFilesLimit
+	}
+
+	var cutoff time.Time
+	if args.ModifiedWithin != "" {
+		d, err := parseAge(args.ModifiedWithin)
+		if err != nil {
+			return toolErr(ErrInvalidInput, "invalid modified_within %q: %v", args.ModifiedWithin, err)
+		}
+		cutoff = time.Now().Add(-d)
+	}
+
+	resolvedRoot, err := resolver.Resolve(sess.Cwd(), args.Path)
+	if err != nil {
+		if args.Path != "" {
+			return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+		}
+		resolvedRoot = sess.Cwd()
+	}
+
+	info, err := os.Stat(resolvedRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return toolErr(ErrPathNotFound, "%s does not exist", resolvedRoot)
+		}
+		return toolErr(ErrIO, "could not stat %s: %v", resolvedRoot, err)
+	}
+	if !info.IsDir() {
+		return toolErr(ErrInvalidInput, "%s is not a directory", resolvedRoot)
+	}
+
+	type recentFile struct {
+		relPath string
+		modTime time.Time
+	}
+	var results []recentFile
+
+	err = walkGlobEntries(ctx, resolvedRoot, excludeDirs, ignoreFiles, func(entryPath, relPath, _ string, isDir bool) {
+		if isDir {
+			return
+		}
+		// Path scoping: silently skip denied files
+		resolvedFile, err := resolver.Resolve(sess.Cwd(), entryPath)
+		if err != nil {
+			return
+		}
+		fInfo, err := os.Stat(resolvedFile)
+		if err != nil {
+			return
+		}
+		if !cutoff.IsZero() && fInfo.ModTime().Before(cutoff) {
+			return
+		}
+		results = append(results, recentFile{relPath: relPath, modTime: fInfo.ModTime()})
+	})
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return toolErr(ErrIO, "could not walk directory %s: %v", resolvedRoot, err)
+	}
+
+	if len(results) == 0 {
+		return globNoFiles()
+	}
+
+	// Newest first; ties broken by path for stable output
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].modTime.Equal(results[j].modTime) {
+			return results[i].modTime.After(results[j].modTime)
+		}
+		return results[i].relPath < results[j].relPath
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	paths := make([]string, len(results))
+	for i, r := range results {
+		paths[i] = r.relPath
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(paths, "\n")}},
+	}, nil, nil
+}
+
+// parseAge parses a duration like time.ParseDuration, additionally accepting
+// a whole number of days with a "d" suffix (e.g. "7d").
+func parseAge(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, errors.New("expected a duration such as 30m, 2h, or 7d")
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		d, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+	return d, nil
+}
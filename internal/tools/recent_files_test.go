@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+// writeFileAged writes a file and sets its mtime to age ago.
+func writeFileAged(t *testing.T, path string, age time.Duration) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func recentFilesSetup(t *testing.T) (string, *session.Session, *pathscope.Resolver) {
+	t.Helper()
+	tmp := t.TempDir()
+	writeFileAged(t, filepath.Join(tmp, "old.txt"), 72*time.Hour)
+	writeFileAged(t, filepath.Join(tmp, "sub", "newest.go"), time.Minute)
+	writeFileAged(t, filepath.Join(tmp, "middle.md"), 3*time.Hour)
+	writeFileAged(t, filepath.Join(tmp, "sub", "deep", "recent.py"), 10*time.Minute)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	return tmp, session.New(tmp), resolver
+}
+
+func TestRecentFilesOrdering(t *testing.T) {
+	_, sess, resolver := recentFilesSetup(t)
+	handler := recentFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, RecentFilesArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		filepath.Join("sub", "newest.go"),
+		filepath.Join("sub", "deep", "recent.py"),
+		"middle.md",
+		"old.txt",
+	}, "\n")
+	if got := resultText(result); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRecentFilesLimit(t *testing.T) {
+	_, sess, resolver := recentFilesSetup(t)
+	handler := recentFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, RecentFilesArgs{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join("sub", "newest.go") + "\n" + filepath.Join("sub", "deep", "recent.py")
+	if got := resultText(result); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	result, _, _ = handler(context.Background(), nil, RecentFilesArgs{Limit: -1})
+	if !hasErrorCode(result, ErrInvalidInput) {
+		t.Errorf("expected %s for negative limit, got: %s", ErrInvalidInput, resultText(result))
+	}
+}
+
+func TestRecentFilesModifiedWithin(t *testing.T) {
+	_, sess, resolver := recentFilesSetup(t)
+	handler := recentFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, RecentFilesArgs{ModifiedWithin: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if strings.Contains(text, "middle.md") || strings.Contains(text, "old.txt") {
+		t.Errorf("files older than 1h should be excluded, got:\n%s", text)
+	}
+	if !strings.Contains(text, "newest.go") || !strings.Contains(text, "recent.py") {
+		t.Errorf("expected recent files, got:\n%s", text)
+	}
+
+	result, _, _ = handler(context.Background(), nil, RecentFilesArgs{ModifiedWithin: "2d"})
+	if text := resultText(result); strings.Contains(text, "old.txt") || !strings.Contains(text, "middle.md") {
+		t.Errorf("2d window: got:\n%s", text)
+	}
+
+	result, _, _ = handler(context.Background(), nil, RecentFilesArgs{ModifiedWithin: "soon"})
+	if !hasErrorCode(result, ErrInvalidInput) {
+		t.Errorf("expected %s for bad duration, got: %s", ErrInvalidInput, resultText(result))
+	}
+}
+
+func TestRecentFilesRespectsIgnoresAndScoping(t *testing.T) {
+	tmp, sess, _ := recentFilesSetup(t)
+	writeFileAged(t, filepath.Join(tmp, "node_modules", "pkg", "index.js"), 0)
+	writeFileAged(t, filepath.Join(tmp, "ignored.log"), 0)
+	writeFileAged(t, filepath.Join(tmp, "secret", "key.pem"), 0)
+	writeFileAged(t, filepath.Join(tmp, "build", "out.bin"), 0)
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("*.log\n"), 0644)
+	os.Chtimes(filepath.Join(tmp, ".gitignore"), time.Now().Add(-100*time.Hour), time.Now().Add(-100*time.Hour))
+
+	resolver, err := pathscope.NewResolver([]string{tmp}, []string{filepath.Join(tmp, "secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.ExcludeDirs = []string{"build"}
+	handler := recentFilesHandler(sess, resolver, cfg)
+
+	result, _, err := handler(context.Background(), nil, RecentFilesArgs{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultText(result); got != filepath.Join("sub", "newest.go") {
+		t.Errorf("ignored, excluded, and denied files should be skipped, got: %q", got)
+	}
+}
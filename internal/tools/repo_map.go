@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RepoMapArgs is the input schema for the repo_map tool.
+type RepoMapArgs struct {
+	Path          string `json:"path,omitempty" jsonschema:"the directory to map (defaults to cwd)"`
+	MaxGroups     int    `json:"max_groups,omitempty" jsonschema:"maximum number of top-level groups to list (default 50)"`
+	FilesPerGroup int    `json:"files_per_group,omitempty" jsonschema:"maximum number of representative files shown per group (default 5)"`
+}
+
+const (
+	defaultRepoMapMaxGroups     = 50
+	defaultRepoMapFilesPerGroup = 5
+)
+
+// repoMapRootGroup names the group of files directly under the mapped root.
+const repoMapRootGroup = "."
+
+func repoMapHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[RepoMapArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
+	return func(ctx context.Context, _ *mcp.CallToolRequest, args RepoMapArgs) (*mcp.CallToolResult, any, error) {
+		return doRepoMap(ctx, sess, resolver, excludeDirs, cfg.ExtraIgnoreFiles, args)
+	}
+}
+
+func doRepoMap(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, excludeDirs map[string]bool, ignoreFiles []string, args RepoMapArgs) (*mcp.CallToolResult, any, error) {
+	maxGroups := args.MaxGroups
+	if maxGroups < 0 {
+		return toolErr(ErrInvalidInput, "max_groups must not be negative, got %d", maxGroups)
+	}
+	if maxGroups == 0 {
+		maxGroups = defaultRepoMapMaxGroups
+	}
+	perGroup := args.FilesPerGroup
+	if perGroup < 0 {
+		return toolErr(ErrInvalidInput, "files_per_group must not be negative, got %d", perGroup)
+	}
+	if perGroup == 0 {
+		perGroup = defaultRepoMapFilesPerGroup
+	}
+
+	resolvedRoot, err := resolver.Resolve(sess.Cwd(), args.Path)
+	if err != nil {
+		if args.Path != "" {
+			return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+		}
+		resolvedRoot = sess.Cwd()
+	}
+
+	info, err := os.Stat(resolvedRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return toolErr(ErrPathNotFound, "%s does not exist", resolvedRoot)
+		}
+		return toolErr(ErrIO, "could not stat %s: %v", resolvedRoot, err)
+	}
+	if !info.IsDir() {
+		return toolErr(ErrInvalidInput, "%s is not a directory", resolvedRoot)
+	}
+
+	groups := make(map[string][]string)
+	total := 0
+	err = walkGlobEntries(ctx, resolvedRoot, excludeDirs, ignoreFiles, func(entryPath, relPath, _ string, isDir bool) {
+		if isDir {
+			return
+		}
+		// Path scoping: silently skip denied files
+		if _, err := resolver.Resolve(sess.Cwd(), entryPath); err != nil {
+			return
+		}
+		group := repoMapRootGroup
+		if top, _, ok := strings.Cut(relPath, string(filepath.Separator)); ok {
+			group = top
+		}
+		groups[group] = append(groups[group], relPath)
+		total++
+	})
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return toolErr(ErrIO, "could not walk directory %s: %v", resolvedRoot, err)
+	}
+
+	if total == 0 {
+		return globNoFiles()
+	}
+
+	// Root files first, then directories by name
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == repoMapRootGroup) != (names[j] == repoMapRootGroup) {
+			return names[i] == repoMapRootGroup
+		}
+		return names[i] < names[j]
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d files in %d groups\n", resolvedRoot, total, len(names))
+	for i, name := range names {
+		if i == maxGroups {
+			rest := 0
+			for _, n := range names[i:] {
+				rest += len(groups[n])
+			}
+			fmt.Fprintf(&b, "\n... (%d more groups, %d files)\n", len(names)-i, rest)
+			break
+		}
+		files := repoMapRepresentatives(groups[name])
+		label := name + string(filepath.Separator)
+		if name == repoMapRootGroup {
+			label = "(root)"
+		}
+		fmt.Fprintf(&b, "\n%s (%d files)\n", label, len(files))
+		for _, f := range files[:min(len(files), perGroup)] {
+			fmt.Fprintf(&b, "  %s\n", f)
+		}
+		if len(files) > perGroup {
+			fmt.Fprintf(&b, "  ... (%d more)\n", len(files)-perGroup)
+		}
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: b.String()}},
+	}, nil, nil
+}
+
+// repoMapRepresentatives orders a group's files so the most representative
+// come first: shallower files (READMEs, manifests, entry points) before
+// deeper ones, then by path. It sorts files in place and returns it.
+func repoMapRepresentatives(files []string) []string {
+	sort.Slice(files, func(i, j int) bool {
+		di := strings.Count(files[i], string(filepath.Separator))
+		dj := strings.Count(files[j], string(filepath.Separator))
+		if di != dj {
+			return di < dj
+		}
+		return files[i] < files[j]
+	})
+	return files
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func TestRepoMap(t *testing.T) {
+	tmp := t.TempDir()
+	for _, f := range []string{
+		"README.md",
+		"go.mod",
+		"cmd/tool/main.go",
+		"internal/a/a.go",
+		"internal/a/a_test.go",
+		"internal/b/b.go",
+		"internal/doc.go",
+		"dist/out.bin",
+		"node_modules/pkg/index.js",
+		"debug.log",
+	} {
+		path := filepath.Join(tmp, filepath.FromSlash(f))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("*.log\ndist/\n"), 0644)
+	sess := session.New(tmp)
+	handler := repoMapHandler(sess, testResolver(t), testConfig())
+
+	call := func(t *testing.T, args RepoMapArgs) string {
+		t.Helper()
+		r, _, err := handler(context.Background(), nil, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(r) {
+			t.Fatal(resultText(r))
+		}
+		return resultText(r)
+	}
+
+	t.Run("groups and counts by top-level directory", func(t *testing.T) {
+		text := call(t, RepoMapArgs{})
+		sep := string(filepath.Separator)
+		for _, want := range []string{
+			tmp + ": 8 files in 3 groups\n",
+			"\n(root) (3 files)\n  .gitignore\n  README.md\n  go.mod\n",
+			"\ncmd" + sep + " (1 files)\n  " + filepath.Join("cmd", "tool", "main.go") + "\n",
+			"\ninternal" + sep + " (4 files)\n  " + filepath.Join("internal", "doc.go") + "\n",
+		} {
+			if !strings.Contains(text, want) {
+				t.Errorf("expected %q in:\n%s", want, text)
+			}
+		}
+		for _, unwanted := range []string{"dist", "node_modules", "debug.log"} {
+			if strings.Contains(text, unwanted) {
+				t.Errorf("ignored path %q listed:\n%s", unwanted, text)
+			}
+		}
+		if !strings.HasPrefix(strings.SplitN(text, "\n\n", 2)[1], "(root)") {
+			t.Errorf("expected root group first:\n%s", text)
+		}
+	})
+
+	t.Run("representative files and groups are truncated", func(t *testing.T) {
+		text := call(t, RepoMapArgs{MaxGroups: 2, FilesPerGroup: 2})
+		if !strings.Contains(text, "\n(root) (3 files)\n  .gitignore\n  README.md\n  ... (1 more)\n") {
+			t.Errorf("expected root group truncated to 2 files:\n%s", text)
+		}
+		if !strings.HasSuffix(text, "\n... (1 more groups, 4 files)\n") {
+			t.Errorf("expected remaining groups summarized:\n%s", text)
+		}
+	})
+
+	t.Run("negative limits are rejected", func(t *testing.T) {
+		r, _, err := handler(context.Background(), nil, RepoMapArgs{FilesPerGroup: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(r, ErrInvalidInput) {
+			t.Errorf("expected %s, got: %s", ErrInvalidInput, resultText(r))
+		}
+	})
+}
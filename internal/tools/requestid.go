@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RequestIDHeader carries the HTTP request's correlation ID to tool
+// handlers. The SDK dispatches tool calls on the session's own context, so
+// the ID set by the HTTP middleware only reaches the handler through the
+// request headers exposed in CallToolRequest.Extra.
+const RequestIDHeader = "X-Boris-Request-Id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the correlation ID id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation ID carried by ctx, or "" if none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logger returns the default logger annotated with the request_id carried by
+// ctx, if any, so that all log lines for one request share an ID.
+func logger(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// withRequestID wraps a tool handler so that its context carries the
+// correlation ID from the request headers, and logs the start and end of
+// each call at debug level. Calls without an ID (e.g. over STDIO) are
+// logged without one.
+func withRequestID[In any](name string, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		if req != nil && req.Extra != nil && req.Extra.Header != nil {
+			if id := req.Extra.Header.Get(RequestIDHeader); id != "" {
+				ctx = WithRequestID(ctx, id)
+			}
+		}
+		log := logger(ctx)
+		log.Debug("tool call started", "tool", name)
+		start := time.Now()
+		result, out, err := h(ctx, req, args)
+		attrs := []any{"tool", name, "duration", time.Since(start)}
+		if err != nil {
+			attrs = append(attrs, "error", err)
+		} else if result != nil && result.IsError {
+			attrs = append(attrs, "error_code", resultErrorCode(result))
+		}
+		log.Debug("tool call finished", attrs...)
+		return result, out, err
+	}
+}
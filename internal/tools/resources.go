@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// resourcesPageSize is how many files one resources/list page reports. The
+// next page is requested with the returned cursor.
+const resourcesPageSize = 1000
+
+// registerResources exposes the workspace as MCP resources: resources/list
+// reports the files under the session cwd (respecting .gitignore, excluded
+// directories, and path scoping), and any in-scope file can be read through
+// the file:///{+path} template, subject to cfg.MaxFileSize.
+func registerResources(server *mcp.Server, sess *session.Session, resolver *pathscope.Resolver, cfg Config) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "file",
+		Description: "A file in the workspace, by absolute path.",
+		URITemplate: "file:///{+path}",
+	}, fileResourceHandler(sess, resolver, cfg))
+
+	// The file list changes as the agent works, so it is computed on every
+	// request rather than registered up front.
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
+	server.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method != "resources/list" {
+				return next(ctx, method, req)
+			}
+			var cursor string
+			if lr, ok := req.(*mcp.ListResourcesRequest); ok && lr.Params != nil {
+				cursor = lr.Params.Cursor
+			}
+			resources, next, err := listWorkspaceResources(ctx, sess, resolver, excludeDirs, cfg.ExtraIgnoreFiles, cursor, resourcesPageSize)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.ListResourcesResult{Resources: resources, NextCursor: next}, nil
+		}
+	})
+}
+
+// listWorkspaceResources walks the session cwd the way grep does and returns
+// one page of resources, one per file, sorted by path. The page starts after
+// cursor, the path of the last resource on the previous page, and holds at
+// most pageSize resources. next is the cursor for the following page, or
+// empty on the last page.
+func listWorkspaceResources(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, excludeDirs map[string]bool, ignoreFiles []string, cursor string, pageSize int) (page []*mcp.Resource, next string, err error) {
+	root := sess.Cwd()
+	resources := []*mcp.Resource{}
+	err = walkSearchFiles(ctx, resolver, sess, root, walkOptions{excludeDirs: excludeDirs, ignoreFiles: ignoreFiles}, func(entry fs.DirEntry, relPath, _ string) bool {
+		if cursor != "" && filepath.ToSlash(relPath) <= cursor {
+			return true
+		}
+		path := filepath.Join(root, relPath)
+		r := &mcp.Resource{
+			URI:      fileURI(path),
+			Name:     filepath.ToSlash(relPath),
+			MIMEType: resourceMIMEType(path),
+		}
+		if info, err := entry.Info(); err == nil {
+			r.Size = info.Size()
+		}
+		resources = append(resources, r)
+		return true
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Name < resources[j].Name })
+	if len(resources) > pageSize {
+		resources = resources[:pageSize]
+		next = resources[pageSize-1].Name
+	}
+	return resources, next, nil
+}
+
+func fileResourceHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ResourceHandler {
+	return func(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		uri := req.Params.URI
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme != "file" {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		resolved, err := resolver.Resolve(sess.Cwd(), u.Path)
+		if err != nil {
+			return nil, fmt.Errorf("access denied: %v", err)
+		}
+		info, err := os.Stat(resolved)
+		if err != nil || info.IsDir() {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		// Opening or reading a FIFO, device, or socket can block forever
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is a %s, not a regular file", resolved, fileKind(info.Mode()))
+		}
+		if cfg.MaxFileSize > 0 && info.Size() > cfg.MaxFileSize {
+			return nil, fmt.Errorf("file %s is %d bytes, exceeds maximum %d bytes", resolved, info.Size(), cfg.MaxFileSize)
+		}
+		data, err := os.ReadFile(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", resolved, err)
+		}
+
+		contents := &mcp.ResourceContents{URI: uri, MIMEType: resourceMIMEType(resolved)}
+		if isBinaryHeader(data[:min(len(data), 512)]) {
+			contents.Blob = data
+			if !strings.HasPrefix(contents.MIMEType, "image/") {
+				contents.MIMEType = "application/octet-stream"
+			}
+		} else {
+			contents.Text = string(data)
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{contents}}, nil
+	}
+}
+
+// fileURI returns the file:// URI for an absolute path.
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// resourceMIMEType guesses a file's MIME type from its extension, defaulting
+// to text/plain.
+func resourceMIMEType(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return "text/plain"
+}
@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func resourcesTestClient(t *testing.T, dir string, resolver *pathscope.Resolver, cfg Config) *mcp.ClientSession {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "boris-test", Version: "test"}, nil)
+	cfg.ExposeResources = true
+	RegisterAll(server, resolver, session.New(dir), cfg)
+
+	ctx := context.Background()
+	t1, t2 := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, t1, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	cs, err := client.Connect(ctx, t2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs
+}
+
+func TestResourcesListAndRead(t *testing.T) {
+	tmp, _ := filepath.EvalSymlinks(t.TempDir())
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("hello\n"), 0o644)
+	os.MkdirAll(filepath.Join(tmp, "sub"), 0o755)
+	os.WriteFile(filepath.Join(tmp, "sub", "b.go"), []byte("package sub\n"), 0o644)
+	os.WriteFile(filepath.Join(tmp, "ignored.log"), []byte("noise\n"), 0o644)
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("*.log\n"), 0o644)
+	os.MkdirAll(filepath.Join(tmp, ".git"), 0o755)
+
+	cs := resourcesTestClient(t, tmp, testResolver(t), testConfig())
+	ctx := context.Background()
+
+	list, err := cs.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]*mcp.Resource{}
+	for _, r := range list.Resources {
+		names[r.Name] = r
+	}
+	if names["a.txt"] == nil || names["sub/b.go"] == nil {
+		t.Fatalf("expected a.txt and sub/b.go in listing, got %v", names)
+	}
+	if names["ignored.log"] != nil {
+		t.Error("gitignored file should not be listed")
+	}
+	if got := names["a.txt"].Size; got != 6 {
+		t.Errorf("a.txt size = %d, want 6", got)
+	}
+
+	res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: names["a.txt"].URI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Contents) != 1 || res.Contents[0].Text != "hello\n" {
+		t.Fatalf("unexpected contents: %+v", res.Contents)
+	}
+}
+
+func TestResourcesReadLimits(t *testing.T) {
+	tmp, _ := filepath.EvalSymlinks(t.TempDir())
+	os.WriteFile(filepath.Join(tmp, "big.txt"), []byte("0123456789"), 0o644)
+	os.WriteFile(filepath.Join(tmp, "bin.dat"), []byte("ab\x00cd"), 0o644)
+
+	cfg := testConfig()
+	cfg.MaxFileSize = 8
+	cs := resourcesTestClient(t, tmp, testResolver(t), cfg)
+	ctx := context.Background()
+
+	if _, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: fileURI(filepath.Join(tmp, "big.txt"))}); err == nil {
+		t.Error("expected error reading file over max size")
+	}
+	if _, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: fileURI(filepath.Join(tmp, "missing.txt"))}); err == nil {
+		t.Error("expected error reading missing file")
+	}
+
+	res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: fileURI(filepath.Join(tmp, "bin.dat"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Contents[0].Blob) != "ab\x00cd" || res.Contents[0].MIMEType != "application/octet-stream" {
+		t.Errorf("unexpected binary contents: %+v", res.Contents[0])
+	}
+}
+
+func TestResourcesListPagination(t *testing.T) {
+	tmp, _ := filepath.EvalSymlinks(t.TempDir())
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+		os.WriteFile(filepath.Join(tmp, name), []byte("x\n"), 0o644)
+	}
+	sess := session.New(tmp)
+	ctx := context.Background()
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, next, err := listWorkspaceResources(ctx, sess, testResolver(t), nil, nil, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) > 2 {
+			t.Fatalf("page has %d resources, want at most 2", len(page))
+		}
+		for _, r := range page {
+			got = append(got, r.Name)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if want := "a.txt b.txt c.txt d.txt e.txt"; strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// The resources/list handler passes the client's cursor through
+	cs := resourcesTestClient(t, tmp, testResolver(t), testConfig())
+	list, err := cs.ListResources(ctx, &mcp.ListResourcesParams{Cursor: "c.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range list.Resources {
+		names = append(names, r.Name)
+	}
+	if want := "d.txt e.txt"; strings.Join(names, " ") != want || list.NextCursor != "" {
+		t.Errorf("after cursor c.txt got %q (next %q), want %q", names, list.NextCursor, want)
+	}
+}
+
+func TestResourcesScopeDenied(t *testing.T) {
+	tmp, _ := filepath.EvalSymlinks(t.TempDir())
+	os.WriteFile(filepath.Join(tmp, "ok.txt"), []byte("ok\n"), 0o644)
+	os.WriteFile(filepath.Join(tmp, "secret.env"), []byte("TOKEN=x\n"), 0o644)
+	outside, _ := filepath.EvalSymlinks(t.TempDir())
+	os.WriteFile(filepath.Join(outside, "other.txt"), []byte("other\n"), 0o644)
+
+	resolver, err := pathscope.NewResolver([]string{tmp}, []string{"**/*.env"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := resourcesTestClient(t, tmp, resolver, testConfig())
+	ctx := context.Background()
+
+	list, err := cs.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range list.Resources {
+		if r.Name == "secret.env" {
+			t.Error("denied file should not be listed")
+		}
+	}
+
+	for _, path := range []string{filepath.Join(tmp, "secret.env"), filepath.Join(outside, "other.txt")} {
+		if _, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: fileURI(path)}); err == nil {
+			t.Errorf("expected access denied reading %s", path)
+		}
+	}
+}
+
+func TestResourcesReadFIFO(t *testing.T) {
+	tmp, _ := filepath.EvalSymlinks(t.TempDir())
+	fifo := filepath.Join(tmp, "pipe")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+	handler := fileResourceHandler(session.New(tmp), testResolver(t), testConfig())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := handler(context.Background(), &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: fileURI(fifo)}})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "named pipe") {
+			t.Errorf("expected an error naming a named pipe, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("resource read blocked on a FIFO")
+	}
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mjkoo/boris/internal/session"
+)
+
+// scratchDir is the directory, relative to the session cwd, that holds
+// output files written on the caller's behalf (large grep results, task
+// output dumps). It contains a .gitignore matching everything, so the files
+// are invisible to git and to grep, glob, and list_directory.
+const scratchDir = ".boris"
+
+// writeScratchFile writes content to a new file named after pattern (as for
+// os.CreateTemp) in dir, the resolved scratchDir, creating the directory and
+// its .gitignore as needed. The file is removed when the session closes.
+func writeScratchFile(sess *session.Session, dir, pattern, content string) (string, error) {
+	f, err := createScratchFile(sess, dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// createScratchFile creates a new, empty file in dir as for writeScratchFile
+// and returns it open for writing. The file is removed when the session
+// closes.
+func createScratchFile(sess *session.Session, dir, pattern string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte("*\n"), 0644); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	sess.OnClose(func() { os.Remove(path) })
+	return f, nil
+}
@@ -0,0 +1,348 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// SearchReplaceFilesArgs is the input schema for the search_replace_files tool.
+type SearchReplaceFilesArgs struct {
+	Pattern         string `json:"pattern" jsonschema:"regex selecting the files to edit; unless old_str is set, its matches are what gets replaced,required"`
+	Path            string `json:"path,omitempty" jsonschema:"file or directory to search in (defaults to cwd)"`
+	Include         string `json:"include,omitempty" jsonschema:"glob pattern to filter files (e.g. '*.js' or '*.{ts,tsx}')"`
+	Type            string `json:"type,omitempty" jsonschema:"file type to search (e.g. js, py, go, ts)"`
+	CaseInsensitive bool   `json:"case_insensitive,omitempty" jsonschema:"case-insensitive pattern matching"`
+	OldStr          string `json:"old_str,omitempty" jsonschema:"literal string to replace in matching files (default: replace matches of pattern)"`
+	NewStr          string `json:"new_str,omitempty" jsonschema:"replacement text; when replacing pattern matches, $1 expands capture groups"`
+	DryRun          bool   `json:"dry_run,omitempty" jsonschema:"report what would change without modifying any files"`
+	Force           bool   `json:"force,omitempty" jsonschema:"edit files that have not been viewed even when view-before-edit is required"`
+	EditToken       string `json:"edit_token,omitempty" jsonschema:"edit_token returned by a recent grep with edit_token set and the same pattern, path, include, type, and case_insensitive; required when the server enforces edit tokens (dry runs are exempt)"`
+}
+
+// fileEdit is a pending replacement in a single file.
+type fileEdit struct {
+	displayPath string
+	resolved    string
+	count       int
+	content     []byte
+	perm        fs.FileMode
+}
+
+// replaceOptions adjusts how doSearchReplaceFiles edits files for callers
+// other than the search_replace_files tool itself.
+type replaceOptions struct {
+	perLine bool // replace pattern matches within each line separately, as grep's replace preview does
+}
+
+func searchReplaceFilesHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[SearchReplaceFilesArgs, any] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, args SearchReplaceFilesArgs) (*mcp.CallToolResult, any, error) {
+		return doSearchReplaceFiles(ctx, sess, resolver, cfg, args, replaceOptions{})
+	}
+}
+
+func doSearchReplaceFiles(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, cfg Config, args SearchReplaceFilesArgs, opts replaceOptions) (*mcp.CallToolResult, any, error) {
+	if args.Pattern == "" {
+		return toolErr(ErrInvalidInput, "pattern must not be empty")
+	}
+
+	if args.Include != "" {
+		if err := globPatternError(args.Include); err != nil {
+			return toolErr(ErrInvalidInput, "invalid include pattern %q: %v", args.Include, err)
+		}
+	}
+
+	var typePatterns []string
+	if args.Type != "" {
+		var err error
+		typePatterns, err = resolveType(args.Type)
+		if err != nil {
+			return toolErr(ErrInvalidInput, "invalid file type: %v", err)
+		}
+	}
+
+	// Multi-line mode keeps ^ and $ anchored at line boundaries, matching
+	// grep's line-by-line semantics when the pattern is applied to whole files.
+	patternStr := "(?m)" + args.Pattern
+	if args.CaseInsensitive {
+		patternStr = "(?i)" + patternStr
+	}
+	re, err := regexp.Compile(patternStr)
+	if err != nil {
+		return toolErr(ErrGrepInvalidPattern, "invalid regex pattern: %v", err)
+	}
+
+	resolvedRoot, err := resolver.Resolve(sess.Cwd(), args.Path)
+	if err != nil {
+		if args.Path != "" {
+			return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+		}
+		resolvedRoot = sess.Cwd()
+	}
+
+	// Require proof that the caller has seen the matches before a real edit.
+	// The token is checked here to fail fast and consumed just before
+	// writing, atomically, so that concurrent calls cannot both use it.
+	var queryKey string
+	if cfg.RequireEditToken && !args.DryRun {
+		if args.EditToken == "" {
+			return toolErr(ErrEditTokenRequired, "edit_token is required. Hint: run grep with the same pattern, path, include, type, and case_insensitive and edit_token set, review the matches, then pass the returned token.")
+		}
+		queryKey = editQuery{
+			Pattern:         args.Pattern,
+			Root:            args.Path,
+			Include:         args.Include,
+			Type:            args.Type,
+			CaseInsensitive: args.CaseInsensitive,
+		}.key(sess, resolver)
+		if !sess.CheckEditToken(args.EditToken, queryKey, editTokenTTL) {
+			return toolErr(ErrEditTokenInvalid, "edit_token is unknown, expired, or was issued for a different query. Hint: rerun grep with edit_token set for this exact query.")
+		}
+	}
+
+	info, err := os.Stat(resolvedRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return toolErr(ErrPathNotFound, "%s does not exist", resolvedRoot)
+		}
+		return toolErr(ErrIO, "could not stat %s: %v", resolvedRoot, err)
+	}
+
+	var edits []fileEdit
+	if info.IsDir() {
+		err = walkSearchFiles(ctx, resolver, sess, resolvedRoot, walkOptions{
+			include:      args.Include,
+			typePatterns: typePatterns,
+			excludeDirs:  excludedDirSet(cfg.ExcludeDirs),
+			ignoreFiles:  cfg.ExtraIgnoreFiles,
+		}, func(_ fs.DirEntry, relPath, resolvedFile string) bool {
+			// Read-only files are skipped like denied ones
+			if _, err := resolver.ResolveForWrite(sess.Cwd(), resolvedFile); err != nil {
+				return true
+			}
+			// Unreadable, oversized, and binary files are silently skipped
+			if edit, ok, _ := planFileEdit(re, args, opts, cfg.MaxFileSize, relPath, resolvedFile); ok {
+				edits = append(edits, edit)
+			}
+			return true
+		})
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return toolErr(ErrIO, "search cancelled before any files were modified: %v", err)
+			}
+			return toolErr(ErrIO, "could not walk directory %s: %v", resolvedRoot, err)
+		}
+	} else {
+		if _, err := resolver.ResolveForWrite(sess.Cwd(), resolvedRoot); err != nil {
+			return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+		}
+		edit, ok, err := planFileEdit(re, args, opts, cfg.MaxFileSize, args.Path, resolvedRoot)
+		if err != nil {
+			if errors.Is(err, errFileTooLarge) {
+				return toolErr(ErrFileTooLarge, "file %s is %d bytes, exceeds maximum %d bytes", resolvedRoot, info.Size(), cfg.MaxFileSize)
+			}
+			return toolErr(ErrIO, "could not read %s: %v", resolvedRoot, err)
+		}
+		if ok {
+			edits = append(edits, edit)
+		}
+	}
+
+	if len(edits) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "No matching files to change"}},
+		}, nil, nil
+	}
+
+	// Check view-before-edit up front so that no file is modified if any is
+	// rejected.
+	if !args.DryRun && cfg.RequireViewBeforeEdit && !args.Force {
+		var unviewed, changed []string
+		for _, e := range edits {
+			switch viewed, stale := viewState(sess, e.resolved); {
+			case !viewed:
+				unviewed = append(unviewed, e.resolved)
+			case stale:
+				changed = append(changed, e.resolved)
+			}
+		}
+		if len(unviewed) > 0 {
+			return toolErr(ErrFileNotViewed, "files must be viewed before editing (or set force): %s. Hint: call view on each file, or set force to skip this check.", strings.Join(unviewed, ", "))
+		}
+		if len(changed) > 0 {
+			return toolErr(ErrFileNotViewed, "files changed since they were viewed (view them again, or set force): %s. Hint: call view on each file, or set force to skip this check.", strings.Join(changed, ", "))
+		}
+	}
+
+	if !args.DryRun {
+		// Tokens are single-use so one grep cannot authorize repeated sweeps
+		if queryKey != "" && !sess.ConsumeEditToken(args.EditToken, queryKey, editTokenTTL) {
+			return toolErr(ErrEditTokenInvalid, "edit_token is unknown, expired, or was already used. Hint: rerun grep with edit_token set for this exact query.")
+		}
+		if err := applyFileEdits(sess, edits); err != nil {
+			return toolErr(ErrIO, "%v", err)
+		}
+	}
+	total := 0
+	for _, e := range edits {
+		total += e.count
+	}
+
+	var b strings.Builder
+	if args.DryRun {
+		fmt.Fprintf(&b, "Dry run: would replace %d occurrences in %d files", total, len(edits))
+	} else {
+		fmt.Fprintf(&b, "Replaced %d occurrences in %d files", total, len(edits))
+	}
+	for _, e := range edits {
+		fmt.Fprintf(&b, "\n%s: %d", e.displayPath, e.count)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: b.String()}},
+	}, nil, nil
+}
+
+// errFileTooLarge is returned by planFileEdit and searchFile for files over
+// the size limit.
+var errFileTooLarge = errors.New("file too large")
+
+// planFileEdit computes the replacement for a single file without writing it.
+// It reports ok=false if the file is binary, the pattern does not match, or
+// nothing would be replaced.
+func planFileEdit(re *regexp.Regexp, args SearchReplaceFilesArgs, opts replaceOptions, maxFileSize int64, displayPath, resolved string) (fileEdit, bool, error) {
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return fileEdit{}, false, err
+	}
+	if maxFileSize > 0 && info.Size() > maxFileSize {
+		return fileEdit{}, false, errFileTooLarge
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return fileEdit{}, false, err
+	}
+	if isBinaryHeader(data[:min(len(data), 512)]) {
+		return fileEdit{}, false, nil
+	}
+
+	content := string(data)
+	if !re.MatchString(content) {
+		return fileEdit{}, false, nil
+	}
+
+	var count int
+	var newContent string
+	switch {
+	case args.OldStr != "":
+		count = strings.Count(content, args.OldStr)
+		newContent = strings.ReplaceAll(content, args.OldStr, args.NewStr)
+	case opts.perLine:
+		newContent, count = replaceLines(re, content, args.NewStr)
+	default:
+		count = len(re.FindAllStringIndex(content, -1))
+		newContent = re.ReplaceAllString(content, args.NewStr)
+	}
+	if count == 0 || newContent == content {
+		return fileEdit{}, false, nil
+	}
+
+	return fileEdit{
+		displayPath: displayPath,
+		resolved:    resolved,
+		count:       count,
+		content:     []byte(newContent),
+		perm:        info.Mode().Perm(),
+	}, true, nil
+}
+
+// replaceLines replaces the matches of re in each line of content separately,
+// leaving line terminators (LF or CRLF) untouched, just as grep shows each
+// matching line in a replace preview. It returns the new content and the
+// number of matches replaced.
+func replaceLines(re *regexp.Regexp, content, repl string) (string, int) {
+	var b strings.Builder
+	count := 0
+	for line := range strings.SplitAfterSeq(content, "\n") {
+		text := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if n := len(re.FindAllStringIndex(text, -1)); n > 0 {
+			count += n
+			b.WriteString(re.ReplaceAllString(text, repl))
+		} else {
+			b.WriteString(text)
+		}
+		b.WriteString(line[len(text):])
+	}
+	return b.String(), count
+}
+
+// applyFileEdits writes every edit in two phases so that a failure leaves as
+// few files changed as possible: the new contents are first staged in
+// temporary files beside their targets, and only once all are staged is each
+// renamed into place. If staging fails, nothing has been modified. If a
+// rename fails, the error lists the files already changed.
+func applyFileEdits(sess *session.Session, edits []fileEdit) error {
+	staged := make([]string, 0, len(edits))
+	removeStaged := func(from int) {
+		for _, tmpPath := range staged[from:] {
+			os.Remove(tmpPath)
+		}
+	}
+	for _, e := range edits {
+		tmpPath, err := stageFile(e.resolved, e.content, e.perm)
+		if err != nil {
+			removeStaged(0)
+			return fmt.Errorf("could not write %s: %v; no files were modified", e.resolved, err)
+		}
+		staged = append(staged, tmpPath)
+	}
+	for i, e := range edits {
+		if err := os.Rename(staged[i], e.resolved); err != nil {
+			removeStaged(i)
+			changed := make([]string, 0, i)
+			for _, done := range edits[:i] {
+				changed = append(changed, done.resolved)
+			}
+			if len(changed) == 0 {
+				return fmt.Errorf("could not write %s: %v; no files were modified", e.resolved, err)
+			}
+			return fmt.Errorf("could not write %s: %v; these files were already changed: %s", e.resolved, err, strings.Join(changed, ", "))
+		}
+		refreshViewed(sess, e.resolved)
+	}
+	return nil
+}
+
+// stageFile writes data to a new temporary file in the same directory as path,
+// so that renaming it over path replaces the file atomically. It returns the
+// temporary file's path.
+func stageFile(path string, data []byte, perm fs.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
@@ -0,0 +1,372 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+// searchReplaceSetup creates a tree of files for bulk replacement tests.
+func searchReplaceSetup(t *testing.T) (string, *session.Session) {
+	t.Helper()
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmp, "a.go"), []byte("oldName()\noldName()\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "sub", "b.go"), []byte("x := oldHelper\n"), 0600)
+	os.WriteFile(filepath.Join(tmp, "c.txt"), []byte("oldName in text\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "d.go"), []byte("nothing here\n"), 0644)
+	return tmp, session.New(tmp)
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSearchReplaceFilesRegex(t *testing.T) {
+	tmp, sess := searchReplaceSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := searchReplaceFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, SearchReplaceFilesArgs{
+		Pattern: `old(\w+)`,
+		NewStr:  "new$1",
+		Type:    "go",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if !strings.HasPrefix(text, "Replaced 3 occurrences in 2 files") {
+		t.Errorf("unexpected summary: %s", text)
+	}
+	if !strings.Contains(text, "a.go: 2") || !strings.Contains(text, filepath.Join("sub", "b.go")+": 1") {
+		t.Errorf("expected per-file counts, got: %s", text)
+	}
+
+	if got := readString(t, filepath.Join(tmp, "a.go")); got != "newName()\nnewName()\n" {
+		t.Errorf("a.go = %q", got)
+	}
+	if got := readString(t, filepath.Join(tmp, "sub", "b.go")); got != "x := newHelper\n" {
+		t.Errorf("sub/b.go = %q", got)
+	}
+	// Filtered out by type
+	if got := readString(t, filepath.Join(tmp, "c.txt")); got != "oldName in text\n" {
+		t.Errorf("c.txt should be untouched, got %q", got)
+	}
+
+	// Permissions are preserved across the atomic rewrite
+	info, err := os.Stat(filepath.Join(tmp, "sub", "b.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("sub/b.go mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// No temp files left behind
+	entries, _ := os.ReadDir(tmp)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("leftover temp file %s", e.Name())
+		}
+	}
+}
+
+func TestSearchReplaceFilesLiteral(t *testing.T) {
+	tmp, sess := searchReplaceSetup(t)
+	os.WriteFile(filepath.Join(tmp, "e.txt"), []byte("a.b and a.b\n"), 0644)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := searchReplaceFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, SearchReplaceFilesArgs{
+		Pattern: `a\.b`,
+		OldStr:  "a.b",
+		NewStr:  "$x",
+		Include: "*.txt",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(result); !strings.HasPrefix(text, "Replaced 2 occurrences in 1 files") {
+		t.Errorf("unexpected summary: %s", text)
+	}
+	if got := readString(t, filepath.Join(tmp, "e.txt")); got != "$x and $x\n" {
+		t.Errorf("e.txt = %q, literal replacement should not expand $x", got)
+	}
+}
+
+func TestSearchReplaceFilesDryRun(t *testing.T) {
+	tmp, sess := searchReplaceSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := searchReplaceFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, SearchReplaceFilesArgs{
+		Pattern: `oldName`,
+		NewStr:  "newName",
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if !strings.HasPrefix(text, "Dry run: would replace 3 occurrences in 2 files") {
+		t.Errorf("unexpected summary: %s", text)
+	}
+	if !strings.Contains(text, "a.go: 2") || !strings.Contains(text, "c.txt: 1") {
+		t.Errorf("expected per-file counts, got: %s", text)
+	}
+	if got := readString(t, filepath.Join(tmp, "a.go")); got != "oldName()\noldName()\n" {
+		t.Errorf("dry run modified a.go: %q", got)
+	}
+}
+
+func TestSearchReplaceFilesNoMatches(t *testing.T) {
+	_, sess := searchReplaceSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := searchReplaceFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, SearchReplaceFilesArgs{
+		Pattern: `absent`,
+		NewStr:  "x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("no matches should not be an error: %s", resultText(result))
+	}
+	if text := resultText(result); text != "No matching files to change" {
+		t.Errorf("unexpected result: %s", text)
+	}
+}
+
+func TestSearchReplaceFilesViewBeforeEdit(t *testing.T) {
+	tmp, sess := searchReplaceSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	cfg := testConfig()
+	cfg.RequireViewBeforeEdit = true
+	handler := searchReplaceFilesHandler(sess, resolver, cfg)
+
+	// Only one of the two matching files has been viewed
+	info, err := os.Stat(filepath.Join(tmp, "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.MarkViewed(filepath.Join(tmp, "a.go"), info.ModTime(), info.Size())
+
+	args := SearchReplaceFilesArgs{Pattern: `oldName`, NewStr: "newName"}
+	result, _, err := handler(context.Background(), nil, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(result, ErrFileNotViewed) {
+		t.Fatalf("expected %s, got: %s", ErrFileNotViewed, resultText(result))
+	}
+	if !strings.Contains(resultText(result), "c.txt") {
+		t.Errorf("error should name the unviewed file: %s", resultText(result))
+	}
+	if got := readString(t, filepath.Join(tmp, "a.go")); got != "oldName()\noldName()\n" {
+		t.Errorf("no file should be modified when any is rejected, a.go = %q", got)
+	}
+
+	// Dry runs do not require viewing
+	args.DryRun = true
+	result, _, _ = handler(context.Background(), nil, args)
+	if isErrorResult(result) {
+		t.Errorf("dry run should not require viewing: %s", resultText(result))
+	}
+
+	// force bypasses the check
+	args.DryRun = false
+	args.Force = true
+	result, _, _ = handler(context.Background(), nil, args)
+	if isErrorResult(result) {
+		t.Fatalf("force should bypass view-before-edit: %s", resultText(result))
+	}
+	if got := readString(t, filepath.Join(tmp, "c.txt")); got != "newName in text\n" {
+		t.Errorf("c.txt = %q", got)
+	}
+}
+
+func TestSearchReplaceFilesRespectsDenyList(t *testing.T) {
+	tmp, sess := searchReplaceSetup(t)
+	resolver, err := pathscope.NewResolver([]string{tmp}, []string{filepath.Join(tmp, "sub")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := searchReplaceFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, SearchReplaceFilesArgs{
+		Pattern: `old\w+`,
+		NewStr:  "new",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if got := readString(t, filepath.Join(tmp, "sub", "b.go")); got != "x := oldHelper\n" {
+		t.Errorf("denied file was modified: %q", got)
+	}
+	if got := readString(t, filepath.Join(tmp, "a.go")); got != "new()\nnew()\n" {
+		t.Errorf("a.go = %q", got)
+	}
+}
+
+func TestSearchReplaceFilesSingleFileAndInvalidPattern(t *testing.T) {
+	tmp, sess := searchReplaceSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := searchReplaceFilesHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, SearchReplaceFilesArgs{
+		Pattern: `oldName`,
+		NewStr:  "newName",
+		Path:    "c.txt",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(result); !strings.Contains(text, "c.txt: 1") {
+		t.Errorf("unexpected result: %s", text)
+	}
+	if got := readString(t, filepath.Join(tmp, "a.go")); got != "oldName()\noldName()\n" {
+		t.Errorf("a.go outside path should be untouched, got %q", got)
+	}
+
+	result, _, _ = handler(context.Background(), nil, SearchReplaceFilesArgs{Pattern: `(`, NewStr: "x"})
+	if !hasErrorCode(result, ErrGrepInvalidPattern) {
+		t.Errorf("expected %s, got: %s", ErrGrepInvalidPattern, resultText(result))
+	}
+}
+
+// editTokenFrom extracts the edit token appended to a grep result.
+func editTokenFrom(t *testing.T, text string) string {
+	t.Helper()
+	const prefix = "[edit_token: "
+	i := strings.LastIndex(text, prefix)
+	if i < 0 || !strings.HasSuffix(text, "]") {
+		t.Fatalf("no edit token in grep output: %q", text)
+	}
+	return text[i+len(prefix) : len(text)-1]
+}
+
+func TestSearchReplaceFilesEditToken(t *testing.T) {
+	tmp, sess := searchReplaceSetup(t)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	cfg := testConfig()
+	cfg.RequireEditToken = true
+	grep := grepHandler(sess, resolver, cfg)
+	replace := searchReplaceFilesHandler(sess, resolver, cfg)
+
+	grepResult, _, _ := grep(context.Background(), nil, GrepArgs{Pattern: "oldName", Type: "go", EditToken: true})
+	if isErrorResult(grepResult) {
+		t.Fatalf("grep failed: %s", resultText(grepResult))
+	}
+	token := editTokenFrom(t, resultText(grepResult))
+
+	// Missing token is rejected, but dry runs are exempt
+	result, _, _ := replace(context.Background(), nil, SearchReplaceFilesArgs{Pattern: "oldName", Type: "go", NewStr: "newName"})
+	if !hasErrorCode(result, ErrEditTokenRequired) {
+		t.Errorf("expected EDIT_TOKEN_REQUIRED, got: %s", resultText(result))
+	}
+	result, _, _ = replace(context.Background(), nil, SearchReplaceFilesArgs{Pattern: "oldName", Type: "go", NewStr: "newName", DryRun: true})
+	if isErrorResult(result) {
+		t.Errorf("dry run should not need a token: %s", resultText(result))
+	}
+
+	// A token for a different query is rejected
+	result, _, _ = replace(context.Background(), nil, SearchReplaceFilesArgs{Pattern: "oldName", NewStr: "newName", EditToken: token})
+	if !hasErrorCode(result, ErrEditTokenInvalid) {
+		t.Errorf("expected EDIT_TOKEN_INVALID for mismatched query, got: %s", resultText(result))
+	}
+	if got := readString(t, filepath.Join(tmp, "a.go")); got != "oldName()\noldName()\n" {
+		t.Errorf("a.go should be unchanged after rejection, got %q", got)
+	}
+
+	// Matching token succeeds once
+	result, _, _ = replace(context.Background(), nil, SearchReplaceFilesArgs{Pattern: "oldName", Type: "go", NewStr: "newName", EditToken: token})
+	if isErrorResult(result) {
+		t.Fatalf("replace with valid token failed: %s", resultText(result))
+	}
+	if got := readString(t, filepath.Join(tmp, "a.go")); got != "newName()\nnewName()\n" {
+		t.Errorf("a.go = %q", got)
+	}
+
+	// A used token is stale
+	result, _, _ = replace(context.Background(), nil, SearchReplaceFilesArgs{Pattern: "oldName", Type: "go", NewStr: "newName", EditToken: token})
+	if !hasErrorCode(result, ErrEditTokenInvalid) {
+		t.Errorf("expected EDIT_TOKEN_INVALID for reused token, got: %s", resultText(result))
+	}
+
+	// A grep narrowed by a filter search_replace_files lacks showed fewer
+	// files than the edit would touch, so its token is not accepted
+	os.MkdirAll(filepath.Join(tmp, ".hidden"), 0755)
+	os.WriteFile(filepath.Join(tmp, ".hidden", "h.go"), []byte("newName\n"), 0644)
+	grepResult, _, _ = grep(context.Background(), nil, GrepArgs{Pattern: "newName", Type: "go", Hidden: boolPtr(false), EditToken: true})
+	if isErrorResult(grepResult) {
+		t.Fatalf("grep failed: %s", resultText(grepResult))
+	}
+	token = editTokenFrom(t, resultText(grepResult))
+	result, _, _ = replace(context.Background(), nil, SearchReplaceFilesArgs{Pattern: "newName", Type: "go", NewStr: "otherName", EditToken: token})
+	if !hasErrorCode(result, ErrEditTokenInvalid) {
+		t.Errorf("expected EDIT_TOKEN_INVALID for a token from a hidden=false grep, got: %s", resultText(result))
+	}
+	if got := readString(t, filepath.Join(tmp, ".hidden", "h.go")); got != "newName\n" {
+		t.Errorf(".hidden/h.go = %q, want unchanged", got)
+	}
+}
+
+func TestSearchReplaceFilesPartialFailure(t *testing.T) {
+	tmp := t.TempDir()
+	sess := session.New(tmp)
+	a := filepath.Join(tmp, "a.go")
+	os.WriteFile(a, []byte("old\n"), 0644)
+	noTemps := func() {
+		t.Helper()
+		if matches, _ := filepath.Glob(filepath.Join(tmp, ".*.tmp-*")); len(matches) > 0 {
+			t.Errorf("temporary files left behind: %v", matches)
+		}
+	}
+
+	t.Run("staging failure modifies nothing", func(t *testing.T) {
+		err := applyFileEdits(sess, []fileEdit{
+			{resolved: a, content: []byte("new\n"), perm: 0644},
+			{resolved: filepath.Join(tmp, "missing", "b.go"), content: []byte("new\n"), perm: 0644},
+		})
+		if err == nil || !strings.Contains(err.Error(), "no files were modified") {
+			t.Fatalf("expected a no-files-modified error, got %v", err)
+		}
+		if got := readString(t, a); got != "old\n" {
+			t.Errorf("a.go = %q, want unchanged", got)
+		}
+		noTemps()
+	})
+
+	t.Run("rename failure lists changed files", func(t *testing.T) {
+		// Renaming a file over a non-empty directory fails
+		dir := filepath.Join(tmp, "c.go")
+		os.MkdirAll(filepath.Join(dir, "inner"), 0755)
+		err := applyFileEdits(sess, []fileEdit{
+			{resolved: a, content: []byte("new\n"), perm: 0644},
+			{resolved: dir, content: []byte("new\n"), perm: 0644},
+		})
+		if err == nil || !strings.Contains(err.Error(), "already changed: "+a) {
+			t.Fatalf("expected an error listing %s, got %v", a, err)
+		}
+		if got := readString(t, a); got != "new\n" {
+			t.Errorf("a.go = %q, want new content", got)
+		}
+		noTemps()
+	})
+}
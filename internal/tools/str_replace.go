@@ -30,13 +30,18 @@ func doStrReplace(sess *session.Session, resolver *pathscope.Resolver, cfg Confi
 		return toolErr(ErrInvalidInput, "old_str must not be empty")
 	}
 
-	resolved, err := resolver.Resolve(sess.Cwd(), path)
+	resolved, err := resolver.ResolveForWrite(sess.Cwd(), path)
 	if err != nil {
 		return toolErr(ErrAccessDenied, "path not allowed: %v", err)
 	}
 
-	if cfg.RequireViewBeforeEdit && !sess.HasViewed(resolved) {
-		return toolErr(ErrFileNotViewed, "file %s must be viewed before editing. Use the view tool first.", resolved)
+	if cfg.RequireViewBeforeEdit {
+		switch viewed, changed := viewState(sess, resolved); {
+		case !viewed:
+			return toolErr(ErrFileNotViewed, "file %s must be viewed before editing. %s", resolved, viewHint(cfg, resolved))
+		case changed:
+			return toolErr(ErrFileNotViewed, "file %s changed since it was viewed; view it again before editing. %s", resolved, viewHint(cfg, resolved))
+		}
 	}
 
 	info, err := os.Stat(resolved)
@@ -55,6 +60,9 @@ func doStrReplace(sess *session.Session, resolver *pathscope.Resolver, cfg Confi
 
 	count := strings.Count(content, oldStr)
 	if count == 0 {
+		if hint := notFoundHint(content, oldStr); hint != "" {
+			return toolErr(ErrStrReplaceNotFound, "old_str not found in %s. %s", resolved, hint)
+		}
 		return toolErr(ErrStrReplaceNotFound, "old_str not found in %s", resolved)
 	}
 
@@ -63,7 +71,9 @@ func doStrReplace(sess *session.Session, resolver *pathscope.Resolver, cfg Confi
 		if err := os.WriteFile(resolved, []byte(newContent), info.Mode().Perm()); err != nil {
 			return toolErr(ErrIO, "could not write %s: %v", resolved, err)
 		}
+		refreshViewed(sess, resolved)
 		text := fmt.Sprintf("Replaced %d occurrences in %s", count, resolved)
+		text = appendEditDiff(cfg, text, resolved, content, newContent)
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{Text: text}},
 		}, nil, nil
@@ -80,11 +90,13 @@ func doStrReplace(sess *session.Session, resolver *pathscope.Resolver, cfg Confi
 	if err := os.WriteFile(resolved, []byte(newContent), info.Mode().Perm()); err != nil {
 		return toolErr(ErrIO, "could not write %s: %v", resolved, err)
 	}
+	refreshViewed(sess, resolved)
 
 	// Build context snippet around the replacement
 	snippet := contextSnippet(newContent, offset)
 
 	text := fmt.Sprintf("Replaced in %s\n\n%s", resolved, snippet)
+	text = appendEditDiff(cfg, text, resolved, content, newContent)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: text}},
 	}, nil, nil
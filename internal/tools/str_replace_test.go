@@ -6,9 +6,11 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mjkoo/boris/internal/pathscope"
 	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func TestStrReplaceSuccessful(t *testing.T) {
@@ -309,6 +311,64 @@ func TestStrReplaceFileNotFound(t *testing.T) {
 	}
 }
 
+func TestStrReplaceNotFoundHints(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		oldStr  string
+		hint    string // empty means no hint expected
+	}{
+		{
+			name:    "closest line",
+			content: "package main\n\nfunc handleRequest(w http.ResponseWriter) {\n}\n",
+			oldStr:  "func handleRequests(w http.ResponseWriter) {\n}",
+			hint:    "Hint: the closest line to the start of old_str is line 3: func handleRequest(w http.ResponseWriter) {",
+		},
+		{
+			name:    "whitespace mismatch",
+			content: "if ok {\n\treturn nil\n}\n",
+			oldStr:  "if ok {\n    return nil\n}",
+			hint:    "Hint: old_str matches if whitespace is ignored",
+		},
+		{
+			name:    "crlf line endings",
+			content: "first\r\nsecond\r\n",
+			oldStr:  "first\nsecond",
+			hint:    "Hint: the file uses CRLF line endings",
+		},
+		{
+			name:    "nothing similar",
+			content: "hello\n",
+			oldStr:  "completely unrelated text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			file := filepath.Join(tmp, "test.txt")
+			os.WriteFile(file, []byte(tt.content), 0644)
+			resolver, _ := pathscope.NewResolver(nil, nil)
+			handler := strReplaceHandler(session.New(tmp), resolver, testConfig())
+
+			result, _, err := handler(context.Background(), nil, StrReplaceArgs{Path: file, OldStr: tt.oldStr, NewStr: "x"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasErrorCode(result, ErrStrReplaceNotFound) {
+				t.Fatalf("expected error code %s, got: %s", ErrStrReplaceNotFound, resultText(result))
+			}
+			text := resultText(result)
+			if tt.hint == "" {
+				if strings.Contains(text, "Hint:") {
+					t.Errorf("expected no hint, got: %s", text)
+				}
+			} else if !strings.Contains(text, tt.hint) {
+				t.Errorf("expected hint %q, got: %s", tt.hint, text)
+			}
+		})
+	}
+}
+
 func TestStrReplaceViewBeforeEdit(t *testing.T) {
 	t.Run("rejected when file not viewed", func(t *testing.T) {
 		tmp := t.TempDir()
@@ -332,6 +392,9 @@ func TestStrReplaceViewBeforeEdit(t *testing.T) {
 		if !hasErrorCode(result, ErrFileNotViewed) {
 			t.Errorf("expected error code %s, got: %s", ErrFileNotViewed, resultText(result))
 		}
+		if want := "Hint: call view with path " + file; !strings.Contains(resultText(result), want) {
+			t.Errorf("expected hint %q, got: %s", want, resultText(result))
+		}
 
 		// File should be unchanged
 		data, _ := os.ReadFile(file)
@@ -392,3 +455,143 @@ func TestStrReplaceViewBeforeEdit(t *testing.T) {
 		}
 	})
 }
+
+func TestViewBeforeEditDetectsChanges(t *testing.T) {
+	setup := func(t *testing.T) (string, *session.Session, *pathscope.Resolver, Config) {
+		t.Helper()
+		tmp := t.TempDir()
+		file := filepath.Join(tmp, "test.txt")
+		os.WriteFile(file, []byte("hello world\n"), 0644)
+		sess := session.New(tmp)
+		resolver, _ := pathscope.NewResolver(nil, nil)
+		cfg := testConfig()
+		cfg.RequireViewBeforeEdit = true
+		result, _, err := viewHandler(sess, resolver, cfg)(context.Background(), nil, ViewArgs{Path: file})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(result) {
+			t.Fatal(resultText(result))
+		}
+		return file, sess, resolver, cfg
+	}
+	expectChanged := func(t *testing.T, result *mcp.CallToolResult) {
+		t.Helper()
+		if !hasErrorCode(result, ErrFileNotViewed) {
+			t.Fatalf("expected %s, got: %s", ErrFileNotViewed, resultText(result))
+		}
+		if !strings.Contains(resultText(result), "changed since it was viewed") {
+			t.Errorf("expected a changed-since-view note, got: %s", resultText(result))
+		}
+	}
+
+	t.Run("str_replace after an external write", func(t *testing.T) {
+		file, sess, resolver, cfg := setup(t)
+		os.WriteFile(file, []byte("hello there world\n"), 0644)
+
+		handler := strReplaceHandler(sess, resolver, cfg)
+		args := StrReplaceArgs{Path: file, OldStr: "hello", NewStr: "goodbye"}
+		result, _, err := handler(context.Background(), nil, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectChanged(t, result)
+		if got := readString(t, file); got != "hello there world\n" {
+			t.Errorf("file modified despite stale view: %q", got)
+		}
+
+		viewHandler(sess, resolver, cfg)(context.Background(), nil, ViewArgs{Path: file})
+		result, _, err = handler(context.Background(), nil, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(result) {
+			t.Fatalf("expected success after re-viewing, got: %s", resultText(result))
+		}
+	})
+
+	t.Run("same-size change detected by mtime", func(t *testing.T) {
+		file, sess, resolver, cfg := setup(t)
+		os.WriteFile(file, []byte("jello world\n"), 0644)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(file, later, later)
+
+		result, _, err := strReplaceHandler(sess, resolver, cfg)(context.Background(), nil, StrReplaceArgs{Path: file, OldStr: "world", NewStr: "there"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectChanged(t, result)
+	})
+
+	t.Run("own edits do not require a re-view", func(t *testing.T) {
+		file, sess, resolver, cfg := setup(t)
+		handler := strReplaceHandler(sess, resolver, cfg)
+		for _, edit := range []StrReplaceArgs{
+			{Path: file, OldStr: "hello", NewStr: "goodbye"},
+			{Path: file, OldStr: "world", NewStr: "everyone and more"},
+		} {
+			result, _, err := handler(context.Background(), nil, edit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isErrorResult(result) {
+				t.Fatalf("consecutive edit %q failed: %s", edit.OldStr, resultText(result))
+			}
+		}
+	})
+
+	t.Run("create_file overwrite after an external write", func(t *testing.T) {
+		file, sess, resolver, cfg := setup(t)
+		os.WriteFile(file, []byte("someone else's content\n"), 0644)
+
+		result, _, err := createFileHandler(sess, resolver, cfg)(context.Background(), nil, CreateFileArgs{Path: file, Content: "mine\n"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, ErrFileNotViewed) || !strings.Contains(resultText(result), "changed since it was viewed") {
+			t.Fatalf("expected %s with a changed-since-view note, got: %s", ErrFileNotViewed, resultText(result))
+		}
+	})
+}
+
+func TestStrReplaceDenyWritePattern(t *testing.T) {
+	tmp := t.TempDir()
+	lock := filepath.Join(tmp, "deps.lock")
+	os.WriteFile(lock, []byte("pinned = 1\n"), 0644)
+
+	sess := session.New(tmp)
+	base, _ := pathscope.NewResolver([]string{tmp}, nil)
+	resolver, err := base.WithDenyWritePatterns([]string{"**/*.lock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading is allowed
+	view := viewHandler(sess, resolver, testConfig())
+	result, _, err := view(context.Background(), nil, ViewArgs{Path: "deps.lock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) || !strings.Contains(resultText(result), "pinned = 1") {
+		t.Fatalf("view of read-only file should succeed, got: %s", resultText(result))
+	}
+
+	// Editing is not
+	edit := strReplaceHandler(sess, resolver, testConfig())
+	result, _, err = edit(context.Background(), nil, StrReplaceArgs{Path: "deps.lock", OldStr: "1", NewStr: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(result, ErrAccessDenied) {
+		t.Errorf("expected %s, got: %s", ErrAccessDenied, resultText(result))
+	}
+
+	create := createFileHandler(sess, resolver, testConfig())
+	result, _, _ = create(context.Background(), nil, CreateFileArgs{Path: "deps.lock", Content: "x"})
+	if !hasErrorCode(result, ErrAccessDenied) {
+		t.Errorf("create_file: expected %s, got: %s", ErrAccessDenied, resultText(result))
+	}
+	if data, _ := os.ReadFile(lock); string(data) != "pinned = 1\n" {
+		t.Errorf("read-only file was modified: %q", data)
+	}
+}
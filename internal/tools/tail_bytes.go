@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultTailBytes is how much of the file tail_bytes returns when bytes is
+// not given.
+const defaultTailBytes = 8 * 1024
+
+// TailBytesArgs is the input schema for the tail_bytes tool.
+type TailBytesArgs struct {
+	Path  string `json:"path" jsonschema:"the file to read the end of"`
+	Bytes int64  `json:"bytes,omitempty" jsonschema:"how many bytes to read from the end of the file (default 8192); a partial first line is dropped"`
+}
+
+func tailBytesHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[TailBytesArgs, any] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, args TailBytesArgs) (*mcp.CallToolResult, any, error) {
+		return doTailBytes(sess, resolver, cfg, args)
+	}
+}
+
+func doTailBytes(sess *session.Session, resolver *pathscope.Resolver, cfg Config, args TailBytesArgs) (*mcp.CallToolResult, any, error) {
+	if args.Path == "" {
+		return toolErr(ErrInvalidInput, "path must not be empty")
+	}
+	n := args.Bytes
+	if n == 0 {
+		n = defaultTailBytes
+	}
+	if n < 0 {
+		return toolErr(ErrInvalidInput, "bytes must be positive, got %d", n)
+	}
+	if limit := cfg.viewFileLimit(); n > limit {
+		return toolErr(ErrFileTooLarge, "bytes is %d, exceeds maximum %d bytes", n, limit)
+	}
+
+	resolved, err := resolver.Resolve(sess.Cwd(), args.Path)
+	if err != nil {
+		return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return toolErr(ErrPathNotFound, "%s does not exist", resolved)
+		}
+		return toolErr(ErrIO, "could not stat %s: %v", resolved, err)
+	}
+	if info.IsDir() {
+		return toolErr(ErrInvalidInput, "%s is a directory", resolved)
+	}
+	// Opening or reading a FIFO, device, or socket can block forever
+	if !info.Mode().IsRegular() {
+		return toolErr(ErrInvalidInput, "%s is a %s, not a regular file", resolved, fileKind(info.Mode()))
+	}
+	f, err := os.Open(resolved)
+	if err != nil {
+		return toolErr(ErrIO, "could not open %s: %v", resolved, err)
+	}
+	defer f.Close()
+
+	size := info.Size()
+	data, start, err := readTail(f, size, n)
+	if err != nil {
+		return toolErr(ErrIO, "could not read %s: %v", resolved, err)
+	}
+
+	if isBinaryHeader(data[:min(len(data), 512)]) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Binary file (%s)", formatSize(size))}},
+		}, nil, nil
+	}
+	text := fmt.Sprintf("[bytes %d-%d of %d]\n%s", start, size, size, data)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+	}, nil, nil
+}
+
+// readTail reads up to the last n bytes of f, whose size is size, dropping a
+// partial first line. It returns the data and the offset it starts at.
+func readTail(f *os.File, size, n int64) ([]byte, int64, error) {
+	// Read one byte before the window to tell whether it starts mid-line.
+	start := max(size-n, 0)
+	readFrom := max(start-1, 0)
+	data, err := io.ReadAll(io.NewSectionReader(f, readFrom, size-readFrom))
+	if err != nil {
+		return nil, 0, err
+	}
+	if start > 0 && len(data) > 0 {
+		startsLine := data[0] == '\n'
+		data = data[1:]
+		if !startsLine {
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				start += int64(i + 1)
+				data = data[i+1:]
+			}
+		}
+	}
+	return data, start, nil
+}
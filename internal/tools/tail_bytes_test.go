@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func callTailBytes(t *testing.T, sess *session.Session, resolver *pathscope.Resolver, args TailBytesArgs) (string, bool) {
+	t.Helper()
+	result, _, err := tailBytesHandler(sess, resolver, testConfig())(context.Background(), nil, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resultText(result), isErrorResult(result)
+}
+
+func TestTailBytes(t *testing.T) {
+	tmp := t.TempDir()
+	var log strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&log, "line %03d\n", i) // 9 bytes per line
+	}
+	content := log.String()
+	os.WriteFile(filepath.Join(tmp, "app.log"), []byte(content), 0644)
+	sess := session.New(tmp)
+	resolver := testResolver(t)
+
+	t.Run("aligned window returns the exact suffix", func(t *testing.T) {
+		text, isErr := callTailBytes(t, sess, resolver, TailBytesArgs{Path: "app.log", Bytes: 27})
+		if isErr {
+			t.Fatal(text)
+		}
+		want := fmt.Sprintf("[bytes 873-900 of 900]\n%s", content[len(content)-27:])
+		if text != want {
+			t.Errorf("got %q, want %q", text, want)
+		}
+	})
+
+	t.Run("partial first line is dropped", func(t *testing.T) {
+		text, _ := callTailBytes(t, sess, resolver, TailBytesArgs{Path: "app.log", Bytes: 30})
+		body := strings.SplitN(text, "\n", 2)[1]
+		if !strings.HasSuffix(content, body) {
+			t.Errorf("%q is not a suffix of the file", body)
+		}
+		if body != "line 098\nline 099\nline 100\n" {
+			t.Errorf("got %q, want the last three whole lines", body)
+		}
+	})
+
+	t.Run("window larger than file returns everything", func(t *testing.T) {
+		text, _ := callTailBytes(t, sess, resolver, TailBytesArgs{Path: "app.log", Bytes: 10000})
+		if text != "[bytes 0-900 of 900]\n"+content {
+			t.Errorf("expected the whole file, got %d bytes", len(text))
+		}
+	})
+
+	t.Run("default size", func(t *testing.T) {
+		text, _ := callTailBytes(t, sess, resolver, TailBytesArgs{Path: "app.log"})
+		if !strings.HasSuffix(text, content) {
+			t.Error("default window should cover this small file")
+		}
+	})
+}
+
+func TestTailBytesErrors(t *testing.T) {
+	tmp := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.log"), []byte("secret\n"), 0644)
+	sess := session.New(tmp)
+	resolver, err := pathscope.NewResolver([]string{tmp}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		args TailBytesArgs
+		code string
+	}{
+		{"out of scope", TailBytesArgs{Path: filepath.Join(outside, "secret.log")}, ErrAccessDenied},
+		{"missing", TailBytesArgs{Path: "missing.log"}, ErrPathNotFound},
+		{"directory", TailBytesArgs{Path: "."}, ErrInvalidInput},
+		{"negative bytes", TailBytesArgs{Path: "x.log", Bytes: -1}, ErrInvalidInput},
+		{"over limit", TailBytesArgs{Path: "x.log", Bytes: 1 << 40}, ErrFileTooLarge},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _, _ := tailBytesHandler(sess, resolver, testConfig())(context.Background(), nil, tt.args)
+			if !hasErrorCode(result, tt.code) {
+				t.Errorf("expected %s, got: %s", tt.code, resultText(result))
+			}
+		})
+	}
+}
+
+func TestTailBytesFIFO(t *testing.T) {
+	tmp := t.TempDir()
+	fifo := filepath.Join(tmp, "pipe")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+
+	done := make(chan *mcp.CallToolResult, 1)
+	go func() {
+		result, _, _ := tailBytesHandler(sess, resolver, testConfig())(context.Background(), nil, TailBytesArgs{Path: fifo})
+		done <- result
+	}()
+	select {
+	case result := <-done:
+		if !hasErrorCode(result, ErrInvalidInput) || !strings.Contains(resultText(result), "named pipe") {
+			t.Errorf("expected INVALID_INPUT naming a named pipe, got: %s", resultText(result))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tail_bytes blocked on a FIFO")
+	}
+}
@@ -2,7 +2,10 @@ package tools
 
 import (
 	"strings"
+	"testing"
 
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -23,16 +26,35 @@ func isErrorResult(r *mcp.CallToolResult) bool {
 	return r != nil && r.IsError
 }
 
-// hasErrorCode returns true if the result is an error with the given code prefix.
+// hasErrorCode returns true if the result is an error with the given code,
+// both as the text prefix and in the structured error content.
 func hasErrorCode(r *mcp.CallToolResult, code string) bool {
-	return isErrorResult(r) && strings.HasPrefix(resultText(r), "["+code+"]")
+	if !isErrorResult(r) || !strings.HasPrefix(resultText(r), "["+code+"]") {
+		return false
+	}
+	te, ok := r.StructuredContent.(toolError)
+	return ok && te.Code == code
+}
+
+// testResolver returns a Resolver with no allow or deny rules.
+func testResolver(t *testing.T) *pathscope.Resolver {
+	t.Helper()
+	resolver, err := pathscope.NewResolver(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolver
 }
 
 // testConfig returns a Config suitable for testing.
 func testConfig() Config {
 	return Config{
-		Shell:          "/bin/sh",
-		DefaultTimeout: 120,
-		MaxFileSize:    10 * 1024 * 1024,
+		Shell:                "/bin/sh",
+		DefaultTimeout:       120,
+		MaxFileSize:          10 * 1024 * 1024,
+		MaxBackgroundTasks:   session.DefaultMaxTasks,
+		NormalizeLineEndings: true,
+		SentinelWarning:      true,
+		FollowSymlinks:       true,
 	}
 }
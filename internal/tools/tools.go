@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/mjkoo/boris/internal/pathscope"
@@ -30,6 +34,11 @@ const (
 	ErrBashTaskNotFound = "BASH_TASK_NOT_FOUND"
 )
 
+// Watch tool codes
+const (
+	ErrWatchFailed = "WATCH_FAILED"
+)
+
 // Str_replace tool codes
 const (
 	ErrStrReplaceNotFound  = "STR_REPLACE_NOT_FOUND"
@@ -41,6 +50,12 @@ const (
 	ErrFileNotViewed = "FILE_NOT_VIEWED"
 )
 
+// Edit token codes
+const (
+	ErrEditTokenRequired = "EDIT_TOKEN_REQUIRED"
+	ErrEditTokenInvalid  = "EDIT_TOKEN_INVALID"
+)
+
 // Grep tool codes
 const (
 	ErrGrepInvalidPattern    = "GREP_INVALID_PATTERN"
@@ -55,22 +70,48 @@ const (
 
 // standardToolNames lists the MCP tool names available in standard mode.
 var standardToolNames = map[string]struct{}{
-	"bash":        {},
-	"task_output": {},
-	"view":        {},
-	"str_replace": {},
-	"create_file": {},
-	"grep":        {},
-	"glob":        {},
+	"bash":                 {},
+	"task_output":          {},
+	"kill_all_tasks":       {},
+	"view":                 {},
+	"str_replace":          {},
+	"create_file":          {},
+	"grep":                 {},
+	"glob":                 {},
+	"glob_explain":         {},
+	"search_replace_files": {},
+	"recent_files":         {},
+	"repo_map":             {},
+	"get_scope":            {},
+	"list_viewed":          {},
+	"count_lines":          {},
+	"diff_files":           {},
+	"list_directory":       {},
+	"find_and_view":        {},
+	"touch":                {},
+	"watch":                {},
+	"tail_bytes":           {},
 }
 
 // anthropicToolNames lists the MCP tool names available in anthropic-compat mode.
 var anthropicToolNames = map[string]struct{}{
 	"bash":               {},
 	"task_output":        {},
+	"kill_all_tasks":     {},
 	"str_replace_editor": {},
 	"grep":               {},
 	"glob":               {},
+	"glob_explain":       {},
+	"recent_files":       {},
+	"repo_map":           {},
+	"get_scope":          {},
+	"list_viewed":        {},
+	"count_lines":        {},
+	"diff_files":         {},
+	"list_directory":     {},
+	"find_and_view":      {},
+	"watch":              {},
+	"tail_bytes":         {},
 }
 
 // ValidateDisableTools checks that all tool names in the set are valid for the given mode.
@@ -99,6 +140,29 @@ func ValidateDisableTools(names map[string]struct{}, anthropicCompat bool) error
 	return nil
 }
 
+// ValidateToolDescriptions checks that every overridden tool name is exposed
+// in the given mode and that no replacement description is empty.
+func ValidateToolDescriptions(descriptions map[string]string, anthropicCompat bool) error {
+	valid := standardToolNames
+	if anthropicCompat {
+		valid = anthropicToolNames
+	}
+	for name, desc := range descriptions {
+		if _, ok := valid[name]; !ok {
+			validNames := make([]string, 0, len(valid))
+			for n := range valid {
+				validNames = append(validNames, n)
+			}
+			sort.Strings(validNames)
+			return fmt.Errorf("unknown tool name %q; valid tools: %v", name, validNames)
+		}
+		if strings.TrimSpace(desc) == "" {
+			return fmt.Errorf("description for tool %q must not be empty", name)
+		}
+	}
+	return nil
+}
+
 // typeSchemas provides custom JSON schema mappings for named types.
 var typeSchemas = map[reflect.Type]*jsonschema.Schema{
 	reflect.TypeFor[EditorCommand](): {
@@ -111,26 +175,101 @@ var typeSchemas = map[reflect.Type]*jsonschema.Schema{
 	},
 }
 
+// toolError is the machine-readable form of a tool error, carried in the
+// result's structuredContent so clients need not parse the text prefix.
+type toolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 // toolErr returns a CallToolResult with IsError set to true.
 // Use this for operational errors (file not found, invalid input, etc.)
 // instead of returning Go errors, which are reserved for infrastructure failures.
 // The code parameter must be one of the Err* constants defined above.
+// The text content reads "[CODE] message"; the same code and message are
+// also set as structured content.
 func toolErr(code string, msg string, args ...any) (*mcp.CallToolResult, any, error) {
 	r := &mcp.CallToolResult{}
-	text := fmt.Sprintf("[%s] %s", code, fmt.Sprintf(msg, args...))
-	r.SetError(errors.New(text))
+	message := fmt.Sprintf(msg, args...)
+	r.SetError(errors.New(fmt.Sprintf("[%s] %s", code, message)))
+	r.StructuredContent = toolError{Code: code, Message: message}
 	return r, nil, nil
 }
 
+// paginationMeta describes which slice of a result list a grep or glob
+// response holds, so that a client paging with offset and head_limit knows
+// whether to request another page. It is attached under the "pagination"
+// key of the result's _meta.
+type paginationMeta struct {
+	Total    int  `json:"total"` // results before offset and limits: files, or output lines in grep content mode; only those counted before a search stopped early
+	Offset   int  `json:"offset"`
+	Returned int  `json:"returned"`
+	HasMore  bool `json:"has_more"`
+}
+
+// withPagination attaches pagination metadata to r and returns it.
+func withPagination(r *mcp.CallToolResult, total, offset, returned int) *mcp.CallToolResult {
+	if r.Meta == nil {
+		r.Meta = mcp.Meta{}
+	}
+	r.Meta["pagination"] = paginationMeta{
+		Total:    total,
+		Offset:   offset,
+		Returned: returned,
+		HasMore:  offset+returned < total,
+	}
+	return r
+}
+
+// markHasMore sets has_more in r's pagination metadata, for results cut short
+// by a limit before every result was counted.
+func markHasMore(r *mcp.CallToolResult) {
+	if pm, ok := r.Meta["pagination"].(paginationMeta); ok {
+		pm.HasMore = true
+		r.Meta["pagination"] = pm
+	}
+}
+
 // Config holds configuration for tool registration.
 type Config struct {
 	DisableTools         map[string]struct{}
+	EnableTools          map[string]struct{} // if non-nil, only these tools (and their companions) register
 	MaxFileSize          int64
+	MaxViewFileSize       int64 // size limit for view; 0 = MaxFileSize
+	MaxCreateFileSize     int64 // size limit for create_file content; 0 = MaxFileSize
 	DefaultTimeout       int
+	MaxBashTimeoutMs      int // largest timeout a bash call may request, in milliseconds; 0 = defaultMaxBashTimeoutMs
 	Shell                string
 	AnthropicCompat      bool
 	BackgroundTaskTimeout int // background task safety-net timeout in seconds (0 = disabled)
 	RequireViewBeforeEdit bool
+	ToolTimeout           int // per-call timeout in seconds for read-only tools (0 = unlimited)
+	MaxBackgroundTasks    int // concurrent background task limit per session (0 = background tasks disabled)
+	BackgroundNiceness    int // niceness applied to background task process groups (0 = unchanged)
+	NormalizeLineEndings  bool // present CRLF/CR line endings as LF in view and grep output
+	ExcludeDirs           []string // directory names skipped by grep, glob, and view, in addition to .git and node_modules
+	ExtraIgnoreFiles      []string // ignore files honored alongside .gitignore in directory walks, e.g. .ignore and .rgignore
+	SanitizeUTF8          bool // replace invalid UTF-8 in tool result text with U+FFFD
+	StructuredBashOutput  bool // attach a JSON result block to bash and task_output responses
+	SentinelWarning       bool // note in bash output when the cwd sentinel was not observed
+	EnableGrepCache       bool // reuse grep results within a session until a searched file's mtime changes
+	ProgressByteCount     bool // bash progress notifications report bytes of output so far instead of lines
+	ProgressStreamPrefix  bool // prefix bash progress messages with "stdout: " or "stderr: "
+	DisableProgress       bool // never send progress notifications from bash or watch
+	RequireEditToken      bool // search_replace_files requires an edit_token from a prior grep with the same query
+	AllowGrepInPlace      bool // grep may write its replace substitution to files when in_place is set
+	EditDiffs             bool // append a unified diff of each edit to str_replace and create_file results
+	ReadOnly              bool // suppress bash and every tool that modifies files
+	ExposeResources       bool // list workspace files as MCP resources and serve their content
+	FollowSymlinks        bool // view reads through symlinks whose targets are in scope; otherwise symlinks are refused
+
+	// ToolDescriptions overrides the built-in description of tools by name.
+	// Tools not present keep their default description.
+	ToolDescriptions map[string]string
+
+	// AuditLog, if non-nil, receives one JSON line per tool invocation.
+	// Writes are serialized, so a single writer may be shared across sessions.
+	AuditLog io.Writer
 
 	// RegisterSession is called on first bash/task_output invocation with the
 	// SDK session ID. In HTTP mode this registers the Boris session in the
@@ -138,8 +277,64 @@ type Config struct {
 	RegisterSession func(sessionID string)
 }
 
-// toolDisabled reports whether the given tool name is in the DisableTools set.
+// mutatingToolNames lists the tools suppressed in read-only mode: those that
+// write files, plus bash and task_output, since shell commands cannot be
+// reliably restricted to reads.
+var mutatingToolNames = map[string]struct{}{
+	"bash":                 {},
+	"task_output":          {},
+	"kill_all_tasks":       {},
+	"str_replace":          {},
+	"create_file":          {},
+	"str_replace_editor":   {},
+	"search_replace_files": {},
+	"touch":                {},
+}
+
+// viewFileLimit returns the largest file view will read.
+func (c Config) viewFileLimit() int64 {
+	if c.MaxViewFileSize > 0 {
+		return c.MaxViewFileSize
+	}
+	return c.MaxFileSize
+}
+
+// defaultMaxBashTimeoutMs is the bash timeout cap when MaxBashTimeoutMs is unset.
+const defaultMaxBashTimeoutMs = 600000
+
+// maxBashTimeoutMs returns the largest timeout a bash call may use.
+func (c Config) maxBashTimeoutMs() int {
+	if c.MaxBashTimeoutMs > 0 {
+		return c.MaxBashTimeoutMs
+	}
+	return defaultMaxBashTimeoutMs
+}
+
+// createFileLimit returns the largest content create_file will write.
+func (c Config) createFileLimit() int64 {
+	if c.MaxCreateFileSize > 0 {
+		return c.MaxCreateFileSize
+	}
+	return c.MaxFileSize
+}
+
+// enableCompanions maps tools that cannot be enabled on their own to the
+// tool that brings them along when listed in EnableTools.
+var enableCompanions = map[string]string{
+	"task_output":    "bash",
+	"kill_all_tasks": "bash",
+}
+
+// toolDisabled reports whether the given tool name is in the DisableTools set,
+// is missing from a non-nil EnableTools set, or is a mutating tool and
+// cfg.ReadOnly is set.
 func toolDisabled(cfg Config, name string) bool {
+	if _, ok := mutatingToolNames[name]; ok && cfg.ReadOnly {
+		return true
+	}
+	if cfg.EnableTools != nil && !toolEnabled(cfg, name) {
+		return true
+	}
 	if cfg.DisableTools == nil {
 		return false
 	}
@@ -147,66 +342,249 @@ func toolDisabled(cfg Config, name string) bool {
 	return ok
 }
 
+// toolEnabled reports whether name is in the EnableTools set, directly or
+// through its companion tool. In anthropic-compat mode, enabling
+// str_replace_editor enables the view, str_replace, and create_file names it
+// stands in for.
+func toolEnabled(cfg Config, name string) bool {
+	if _, ok := cfg.EnableTools[name]; ok {
+		return true
+	}
+	if parent, ok := enableCompanions[name]; ok {
+		_, ok = cfg.EnableTools[parent]
+		return ok
+	}
+	if cfg.AnthropicCompat {
+		switch name {
+		case "view", "str_replace", "create_file":
+			_, ok := cfg.EnableTools["str_replace_editor"]
+			return ok
+		}
+	}
+	return false
+}
+
+// withToolTimeout wraps the handler of the tool called name so that its
+// context carries a per-call deadline. If the deadline expires before the
+// handler returns, any partial result is discarded in favor of a timeout
+// error. A non-positive timeout returns the handler unchanged, as does a
+// tool in mutatingToolNames: reporting a timeout after its write has landed
+// would misstate what happened.
+func withToolTimeout[In any](name string, h mcp.ToolHandlerFor[In, any], timeout time.Duration) mcp.ToolHandlerFor[In, any] {
+	if _, mutating := mutatingToolNames[name]; mutating || timeout <= 0 {
+		return h
+	}
+	return func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		result, out, err := h(ctx, req, args)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return toolErr(ErrIO, "tool call timed out after %s", timeout)
+		}
+		return result, out, err
+	}
+}
+
+// grepWithTimeout applies the tool timeout to grep calls except in_place
+// ones, which write files and so are exempt like the mutating tools.
+func grepWithTimeout(h mcp.ToolHandlerFor[GrepArgs, any], timeout time.Duration) mcp.ToolHandlerFor[GrepArgs, any] {
+	timed := withToolTimeout("grep", h, timeout)
+	return func(ctx context.Context, req *mcp.CallToolRequest, args GrepArgs) (*mcp.CallToolResult, any, error) {
+		if args.InPlace {
+			return h(ctx, req, args)
+		}
+		return timed(ctx, req, args)
+	}
+}
+
+// addTool registers a tool handler with the server, wrapping it with the
+// cross-cutting behavior shared by every tool (request ID logging and audit
+// logging) and applying any configured description override.
+func addTool[In any](server *mcp.Server, cfg Config, t *mcp.Tool, h mcp.ToolHandlerFor[In, any]) {
+	if desc, ok := cfg.ToolDescriptions[t.Name]; ok {
+		t.Description = desc
+	}
+	mcp.AddTool(server, t, withRequestID(t.Name, withAudit(cfg.AuditLog, t.Name, withValidUTF8(cfg.SanitizeUTF8, h))))
+}
+
 // RegisterAll registers all tools with the MCP server.
 func RegisterAll(server *mcp.Server, resolver *pathscope.Resolver, sess *session.Session, cfg Config) {
-	// Disabling bash also disables task_output
+	toolTimeout := time.Duration(cfg.ToolTimeout) * time.Second
+
+	// Disabling bash also disables task_output and kill_all_tasks
 	if !toolDisabled(cfg, "bash") && !toolDisabled(cfg, "task_output") {
 		bashDesc := "Executes a bash command with optional timeout. The working directory persists between calls. When run_in_background is true, the command runs asynchronously and returns a task_id for later retrieval via task_output."
-		taskOutputDesc := "Retrieve output from a running or completed background bash command by task_id. Running tasks return current output with status: running. Completed tasks return final output, exit code, and are cleaned up after retrieval."
+		taskOutputDesc := "Retrieve output from a running or completed background bash command by task_id. Running tasks return current output with status: running. Completed tasks return final output, exit code, and are cleaned up after retrieval unless peek is true."
 		if cfg.AnthropicCompat {
-			bashDesc = `Executes a given bash command with optional timeout. Working directory persists between commands; shell state (everything else) does not. Timeout in milliseconds (default 120000, max 600000). Output truncated at 30000 characters.`
+			bashDesc = fmt.Sprintf(`Executes a given bash command with optional timeout. Working directory persists between commands; shell state (everything else) does not. Timeout in milliseconds (default 120000, max %d). Output truncated at 30000 characters.`, cfg.maxBashTimeoutMs())
 
-			taskOutputDesc = `Retrieves output from a running or completed background bash command. Takes a task_id returned by a background bash command. Running tasks return current output with status: running. Completed tasks return final output, exit code, and are cleaned up after retrieval.`
+			taskOutputDesc = `Retrieves output from a running or completed background bash command. Takes a task_id returned by a background bash command. Running tasks return current output with status: running. Completed tasks return final output, exit code, and are cleaned up after retrieval unless peek is true.`
 		}
 
-		mcp.AddTool(server, &mcp.Tool{
+		sess.SetMaxTasks(cfg.MaxBackgroundTasks)
+
+		addTool(server, cfg, &mcp.Tool{
 			Name:        "bash",
 			Description: bashDesc,
-		}, bashHandler(sess, cfg))
+		}, bashHandler(sess, resolver, cfg))
 
-		mcp.AddTool(server, &mcp.Tool{
+		addTool(server, cfg, &mcp.Tool{
 			Name:        "task_output",
 			Description: taskOutputDesc,
-		}, taskOutputHandler(sess, cfg))
+		}, taskOutputHandler(sess, resolver, cfg))
+
+		if !toolDisabled(cfg, "kill_all_tasks") {
+			addTool(server, cfg, &mcp.Tool{
+				Name:        "kill_all_tasks",
+				Description: "Terminate every running background bash task in this session (SIGTERM, then SIGKILL after 5 seconds) and discard them. Completed tasks are left for task_output. Returns the IDs of the killed tasks.",
+			}, killAllTasksHandler(sess))
+		}
 	}
 
 	if !toolDisabled(cfg, "grep") {
 		if cfg.AnthropicCompat {
-			mcp.AddTool(server, &mcp.Tool{
+			addTool(server, cfg, &mcp.Tool{
 				Name: "grep",
 				Description: `Search file contents using regex patterns. Supports full regex syntax.
 - Filter files with glob parameter (e.g., "*.js", "**/*.tsx") or type parameter (e.g., "js", "py", "rust")
 - Output modes: "content" shows matching lines, "files_with_matches" shows only file paths (default), "count" shows match counts
 - Multiline matching: By default patterns match within single lines only. For cross-line patterns, use multiline: true`,
-			}, grepCompatHandler(sess, resolver, cfg.MaxFileSize))
+			}, withToolTimeout("grep", grepCompatHandler(sess, resolver, cfg), toolTimeout))
 		} else {
-			mcp.AddTool(server, &mcp.Tool{
+			addTool(server, cfg, &mcp.Tool{
 				Name:        "grep",
 				Description: "Search file contents using regex patterns. Returns matching file paths (sorted by modification time), matching lines with context, or match counts.",
-			}, grepHandler(sess, resolver, cfg.MaxFileSize))
+			}, grepWithTimeout(grepHandler(sess, resolver, cfg), toolTimeout))
 		}
 	}
 
 	if !toolDisabled(cfg, "glob") {
 		if cfg.AnthropicCompat {
-			mcp.AddTool(server, &mcp.Tool{
+			addTool(server, cfg, &mcp.Tool{
 				Name: "glob",
 				Description: `- Fast file pattern matching tool that works with any codebase size
 - Supports glob patterns like "**/*.js" or "src/**/*.ts"
 - Returns matching file paths sorted by modification time
 - Use this tool when you need to find files by name patterns`,
-			}, globCompatHandler(sess, resolver))
+			}, withToolTimeout("glob", globCompatHandler(sess, resolver, cfg), toolTimeout))
 		} else {
-			mcp.AddTool(server, &mcp.Tool{
+			addTool(server, cfg, &mcp.Tool{
 				Name:        "glob",
 				Description: "Find files by glob pattern. Returns matching file paths sorted by modification time (newest first). Supports doublestar patterns, brace expansion, and character classes. Respects .gitignore and skips .git/node_modules.",
-			}, globHandler(sess, resolver))
+			}, withToolTimeout("glob", globHandler(sess, resolver, cfg), toolTimeout))
+		}
+	}
+
+	if !toolDisabled(cfg, "glob_explain") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "glob_explain",
+			Description: "Explain a glob pattern without touching the filesystem: lists the patterns its braces expand to and what each path segment matches, or reports why the pattern is invalid. Useful for checking a pattern before running glob or grep.",
+		}, globExplainHandler())
+	}
+
+	if !toolDisabled(cfg, "recent_files") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "recent_files",
+			Description: "List the most recently modified files under a directory, newest first. Useful for finding what just changed. Optionally restrict to files modified within a duration (e.g. 30m, 2h, 7d). Respects .gitignore and skips .git/node_modules.",
+		}, withToolTimeout("recent_files", recentFilesHandler(sess, resolver, cfg), toolTimeout))
+	}
+
+	if !toolDisabled(cfg, "repo_map") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "repo_map",
+			Description: "Outline a directory tree for orientation: groups files by top-level directory and shows each group's file count and a few representative files, shallowest first. Respects .gitignore and skips .git/node_modules. Useful as a first look at an unfamiliar codebase.",
+		}, withToolTimeout("repo_map", repoMapHandler(sess, resolver, cfg), toolTimeout))
+	}
+
+	if !toolDisabled(cfg, "get_scope") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "get_scope",
+			Description: "Show which paths file tools may access: the current working directory, the allowed directories, and the denied patterns. Read-only. Useful for understanding ACCESS_DENIED errors.",
+		}, getScopeHandler(sess, resolver))
+	}
+
+	if !toolDisabled(cfg, "list_viewed") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "list_viewed",
+			Description: "List the absolute paths of files viewed in this session, sorted. Read-only. When view-before-edit is enforced, only these files may be edited or overwritten without viewing them first.",
+		}, listViewedHandler(sess))
+	}
+
+	if !toolDisabled(cfg, "count_lines") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "count_lines",
+			Description: "Count lines, words, and bytes in one or more files, like wc. Reports per-file counts and a total when several paths are given. Binary and oversized files are skipped with a note.",
+		}, withToolTimeout("count_lines", countLinesHandler(sess, resolver, cfg), toolTimeout))
+	}
+
+	if !toolDisabled(cfg, "diff_files") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "diff_files",
+			Description: "Compare two files and return a unified diff, like diff -u. Identical files return a note instead of an empty diff. Useful for checking generated output against an expected file.",
+		}, withToolTimeout("diff_files", diffFilesHandler(sess, resolver, cfg), toolTimeout))
+	}
+
+	if !toolDisabled(cfg, "list_directory") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "list_directory",
+			Description: "List a directory as a JSON array of entries with name (relative path), type (file, directory, symlink, other), size, mtime, and symlink_target. Descends depth levels (default 1). Respects .gitignore and skips .git/node_modules.",
+		}, withToolTimeout("list_directory", listDirectoryHandler(sess, resolver, cfg), toolTimeout))
+	}
+
+	if !toolDisabled(cfg, "find_and_view") {
+		findAndViewSchema, err := jsonschema.For[FindAndViewArgs](&jsonschema.ForOptions{
+			TypeSchemas: typeSchemas,
+		})
+		if err != nil {
+			panic(fmt.Sprintf("failed to build find_and_view schema: %v", err))
 		}
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "find_and_view",
+			Description: "Find a file by glob pattern and view it in one call. Views the single matching file with line numbers; if several files match, fails with the candidates unless first is set, in which case the most recently modified one is shown.",
+			InputSchema: findAndViewSchema,
+		}, withToolTimeout("find_and_view", findAndViewHandler(sess, resolver, cfg), toolTimeout))
+	}
+
+	if !toolDisabled(cfg, "tail_bytes") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "tail_bytes",
+			Description: "Read the last N bytes of a file (default 8192), dropping a partial first line. Cheaper than counting lines for large append-only files such as logs. Output starts with the byte range returned.",
+		}, withToolTimeout("tail_bytes", tailBytesHandler(sess, resolver, cfg), toolTimeout))
+	}
+
+	if !toolDisabled(cfg, "watch") {
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "watch",
+			Description: "Watch a directory recursively for file changes, reporting each create, write, remove, rename, or chmod event as a progress notification. Runs as a background task until the call is cancelled, the timeout elapses, kill_all_tasks is called, or the session closes, then returns the events seen. Optionally filter by glob pattern.",
+		}, watchHandler(sess, resolver, cfg))
+	}
+
+	if cfg.ExposeResources {
+		registerResources(server, sess, resolver, cfg)
+	}
+
+	registerView := func() {
+		viewSchema, err := jsonschema.For[ViewArgs](&jsonschema.ForOptions{
+			TypeSchemas: typeSchemas,
+		})
+		if err != nil {
+			panic(fmt.Sprintf("failed to build view schema: %v", err))
+		}
+		addTool(server, cfg, &mcp.Tool{
+			Name:        "view",
+			Description: "Read a file from the filesystem with line numbers, or list a directory (2 levels deep). Supports line ranges for large files. Returns images as inline content. Lines longer than 2000 characters are truncated.",
+			InputSchema: viewSchema,
+		}, withToolTimeout("view", viewHandler(sess, resolver, cfg), toolTimeout))
 	}
 
 	// In anthropic-compat mode, disabling any of view/str_replace/create_file
-	// disables the combined str_replace_editor tool.
-	if cfg.AnthropicCompat {
+	// disables the combined str_replace_editor tool. Read-only mode replaces
+	// it with the standalone view tool so that files can still be read.
+	if cfg.AnthropicCompat && cfg.ReadOnly {
+		if !toolDisabled(cfg, "view") {
+			registerView()
+		}
+	} else if cfg.AnthropicCompat {
 		editorDisabled := toolDisabled(cfg, "str_replace_editor") ||
 			toolDisabled(cfg, "view") ||
 			toolDisabled(cfg, "str_replace") ||
@@ -218,42 +596,46 @@ func RegisterAll(server *mcp.Server, resolver *pathscope.Resolver, sess *session
 			if err != nil {
 				panic(fmt.Sprintf("failed to build str_replace_editor schema: %v", err))
 			}
-			mcp.AddTool(server, &mcp.Tool{
+			addTool(server, cfg, &mcp.Tool{
 				Name: "str_replace_editor",
 				Description: `View, create, and edit files. Commands:
 - 'view': Read a file with line numbers, or list a directory. Supports optional view_range [start, end]. Lines longer than 2000 characters are truncated.
 - 'str_replace': Replace a unique string in a file. old_str must appear exactly once unless replace_all is true. Omit new_str to delete.
 - 'create': Create a new file or overwrite an existing one. Creates parent directories as needed.`,
 				InputSchema: editorSchema,
-			}, strReplaceEditorHandler(sess, resolver, cfg))
+			}, withToolTimeout("str_replace_editor", strReplaceEditorHandler(sess, resolver, cfg), toolTimeout))
 		}
 	} else {
 		if !toolDisabled(cfg, "view") {
-			viewSchema, err := jsonschema.For[ViewArgs](&jsonschema.ForOptions{
-				TypeSchemas: typeSchemas,
-			})
-			if err != nil {
-				panic(fmt.Sprintf("failed to build view schema: %v", err))
-			}
-			mcp.AddTool(server, &mcp.Tool{
-				Name:        "view",
-				Description: "Read a file from the filesystem with line numbers, or list a directory (2 levels deep). Supports line ranges for large files. Returns images as inline content. Lines longer than 2000 characters are truncated.",
-				InputSchema: viewSchema,
-			}, viewHandler(sess, resolver, cfg))
+			registerView()
 		}
 
 		if !toolDisabled(cfg, "str_replace") {
-			mcp.AddTool(server, &mcp.Tool{
+			addTool(server, cfg, &mcp.Tool{
 				Name:        "str_replace",
 				Description: "Replace a unique string in a file. The old_str must appear exactly once unless replace_all is true. Omit new_str or set it to empty string to delete the matched text.",
-			}, strReplaceHandler(sess, resolver, cfg))
+			}, withToolTimeout("str_replace", strReplaceHandler(sess, resolver, cfg), toolTimeout))
 		}
 
 		if !toolDisabled(cfg, "create_file") {
-			mcp.AddTool(server, &mcp.Tool{
+			addTool(server, cfg, &mcp.Tool{
 				Name:        "create_file",
 				Description: "Create a new file or overwrite an existing one. Creates parent directories as needed.",
-			}, createFileHandler(sess, resolver, cfg))
+			}, withToolTimeout("create_file", createFileHandler(sess, resolver, cfg), toolTimeout))
+		}
+
+		if !toolDisabled(cfg, "search_replace_files") {
+			addTool(server, cfg, &mcp.Tool{
+				Name:        "search_replace_files",
+				Description: "Apply the same replacement across every file whose contents match a grep-style pattern. Replaces matches of pattern with new_str ($1 expands capture groups), or literal old_str with new_str if old_str is set. Each file is rewritten atomically. Use dry_run to preview the files and counts without modifying anything. Respects .gitignore and skips .git/node_modules.",
+			}, withToolTimeout("search_replace_files", searchReplaceFilesHandler(sess, resolver, cfg), toolTimeout))
+		}
+
+		if !toolDisabled(cfg, "touch") {
+			addTool(server, cfg, &mcp.Tool{
+				Name:        "touch",
+				Description: "Create an empty file if it does not exist (creating parent directories as needed), or set an existing file's modification time to now. Never changes file contents.",
+			}, withToolTimeout("touch", touchHandler(sess, resolver), toolTimeout))
 		}
 	}
 }
@@ -271,7 +653,7 @@ const (
 type StrReplaceEditorArgs struct {
 	Command    EditorCommand `json:"command" jsonschema:"the operation to perform: view, str_replace, or create"`
 	Path       string        `json:"path" jsonschema:"file path"`
-	ViewRange  ViewRange     `json:"view_range,omitempty" jsonschema:"optional line range [start end] (1-indexed, for view command)"`
+	ViewRange  ViewRange     `json:"view_range,omitempty" jsonschema:"optional line range [start end] (1-indexed, negative counts from the end; for view command)"`
 	OldStr     string        `json:"old_str,omitempty" jsonschema:"the string to find (for str_replace command)"`
 	NewStr     string        `json:"new_str,omitempty" jsonschema:"replacement string (for str_replace command)"`
 	ReplaceAll bool          `json:"replace_all,omitempty" jsonschema:"replace all occurrences (for str_replace command)"`
@@ -279,10 +661,10 @@ type StrReplaceEditorArgs struct {
 }
 
 func strReplaceEditorHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[StrReplaceEditorArgs, any] {
-	return func(_ context.Context, _ *mcp.CallToolRequest, args StrReplaceEditorArgs) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, args StrReplaceEditorArgs) (*mcp.CallToolResult, any, error) {
 		switch args.Command {
 		case EditorCommandView:
-			return doView(sess, resolver, cfg, args.Path, args.ViewRange)
+			return doView(ctx, sess, resolver, cfg, args.Path, args.ViewRange, viewOptions{})
 		case EditorCommandStrReplace:
 			return doStrReplace(sess, resolver, cfg, args.Path, args.OldStr, args.NewStr, args.ReplaceAll)
 		case EditorCommandCreate:
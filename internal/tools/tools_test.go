@@ -1,9 +1,18 @@
 package tools
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func TestViewRangeSchemaNotNullable(t *testing.T) {
@@ -62,3 +71,169 @@ func TestEditorViewRangeSchemaNotNullable(t *testing.T) {
 	}
 }
 
+
+// makeLargeTree creates dirs*files files spread across dirs subdirectories.
+func makeLargeTree(t *testing.T, root string, dirs, files int) {
+	t.Helper()
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("dir%03d", d))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			path := filepath.Join(dir, fmt.Sprintf("file%03d.txt", f))
+			if err := os.WriteFile(path, []byte("needle\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestToolTimeout(t *testing.T) {
+	tmp := t.TempDir()
+	makeLargeTree(t, tmp, 50, 20)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	sess := session.New(tmp)
+
+	t.Run("glob times out", func(t *testing.T) {
+		h := withToolTimeout("glob", globHandler(sess, resolver, testConfig()), time.Nanosecond)
+		result, _, err := h(context.Background(), nil, GlobArgs{Pattern: "**/*.txt"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, ErrIO) {
+			t.Fatalf("expected error code %s, got: %s", ErrIO, resultText(result))
+		}
+		if !strings.Contains(resultText(result), "timed out") {
+			t.Errorf("expected timeout message, got: %s", resultText(result))
+		}
+	})
+
+	t.Run("grep times out", func(t *testing.T) {
+		h := withToolTimeout("grep", grepHandler(sess, resolver, testConfig()), time.Nanosecond)
+		result, _, err := h(context.Background(), nil, GrepArgs{Pattern: "needle"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, ErrIO) {
+			t.Fatalf("expected error code %s, got: %s", ErrIO, resultText(result))
+		}
+	})
+
+	t.Run("view directory times out", func(t *testing.T) {
+		h := withToolTimeout("view", viewHandler(sess, resolver, testConfig()), time.Nanosecond)
+		result, _, err := h(context.Background(), nil, ViewArgs{Path: tmp})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasErrorCode(result, ErrIO) {
+			t.Fatalf("expected error code %s, got: %s", ErrIO, resultText(result))
+		}
+	})
+
+	t.Run("mutating tools are not timed out", func(t *testing.T) {
+		dir := t.TempDir()
+		h := withToolTimeout("create_file", createFileHandler(session.New(dir), resolver, testConfig()), time.Nanosecond)
+		result, _, err := h(context.Background(), nil, CreateFileArgs{Path: "written.txt", Content: "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(result) {
+			t.Fatalf("unexpected error: %s", resultText(result))
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, "written.txt")); err != nil || string(data) != "x" {
+			t.Errorf("file not written: %q, %v", data, err)
+		}
+	})
+
+	t.Run("zero timeout is unlimited", func(t *testing.T) {
+		h := withToolTimeout("glob", globHandler(sess, resolver, testConfig()), 0)
+		result, _, err := h(context.Background(), nil, GlobArgs{Pattern: "**/*.txt"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(result) {
+			t.Fatalf("unexpected error: %s", resultText(result))
+		}
+		if n := len(strings.Split(resultText(result), "\n")); n != 1000 {
+			t.Errorf("expected 1000 results, got %d", n)
+		}
+	})
+}
+
+func TestToolDescriptionOverrides(t *testing.T) {
+	listDescriptions := func(t *testing.T, cfg Config) map[string]string {
+		t.Helper()
+		tmp := t.TempDir()
+		server := mcp.NewServer(&mcp.Implementation{Name: "boris-test", Version: "test"}, nil)
+		resolver, _ := pathscope.NewResolver(nil, nil)
+		sess := session.New(tmp)
+		t.Cleanup(sess.Close)
+		RegisterAll(server, resolver, sess, cfg)
+
+		ctx := context.Background()
+		t1, t2 := mcp.NewInMemoryTransports()
+		if _, err := server.Connect(ctx, t1, nil); err != nil {
+			t.Fatal(err)
+		}
+		client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+		clientSession, err := client.Connect(ctx, t2, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { clientSession.Close() })
+
+		toolList, err := clientSession.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		descs := make(map[string]string)
+		for _, tool := range toolList.Tools {
+			descs[tool.Name] = tool.Description
+		}
+		return descs
+	}
+
+	defaults := listDescriptions(t, testConfig())
+
+	cfg := testConfig()
+	cfg.ToolDescriptions = map[string]string{
+		"bash": "Run a command in the project sandbox.",
+		"grep": "Search the codebase.",
+	}
+	descs := listDescriptions(t, cfg)
+	if descs["bash"] != "Run a command in the project sandbox." {
+		t.Errorf("bash description = %q", descs["bash"])
+	}
+	if descs["grep"] != "Search the codebase." {
+		t.Errorf("grep description = %q", descs["grep"])
+	}
+	// Tools without an override keep the built-in description
+	if descs["view"] == "" || descs["view"] != defaults["view"] {
+		t.Errorf("view description = %q, want built-in %q", descs["view"], defaults["view"])
+	}
+
+	cfg = testConfig()
+	cfg.AnthropicCompat = true
+	cfg.ToolDescriptions = map[string]string{"str_replace_editor": "Edit files."}
+	if descs := listDescriptions(t, cfg); descs["str_replace_editor"] != "Edit files." {
+		t.Errorf("str_replace_editor description = %q", descs["str_replace_editor"])
+	}
+}
+
+func TestValidateToolDescriptions(t *testing.T) {
+	if err := ValidateToolDescriptions(map[string]string{"bash": "x", "view": "y"}, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateToolDescriptions(map[string]string{"nope": "x"}, false); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("expected unknown tool error, got %v", err)
+	}
+	// view is folded into str_replace_editor in compat mode
+	if err := ValidateToolDescriptions(map[string]string{"view": "x"}, true); err == nil {
+		t.Error("expected error overriding view in compat mode")
+	}
+	if err := ValidateToolDescriptions(map[string]string{"bash": "  "}, false); err == nil {
+		t.Error("expected error for empty description")
+	}
+}
+
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// TouchArgs is the input schema for the touch tool.
+type TouchArgs struct {
+	Path string `json:"path" jsonschema:"file to create if missing, or whose modification time to set to now"`
+}
+
+func touchHandler(sess *session.Session, resolver *pathscope.Resolver) mcp.ToolHandlerFor[TouchArgs, any] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, args TouchArgs) (*mcp.CallToolResult, any, error) {
+		return doTouch(sess, resolver, args.Path)
+	}
+}
+
+func doTouch(sess *session.Session, resolver *pathscope.Resolver, path string) (*mcp.CallToolResult, any, error) {
+	if path == "" {
+		return toolErr(ErrInvalidInput, "path must not be empty")
+	}
+	resolved, err := resolver.ResolveForWrite(sess.Cwd(), path)
+	if err != nil {
+		return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+	}
+
+	now := time.Now()
+	if _, err := os.Stat(resolved); err == nil {
+		if err := os.Chtimes(resolved, now, now); err != nil {
+			return toolErr(ErrIO, "could not update times of %s: %v", resolved, err)
+		}
+		refreshViewed(sess, resolved)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Updated modification time of %s", resolved)}},
+		}, nil, nil
+	} else if !os.IsNotExist(err) {
+		return toolErr(ErrIO, "could not stat %s: %v", resolved, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolved), 0755); err != nil {
+		return toolErr(ErrIO, "could not create directories for %s: %v", resolved, err)
+	}
+	// O_EXCL so a file created concurrently is not truncated
+	f, err := os.OpenFile(resolved, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return toolErr(ErrIO, "could not create %s: %v", resolved, err)
+	}
+	if err := f.Close(); err != nil {
+		return toolErr(ErrIO, "could not create %s: %v", resolved, err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Created empty file %s", resolved)}},
+	}, nil, nil
+}
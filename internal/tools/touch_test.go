@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+)
+
+func TestTouchCreatesFile(t *testing.T) {
+	tmp := t.TempDir()
+	resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+	handler := touchHandler(session.New(tmp), resolver)
+
+	result, _, err := handler(context.Background(), nil, TouchArgs{Path: filepath.Join("a", "b", "placeholder")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	info, err := os.Stat(filepath.Join(tmp, "a", "b", "placeholder"))
+	if err != nil {
+		t.Fatalf("file should exist: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("file should be empty, got %d bytes", info.Size())
+	}
+}
+
+func TestTouchUpdatesMtime(t *testing.T) {
+	tmp := t.TempDir()
+	file := filepath.Join(tmp, "stamp")
+	os.WriteFile(file, []byte("keep me"), 0644)
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(file, old, old)
+
+	resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+	handler := touchHandler(session.New(tmp), resolver)
+
+	before := time.Now().Add(-time.Second)
+	result, _, err := handler(context.Background(), nil, TouchArgs{Path: "stamp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	info, _ := os.Stat(file)
+	if info.ModTime().Before(before) {
+		t.Errorf("mtime = %v, want after %v", info.ModTime(), before)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "keep me" {
+		t.Errorf("contents should be unchanged, got %q", data)
+	}
+}
+
+func TestTouchKeepsViewStamp(t *testing.T) {
+	tmp := t.TempDir()
+	file := filepath.Join(tmp, "stamp")
+	os.WriteFile(file, []byte("keep me"), 0644)
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(file, old, old)
+
+	sess := session.New(tmp)
+	sess.MarkViewed(file, old, int64(len("keep me")))
+	resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+	handler := touchHandler(sess, resolver)
+
+	result, _, err := handler(context.Background(), nil, TouchArgs{Path: "stamp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErrorResult(result) {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if viewed, changed := viewState(sess, file); !viewed || changed {
+		t.Errorf("viewState after touch = (%v, %v), want (true, false)", viewed, changed)
+	}
+}
+
+func TestTouchScoping(t *testing.T) {
+	tmp := t.TempDir()
+	outside := t.TempDir()
+	resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+	handler := touchHandler(session.New(tmp), resolver)
+
+	target := filepath.Join(outside, "nope")
+	result, _, err := handler(context.Background(), nil, TouchArgs{Path: target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasErrorCode(result, ErrAccessDenied) {
+		t.Errorf("expected %s, got: %s", ErrAccessDenied, resultText(result))
+	}
+	if _, err := os.Stat(target); err == nil {
+		t.Error("file outside scope should not be created")
+	}
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// validUTF8 returns s with each run of invalid UTF-8 bytes replaced by the
+// Unicode replacement character.
+func validUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	return strings.ToValidUTF8(s, "\uFFFD")
+}
+
+// sanitizeResult makes the text content and error message of r valid UTF-8
+// in place.
+func sanitizeResult(r *mcp.CallToolResult) {
+	if r == nil {
+		return
+	}
+	for _, c := range r.Content {
+		if tc, ok := c.(*mcp.TextContent); ok {
+			tc.Text = validUTF8(tc.Text)
+		}
+	}
+	if te, ok := r.StructuredContent.(toolError); ok {
+		te.Message = validUTF8(te.Message)
+		r.StructuredContent = te
+	}
+}
+
+// withValidUTF8 wraps a tool handler so that the text it returns is valid
+// UTF-8. Command output and file contents may hold arbitrary bytes, which
+// some clients reject. If disabled, the handler is returned unchanged.
+func withValidUTF8[In any](enabled bool, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	if !enabled {
+		return h
+	}
+	return func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		result, out, err := h(ctx, req, args)
+		sanitizeResult(result)
+		return result, out, err
+	}
+}
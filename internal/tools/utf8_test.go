@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestSanitizeUTF8(t *testing.T) {
+	t.Run("bash output", func(t *testing.T) {
+		sess := session.New(t.TempDir())
+		handler := withValidUTF8(true, bashHandler(sess, testResolver(t), testConfig()))
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: `printf 'ok \377\376 end\n'`})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := resultText(result)
+		if !utf8.ValidString(text) {
+			t.Fatalf("output is not valid UTF-8: %q", text)
+		}
+		if !strings.Contains(text, "ok � end") {
+			t.Errorf("expected invalid bytes replaced, got: %q", text)
+		}
+	})
+
+	t.Run("grep matches", func(t *testing.T) {
+		tmp, sess, resolver := grepTestSetup(t)
+		os.WriteFile(filepath.Join(tmp, "latin1.txt"), []byte("caf\xe9 needle\n"), 0644)
+		handler := withValidUTF8(true, grepHandler(sess, resolver, testConfig()))
+		result, _, err := handler(context.Background(), nil, GrepArgs{Pattern: "needle", OutputMode: "content"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := resultText(result)
+		if !utf8.ValidString(text) {
+			t.Fatalf("output is not valid UTF-8: %q", text)
+		}
+		if !strings.Contains(text, "caf� needle") {
+			t.Errorf("expected invalid byte replaced, got: %q", text)
+		}
+	})
+
+	t.Run("error messages", func(t *testing.T) {
+		handler := withValidUTF8(true, func(context.Context, *mcp.CallToolRequest, struct{}) (*mcp.CallToolResult, any, error) {
+			return toolErr(ErrPathNotFound, "%s does not exist", "/tmp/bad\xff")
+		})
+		result, _, _ := handler(context.Background(), nil, struct{}{})
+		if text := resultText(result); !utf8.ValidString(text) {
+			t.Errorf("error text is not valid UTF-8: %q", text)
+		}
+		if te, ok := result.StructuredContent.(toolError); !ok || !utf8.ValidString(te.Message) {
+			t.Errorf("structured error message is not valid UTF-8: %#v", result.StructuredContent)
+		}
+	})
+
+	t.Run("disabled passes bytes through", func(t *testing.T) {
+		sess := session.New(t.TempDir())
+		handler := withValidUTF8(false, bashHandler(sess, testResolver(t), testConfig()))
+		result, _, err := handler(context.Background(), nil, BashArgs{Command: `printf '\377\n'`})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resultText(result), "\xff") {
+			t.Errorf("expected raw byte with sanitizing disabled, got: %q", resultText(result))
+		}
+	})
+
+	t.Run("disabled leaves error messages alone", func(t *testing.T) {
+		handler := withValidUTF8(false, func(context.Context, *mcp.CallToolRequest, struct{}) (*mcp.CallToolResult, any, error) {
+			return toolErr(ErrPathNotFound, "%s does not exist", "/tmp/bad\xff")
+		})
+		result, _, _ := handler(context.Background(), nil, struct{}{})
+		if !strings.Contains(resultText(result), "\xff") {
+			t.Errorf("expected raw byte with sanitizing disabled, got: %q", resultText(result))
+		}
+	})
+}
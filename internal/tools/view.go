@@ -25,6 +25,20 @@ var excludedDirs = map[string]bool{
 	"node_modules": true,
 }
 
+// excludedDirSet returns the built-in excluded directories plus extra names.
+func excludedDirSet(extra []string) map[string]bool {
+	set := make(map[string]bool, len(excludedDirs)+len(extra))
+	for name := range excludedDirs {
+		set[name] = true
+	}
+	for _, name := range extra {
+		if name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
+
 // ViewRange is a custom type for view_range so that the JSON schema
 // generates {"type": "array"} instead of {"type": ["null", "array"]}.
 type ViewRange []int
@@ -32,21 +46,43 @@ type ViewRange []int
 // ViewArgs is the input schema for the view tool.
 type ViewArgs struct {
 	Path      string    `json:"path" jsonschema:"file or directory path to view"`
-	ViewRange ViewRange `json:"view_range,omitempty" jsonschema:"optional line range [start end] (1-indexed)"`
+	ViewRange ViewRange `json:"view_range,omitempty" jsonschema:"optional line range [start end] (1-indexed; negative values count from the end, -1 = last line)"`
+	SkipBlank bool      `json:"skip_blank,omitempty" jsonschema:"omit blank and whitespace-only lines; shown lines keep their true line numbers"`
+	Fenced    bool      `json:"fenced,omitempty" jsonschema:"wrap file content in a markdown code fence tagged with the language detected from the file extension"`
+	GitInfo   bool      `json:"git_info,omitempty" jsonschema:"when listing a git repository root, show the current branch and whether tracked files have uncommitted changes"`
+	Hex       bool      `json:"hex,omitempty" jsonschema:"show binary files of up to 16KB as an xxd-style hex and ASCII dump instead of a size summary; images are still returned as images"`
+}
+
+// viewOptions holds optional view behavior beyond path and range.
+type viewOptions struct {
+	skipBlank    bool
+	normalizeEOL bool // treat CRLF and lone CR as line endings
+	fenced       bool // wrap numbered file content in a markdown code fence
+	gitInfo      bool // prefix directory listings of repository roots with git status
+	hex          bool // dump small binary files as hex instead of summarizing them
 }
 
 func viewHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[ViewArgs, any] {
-	return func(_ context.Context, _ *mcp.CallToolRequest, args ViewArgs) (*mcp.CallToolResult, any, error) {
-		return doView(sess, resolver, cfg, args.Path, args.ViewRange)
+	return func(ctx context.Context, _ *mcp.CallToolRequest, args ViewArgs) (*mcp.CallToolResult, any, error) {
+		return doView(ctx, sess, resolver, cfg, args.Path, args.ViewRange, viewOptions{skipBlank: args.SkipBlank, fenced: args.Fenced, gitInfo: args.GitInfo, hex: args.Hex})
 	}
 }
 
-func doView(sess *session.Session, resolver *pathscope.Resolver, cfg Config, path string, viewRange []int) (*mcp.CallToolResult, any, error) {
+func doView(ctx context.Context, sess *session.Session, resolver *pathscope.Resolver, cfg Config, path string, viewRange []int, opts viewOptions) (*mcp.CallToolResult, any, error) {
 	resolved, err := resolver.Resolve(sess.Cwd(), path)
 	if err != nil {
 		return toolErr(ErrAccessDenied, "path not allowed: %v", err)
 	}
 
+	// Resolve has already followed any symlink and checked its target
+	// against the allow and deny lists; without FollowSymlinks, a path
+	// that goes through a symlink anywhere is refused outright.
+	if !cfg.FollowSymlinks {
+		if link, ok := symlinkInPath(sess.Cwd(), path); ok {
+			return toolErr(ErrAccessDenied, "%s is a symlink (%s resolves to %s); following symlinks is disabled", link, path, resolved)
+		}
+	}
+
 	info, err := os.Lstat(resolved)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -56,23 +92,127 @@ func doView(sess *session.Session, resolver *pathscope.Resolver, cfg Config, pat
 	}
 
 	if info.IsDir() {
-		text, err := listDirectory(resolved)
+		text, err := listDirectory(ctx, resolved, excludedDirSet(cfg.ExcludeDirs))
 		if err != nil {
 			return toolErr(ErrIO, "could not list directory %s: %v", resolved, err)
 		}
+		if opts.gitInfo {
+			if summary, ok := gitSummary(resolved); ok {
+				text = summary + "\n" + text
+			}
+		}
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{Text: text}},
 		}, nil, nil
 	}
 
-	result, extra, err := readFile(resolved, info, viewRange, cfg.MaxFileSize)
+	opts.normalizeEOL = cfg.NormalizeLineEndings
+	result, extra, err := readFile(resolved, info, viewRange, cfg.viewFileLimit(), opts)
 	if err == nil && result != nil && !result.IsError {
-		sess.MarkViewed(resolved)
+		sess.MarkViewed(resolved, info.ModTime(), info.Size())
 	}
 	return result, extra, err
 }
 
-func readFile(path string, info os.FileInfo, viewRange []int, maxFileSize int64) (*mcp.CallToolResult, any, error) {
+// symlinkInPath returns the first component of path, taken relative to cwd,
+// that is a symlink. For a relative path inside cwd only the components
+// below cwd are checked, since cwd itself is the session's own; any other
+// path is checked from the root.
+func symlinkInPath(cwd, path string) (string, bool) {
+	full := path
+	if !filepath.IsAbs(path) {
+		full = filepath.Join(cwd, path)
+	}
+	full = filepath.Clean(full)
+	cur, rest := string(filepath.Separator), strings.TrimPrefix(full, string(filepath.Separator))
+	if prefix := filepath.Clean(cwd) + string(filepath.Separator); !filepath.IsAbs(path) && strings.HasPrefix(full, prefix) {
+		cur, rest = filepath.Clean(cwd), strings.TrimPrefix(full, prefix)
+	}
+	for _, part := range strings.Split(rest, string(filepath.Separator)) {
+		if part == "" {
+			continue
+		}
+		cur = filepath.Join(cur, part)
+		if li, err := os.Lstat(cur); err == nil && li.Mode()&os.ModeSymlink != 0 {
+			return cur, true
+		}
+	}
+	return "", false
+}
+
+// viewState reports whether resolved was viewed in sess and, if so, whether
+// it has changed on disk since. A file that cannot be stat'd counts as
+// unchanged; the caller reports the stat error itself.
+func viewState(sess *session.Session, resolved string) (viewed, changed bool) {
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return sess.HasViewed(resolved), false
+	}
+	return sess.CheckViewed(resolved, info.ModTime(), info.Size())
+}
+
+// refreshViewed re-stamps a viewed file after a tool has written it, so that
+// the tool's own edit does not require another view.
+func refreshViewed(sess *session.Session, resolved string) {
+	if info, err := os.Stat(resolved); err == nil {
+		sess.RefreshViewed(resolved, info.ModTime(), info.Size())
+	}
+}
+
+// fileKind names the type of a non-regular file for error messages.
+func fileKind(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "character device"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	}
+	return "special file"
+}
+
+// maxHexDumpBytes is the largest binary file view will render as a hex dump.
+const maxHexDumpBytes = 16 * 1024
+
+// hexDump renders data in the style of xxd: an 8-digit hex offset, 16 bytes
+// per line in groups of two, and the printable ASCII characters with other
+// bytes shown as '.'.
+func hexDump(data []byte) string {
+	var b strings.Builder
+	for off := 0; off < len(data); off += 16 {
+		row := data[off:min(off+16, len(data))]
+		fmt.Fprintf(&b, "%08x: ", off)
+		for i := 0; i < 16; i++ {
+			if i < len(row) {
+				fmt.Fprintf(&b, "%02x", row[i])
+			} else {
+				b.WriteString("  ")
+			}
+			if i%2 == 1 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte(' ')
+		for _, c := range row {
+			if c >= 0x20 && c < 0x7f {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func readFile(path string, info os.FileInfo, viewRange []int, maxFileSize int64, opts viewOptions) (*mcp.CallToolResult, any, error) {
+	// Opening or reading a FIFO, device, or socket can block forever
+	if !info.Mode().IsRegular() {
+		return toolErr(ErrInvalidInput, "%s is a %s, not a regular file", path, fileKind(info.Mode()))
+	}
 	if info.Size() > maxFileSize {
 		return toolErr(ErrFileTooLarge, "file %s is %d bytes, exceeds maximum %d bytes", path, info.Size(), maxFileSize)
 	}
@@ -109,6 +249,18 @@ func readFile(path string, info os.FileInfo, viewRange []int, maxFileSize int64)
 	// Check for binary (NUL bytes in header)
 	if isBinaryHeader(header) {
 		text := fmt.Sprintf("Binary file (%s)", formatSize(info.Size()))
+		if opts.hex && info.Size() <= maxHexDumpBytes {
+			if _, err := f.Seek(0, 0); err != nil {
+				return toolErr(ErrIO, "could not seek %s: %v", path, err)
+			}
+			data, err := io.ReadAll(io.LimitReader(f, maxHexDumpBytes))
+			if err != nil {
+				return toolErr(ErrIO, "could not read %s: %v", path, err)
+			}
+			text = hexDump(data)
+		} else if opts.hex {
+			text += fmt.Sprintf("; too large for a hex dump (max %s)", formatSize(maxHexDumpBytes))
+		}
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{Text: text}},
 		}, nil, nil
@@ -116,7 +268,7 @@ func readFile(path string, info os.FileInfo, viewRange []int, maxFileSize int64)
 
 	// For view_range requests, use efficient range reading
 	if len(viewRange) == 2 {
-		return readFileRange(f, path, viewRange[0], viewRange[1])
+		return readFileRange(f, path, viewRange[0], viewRange[1], opts)
 	}
 
 	// Read entire file
@@ -127,6 +279,9 @@ func readFile(path string, info os.FileInfo, viewRange []int, maxFileSize int64)
 	if err != nil {
 		return toolErr(ErrIO, "could not read %s: %v", path, err)
 	}
+	if opts.normalizeEOL {
+		data = normalizeLineEndings(data)
+	}
 
 	lines := strings.Split(string(data), "\n")
 	// Remove trailing empty line from final newline
@@ -135,16 +290,23 @@ func readFile(path string, info os.FileInfo, viewRange []int, maxFileSize int64)
 	}
 	totalLines := len(lines)
 
-	if totalLines > maxViewLines {
-		lines = lines[:maxViewLines]
-		text := formatLines(lines, 1)
+	numbered := numberLines(lines, 1, opts.skipBlank)
+	if len(numbered) > maxViewLines {
+		numbered = numbered[:maxViewLines]
+		text := formatNumberedLines(numbered)
+		if opts.fenced {
+			text = fenceCode(text, path)
+		}
 		text += fmt.Sprintf("\n[Truncated: file has %d lines. Use view_range to read specific sections.]", totalLines)
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{Text: text}},
 		}, nil, nil
 	}
 
-	text := formatLines(lines, 1)
+	text := formatNumberedLines(numbered)
+	if opts.fenced {
+		text = fenceCode(text, path)
+	}
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: text}},
 	}, nil, nil
@@ -152,19 +314,36 @@ func readFile(path string, info os.FileInfo, viewRange []int, maxFileSize int64)
 
 // readFileRange reads a specific line range from an already-opened file using
 // a scanner to avoid loading the entire file into memory.
-func readFileRange(f *os.File, path string, start, end int) (*mcp.CallToolResult, any, error) {
-	if start < 1 {
-		return toolErr(ErrInvalidInput, "invalid view_range: start must be >= 1, got %d", start)
+// Negative start or end values count from the end of the file (-1 is the
+// last line); a negative start reaching before the first line is clamped to 1.
+func readFileRange(f *os.File, path string, start, end int, opts viewOptions) (*mcp.CallToolResult, any, error) {
+	if start == 0 || end == 0 {
+		return toolErr(ErrInvalidInput, "invalid view_range: line numbers start at 1 (or -1 for the last line), got [%d, %d]", start, end)
+	}
+	if start < 0 || end < 0 {
+		total, err := countLines(f, opts)
+		if err != nil {
+			return toolErr(ErrIO, "could not read %s: %v", path, err)
+		}
+		origStart, origEnd := start, end
+		if start < 0 {
+			start = max(total+1+start, 1)
+		}
+		if end < 0 {
+			end = total + 1 + end
+		}
+		if start > end {
+			return toolErr(ErrInvalidInput, "invalid view_range: [%d, %d] resolves to start %d > end %d in a %d-line file", origStart, origEnd, start, end, total)
+		}
 	}
 	if start > end {
 		return toolErr(ErrInvalidInput, "invalid view_range: start %d > end %d", start, end)
 	}
 
-	if _, err := f.Seek(0, 0); err != nil {
+	scanner, err := newLineScanner(f, opts)
+	if err != nil {
 		return toolErr(ErrIO, "could not seek %s: %v", path, err)
 	}
-
-	scanner := bufio.NewScanner(f)
 	lineNum := 0
 	var lines []string
 	for scanner.Scan() {
@@ -187,12 +366,95 @@ func readFileRange(f *os.File, path string, start, end int) (*mcp.CallToolResult
 	}
 
 	// Clamp end to totalLines (already handled by scan stopping)
-	text := formatLines(lines, start)
+	text := formatNumberedLines(numberLines(lines, start, opts.skipBlank))
+	if opts.fenced {
+		text = fenceCode(text, path)
+	}
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: text}},
 	}, nil, nil
 }
 
+// newLineScanner rewinds f and returns a scanner over its lines.
+func newLineScanner(f *os.File, opts viewOptions) (*bufio.Scanner, error) {
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	if opts.normalizeEOL {
+		scanner.Split(scanNormalizedLines)
+	}
+	return scanner, nil
+}
+
+// countLines returns the number of lines in f, as readFileRange numbers them.
+func countLines(f *os.File, opts viewOptions) (int, error) {
+	scanner, err := newLineScanner(f, opts)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for scanner.Scan() {
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// fenceLanguages maps file extensions to markdown code fence language tags.
+var fenceLanguages = map[string]string{
+	".c":    "c",
+	".h":    "c",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".cxx":  "cpp",
+	".hpp":  "cpp",
+	".css":  "css",
+	".go":   "go",
+	".html": "html",
+	".htm":  "html",
+	".java": "java",
+	".js":   "javascript",
+	".mjs":  "javascript",
+	".cjs":  "javascript",
+	".jsx":  "jsx",
+	".json": "json",
+	".md":   "markdown",
+	".py":   "python",
+	".rb":   "ruby",
+	".rs":   "rust",
+	".scss": "scss",
+	".sh":   "bash",
+	".bash": "bash",
+	".sql":  "sql",
+	".toml": "toml",
+	".ts":   "typescript",
+	".tsx":  "tsx",
+	".xml":  "xml",
+	".yaml": "yaml",
+	".yml":  "yaml",
+}
+
+// fenceCode wraps text in a markdown code fence tagged with the language
+// for path's extension, if known. The fence is made longer than any run of
+// backticks in text so that embedded fences cannot terminate it.
+func fenceCode(text, path string) string {
+	longest, run := 0, 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	lang := fenceLanguages[strings.ToLower(filepath.Ext(path))]
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return fence + lang + "\n" + text + fence + "\n"
+}
+
 // detectImage checks if the header bytes represent an image format.
 // Uses net/http.DetectContentType for magic byte sniffing, with SVG
 // extension fallback since SVG is text-based.
@@ -220,10 +482,39 @@ func truncateLine(line string) string {
 }
 
 func formatLines(lines []string, startNum int) string {
-	var b strings.Builder
-	width := len(fmt.Sprintf("%d", startNum+len(lines)-1))
+	return formatNumberedLines(numberLines(lines, startNum, false))
+}
+
+// numberedLine is a line of file content paired with its 1-indexed line number.
+type numberedLine struct {
+	num  int
+	text string
+}
+
+// numberLines pairs lines with consecutive line numbers starting at startNum.
+// If skipBlank is set, blank and whitespace-only lines are dropped while the
+// remaining lines keep their original numbers.
+func numberLines(lines []string, startNum int, skipBlank bool) []numberedLine {
+	numbered := make([]numberedLine, 0, len(lines))
 	for i, line := range lines {
-		fmt.Fprintf(&b, "%*d\t%s\n", width, startNum+i, truncateLine(line))
+		if skipBlank && strings.TrimSpace(line) == "" {
+			continue
+		}
+		numbered = append(numbered, numberedLine{num: startNum + i, text: line})
+	}
+	return numbered
+}
+
+// formatNumberedLines renders lines as right-aligned line numbers, a tab,
+// and the (possibly truncated) content.
+func formatNumberedLines(lines []numberedLine) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	width := len(fmt.Sprintf("%d", lines[len(lines)-1].num))
+	for _, l := range lines {
+		fmt.Fprintf(&b, "%*d\t%s\n", width, l.num, truncateLine(l.text))
 	}
 	return b.String()
 }
@@ -246,20 +537,23 @@ func formatSize(size int64) string {
 	}
 }
 
-func listDirectory(path string) (string, error) {
+func listDirectory(ctx context.Context, path string, excluded map[string]bool) (string, error) {
 	var b strings.Builder
 	fmt.Fprintf(&b, "%s/\n", filepath.Base(path))
-	err := walkDir(path, "", 0, 2, &b)
+	err := walkDir(ctx, path, "", 0, 2, excluded, &b)
 	if err != nil {
 		return "", err
 	}
 	return b.String(), nil
 }
 
-func walkDir(path string, prefix string, depth int, maxDepth int, b *strings.Builder) error {
+func walkDir(ctx context.Context, path string, prefix string, depth int, maxDepth int, excluded map[string]bool, b *strings.Builder) error {
 	if depth >= maxDepth {
 		return nil
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	entries, err := os.ReadDir(path)
 	if err != nil {
@@ -269,7 +563,7 @@ func walkDir(path string, prefix string, depth int, maxDepth int, b *strings.Bui
 	// Filter only specifically excluded directories
 	var visible []os.DirEntry
 	for _, e := range entries {
-		if excludedDirs[e.Name()] {
+		if excluded[e.Name()] {
 			continue
 		}
 		visible = append(visible, e)
@@ -298,7 +592,7 @@ func walkDir(path string, prefix string, depth int, maxDepth int, b *strings.Bui
 			if isLast {
 				childPrefix = prefix + "    "
 			}
-			if err := walkDir(filepath.Join(path, entry.Name()), childPrefix, depth+1, maxDepth, b); err != nil {
+			if err := walkDir(ctx, filepath.Join(path, entry.Name()), childPrefix, depth+1, maxDepth, excluded, b); err != nil {
 				return err
 			}
 		}
@@ -2,10 +2,13 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 	"unicode/utf8"
 
 	"github.com/mjkoo/boris/internal/pathscope"
@@ -113,6 +116,60 @@ func TestViewRangeStartExceedsTotal(t *testing.T) {
 	}
 }
 
+func TestViewRangeNegative(t *testing.T) {
+	tmp := t.TempDir()
+	file := filepath.Join(tmp, "test.txt")
+	// 50-line file
+	var content string
+	for i := 1; i <= 50; i++ {
+		content += fmt.Sprintf("line %d\n", i)
+	}
+	os.WriteFile(file, []byte(content), 0644)
+
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := viewHandler(sess, resolver, testConfig())
+
+	tests := []struct {
+		viewRange   []int
+		first, last int
+	}{
+		{[]int{-20, -1}, 31, 50},
+		{[]int{-5, -1}, 46, 50},
+		{[]int{45, -1}, 45, 50},
+		{[]int{-3, 100}, 48, 50},
+		{[]int{-100, 2}, 1, 2},
+	}
+	for _, tt := range tests {
+		result, _, err := handler(context.Background(), nil, ViewArgs{Path: file, ViewRange: tt.viewRange})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErrorResult(result) {
+			t.Errorf("%v: unexpected error: %s", tt.viewRange, resultText(result))
+			continue
+		}
+		lines := strings.Split(strings.TrimSpace(resultText(result)), "\n")
+		if len(lines) != tt.last-tt.first+1 {
+			t.Errorf("%v: expected %d lines, got %d", tt.viewRange, tt.last-tt.first+1, len(lines))
+		}
+		if want := fmt.Sprintf("%d\tline %d", tt.first, tt.first); !strings.HasSuffix(lines[0], want) {
+			t.Errorf("%v: first line = %q, want suffix %q", tt.viewRange, lines[0], want)
+		}
+		if want := fmt.Sprintf("%d\tline %d", tt.last, tt.last); !strings.HasSuffix(lines[len(lines)-1], want) {
+			t.Errorf("%v: last line = %q, want suffix %q", tt.viewRange, lines[len(lines)-1], want)
+		}
+	}
+
+	// Ranges that resolve backwards are rejected
+	for _, vr := range [][]int{{-1, -5}, {40, -20}, {1, -100}} {
+		result, _, _ := handler(context.Background(), nil, ViewArgs{Path: file, ViewRange: vr})
+		if !hasErrorCode(result, ErrInvalidInput) {
+			t.Errorf("%v: expected %s, got: %s", vr, ErrInvalidInput, resultText(result))
+		}
+	}
+}
+
 func TestViewInvalidRange(t *testing.T) {
 	tmp := t.TempDir()
 	file := filepath.Join(tmp, "test.txt")
@@ -128,6 +185,7 @@ func TestViewInvalidRange(t *testing.T) {
 	}{
 		{"start < 1", []int{0, 2}},
 		{"start > end", []int{3, 1}},
+		{"end = 0", []int{1, 0}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -271,6 +329,36 @@ func TestViewBinaryDetection(t *testing.T) {
 	}
 }
 
+func TestViewHexDump(t *testing.T) {
+	tmp := t.TempDir()
+	file := filepath.Join(tmp, "fixture.bin")
+	os.WriteFile(file, []byte("boris\x00binary\x7f\x80 file!"), 0644)
+	large := filepath.Join(tmp, "large.bin")
+	os.WriteFile(large, make([]byte, maxHexDumpBytes+1), 0644)
+
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := viewHandler(sess, resolver, testConfig())
+
+	result, _, err := handler(context.Background(), nil, ViewArgs{Path: file, Hex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "00000000: 626f 7269 7300 6269 6e61 7279 7f80 2066  boris.binary.. f\n" +
+		"00000010: 696c 6521" + strings.Repeat(" ", 32) + "ile!\n"
+	if got := resultText(result); got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+
+	result, _, err = handler(context.Background(), nil, ViewArgs{Path: large, Hex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(result); !strings.Contains(text, "Binary file") || !strings.Contains(text, "too large for a hex dump") {
+		t.Errorf("expected size summary for large binary, got: %s", text)
+	}
+}
+
 func TestViewImageDetection(t *testing.T) {
 	tmp := t.TempDir()
 	sess := session.New(tmp)
@@ -536,3 +624,291 @@ func TestViewMaxFileSize(t *testing.T) {
 		t.Errorf("expected error code %s, got: %s", ErrFileTooLarge, resultText(result))
 	}
 }
+
+func TestViewSkipBlank(t *testing.T) {
+	tmp := t.TempDir()
+	file := filepath.Join(tmp, "test.txt")
+	os.WriteFile(file, []byte("one\n\n   \ntwo\n\t\nthree\n\nfour\n"), 0644)
+
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := viewHandler(sess, resolver, testConfig())
+
+	t.Run("whole file", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, ViewArgs{Path: file, SkipBlank: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "1\tone\n4\ttwo\n6\tthree\n8\tfour\n"
+		if got := resultText(result); got != want {
+			t.Errorf("got:\n%q\nwant:\n%q", got, want)
+		}
+	})
+
+	t.Run("with view_range", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, ViewArgs{Path: file, ViewRange: []int{2, 6}, SkipBlank: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "4\ttwo\n6\tthree\n"
+		if got := resultText(result); got != want {
+			t.Errorf("got:\n%q\nwant:\n%q", got, want)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, ViewArgs{Path: file})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Split(strings.TrimSuffix(resultText(result), "\n"), "\n"); len(lines) != 8 {
+			t.Errorf("expected 8 lines without skip_blank, got %d", len(lines))
+		}
+	})
+}
+
+func TestViewNormalizesLineEndings(t *testing.T) {
+	tmp := t.TempDir()
+	crlf := filepath.Join(tmp, "crlf.txt")
+	os.WriteFile(crlf, []byte("one\r\ntwo\r\nthree\r\n"), 0644)
+	cr := filepath.Join(tmp, "cr.txt")
+	os.WriteFile(cr, []byte("one\rtwo\rthree"), 0644)
+
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := viewHandler(sess, resolver, testConfig())
+
+	want := "1\tone\n2\ttwo\n3\tthree\n"
+	for _, file := range []string{crlf, cr} {
+		t.Run(filepath.Base(file)+" whole file", func(t *testing.T) {
+			result, _, err := handler(context.Background(), nil, ViewArgs{Path: file})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultText(result); got != want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, want)
+			}
+		})
+
+		t.Run(filepath.Base(file)+" view_range", func(t *testing.T) {
+			result, _, err := handler(context.Background(), nil, ViewArgs{Path: file, ViewRange: []int{2, 3}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultText(result); got != "2\ttwo\n3\tthree\n" {
+				t.Errorf("got %q", got)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.NormalizeLineEndings = false
+		raw := viewHandler(sess, resolver, cfg)
+		result, _, err := raw(context.Background(), nil, ViewArgs{Path: crlf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resultText(result); !strings.Contains(got, "one\r") {
+			t.Errorf("expected raw CR without normalization, got %q", got)
+		}
+	})
+
+	t.Run("file bytes unchanged", func(t *testing.T) {
+		data, _ := os.ReadFile(crlf)
+		if string(data) != "one\r\ntwo\r\nthree\r\n" {
+			t.Errorf("view modified file: %q", data)
+		}
+	})
+}
+
+func TestViewExcludeDirs(t *testing.T) {
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "build"), 0755)
+	os.MkdirAll(filepath.Join(tmp, "src"), 0755)
+
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+
+	cfg := testConfig()
+	cfg.ExcludeDirs = []string{"build"}
+	result, _, err := viewHandler(sess, resolver, cfg)(context.Background(), nil, ViewArgs{Path: tmp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := resultText(result)
+	if strings.Contains(text, "build/") {
+		t.Errorf("excluded directory should not be listed, got:\n%s", text)
+	}
+	if !strings.Contains(text, "src/") {
+		t.Errorf("expected src/ in listing, got:\n%s", text)
+	}
+
+	result, _, err = viewHandler(sess, resolver, testConfig())(context.Background(), nil, ViewArgs{Path: tmp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resultText(result); !strings.Contains(text, "build/") {
+		t.Errorf("directory should be listed when not excluded, got:\n%s", text)
+	}
+}
+
+func TestViewFenced(t *testing.T) {
+	tmp := t.TempDir()
+	goFile := filepath.Join(tmp, "main.go")
+	os.WriteFile(goFile, []byte("package main\n\nfunc main() {}\n"), 0644)
+
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := viewHandler(sess, resolver, testConfig())
+
+	t.Run("go file", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, ViewArgs{Path: goFile, Fenced: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "```go\n1\tpackage main\n2\t\n3\tfunc main() {}\n```\n"
+		if got := resultText(result); got != want {
+			t.Errorf("got:\n%q\nwant:\n%q", got, want)
+		}
+	})
+
+	t.Run("with view_range", func(t *testing.T) {
+		result, _, err := handler(context.Background(), nil, ViewArgs{Path: goFile, ViewRange: []int{3, 3}, Fenced: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "```go\n3\tfunc main() {}\n```\n"
+		if got := resultText(result); got != want {
+			t.Errorf("got:\n%q\nwant:\n%q", got, want)
+		}
+	})
+
+	t.Run("unknown extension has no tag", func(t *testing.T) {
+		file := filepath.Join(tmp, "notes.unknownext")
+		os.WriteFile(file, []byte("hello\n"), 0644)
+		result, _, _ := handler(context.Background(), nil, ViewArgs{Path: file, Fenced: true})
+		if got := resultText(result); got != "```\n1\thello\n```\n" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("embedded fence is not terminated early", func(t *testing.T) {
+		file := filepath.Join(tmp, "README.md")
+		os.WriteFile(file, []byte("```sh\nls\n```\n"), 0644)
+		result, _, _ := handler(context.Background(), nil, ViewArgs{Path: file, Fenced: true})
+		text := resultText(result)
+		if !strings.HasPrefix(text, "````markdown\n") || !strings.HasSuffix(text, "\n````\n") {
+			t.Errorf("expected a four-backtick fence, got:\n%s", text)
+		}
+	})
+
+	t.Run("directories are exempt", func(t *testing.T) {
+		result, _, _ := handler(context.Background(), nil, ViewArgs{Path: tmp, Fenced: true})
+		if strings.Contains(resultText(result), "```") {
+			t.Errorf("directory listing should not be fenced, got:\n%s", resultText(result))
+		}
+	})
+
+	t.Run("images are exempt", func(t *testing.T) {
+		file := filepath.Join(tmp, "pixel.png")
+		data := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 100)...)
+		os.WriteFile(file, data, 0644)
+		result, _, _ := handler(context.Background(), nil, ViewArgs{Path: file, Fenced: true})
+		if _, ok := result.Content[0].(*mcp.ImageContent); !ok {
+			t.Errorf("expected image content, got %T", result.Content[0])
+		}
+	})
+}
+
+func TestViewSymlinkPolicy(t *testing.T) {
+	tmp := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "target.txt"), []byte("inside\n"), 0644)
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("outside\n"), 0644)
+	os.Symlink(filepath.Join(tmp, "target.txt"), filepath.Join(tmp, "in-scope.txt"))
+	os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(tmp, "escape.txt"))
+
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver([]string{tmp}, nil)
+
+	view := func(cfg Config, path string) *mcp.CallToolResult {
+		t.Helper()
+		result, _, err := viewHandler(sess, resolver, cfg)(context.Background(), nil, ViewArgs{Path: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	t.Run("in-scope symlink is followed", func(t *testing.T) {
+		result := view(testConfig(), "in-scope.txt")
+		if isErrorResult(result) || !strings.Contains(resultText(result), "inside") {
+			t.Errorf("expected target content, got: %s", resultText(result))
+		}
+	})
+
+	t.Run("escaping symlink is denied", func(t *testing.T) {
+		result := view(testConfig(), "escape.txt")
+		if !hasErrorCode(result, ErrAccessDenied) {
+			t.Errorf("expected %s, got: %s", ErrAccessDenied, resultText(result))
+		}
+		if strings.Contains(resultText(result), "outside\n") {
+			t.Error("content outside the allowed directories leaked")
+		}
+	})
+
+	t.Run("symlinks refused when not following", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.FollowSymlinks = false
+		result := view(cfg, "in-scope.txt")
+		if !hasErrorCode(result, ErrAccessDenied) {
+			t.Errorf("expected %s, got: %s", ErrAccessDenied, resultText(result))
+		}
+		result = view(cfg, "target.txt")
+		if isErrorResult(result) {
+			t.Errorf("regular file should still be viewable, got: %s", resultText(result))
+		}
+	})
+
+	t.Run("symlinked parent directories refused when not following", func(t *testing.T) {
+		os.MkdirAll(filepath.Join(tmp, "real"), 0755)
+		os.WriteFile(filepath.Join(tmp, "real", "f.txt"), []byte("nested\n"), 0644)
+		os.Symlink(filepath.Join(tmp, "real"), filepath.Join(tmp, "linkdir"))
+		cfg := testConfig()
+		cfg.FollowSymlinks = false
+		for _, path := range []string{filepath.Join("linkdir", "f.txt"), filepath.Join(tmp, "linkdir", "f.txt")} {
+			if result := view(cfg, path); !hasErrorCode(result, ErrAccessDenied) {
+				t.Errorf("%s: expected %s, got: %s", path, ErrAccessDenied, resultText(result))
+			}
+		}
+		if result := view(cfg, filepath.Join("real", "f.txt")); isErrorResult(result) {
+			t.Errorf("file under a real directory should be viewable, got: %s", resultText(result))
+		}
+	})
+}
+
+func TestViewFIFO(t *testing.T) {
+	tmp := t.TempDir()
+	fifo := filepath.Join(tmp, "pipe")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+	sess := session.New(tmp)
+	resolver, _ := pathscope.NewResolver(nil, nil)
+	handler := viewHandler(sess, resolver, testConfig())
+
+	done := make(chan *mcp.CallToolResult, 1)
+	go func() {
+		result, _, _ := handler(context.Background(), nil, ViewArgs{Path: fifo})
+		done <- result
+	}()
+	select {
+	case result := <-done:
+		if !hasErrorCode(result, ErrInvalidInput) || !strings.Contains(resultText(result), "named pipe") {
+			t.Errorf("expected INVALID_INPUT naming a named pipe, got: %s", resultText(result))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("view blocked on a FIFO")
+	}
+}
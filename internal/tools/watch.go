@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mjkoo/boris/internal/pathscope"
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxWatchEvents caps how many events a watch call records in its result.
+// Later events are still counted and reported as progress notifications.
+const maxWatchEvents = 1000
+
+// WatchArgs is the input schema for the watch tool.
+type WatchArgs struct {
+	Path    string `json:"path,omitempty" jsonschema:"directory to watch recursively (default: current working directory)"`
+	Pattern string `json:"pattern,omitempty" jsonschema:"glob pattern a changed path must match, relative to path or by base name (e.g. *.go or src/**/*.ts); default: every file"`
+	Timeout int    `json:"timeout,omitempty" jsonschema:"stop watching after this many seconds (default: watch until the call is cancelled, the task is killed, or the session closes)"`
+}
+
+func watchHandler(sess *session.Session, resolver *pathscope.Resolver, cfg Config) mcp.ToolHandlerFor[WatchArgs, any] {
+	excludeDirs := excludedDirSet(cfg.ExcludeDirs)
+	return func(ctx context.Context, req *mcp.CallToolRequest, args WatchArgs) (*mcp.CallToolResult, any, error) {
+		if args.Timeout < 0 {
+			return toolErr(ErrInvalidInput, "timeout must be non-negative, got %d", args.Timeout)
+		}
+		if args.Pattern != "" {
+			if err := globPatternError(args.Pattern); err != nil {
+				return toolErr(ErrInvalidInput, "invalid pattern %q: %v", args.Pattern, err)
+			}
+		}
+
+		root, err := resolver.Resolve(sess.Cwd(), args.Path)
+		if err != nil {
+			if args.Path != "" {
+				return toolErr(ErrAccessDenied, "path not allowed: %v", err)
+			}
+			root = sess.Cwd()
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return toolErr(ErrPathNotFound, "%s does not exist", root)
+			}
+			return toolErr(ErrIO, "could not stat %s: %v", root, err)
+		}
+		if !info.IsDir() {
+			return toolErr(ErrInvalidInput, "%s is not a directory", root)
+		}
+
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return toolErr(ErrWatchFailed, "could not start watcher: %v", err)
+		}
+		defer watcher.Close()
+		if err := addWatchDirs(watcher, resolver, root, excludeDirs); err != nil {
+			return toolErr(ErrWatchFailed, "could not watch %s: %v", root, err)
+		}
+
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return toolErr(ErrIO, "could not generate task ID: %v", err)
+		}
+
+		// The watch is tracked as a background task so that kill_all_tasks
+		// and session close stop it, and task_output can report its events
+		// while it runs.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		task := &session.BackgroundTask{
+			ID:     hex.EncodeToString(b),
+			Stop:   cancel,
+			Stdout: &session.SyncBuffer{},
+			Stderr: &session.SyncBuffer{},
+			Done:   make(chan struct{}),
+		}
+		if err := sess.AddTask(task); err != nil {
+			return toolErr(ErrBashTaskLimit, "could not add background task: %v", err)
+		}
+		defer func() {
+			close(task.Done)
+			sess.RemoveTask(task.ID)
+		}()
+
+		var timedOut <-chan time.Time
+		if args.Timeout > 0 {
+			timer := time.NewTimer(time.Duration(args.Timeout) * time.Second)
+			defer timer.Stop()
+			timedOut = timer.C
+		}
+
+		var progressToken any
+		if req != nil && req.Params != nil {
+			progressToken = req.Params.GetProgressToken()
+		}
+		notify := func(n int, msg string) {
+			if progressToken != nil && req.Session != nil && !cfg.DisableProgress {
+				_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+					ProgressToken: progressToken,
+					Progress:      float64(n),
+					Message:       msg,
+				})
+			}
+		}
+		notify(0, fmt.Sprintf("task_id: %s\nwatching %s", task.ID, root))
+
+		status := "stopped"
+		events := 0
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-timedOut:
+				status = "timed out"
+				break loop
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					break loop
+				}
+				line, ok := watchEventLine(watcher, resolver, root, args.Pattern, excludeDirs, ev)
+				if !ok {
+					continue
+				}
+				events++
+				if events <= maxWatchEvents {
+					fmt.Fprintln(task.Stdout, line)
+				}
+				notify(events, line)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					break loop
+				}
+				fmt.Fprintln(task.Stderr, err)
+			}
+		}
+
+		var result strings.Builder
+		fmt.Fprintf(&result, "task_id: %s\nstatus: %s\nevents: %d\n", task.ID, status, events)
+		if out := task.Stdout.String(); out != "" {
+			result.WriteString("\n")
+			result.WriteString(out)
+			if events > maxWatchEvents {
+				fmt.Fprintf(&result, "... %d more events not shown\n", events-maxWatchEvents)
+			}
+		}
+		if errs := task.Stderr.String(); errs != "" {
+			result.WriteString("\nerrors:\n")
+			result.WriteString(errs)
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: result.String()}},
+		}, nil, nil
+	}
+}
+
+// addWatchDirs adds dir and every directory beneath it to the watcher,
+// skipping excluded directories and directories outside the allowed scope.
+// fsnotify does not watch recursively, so each directory is added on its own.
+func addWatchDirs(watcher *fsnotify.Watcher, resolver *pathscope.Resolver, dir string, excludeDirs map[string]bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && excludeDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		if _, err := resolver.Resolve(dir, path); err != nil {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// watchEventLine formats a filesystem event as "<op> <relative path>",
+// reporting false for events that are out of scope, under an excluded
+// directory, or do not match pattern. Newly created directories are added
+// to the watcher so that files created inside them are also reported.
+func watchEventLine(watcher *fsnotify.Watcher, resolver *pathscope.Resolver, root, pattern string, excludeDirs map[string]bool, ev fsnotify.Event) (string, bool) {
+	relPath, err := filepath.Rel(root, ev.Name)
+	if err != nil || relPath == "." {
+		return "", false
+	}
+	for _, part := range strings.Split(filepath.Dir(relPath), string(filepath.Separator)) {
+		if excludeDirs[part] {
+			return "", false
+		}
+	}
+	if excludeDirs[filepath.Base(relPath)] {
+		return "", false
+	}
+	if _, err := resolver.Resolve(root, ev.Name); err != nil {
+		return "", false
+	}
+	if ev.Has(fsnotify.Create) {
+		if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+			_ = addWatchDirs(watcher, resolver, ev.Name, excludeDirs)
+		}
+	}
+	relPath = filepath.ToSlash(relPath)
+	if pattern != "" && !matchesGlobPattern(pattern, relPath, filepath.Base(relPath)) {
+		return "", false
+	}
+	return strings.ToLower(ev.Op.String()) + " " + relPath, true
+}
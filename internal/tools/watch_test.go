@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjkoo/boris/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// watchTestClient connects a client to a server whose session is returned,
+// forwarding progress messages to the returned channel.
+func watchTestClient(t *testing.T, dir string) (*mcp.ClientSession, *session.Session, <-chan string) {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "boris-test", Version: "test"}, nil)
+	sess := session.New(dir)
+	RegisterAll(server, testResolver(t), sess, testConfig())
+	t.Cleanup(sess.Close)
+
+	messages := make(chan string, 100)
+	ctx := context.Background()
+	t1, t2 := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, t1, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			messages <- req.Params.Message
+		},
+	})
+	cs, err := client.Connect(ctx, t2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs, sess, messages
+}
+
+// startWatch calls the watch tool in the background and waits for its
+// initial progress message. The call's result is delivered on the returned
+// channel.
+func startWatch(t *testing.T, cs *mcp.ClientSession, messages <-chan string, args map[string]any) <-chan *mcp.CallToolResult {
+	t.Helper()
+	done := make(chan *mcp.CallToolResult, 1)
+	go func() {
+		res, err := cs.CallTool(context.Background(), &mcp.CallToolParams{
+			Meta:      mcp.Meta{"progressToken": "tok"},
+			Name:      "watch",
+			Arguments: args,
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- res
+	}()
+	waitForMessage(t, messages, "task_id: ")
+	return done
+}
+
+func waitForMessage(t *testing.T, messages <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-messages:
+			if strings.Contains(msg, want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for progress message containing %q", want)
+		}
+	}
+}
+
+func waitForResult(t *testing.T, done <-chan *mcp.CallToolResult) *mcp.CallToolResult {
+	t.Helper()
+	select {
+	case res := <-done:
+		if res == nil {
+			t.FailNow()
+		}
+		return res
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch did not return")
+		return nil
+	}
+}
+
+func TestWatchReportsEvents(t *testing.T) {
+	tmp := t.TempDir()
+	cs, sess, messages := watchTestClient(t, tmp)
+	done := startWatch(t, cs, messages, map[string]any{"pattern": "*.go"})
+
+	if sess.TaskCount() != 1 {
+		t.Fatalf("TaskCount = %d, want 1 while watching", sess.TaskCount())
+	}
+
+	os.WriteFile(filepath.Join(tmp, "skip.txt"), []byte("x"), 0o644)
+	os.MkdirAll(filepath.Join(tmp, "sub"), 0o755)
+	time.Sleep(100 * time.Millisecond) // let the watcher pick up sub
+	os.WriteFile(filepath.Join(tmp, "sub", "a.go"), []byte("package sub\n"), 0o644)
+	waitForMessage(t, messages, "create sub/a.go")
+
+	sess.Close()
+	text := resultText(waitForResult(t, done))
+	if !strings.Contains(text, "status: stopped") {
+		t.Errorf("expected stopped status, got:\n%s", text)
+	}
+	if !strings.Contains(text, "create sub/a.go") {
+		t.Errorf("expected create event for sub/a.go, got:\n%s", text)
+	}
+	if strings.Contains(text, "skip.txt") {
+		t.Errorf("skip.txt does not match the pattern and should not be reported:\n%s", text)
+	}
+	if sess.TaskCount() != 0 {
+		t.Errorf("TaskCount = %d after session close, want 0", sess.TaskCount())
+	}
+}
+
+func TestWatchKillAllTasks(t *testing.T) {
+	tmp := t.TempDir()
+	cs, sess, messages := watchTestClient(t, tmp)
+	done := startWatch(t, cs, messages, map[string]any{})
+
+	if ids := sess.KillTasks(); len(ids) != 1 {
+		t.Fatalf("KillTasks = %v, want one watch task", ids)
+	}
+	text := resultText(waitForResult(t, done))
+	if !strings.Contains(text, "events: 0") {
+		t.Errorf("expected no events, got:\n%s", text)
+	}
+}
+
+func TestWatchTimeout(t *testing.T) {
+	tmp := t.TempDir()
+	cs, sess, messages := watchTestClient(t, tmp)
+	done := startWatch(t, cs, messages, map[string]any{"timeout": 1})
+
+	text := resultText(waitForResult(t, done))
+	if !strings.Contains(text, "status: timed out") {
+		t.Errorf("expected timed out status, got:\n%s", text)
+	}
+	if sess.TaskCount() != 0 {
+		t.Errorf("TaskCount = %d after timeout, want 0", sess.TaskCount())
+	}
+}
+
+func TestWatchInvalidInput(t *testing.T) {
+	tmp := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "file.txt"), []byte("x"), 0o644)
+	sess := session.New(tmp)
+	handler := watchHandler(sess, testResolver(t), testConfig())
+
+	for _, tt := range []struct {
+		name string
+		args WatchArgs
+		code string
+	}{
+		{"missing dir", WatchArgs{Path: "missing"}, ErrPathNotFound},
+		{"not a dir", WatchArgs{Path: "file.txt"}, ErrInvalidInput},
+		{"bad pattern", WatchArgs{Pattern: "[a"}, ErrInvalidInput},
+		{"negative timeout", WatchArgs{Timeout: -1}, ErrInvalidInput},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _, _ := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}, tt.args)
+			if !hasErrorCode(res, tt.code) {
+				t.Errorf("expected %s, got %s", tt.code, resultText(res))
+			}
+		})
+	}
+}